@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// ロール（下に行くほど強い権限を持ち、上位のロールは下位のロールの操作もできる）
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleLevels = map[string]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// TokenQueryParam はAuthorizationヘッダーを付けられないGETリクエスト（WebSocket・EventSource）でIDトークンを渡すクエリ
+const TokenQueryParam = "access_token"
+
+// ValidRole はroleが定義済みのロールかを返す
+func ValidRole(role string) bool {
+	_, ok := roleLevels[role]
+	return ok
+}
+
+// HasRole はroleがrequired以上の権限を持つかを返す
+func HasRole(role, required string) bool {
+	return ValidRole(role) && roleLevels[role] >= roleLevels[required]
+}
+
+// RoleStore はユーザーのロールと利用停止状態を取得する（存在しない場合はsql.ErrNoRows）
+type RoleStore interface {
+	LookupRole(uid string) (role string, suspended bool, err error)
+}
+
+// Actor は認可されたリクエストの操作者
+type Actor struct {
+	UID  string
+	Role string
+}
+
+type actorKey struct{}
+
+// WithActor は操作者をcontextに入れる
+func WithActor(ctx context.Context, actor *Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext はRequireRoleが入れた操作者を取り出す
+func ActorFromContext(ctx context.Context) (*Actor, bool) {
+	actor, ok := ctx.Value(actorKey{}).(*Actor)
+	return actor, ok
+}
+
+// RequireRole は操作者がrequired以上のロールを持つ場合だけnextを呼ぶミドルウェア
+// 操作者のuidは検証したIDトークン（Authorization: Bearer）から取り出す。クライアントが指定したuidは使わない
+// 利用停止中のユーザーはロールに関係なく拒否する
+func RequireRole(verifier TokenVerifier, store RoleStore, required string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if token == "" {
+			writeJSONError(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		uid, err := verifier.VerifyIDToken(r.Context(), token)
+		if errors.Is(err, ErrInvalidToken) {
+			writeJSONError(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			log.Printf("failed to verify ID token: %v", err)
+			writeJSONError(w, "Failed to verify token", http.StatusInternalServerError)
+			return
+		}
+
+		role, suspended, err := store.LookupRole(uid)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			log.Printf("failed to look up role for uid=%s: %v", uid, err)
+			writeJSONError(w, "Failed to check permission", http.StatusInternalServerError)
+			return
+		}
+		if suspended || !HasRole(role, required) {
+			writeJSONError(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		next(w, r.WithContext(WithActor(r.Context(), &Actor{UID: uid, Role: role})))
+	}
+}
+
+// OptionalUser はIDトークンがあれば検証し、そのユーザーを操作者としてcontextに入れてnextを呼ぶミドルウェア
+// 未ログインでも使える公開API用。トークンがなければ操作者なしで呼ぶ（不正なトークンは401）
+// 存在しない・利用停止中・退会済みのユーザーも操作者なしとして扱う
+func OptionalUser(verifier TokenVerifier, store RoleStore, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if token == "" {
+			next(w, r)
+			return
+		}
+		uid, err := verifier.VerifyIDToken(r.Context(), token)
+		if errors.Is(err, ErrInvalidToken) {
+			writeJSONError(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			log.Printf("failed to verify ID token: %v", err)
+			writeJSONError(w, "Failed to verify token", http.StatusInternalServerError)
+			return
+		}
+
+		role, suspended, err := store.LookupRole(uid)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && suspended) {
+			next(w, r)
+			return
+		}
+		if err != nil {
+			log.Printf("failed to look up role for uid=%s: %v", uid, err)
+			writeJSONError(w, "Failed to check permission", http.StatusInternalServerError)
+			return
+		}
+		next(w, r.WithContext(WithActor(r.Context(), &Actor{UID: uid, Role: role})))
+	}
+}
+
+// ViewerUID はOptionalUser・RequireRoleが入れた操作者のuidを返す（未ログインなら空文字列）
+func ViewerUID(ctx context.Context) string {
+	if actor, ok := ActorFromContext(ctx); ok {
+		return actor.UID
+	}
+	return ""
+}
+
+// bearerToken はAuthorizationヘッダーのIDトークンを返す
+// ヘッダーを付けられないGETリクエストに限り、クエリの access_token も受け付ける
+func bearerToken(r *http.Request) string {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	if r.Method == http.MethodGet {
+		return r.URL.Query().Get(TokenQueryParam)
+	}
+	return ""
+}
+
+func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeRoleStore はテスト用のRoleStore
+type fakeRoleStore struct {
+	roles     map[string]string
+	suspended map[string]bool
+	err       error
+}
+
+func (f *fakeRoleStore) LookupRole(uid string) (string, bool, error) {
+	if f.err != nil {
+		return "", false, f.err
+	}
+	role, ok := f.roles[uid]
+	if !ok {
+		return "", false, sql.ErrNoRows
+	}
+	return role, f.suspended[uid], nil
+}
+
+// TestHasRole 上位のロールは下位のロールの権限を持つ
+func TestHasRole(t *testing.T) {
+	cases := []struct {
+		role, required string
+		want           bool
+	}{
+		{RoleUser, RoleUser, true},
+		{RoleUser, RoleModerator, false},
+		{RoleModerator, RoleModerator, true},
+		{RoleModerator, RoleAdmin, false},
+		{RoleAdmin, RoleModerator, true},
+		{RoleAdmin, RoleAdmin, true},
+		{"", RoleUser, false},
+		{"root", RoleUser, false},
+	}
+	for _, c := range cases {
+		if got := HasRole(c.role, c.required); got != c.want {
+			t.Errorf("HasRole(%q, %q) = %v, want %v", c.role, c.required, got, c.want)
+		}
+	}
+}
+
+// fakeVerifier はテスト用のTokenVerifier（トークン -> uid）
+type fakeVerifier struct {
+	uids map[string]string
+	err  error
+}
+
+func (f *fakeVerifier) VerifyIDToken(ctx context.Context, token string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	uid, ok := f.uids[token]
+	if !ok {
+		return "", ErrInvalidToken
+	}
+	return uid, nil
+}
+
+// TestRequireRole IDトークンのユーザーのロールと利用停止状態に応じてリクエストを通す・拒否する
+func TestRequireRole(t *testing.T) {
+	store := &fakeRoleStore{
+		roles: map[string]string{
+			"alice": RoleUser,
+			"mod":   RoleModerator,
+			"admin": RoleAdmin,
+			"gone":  RoleAdmin,
+		},
+		suspended: map[string]bool{"gone": true},
+	}
+	verifier := &fakeVerifier{uids: map[string]string{
+		"alice-token":  "alice",
+		"mod-token":    "mod",
+		"admin-token":  "admin",
+		"gone-token":   "gone",
+		"nobody-token": "nobody",
+	}}
+
+	var gotActor *Actor
+	handler := RequireRole(verifier, store, RoleModerator, func(w http.ResponseWriter, r *http.Request) {
+		gotActor, _ = ActorFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cases := []struct {
+		name    string
+		method  string
+		header  map[string]string
+		query   string
+		want    int
+		wantUID string
+	}{
+		{"no token", http.MethodGet, nil, "", http.StatusUnauthorized, ""},
+		{"uid header is not trusted", http.MethodGet, map[string]string{"X-User-UID": "admin"}, "uid=admin", http.StatusUnauthorized, ""},
+		{"invalid token", http.MethodGet, map[string]string{"Authorization": "Bearer forged"}, "", http.StatusUnauthorized, ""},
+		{"unknown user", http.MethodGet, map[string]string{"Authorization": "Bearer nobody-token"}, "", http.StatusForbidden, ""},
+		{"insufficient role", http.MethodGet, map[string]string{"Authorization": "Bearer alice-token"}, "", http.StatusForbidden, ""},
+		{"suspended admin", http.MethodGet, map[string]string{"Authorization": "Bearer gone-token"}, "", http.StatusForbidden, ""},
+		{"moderator via header", http.MethodGet, map[string]string{"Authorization": "Bearer mod-token"}, "", http.StatusOK, "mod"},
+		{"uid comes from token", http.MethodPost, map[string]string{"Authorization": "bearer admin-token", "X-User-UID": "alice"}, "uid=alice", http.StatusOK, "admin"},
+		{"token via query on GET", http.MethodGet, nil, "access_token=admin-token", http.StatusOK, "admin"},
+		{"token via query on POST", http.MethodPost, nil, "access_token=admin-token", http.StatusUnauthorized, ""},
+	}
+	for _, c := range cases {
+		gotActor = nil
+		req := httptest.NewRequest(c.method, "/api/v1/admin/items", nil)
+		for k, v := range c.header {
+			req.Header.Set(k, v)
+		}
+		req.URL.RawQuery = c.query
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("%s: expected status %d, got %d", c.name, c.want, rec.Code)
+		}
+		if c.want == http.StatusOK && (gotActor == nil || gotActor.UID != c.wantUID) {
+			t.Errorf("%s: expected actor %s in context, got %+v", c.name, c.wantUID, gotActor)
+		}
+		if c.want != http.StatusOK && gotActor != nil {
+			t.Errorf("%s: handler should not be called", c.name)
+		}
+	}
+}
+
+// TestRequireRole_StoreError ロールの取得・トークンの検証に失敗した場合は500
+func TestRequireRole_StoreError(t *testing.T) {
+	cases := []struct {
+		name     string
+		verifier *fakeVerifier
+		store    *fakeRoleStore
+	}{
+		{"store", &fakeVerifier{uids: map[string]string{"admin-token": "admin"}}, &fakeRoleStore{err: errors.New("db down")}},
+		{"verifier", &fakeVerifier{err: errors.New("failed to fetch public keys")}, &fakeRoleStore{}},
+	}
+	for _, c := range cases {
+		handler := RequireRole(c.verifier, c.store, RoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("%s: handler should not be called", c.name)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/items", nil)
+		req.Header.Set("Authorization", "Bearer admin-token")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("%s: expected status 500, got %d", c.name, rec.Code)
+		}
+	}
+}
+
+// TestOptionalUser トークンがなければ操作者なしで通し、あればそのユーザーを操作者にする
+func TestOptionalUser(t *testing.T) {
+	store := &fakeRoleStore{
+		roles:     map[string]string{"alice": RoleUser, "gone": RoleUser},
+		suspended: map[string]bool{"gone": true},
+	}
+	verifier := &fakeVerifier{uids: map[string]string{
+		"alice-token":  "alice",
+		"gone-token":   "gone",
+		"nobody-token": "nobody",
+	}}
+
+	called := false
+	var viewer string
+	handler := OptionalUser(verifier, store, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		viewer = ViewerUID(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cases := []struct {
+		name       string
+		header     map[string]string
+		query      string
+		want       int
+		wantViewer string
+	}{
+		{"anonymous", nil, "", http.StatusOK, ""},
+		{"viewer_uid is not trusted", nil, "viewer_uid=alice", http.StatusOK, ""},
+		{"user", map[string]string{"Authorization": "Bearer alice-token"}, "viewer_uid=bob", http.StatusOK, "alice"},
+		{"token via query", nil, "access_token=alice-token", http.StatusOK, "alice"},
+		{"unknown user", map[string]string{"Authorization": "Bearer nobody-token"}, "", http.StatusOK, ""},
+		{"suspended user", map[string]string{"Authorization": "Bearer gone-token"}, "", http.StatusOK, ""},
+		{"invalid token", map[string]string{"Authorization": "Bearer forged"}, "", http.StatusUnauthorized, ""},
+	}
+	for _, c := range cases {
+		called, viewer = false, ""
+		req := httptest.NewRequest(http.MethodGet, "/getItems/1", nil)
+		for k, v := range c.header {
+			req.Header.Set(k, v)
+		}
+		req.URL.RawQuery = c.query
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("%s: expected status %d, got %d", c.name, c.want, rec.Code)
+		}
+		if called != (c.want == http.StatusOK) {
+			t.Errorf("%s: unexpected handler call: %v", c.name, called)
+		}
+		if viewer != c.wantViewer {
+			t.Errorf("%s: expected viewer %q, got %q", c.name, c.wantViewer, viewer)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidToken はIDトークンが不正・期限切れの場合のエラー
+var ErrInvalidToken = errors.New("invalid ID token")
+
+// TokenVerifier はIDトークンを検証し、トークンのユーザーのuidを返す
+// トークンが不正ならErrInvalidTokenを返す（公開鍵の取得の失敗などはそれ以外のエラー）
+type TokenVerifier interface {
+	VerifyIDToken(ctx context.Context, token string) (string, error)
+}
+
+// firebaseCertsURL はFirebase AuthのIDトークンの署名を検証する公開鍵（X.509証明書）の配布先
+const firebaseCertsURL = "https://www.googleapis.com/robot/v1/metadata/x509/securetoken@system.gserviceaccount.com"
+
+// clockSkew はトークンの発行・期限の時刻を比べるときに許容する時計のずれ
+const clockSkew = time.Minute
+
+// FirebaseVerifier はFirebase AuthのIDトークン（RS256のJWT）を検証する
+// 公開鍵はCache-Controlのmax-ageの間キャッシュする
+type FirebaseVerifier struct {
+	projectID string
+	certsURL  string
+	client    *http.Client
+	now       func() time.Time
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey // kid -> 公開鍵
+	expires time.Time
+}
+
+func NewFirebaseVerifier(projectID string) *FirebaseVerifier {
+	return &FirebaseVerifier{
+		projectID: projectID,
+		certsURL:  firebaseCertsURL,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		now:       time.Now,
+	}
+}
+
+// NewVerifierFromEnv は環境変数 FIREBASE_PROJECT_ID のプロジェクトのIDトークンを検証するVerifierを生成する
+func NewVerifierFromEnv() (*FirebaseVerifier, error) {
+	projectID := os.Getenv("FIREBASE_PROJECT_ID")
+	if projectID == "" {
+		return nil, errors.New("FIREBASE_PROJECT_ID is required")
+	}
+	return NewFirebaseVerifier(projectID), nil
+}
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type tokenClaims struct {
+	Iss      string `json:"iss"`
+	Aud      string `json:"aud"`
+	Sub      string `json:"sub"`
+	Iat      int64  `json:"iat"`
+	Exp      int64  `json:"exp"`
+	AuthTime int64  `json:"auth_time"`
+}
+
+// VerifyIDToken は署名・発行者・対象のプロジェクト・有効期限を検証し、uid（sub）を返す
+func (v *FirebaseVerifier) VerifyIDToken(ctx context.Context, token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", err
+	}
+	if header.Alg != "RS256" || header.Kid == "" {
+		return "", fmt.Errorf("%w: unexpected alg %q", ErrInvalidToken, header.Alg)
+	}
+	var claims tokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+
+	key, err := v.publicKey(ctx, header.Kid)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return "", fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	now := v.now()
+	switch {
+	case claims.Aud != v.projectID:
+		return "", fmt.Errorf("%w: unexpected audience %q", ErrInvalidToken, claims.Aud)
+	case claims.Iss != "https://securetoken.google.com/"+v.projectID:
+		return "", fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Iss)
+	case claims.Sub == "" || len(claims.Sub) > 128:
+		return "", fmt.Errorf("%w: invalid subject", ErrInvalidToken)
+	case now.After(time.Unix(claims.Exp, 0).Add(clockSkew)):
+		return "", fmt.Errorf("%w: token expired", ErrInvalidToken)
+	case time.Unix(claims.Iat, 0).After(now.Add(clockSkew)), time.Unix(claims.AuthTime, 0).After(now.Add(clockSkew)):
+		return "", fmt.Errorf("%w: token issued in the future", ErrInvalidToken)
+	}
+	return claims.Sub, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: malformed segment", ErrInvalidToken)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: malformed segment", ErrInvalidToken)
+	}
+	return nil
+}
+
+// publicKey はkidの公開鍵を返す（キャッシュが期限切れなら取得し直す）
+func (v *FirebaseVerifier) publicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.keys == nil || !v.now().Before(v.expires) {
+		keys, maxAge, err := v.fetchKeys(ctx)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = keys
+		v.expires = v.now().Add(maxAge)
+	}
+	key, ok := v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key id %q", ErrInvalidToken, kid)
+	}
+	return key, nil
+}
+
+func (v *FirebaseVerifier) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.certsURL, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch public keys: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("failed to fetch public keys: status %d", resp.StatusCode)
+	}
+
+	var certs map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&certs); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode public keys: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(certs))
+	for kid, certPEM := range certs {
+		block, _ := pem.Decode([]byte(certPEM))
+		if block == nil {
+			return nil, 0, fmt.Errorf("failed to decode certificate %s", kid)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to parse certificate %s: %w", kid, err)
+		}
+		key, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, 0, fmt.Errorf("certificate %s does not have an RSA key", kid)
+		}
+		keys[kid] = key
+	}
+	return keys, maxAge(resp.Header.Get("Cache-Control")), nil
+}
+
+// maxAge はCache-Controlのmax-ageを返す（なければ1時間）
+func maxAge(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(directive), "=")
+		if !ok || !strings.EqualFold(name, "max-age") {
+			continue
+		}
+		if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return time.Hour
+}
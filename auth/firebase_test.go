@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeCertServer はFirebaseの公開鍵の配布先の代わりに、テスト用の鍵の証明書を返す
+type fakeCertServer struct {
+	*httptest.Server
+	key       *rsa.PrivateKey
+	kid       string
+	fetches   atomic.Int32
+	failFetch atomic.Bool
+}
+
+func newFakeCertServer(t *testing.T) *fakeCertServer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "securetoken.system.gserviceaccount.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+
+	s := &fakeCertServer{key: key, kid: "test-kid"}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		if s.failFetch.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "public, max-age=600, must-revalidate")
+		json.NewEncoder(w).Encode(map[string]string{s.kid: certPEM})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// sign はclaimsをテスト用の鍵でRS256署名したJWTを返す
+func (s *fakeCertServer) sign(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func newTestVerifier(server *fakeCertServer, now time.Time) *FirebaseVerifier {
+	v := NewFirebaseVerifier("test-project")
+	v.certsURL = server.URL
+	v.now = func() time.Time { return now }
+	return v
+}
+
+func validClaims(now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"iss":       "https://securetoken.google.com/test-project",
+		"aud":       "test-project",
+		"sub":       "alice",
+		"iat":       now.Add(-time.Minute).Unix(),
+		"auth_time": now.Add(-time.Hour).Unix(),
+		"exp":       now.Add(time.Hour).Unix(),
+	}
+}
+
+// TestFirebaseVerifier_Valid 正しく署名されたトークンのsubをuidとして返す
+func TestFirebaseVerifier_Valid(t *testing.T) {
+	server := newFakeCertServer(t)
+	now := time.Now()
+	v := newTestVerifier(server, now)
+
+	token := server.sign(t, server.key, server.kid, validClaims(now))
+	for i := 0; i < 3; i++ {
+		uid, err := v.VerifyIDToken(context.Background(), token)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if uid != "alice" {
+			t.Errorf("expected uid alice, got %s", uid)
+		}
+	}
+	// 公開鍵はmax-ageの間キャッシュする
+	if got := server.fetches.Load(); got != 1 {
+		t.Errorf("expected keys to be fetched once, got %d", got)
+	}
+}
+
+// TestFirebaseVerifier_Invalid 署名・発行者・対象・期限が不正なトークンはErrInvalidToken
+func TestFirebaseVerifier_Invalid(t *testing.T) {
+	server := newFakeCertServer(t)
+	now := time.Now()
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := validClaims(now)
+		claims[key] = value
+		return claims
+	}
+	valid := server.sign(t, server.key, server.kid, validClaims(now))
+	parts := strings.Split(valid, ".")
+
+	cases := []struct {
+		name  string
+		token string
+	}{
+		{"malformed", "not-a-jwt"},
+		{"wrong key", server.sign(t, otherKey, server.kid, validClaims(now))},
+		{"unknown kid", server.sign(t, server.key, "other-kid", validClaims(now))},
+		{"tampered payload", parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin"}`)) + "." + parts[2]},
+		{"other project", server.sign(t, server.key, server.kid, with("aud", "other-project"))},
+		{"other issuer", server.sign(t, server.key, server.kid, with("iss", "https://securetoken.google.com/other-project"))},
+		{"no subject", server.sign(t, server.key, server.kid, with("sub", ""))},
+		{"expired", server.sign(t, server.key, server.kid, with("exp", now.Add(-time.Hour).Unix()))},
+		{"issued in the future", server.sign(t, server.key, server.kid, with("iat", now.Add(time.Hour).Unix()))},
+	}
+	v := newTestVerifier(server, now)
+	for _, c := range cases {
+		if _, err := v.VerifyIDToken(context.Background(), c.token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", c.name, err)
+		}
+	}
+}
+
+// TestFirebaseVerifier_FetchError 公開鍵を取得できない場合はErrInvalidToken以外のエラー（500にする）
+func TestFirebaseVerifier_FetchError(t *testing.T) {
+	server := newFakeCertServer(t)
+	server.failFetch.Store(true)
+	now := time.Now()
+	v := newTestVerifier(server, now)
+
+	_, err := v.VerifyIDToken(context.Background(), server.sign(t, server.key, server.kid, validClaims(now)))
+	if err == nil || errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected fetch error, got %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	auditDao "uttc-hackathon-backend/dao/audit"
+	"uttc-hackathon-backend/events"
+)
+
+// Item は管理画面用の商品（非表示の商品も含む）
+type Item struct {
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	Price       int       `json:"price"`
+	UID         string    `json:"uid"`
+	Status      string    `json:"status"`
+	Hidden      bool      `json:"hidden"`
+	ChainItemID *int64    `json:"chain_item_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ErrStatusChanged は更新中に商品の状態が変わっていた場合のエラー
+var ErrStatusChanged = errors.New("item status has changed")
+
+// ErrActiveOrder は商品に取引中（completed / cancelled / refunded 以外）の注文がある場合のエラー
+var ErrActiveOrder = errors.New("item has an active order")
+
+// ItemFilter は商品一覧の検索条件
+type ItemFilter struct {
+	Status string // 空なら全て
+	Hidden *bool  // nilなら全て
+}
+
+// AdminDAOInterface はモック化のためのインターフェース
+type AdminDAOInterface interface {
+	LookupRole(uid string) (role string, suspended bool, err error)
+	ListItems(filter ItemFilter, limit, offset int) ([]*Item, error)
+	GetItemStatus(itemID int) (string, error)
+	ForceItemStatus(itemID int, from, to, actorUID, reason string) error
+	SetSuspended(uid string, suspended bool, actorUID, reason string) error
+	SetRole(uid, role, actorUID, reason string) (before string, err error)
+}
+
+type AdminDAO struct {
+	db  *sql.DB
+	bus *events.Bus
+}
+
+// NewAdminDAO は商品の状態の変化をbusへ送るAdminDAOを生成する（busはnilでもよい）
+func NewAdminDAO(db *sql.DB, bus *events.Bus) *AdminDAO {
+	return &AdminDAO{db: db, bus: bus}
+}
+
+// LookupRole はユーザーのロールと利用停止中かを返す（存在しない場合はsql.ErrNoRows、退会済みは利用停止と同じ扱い）
+func (d *AdminDAO) LookupRole(uid string) (string, bool, error) {
+	var role string
+	var suspendedAt, deletedAt sql.NullTime
+	err := d.db.QueryRow("SELECT role, suspended_at, deleted_at FROM users WHERE uid = ?", uid).Scan(&role, &suspendedAt, &deletedAt)
+	if err != nil {
+		return "", false, err
+	}
+	return role, suspendedAt.Valid || deletedAt.Valid, nil
+}
+
+// ListItems は商品を新しい順に取得する
+func (d *AdminDAO) ListItems(filter ItemFilter, limit, offset int) ([]*Item, error) {
+	query := `
+		SELECT id, title, price, uid, status, hidden, chain_item_id, created_at, updated_at
+		FROM items
+		WHERE (? = '' OR status = ?)
+		  AND (? IS NULL OR hidden = ?)
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`
+	var hidden interface{}
+	if filter.Hidden != nil {
+		hidden = *filter.Hidden
+	}
+	rows, err := d.db.Query(query, filter.Status, filter.Status, hidden, hidden, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []*Item{}
+	for rows.Next() {
+		var item Item
+		var priceStr string
+		var status sql.NullString
+		var chainItemID sql.NullInt64
+		if err := rows.Scan(&item.ID, &item.Title, &priceStr, &item.UID, &status, &item.Hidden, &chainItemID, &item.CreatedAt, &item.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan item row: %w", err)
+		}
+		price, err := strconv.Atoi(priceStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert price '%s' to int for item id %d: %w", priceStr, item.ID, err)
+		}
+		item.Price = price
+		item.Status = status.String
+		if item.Status == "" {
+			item.Status = "listed" // デフォルト値
+		}
+		if chainItemID.Valid {
+			val := chainItemID.Int64
+			item.ChainItemID = &val
+		}
+		items = append(items, &item)
+	}
+	return items, rows.Err()
+}
+
+// GetItemStatus は商品の状態を取得する（存在しない場合はsql.ErrNoRows）
+func (d *AdminDAO) GetItemStatus(itemID int) (string, error) {
+	var status string
+	if err := d.db.QueryRow("SELECT COALESCE(status, 'listed') FROM items WHERE id = ?", itemID).Scan(&status); err != nil {
+		return "", err
+	}
+	return status, nil
+}
+
+// ForceItemStatus は管理者の操作で商品をfromからtoへ遷移させ、同じトランザクションで監査ログに残す
+// 遷移の妥当性は呼び出し側（itemStatus.Lifecycle.CheckOverride）で検証する
+// 注文と食い違わないように、取引中の注文がある商品は変更しない（先に注文をキャンセル・返金する）
+// 商品が存在しない場合はsql.ErrNoRows、更新中に状態が変わっていた場合はErrStatusChanged、取引中の注文がある場合はErrActiveOrder
+func (d *AdminDAO) ForceItemStatus(itemID int, from, to, actorUID, reason string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before sql.NullString
+	var sellerUID, buyerUID string
+	row := tx.QueryRow(`
+		SELECT i.status, i.uid, COALESCE((
+			SELECT o.buyer_uid FROM orders o
+			WHERE o.item_id = i.id AND o.status NOT IN ('cancelled', 'refunded')
+			ORDER BY o.id DESC LIMIT 1
+		), '')
+		FROM items i WHERE i.id = ? FOR UPDATE
+	`, itemID)
+	if err := row.Scan(&before, &sellerUID, &buyerUID); err != nil {
+		return err
+	}
+	if before.String == "" {
+		before.String = "listed" // デフォルト値
+	}
+	if before.String != from {
+		return ErrStatusChanged
+	}
+	var orderID int64
+	err = tx.QueryRow(`
+		SELECT id FROM orders
+		WHERE item_id = ? AND status NOT IN ('completed', 'cancelled', 'refunded')
+		ORDER BY id DESC LIMIT 1 FOR UPDATE
+	`, itemID).Scan(&orderID)
+	if err == nil {
+		return ErrActiveOrder
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to check active order: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE items SET status = ? WHERE id = ?", to, itemID); err != nil {
+		return fmt.Errorf("failed to update item status: %w", err)
+	}
+	err = auditDao.Insert(tx, &auditDao.Event{
+		ActorType:  auditDao.ActorAdmin,
+		ActorUID:   actorUID,
+		Source:     auditDao.SourceAdmin,
+		Action:     "item.force_status",
+		EntityType: "item",
+		EntityID:   strconv.Itoa(itemID),
+		Before:     auditDao.Values(map[string]string{"status": before.String}),
+		After:      auditDao.Values(map[string]string{"status": to}),
+		Reason:     reason,
+	})
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	d.bus.Publish(events.ItemStatusChanged, itemID, []string{sellerUID, buyerUID},
+		events.StatusChange{From: from, To: to})
+	return nil
+}
+
+// SetSuspended はユーザーを利用停止（または解除）にし、監査ログに残す（存在しない場合はsql.ErrNoRows）
+func (d *AdminDAO) SetSuspended(uid string, suspended bool, actorUID, reason string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before sql.NullTime
+	if err := tx.QueryRow("SELECT suspended_at FROM users WHERE uid = ? FOR UPDATE", uid).Scan(&before); err != nil {
+		return err
+	}
+	query := "UPDATE users SET suspended_at = NULL WHERE uid = ?"
+	action := "user.unsuspend"
+	if suspended {
+		query = "UPDATE users SET suspended_at = COALESCE(suspended_at, CURRENT_TIMESTAMP) WHERE uid = ?"
+		action = "user.suspend"
+	}
+	if _, err := tx.Exec(query, uid); err != nil {
+		return fmt.Errorf("failed to update suspension: %w", err)
+	}
+	err = auditDao.Insert(tx, &auditDao.Event{
+		ActorType:  auditDao.ActorAdmin,
+		ActorUID:   actorUID,
+		Source:     auditDao.SourceAdmin,
+		Action:     action,
+		EntityType: "user",
+		EntityID:   uid,
+		Before:     auditDao.Values(map[string]bool{"suspended": before.Valid}),
+		After:      auditDao.Values(map[string]bool{"suspended": suspended}),
+		Reason:     reason,
+	})
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+// SetRole はユーザーのロールを変更し、監査ログに残す
+// 変更前のロールを返す（存在しない場合はsql.ErrNoRows）
+func (d *AdminDAO) SetRole(uid, role, actorUID, reason string) (string, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before string
+	if err := tx.QueryRow("SELECT role FROM users WHERE uid = ? FOR UPDATE", uid).Scan(&before); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec("UPDATE users SET role = ? WHERE uid = ?", role, uid); err != nil {
+		return "", fmt.Errorf("failed to update role: %w", err)
+	}
+	err = auditDao.Insert(tx, &auditDao.Event{
+		ActorType:  auditDao.ActorAdmin,
+		ActorUID:   actorUID,
+		Source:     auditDao.SourceAdmin,
+		Action:     "user.set_role",
+		EntityType: "user",
+		EntityID:   uid,
+		Before:     auditDao.Values(map[string]string{"role": before}),
+		After:      auditDao.Values(map[string]string{"role": role}),
+		Reason:     reason,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit: %w", err)
+	}
+	return before, nil
+}
@@ -0,0 +1,205 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 監査ログの操作元
+const (
+	SourceAPI        = "api"
+	SourceWebhook    = "webhook"
+	SourceAdmin      = "admin"
+	SourceModeration = "moderation"
+)
+
+// 操作した主体の種類
+const (
+	ActorUser    = "user"
+	ActorWallet  = "wallet"
+	ActorWebhook = "webhook"
+	ActorAdmin   = "admin"
+)
+
+// Actor は変更を行った主体と操作元
+type Actor struct {
+	Type   string
+	UID    string
+	Wallet string
+	Source string
+}
+
+// UserActor は本人がAPIで行った変更
+func UserActor(uid string) Actor {
+	return Actor{Type: ActorUser, UID: uid, Source: SourceAPI}
+}
+
+// WebhookActor はonchainイベントによる変更（ウォレットが分かればwallet、分からなければwebhook）
+func WebhookActor(wallet, uid string) Actor {
+	if wallet == "" {
+		return Actor{Type: ActorWebhook, UID: uid, Source: SourceWebhook}
+	}
+	return Actor{Type: ActorWallet, UID: uid, Wallet: wallet, Source: SourceWebhook}
+}
+
+// AdminActor は管理者・モデレーターによる変更（sourceはSourceAdminかSourceModeration）
+func AdminActor(uid, source string) Actor {
+	return Actor{Type: ActorAdmin, UID: uid, Source: source}
+}
+
+// Event は監査ログの1件
+type Event struct {
+	ID          int64           `json:"id"`
+	ActorType   string          `json:"actor_type"`
+	ActorUID    string          `json:"actor_uid,omitempty"`
+	ActorWallet string          `json:"actor_wallet,omitempty"`
+	Source      string          `json:"source"`
+	Action      string          `json:"action"`
+	EntityType  string          `json:"entity_type"`
+	EntityID    string          `json:"entity_id"`
+	Before      json.RawMessage `json:"before,omitempty"`
+	After       json.RawMessage `json:"after,omitempty"`
+	Reason      string          `json:"reason,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// Filter は監査ログの検索条件（空のフィールドは条件にしない）
+type Filter struct {
+	EntityType string
+	EntityID   string
+	ActorUID   string
+	Action     string
+}
+
+// Execer は *sql.DB と *sql.Tx のどちらでも監査ログを書き込めるようにするためのインターフェース
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Values は変更前後の値をJSONにする（nilならNULL）
+func Values(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// Record はactorによる変更を監査ログに書き込む（before/afterはJSONにする）
+// 状態の変更と同じトランザクション（*sql.Tx）を渡すこと
+func Record(exec Execer, actor Actor, action, entityType, entityID string, before, after interface{}) error {
+	return Insert(exec, &Event{
+		ActorType:   actor.Type,
+		ActorUID:    actor.UID,
+		ActorWallet: actor.Wallet,
+		Source:      actor.Source,
+		Action:      action,
+		EntityType:  entityType,
+		EntityID:    entityID,
+		Before:      Values(before),
+		After:       Values(after),
+	})
+}
+
+// Insert は監査ログを書き込む
+// 状態の変更と同じトランザクション（*sql.Tx）を渡すこと
+func Insert(exec Execer, e *Event) error {
+	actorType := e.ActorType
+	if actorType == "" {
+		actorType = "system"
+	}
+	query := `
+		INSERT INTO audit_events (actor_type, actor_uid, actor_wallet, source, action, entity_type, entity_id, before_value, after_value, reason)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := exec.Exec(query, actorType, nullIfEmpty(e.ActorUID), nullIfEmpty(e.ActorWallet), e.Source, e.Action, e.EntityType, e.EntityID,
+		nullIfEmpty(string(e.Before)), nullIfEmpty(string(e.After)), nullIfEmpty(e.Reason))
+	if err != nil {
+		return fmt.Errorf("failed to insert audit event: %w", err)
+	}
+	return nil
+}
+
+// AuditDAOInterface はモック化のためのインターフェース
+type AuditDAOInterface interface {
+	ListEvents(filter Filter, limit, offset int) ([]*Event, error)
+}
+
+type AuditDAO struct {
+	db *sql.DB
+}
+
+func NewAuditDAO(db *sql.DB) *AuditDAO {
+	return &AuditDAO{db: db}
+}
+
+// ListEvents は監査ログを新しい順に取得する
+func (d *AuditDAO) ListEvents(filter Filter, limit, offset int) ([]*Event, error) {
+	var conditions []string
+	var args []interface{}
+	for _, c := range []struct {
+		column string
+		value  string
+	}{
+		{"entity_type", filter.EntityType},
+		{"entity_id", filter.EntityID},
+		{"actor_uid", filter.ActorUID},
+		{"action", filter.Action},
+	} {
+		if c.value != "" {
+			conditions = append(conditions, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	query := `
+		SELECT id, actor_type, actor_uid, actor_wallet, source, action, entity_type, entity_id, before_value, after_value, reason, created_at
+		FROM audit_events
+		` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`
+	args = append(args, limit, offset)
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*Event{}
+	for rows.Next() {
+		var e Event
+		var actorUID, actorWallet, before, after, reason sql.NullString
+		if err := rows.Scan(&e.ID, &e.ActorType, &actorUID, &actorWallet, &e.Source, &e.Action, &e.EntityType, &e.EntityID, &before, &after, &reason, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		e.ActorUID = actorUID.String
+		e.ActorWallet = actorWallet.String
+		if before.Valid {
+			e.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			e.After = json.RawMessage(after.String)
+		}
+		e.Reason = reason.String
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
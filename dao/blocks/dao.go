@@ -0,0 +1,111 @@
+package blocks
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// BlockedUser はブロック中のユーザー一覧の1件
+type BlockedUser struct {
+	UID       string    `json:"uid"`
+	Nickname  string    `json:"nickname"`
+	AvatarURL string    `json:"avatar_url"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+// BlockDAOInterface はモック化のためのインターフェース
+type BlockDAOInterface interface {
+	UserExists(uid string) (bool, error)
+	Block(blockerUID, blockedUID string) error
+	Unblock(blockerUID, blockedUID string) error
+	IsBlocking(blockerUID, blockedUID string) (bool, error)
+	GetBlockedUsers(uid string, limit, offset int) ([]*BlockedUser, error)
+}
+
+type BlockDAO struct {
+	db *sql.DB
+}
+
+func NewBlockDAO(db *sql.DB) *BlockDAO {
+	return &BlockDAO{db: db}
+}
+
+// UserExists はusersテーブルにuidが登録されているかを返す
+func (d *BlockDAO) UserExists(uid string) (bool, error) {
+	var exists bool
+	if err := d.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE uid = ?)", uid).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check user: %w", err)
+	}
+	return exists, nil
+}
+
+// Block はブロックし、お互いのフォローを解除する（既にブロック済みの場合も成功）
+func (d *BlockDAO) Block(blockerUID, blockedUID string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("INSERT IGNORE INTO blocks (blocker_uid, blocked_uid) VALUES (?, ?)", blockerUID, blockedUID); err != nil {
+		return fmt.Errorf("failed to insert block: %w", err)
+	}
+	query := `
+		DELETE FROM follows
+		WHERE (follower_uid = ? AND followee_uid = ?)
+		   OR (follower_uid = ? AND followee_uid = ?)
+	`
+	if _, err := tx.Exec(query, blockerUID, blockedUID, blockedUID, blockerUID); err != nil {
+		return fmt.Errorf("failed to delete follows: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Unblock はブロックを解除する（ブロックしていない場合も成功）
+func (d *BlockDAO) Unblock(blockerUID, blockedUID string) error {
+	_, err := d.db.Exec("DELETE FROM blocks WHERE blocker_uid = ? AND blocked_uid = ?", blockerUID, blockedUID)
+	if err != nil {
+		return fmt.Errorf("failed to delete block: %w", err)
+	}
+	return nil
+}
+
+func (d *BlockDAO) IsBlocking(blockerUID, blockedUID string) (bool, error) {
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM blocks WHERE blocker_uid = ? AND blocked_uid = ?)"
+	if err := d.db.QueryRow(query, blockerUID, blockedUID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check block: %w", err)
+	}
+	return exists, nil
+}
+
+// GetBlockedUsers はuidがブロックしているユーザーを新しい順に取得する
+func (d *BlockDAO) GetBlockedUsers(uid string, limit, offset int) ([]*BlockedUser, error) {
+	query := `
+		SELECT b.blocked_uid, u.nickname, COALESCE(u.avatar_thumbnail_url, u.avatar_url), b.created_at
+		FROM blocks b
+		LEFT JOIN users u ON u.uid = b.blocked_uid
+		WHERE b.blocker_uid = ?
+		ORDER BY b.created_at DESC, b.id DESC
+		LIMIT ? OFFSET ?
+	`
+	rows, err := d.db.Query(query, uid, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blocks: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*BlockedUser{}
+	for rows.Next() {
+		var u BlockedUser
+		var nickname, avatarURL sql.NullString
+		if err := rows.Scan(&u.UID, &nickname, &avatarURL, &u.BlockedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan block row: %w", err)
+		}
+		u.Nickname = nickname.String
+		u.AvatarURL = avatarURL.String
+		users = append(users, &u)
+	}
+	return users, rows.Err()
+}
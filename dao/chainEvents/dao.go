@@ -0,0 +1,88 @@
+package chainEvents
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ChainEvent はonchainサービスから受け取ったイベントの履歴
+type ChainEvent struct {
+	ID          int64           `json:"id"`
+	EventType   string          `json:"event_type"`
+	ChainItemID int64           `json:"chain_item_id"`
+	TxHash      string          `json:"tx_hash,omitempty"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	Status      string          `json:"status"` // processed, failed
+	Error       string          `json:"error,omitempty"`
+	ReceivedAt  time.Time       `json:"received_at"`
+}
+
+// ChainEventDAOInterface はモック化のためのインターフェース
+type ChainEventDAOInterface interface {
+	Record(e *ChainEvent) error
+	ListEvents(chainItemID int64, limit, offset int) ([]*ChainEvent, error)
+}
+
+type ChainEventDAO struct {
+	db *sql.DB
+}
+
+func NewChainEventDAO(db *sql.DB) *ChainEventDAO {
+	return &ChainEventDAO{db: db}
+}
+
+// Record はイベントとその処理結果を記録する
+func (d *ChainEventDAO) Record(e *ChainEvent) error {
+	query := `
+		INSERT INTO chain_events (event_type, chain_item_id, tx_hash, payload, status, error)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	var txHash, payload, errMsg interface{}
+	if e.TxHash != "" {
+		txHash = e.TxHash
+	}
+	if len(e.Payload) > 0 {
+		payload = string(e.Payload)
+	}
+	if e.Error != "" {
+		errMsg = e.Error
+	}
+	if _, err := d.db.Exec(query, e.EventType, e.ChainItemID, txHash, payload, e.Status, errMsg); err != nil {
+		return fmt.Errorf("failed to insert chain event: %w", err)
+	}
+	return nil
+}
+
+// ListEvents はイベント履歴を新しい順に取得する（chainItemIDが0なら全件）
+func (d *ChainEventDAO) ListEvents(chainItemID int64, limit, offset int) ([]*ChainEvent, error) {
+	query := `
+		SELECT id, event_type, chain_item_id, tx_hash, payload, status, error, received_at
+		FROM chain_events
+		WHERE (? = 0 OR chain_item_id = ?)
+		ORDER BY received_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`
+	rows, err := d.db.Query(query, chainItemID, chainItemID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chain events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*ChainEvent{}
+	for rows.Next() {
+		var e ChainEvent
+		var txHash, payload, errMsg sql.NullString
+		if err := rows.Scan(&e.ID, &e.EventType, &e.ChainItemID, &txHash, &payload, &e.Status, &errMsg, &e.ReceivedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chain event: %w", err)
+		}
+		e.TxHash = txHash.String
+		if payload.Valid {
+			e.Payload = json.RawMessage(payload.String)
+		}
+		e.Error = errMsg.String
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
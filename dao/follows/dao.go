@@ -0,0 +1,116 @@
+package follows
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// FollowUser はフォロー・フォロワー一覧の1件
+type FollowUser struct {
+	UID        string    `json:"uid"`
+	Nickname   string    `json:"nickname"`
+	AvatarURL  string    `json:"avatar_url"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// FollowDAOInterface はモック化のためのインターフェース
+type FollowDAOInterface interface {
+	UserExists(uid string) (bool, error)
+	Follow(followerUID, followeeUID string) error
+	Unfollow(followerUID, followeeUID string) error
+	IsFollowing(followerUID, followeeUID string) (bool, error)
+	GetFollowers(uid string, limit, offset int) ([]*FollowUser, error)
+	GetFollowing(uid string, limit, offset int) ([]*FollowUser, error)
+}
+
+type FollowDAO struct {
+	db *sql.DB
+}
+
+func NewFollowDAO(db *sql.DB) *FollowDAO {
+	return &FollowDAO{db: db}
+}
+
+// UserExists はusersテーブルにuidが登録されているかを返す
+func (d *FollowDAO) UserExists(uid string) (bool, error) {
+	var exists bool
+	if err := d.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE uid = ?)", uid).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check user: %w", err)
+	}
+	return exists, nil
+}
+
+// Follow はフォローする（既にフォロー済みの場合は何もしない）
+func (d *FollowDAO) Follow(followerUID, followeeUID string) error {
+	_, err := d.db.Exec("INSERT IGNORE INTO follows (follower_uid, followee_uid) VALUES (?, ?)", followerUID, followeeUID)
+	if err != nil {
+		return fmt.Errorf("failed to insert follow: %w", err)
+	}
+	return nil
+}
+
+// Unfollow はフォローを解除する（フォローしていない場合も成功）
+func (d *FollowDAO) Unfollow(followerUID, followeeUID string) error {
+	_, err := d.db.Exec("DELETE FROM follows WHERE follower_uid = ? AND followee_uid = ?", followerUID, followeeUID)
+	if err != nil {
+		return fmt.Errorf("failed to delete follow: %w", err)
+	}
+	return nil
+}
+
+func (d *FollowDAO) IsFollowing(followerUID, followeeUID string) (bool, error) {
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM follows WHERE follower_uid = ? AND followee_uid = ?)"
+	if err := d.db.QueryRow(query, followerUID, followeeUID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check follow: %w", err)
+	}
+	return exists, nil
+}
+
+// GetFollowers はuidをフォローしているユーザーを新しい順に取得する
+func (d *FollowDAO) GetFollowers(uid string, limit, offset int) ([]*FollowUser, error) {
+	query := `
+		SELECT f.follower_uid, u.nickname, COALESCE(u.avatar_thumbnail_url, u.avatar_url), f.created_at
+		FROM follows f
+		LEFT JOIN users u ON u.uid = f.follower_uid
+		WHERE f.followee_uid = ?
+		ORDER BY f.created_at DESC, f.id DESC
+		LIMIT ? OFFSET ?
+	`
+	return d.queryUsers(query, uid, limit, offset)
+}
+
+// GetFollowing はuidがフォローしているユーザーを新しい順に取得する
+func (d *FollowDAO) GetFollowing(uid string, limit, offset int) ([]*FollowUser, error) {
+	query := `
+		SELECT f.followee_uid, u.nickname, COALESCE(u.avatar_thumbnail_url, u.avatar_url), f.created_at
+		FROM follows f
+		LEFT JOIN users u ON u.uid = f.followee_uid
+		WHERE f.follower_uid = ?
+		ORDER BY f.created_at DESC, f.id DESC
+		LIMIT ? OFFSET ?
+	`
+	return d.queryUsers(query, uid, limit, offset)
+}
+
+func (d *FollowDAO) queryUsers(query string, args ...interface{}) ([]*FollowUser, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query follows: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*FollowUser{}
+	for rows.Next() {
+		var u FollowUser
+		var nickname, avatarURL sql.NullString
+		if err := rows.Scan(&u.UID, &nickname, &avatarURL, &u.FollowedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan follow row: %w", err)
+		}
+		u.Nickname = nickname.String
+		u.AvatarURL = avatarURL.String
+		users = append(users, &u)
+	}
+	return users, rows.Err()
+}
@@ -4,22 +4,42 @@ import (
 	"database/sql"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Item struct {
-	ID          int       `json:"id"`
-	Title       string    `json:"title"`
-	Price       int       `json:"price"`
-	Explanation string    `json:"explanation"`
-	ImageURLs   []string  `json:"image_urls"`
-	UID         string    `json:"uid"`
-	Status      string    `json:"status"`
-	Category    string    `json:"category"`
-	LikeCount   int       `json:"like_count"`
-	CreatedAt   time.Time `json:"created_at"`
-	ChainItemID *int64    `json:"chain_item_id,omitempty"`
-	IfPurchased bool      `json:"ifPurchased"`
+	ID          int          `json:"id"`
+	Title       string       `json:"title"`
+	Price       int          `json:"price"`
+	Explanation string       `json:"explanation"`
+	ImageURLs   []string     `json:"image_urls"`
+	Images      []*ItemImage `json:"images"`
+	UID         string       `json:"uid"`
+	Status      string       `json:"status"`
+	Category    string       `json:"category"`
+	LikeCount   int          `json:"like_count"`
+	CreatedAt   time.Time    `json:"created_at"`
+	ChainItemID *int64       `json:"chain_item_id,omitempty"`
+	IfPurchased bool         `json:"ifPurchased"`
+	Seller      *Seller      `json:"seller"`
+}
+
+// Seller は商品に埋め込む出品者の概要
+type Seller struct {
+	UID            string   `json:"uid"`
+	Nickname       string   `json:"nickname"`
+	AvatarURL      string   `json:"avatar_url"`
+	Rating         *float64 `json:"rating"` // 評価がまだない場合はnull
+	ReviewCount    int      `json:"review_count"`
+	CompletedSales int      `json:"completed_sales"`
+}
+
+// ItemImage は商品画像のサイズ別URL（縮小版がない場合は空）
+type ItemImage struct {
+	URL          string `json:"image_url"`
+	MediumURL    string `json:"medium_url,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
 }
 
 type ItemDAO struct {
@@ -30,29 +50,121 @@ func NewItemDAO(db *sql.DB) *ItemDAO {
 	return &ItemDAO{db: db}
 }
 
-func (d *ItemDAO) getImageURLsForItem(itemID int) ([]string, error) {
-	query := "SELECT image_url FROM item_images WHERE item_id = ? ORDER BY id"
+func (d *ItemDAO) getImagesForItem(itemID int) ([]*ItemImage, error) {
+	query := "SELECT image_url, medium_url, thumbnail_url FROM item_images WHERE item_id = ? ORDER BY id"
 	rows, err := d.db.Query(query, itemID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var urls []string
+	var images []*ItemImage
+	for rows.Next() {
+		var img ItemImage
+		var mediumURL, thumbnailURL sql.NullString
+		if err := rows.Scan(&img.URL, &mediumURL, &thumbnailURL); err != nil {
+			return nil, err
+		}
+		img.MediumURL = mediumURL.String
+		img.ThumbnailURL = thumbnailURL.String
+		images = append(images, &img)
+	}
+	return images, rows.Err()
+}
+
+// attachImages は商品に画像URL（従来のimage_urlsとサイズ別のimages）を設定する
+func (d *ItemDAO) attachImages(item *Item) error {
+	images, err := d.getImagesForItem(item.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get image URLs for item id %d: %w", item.ID, err)
+	}
+	item.Images = images
+	item.ImageURLs = nil
+	for _, img := range images {
+		item.ImageURLs = append(item.ImageURLs, img.URL)
+	}
+	return nil
+}
+
+// getSellers は出品者の概要をuidごとにまとめて取得する
+func (d *ItemDAO) getSellers(uids []string) (map[string]*Seller, error) {
+	sellers := make(map[string]*Seller, len(uids))
+	if len(uids) == 0 {
+		return sellers, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(uids)), ",")
+	args := make([]interface{}, len(uids))
+	for i, uid := range uids {
+		args[i] = uid
+	}
+	query := `
+		SELECT u.uid, u.nickname, COALESCE(u.avatar_thumbnail_url, u.avatar_url),
+			(SELECT AVG(r.rating) FROM reviews r WHERE r.reviewee_uid = u.uid),
+			(SELECT COUNT(*) FROM reviews r WHERE r.reviewee_uid = u.uid),
+			(SELECT COUNT(*) FROM items s WHERE s.uid = u.uid AND s.status = 'completed')
+		FROM users u
+		WHERE u.uid IN (` + placeholders + `)
+	`
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
 	for rows.Next() {
-		var url string
-		if err := rows.Scan(&url); err != nil {
+		var seller Seller
+		var nickname, avatarURL sql.NullString
+		var rating sql.NullFloat64
+		if err := rows.Scan(&seller.UID, &nickname, &avatarURL, &rating, &seller.ReviewCount, &seller.CompletedSales); err != nil {
 			return nil, err
 		}
-		urls = append(urls, url)
+		seller.Nickname = nickname.String
+		seller.AvatarURL = avatarURL.String
+		if rating.Valid {
+			seller.Rating = &rating.Float64
+		}
+		sellers[seller.UID] = &seller
+	}
+	return sellers, rows.Err()
+}
+
+// attachSellers は商品に出品者の概要を設定する（usersに未登録の出品者はuidのみ）
+func (d *ItemDAO) attachSellers(items []*Item) error {
+	var uids []string
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if !seen[item.UID] {
+			seen[item.UID] = true
+			uids = append(uids, item.UID)
+		}
 	}
-	return urls, rows.Err()
+	sellers, err := d.getSellers(uids)
+	if err != nil {
+		return fmt.Errorf("failed to get sellers: %w", err)
+	}
+	for _, item := range items {
+		if seller, ok := sellers[item.UID]; ok {
+			item.Seller = seller
+		} else {
+			item.Seller = &Seller{UID: item.UID}
+		}
+	}
+	return nil
+}
+
+// visibleCondition は閲覧者に表示してよい商品の条件を返す（プレースホルダー2つにどちらも閲覧者のuidを渡す）
+// 非表示にされた商品・利用停止中のユーザーの商品・閲覧者をブロックしている出品者の商品を除く（自分の商品は常に表示）
+// 閲覧者のuidはIDトークンから取り出したもの（auth.ViewerUID）を渡すこと。未ログインなら空文字列で、どの出品者とも一致しない
+func visibleCondition(alias string) string {
+	return `(` + alias + `.uid = ? OR (` + alias + `.hidden = FALSE
+		AND NOT EXISTS (SELECT 1 FROM users su WHERE su.uid = ` + alias + `.uid AND su.suspended_at IS NOT NULL)
+		AND NOT EXISTS (SELECT 1 FROM blocks b WHERE b.blocker_uid = ` + alias + `.uid AND b.blocked_uid = ?)))`
 }
 
-func (d *ItemDAO) GetItemsByCategory(category string, page, limit int) ([]*Item, error) {
+func (d *ItemDAO) GetItemsByCategory(category, viewerUID string, page, limit int) ([]*Item, error) {
 	offset := (page - 1) * limit
-	query := "SELECT id, title, price, explanation, uid, status, category, like_count, created_at, chain_item_id FROM items WHERE category = ? ORDER BY created_at DESC LIMIT ? OFFSET ?"
-	rows, err := d.db.Query(query, category, limit, offset)
+	query := "SELECT id, title, price, explanation, uid, status, category, like_count, created_at, chain_item_id FROM items WHERE category = ? AND " + visibleCondition("items") + " ORDER BY created_at DESC LIMIT ? OFFSET ?"
+	rows, err := d.db.Query(query, category, viewerUID, viewerUID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query items by category: %w", err)
 	}
@@ -115,19 +227,21 @@ func (d *ItemDAO) GetItemsByCategory(category string, page, limit int) ([]*Item,
 
 	// 各アイテムの画像URLを取得
 	for _, item := range items {
-		urls, err := d.getImageURLsForItem(item.ID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get image URLs for item id %d: %w", item.ID, err)
+		if err := d.attachImages(item); err != nil {
+			return nil, err
 		}
-		item.ImageURLs = urls
+	}
+	if err := d.attachSellers(items); err != nil {
+		return nil, err
 	}
 
 	return items, nil
 }
 
-func (d *ItemDAO) GetItemByID(id int) (*Item, error) {
-	query := "SELECT id, title, price, explanation, uid, status, category, like_count, created_at, chain_item_id FROM items WHERE id = ?"
-	row := d.db.QueryRow(query, id)
+// 閲覧者に表示できない商品はsql.ErrNoRowsを返す
+func (d *ItemDAO) GetItemByID(id int, viewerUID string) (*Item, error) {
+	query := "SELECT id, title, price, explanation, uid, status, category, like_count, created_at, chain_item_id FROM items WHERE id = ? AND " + visibleCondition("items")
+	row := d.db.QueryRow(query, id, viewerUID, viewerUID)
 
 	var item Item
 	var chainItemID sql.NullInt64
@@ -177,21 +291,41 @@ func (d *ItemDAO) GetItemByID(id int) (*Item, error) {
 	item.IfPurchased = item.Status == "purchased" || item.Status == "completed"
 
 	// 画像URLを取得
-	urls, err := d.getImageURLsForItem(item.ID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get image URLs for item id %d: %w", item.ID, err)
+	if err := d.attachImages(&item); err != nil {
+		return nil, err
+	}
+	if err := d.attachSellers([]*Item{&item}); err != nil {
+		return nil, err
 	}
-	item.ImageURLs = urls
 
 	return &item, nil
 }
 
 // 新着商品を取得
-func (d *ItemDAO) GetLatestItems(limit int) ([]*Item, error) {
-	query := "SELECT id, title, price, explanation, uid, status, category, like_count, created_at, chain_item_id FROM items ORDER BY created_at DESC LIMIT ?"
-	rows, err := d.db.Query(query, limit)
+func (d *ItemDAO) GetLatestItems(limit int, viewerUID string) ([]*Item, error) {
+	query := "SELECT id, title, price, explanation, uid, status, category, like_count, created_at, chain_item_id FROM items WHERE " + visibleCondition("items") + " ORDER BY created_at DESC LIMIT ?"
+	return d.queryItems(query, viewerUID, viewerUID, limit)
+}
+
+// フォロー中の出品者の新着出品を取得（GetLatestItemsと同じ取得処理を使う）
+func (d *ItemDAO) GetFeedItems(followerUID string, limit, offset int) ([]*Item, error) {
+	query := `
+		SELECT i.id, i.title, i.price, i.explanation, i.uid, i.status, i.category, i.like_count, i.created_at, i.chain_item_id
+		FROM items i
+		JOIN follows f ON f.followee_uid = i.uid
+		WHERE f.follower_uid = ? AND i.status = 'listed' AND ` + visibleCondition("i") + `
+		ORDER BY i.created_at DESC
+		LIMIT ? OFFSET ?
+	`
+	return d.queryItems(query, followerUID, followerUID, followerUID, limit, offset)
+}
+
+// queryItems は商品一覧のクエリを実行し、画像と出品者の概要を付けて返す
+// SELECT句は id, title, price, explanation, uid, status, category, like_count, created_at, chain_item_id の順にすること
+func (d *ItemDAO) queryItems(query string, args ...interface{}) ([]*Item, error) {
+	rows, err := d.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query latest items: %w", err)
+		return nil, fmt.Errorf("failed to query items: %w", err)
 	}
 	defer rows.Close()
 
@@ -252,19 +386,20 @@ func (d *ItemDAO) GetLatestItems(limit int) ([]*Item, error) {
 	}
 
 	for _, item := range items {
-		urls, err := d.getImageURLsForItem(item.ID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get image URLs for item id %d: %w", item.ID, err)
+		if err := d.attachImages(item); err != nil {
+			return nil, err
 		}
-		item.ImageURLs = urls
+	}
+	if err := d.attachSellers(items); err != nil {
+		return nil, err
 	}
 
 	return items, nil
 }
 
-func (d *ItemDAO) GetItemsByUid(uid string) ([]*Item, error) {
-	query := "SELECT id, title, price, explanation, uid, status, category, like_count, created_at, chain_item_id FROM items WHERE uid = ? ORDER BY created_at DESC"
-	rows, err := d.db.Query(query, uid)
+func (d *ItemDAO) GetItemsByUid(uid, viewerUID string) ([]*Item, error) {
+	query := "SELECT id, title, price, explanation, uid, status, category, like_count, created_at, chain_item_id FROM items WHERE uid = ? AND " + visibleCondition("items") + " ORDER BY created_at DESC"
+	rows, err := d.db.Query(query, uid, viewerUID, viewerUID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query items by uid: %w", err)
 	}
@@ -327,11 +462,12 @@ func (d *ItemDAO) GetItemsByUid(uid string) ([]*Item, error) {
 
 	// 各アイテムの画像URLを取得
 	for _, item := range items {
-		urls, err := d.getImageURLsForItem(item.ID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get image URLs for item id %d: %w", item.ID, err)
+		if err := d.attachImages(item); err != nil {
+			return nil, err
 		}
-		item.ImageURLs = urls
+	}
+	if err := d.attachSellers(items); err != nil {
+		return nil, err
 	}
 
 	return items, nil
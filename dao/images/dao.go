@@ -0,0 +1,146 @@
+package images
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Image はコンテンツハッシュで管理されるアップロード画像
+type Image struct {
+	Hash           string    `json:"hash"`
+	Ext            string    `json:"ext"`
+	URL            string    `json:"image_url"`
+	MediumURL      string    `json:"medium_url"`
+	ThumbnailURL   string    `json:"thumbnail_url"`
+	SizeBytes      int       `json:"size_bytes"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastUploadedAt time.Time `json:"last_uploaded_at"`
+}
+
+// PrivateImage は署名付きURLでのみ配信する画像（メッセージの添付など。imagesテーブルには登録しない）
+type PrivateImage struct {
+	Hash      string
+	Ext       string
+	SizeBytes int
+}
+
+// ImageDAOInterface はモック化のためのインターフェース
+type ImageDAOInterface interface {
+	FindByHash(hash string) (*Image, error)
+	Touch(hash string) (bool, error)
+	Insert(image *Image) error
+	ListUnreferenced(olderThan time.Time, limit int) ([]*Image, error)
+	DeleteIfUnreferenced(hash string, olderThan time.Time, removeObjects func()) (bool, error)
+}
+
+type ImageDAO struct {
+	db *sql.DB
+}
+
+func NewImageDAO(db *sql.DB) *ImageDAO {
+	return &ImageDAO{db: db}
+}
+
+// unreferencedCondition は画像がどこからも参照されていないことを表すSQL条件
+// 商品画像とユーザーのアバターから参照される
+const unreferencedCondition = `
+	NOT EXISTS (SELECT 1 FROM item_images ii WHERE ii.image_hash = images.hash)
+	AND NOT EXISTS (SELECT 1 FROM users u WHERE u.avatar_hash = images.hash)
+`
+
+// FindByHash はハッシュで画像を検索する（存在しない場合はsql.ErrNoRows）
+func (d *ImageDAO) FindByHash(hash string) (*Image, error) {
+	query := `
+		SELECT hash, ext, image_url, medium_url, thumbnail_url, size_bytes, created_at, last_uploaded_at
+		FROM images WHERE hash = ?
+	`
+	var img Image
+	err := d.db.QueryRow(query, hash).Scan(&img.Hash, &img.Ext, &img.URL, &img.MediumURL, &img.ThumbnailURL, &img.SizeBytes, &img.CreatedAt, &img.LastUploadedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &img, nil
+}
+
+// Touch は同じ画像が再アップロードされた際に最終アップロード日時を更新する
+// GCの猶予期間を延長するため。画像が存在しない場合はfalseを返す
+func (d *ImageDAO) Touch(hash string) (bool, error) {
+	result, err := d.db.Exec("UPDATE images SET last_uploaded_at = CURRENT_TIMESTAMP WHERE hash = ?", hash)
+	if err != nil {
+		return false, fmt.Errorf("failed to touch image: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// Insert は画像を登録する（同時アップロードで既に存在する場合は何もしない）
+func (d *ImageDAO) Insert(image *Image) error {
+	query := `
+		INSERT IGNORE INTO images (hash, ext, image_url, medium_url, thumbnail_url, size_bytes)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	_, err := d.db.Exec(query, image.Hash, image.Ext, image.URL, image.MediumURL, image.ThumbnailURL, image.SizeBytes)
+	if err != nil {
+		return fmt.Errorf("failed to insert image: %w", err)
+	}
+	return nil
+}
+
+// ListUnreferenced は参照されておらず、olderThanより前にアップロードされた画像を取得する
+func (d *ImageDAO) ListUnreferenced(olderThan time.Time, limit int) ([]*Image, error) {
+	query := `
+		SELECT hash, ext, image_url, medium_url, thumbnail_url, size_bytes, created_at, last_uploaded_at
+		FROM images
+		WHERE last_uploaded_at < ? AND ` + unreferencedCondition + `
+		ORDER BY last_uploaded_at
+		LIMIT ?
+	`
+	rows, err := d.db.Query(query, olderThan, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unreferenced images: %w", err)
+	}
+	defer rows.Close()
+
+	var images []*Image
+	for rows.Next() {
+		var img Image
+		if err := rows.Scan(&img.Hash, &img.Ext, &img.URL, &img.MediumURL, &img.ThumbnailURL, &img.SizeBytes, &img.CreatedAt, &img.LastUploadedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan image row: %w", err)
+		}
+		images = append(images, &img)
+	}
+	return images, rows.Err()
+}
+
+// DeleteIfUnreferenced は参照と猶予期間を再確認してから、removeObjectsでストレージのファイルを消して画像を削除する
+// 一覧取得後に参照・再アップロードされた場合はfalseを返す（removeObjectsは呼ばない）
+// ファイルを消す間は行をロックしておくので、同じ画像の再アップロード（Touch）は削除が終わるまで待ち、
+// 行がなくなったのを見てファイルを保存し直す（消したファイルを参照する行が残らない）
+func (d *ImageDAO) DeleteIfUnreferenced(hash string, olderThan time.Time, removeObjects func()) (bool, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := "SELECT hash FROM images WHERE hash = ? AND last_uploaded_at < ? AND " + unreferencedCondition + " FOR UPDATE"
+	if err := tx.QueryRow(query, hash, olderThan).Scan(&hash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to lock image: %w", err)
+	}
+	removeObjects()
+	if _, err := tx.Exec("DELETE FROM images WHERE hash = ?", hash); err != nil {
+		return false, fmt.Errorf("failed to delete image: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit: %w", err)
+	}
+	return true, nil
+}
@@ -2,7 +2,9 @@ package likes
 
 import (
 	"database/sql"
+	"log"
 	"time"
+	"uttc-hackathon-backend/events"
 )
 
 type Like struct {
@@ -22,11 +24,13 @@ type LikeDAOInterface interface {
 }
 
 type LikeDAO struct {
-	db *sql.DB
+	db  *sql.DB
+	bus *events.Bus
 }
 
-func NewLikeDAO(db *sql.DB) *LikeDAO {
-	return &LikeDAO{db: db}
+// NewLikeDAO はいいね数の変化をbusへ送るLikeDAOを生成する（busはnilでもよい）
+func NewLikeDAO(db *sql.DB, bus *events.Bus) *LikeDAO {
+	return &LikeDAO{db: db, bus: bus}
 }
 
 // いいねを追加（トランザクションでlike_countも更新）
@@ -49,7 +53,11 @@ func (d *LikeDAO) AddLike(itemID int, uid string) error {
 		return err
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	d.publishLikeCount(itemID)
+	return nil
 }
 
 // いいねを削除（トランザクションでlike_countも更新）
@@ -78,7 +86,29 @@ func (d *LikeDAO) RemoveLike(itemID int, uid string) error {
 		}
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if rowsAffected > 0 {
+		d.publishLikeCount(itemID)
+	}
+	return nil
+}
+
+// publishLikeCount はコミット後のいいね数を出品者と商品ページへ送る
+// いいね自体は成功しているので、取得に失敗してもログに残すだけにする
+func (d *LikeDAO) publishLikeCount(itemID int) {
+	if d.bus == nil {
+		return
+	}
+	var count int
+	var sellerUID string
+	err := d.db.QueryRow("SELECT like_count, uid FROM items WHERE id = ?", itemID).Scan(&count, &sellerUID)
+	if err != nil {
+		log.Printf("WARNING: failed to get like count for item %d: %v", itemID, err)
+		return
+	}
+	d.bus.Publish(events.ItemLikeCountChanged, itemID, []string{sellerUID}, events.LikeCountChange{LikeCount: count})
 }
 
 // ユーザーが特定の商品にいいねしているか確認
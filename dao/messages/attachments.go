@@ -0,0 +1,224 @@
+package messages
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrAttachmentUnavailable は添付しようとした画像が存在しない・他人のもの・添付済みの場合のエラー
+var ErrAttachmentUnavailable = errors.New("attachment is not available")
+
+// Attachment はメッセージの添付画像
+// URLとThumbnailURLは署名付きURLで、返す直前にusecaseで設定する
+type Attachment struct {
+	ID           int64     `json:"id"`
+	MessageID    *int      `json:"message_id"`
+	UploaderUID  string    `json:"-"`
+	Hash         string    `json:"-"`
+	Ext          string    `json:"-"`
+	SizeBytes    int       `json:"size_bytes"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	CreatedAt    time.Time `json:"created_at"`
+	// 添付先のメッセージの送信者・受信者と、メッセージが削除・保留されたか（アクセスの確認用。送信前は空）
+	SenderUID          string `json:"-"`
+	ReceiverUID        string `json:"-"`
+	MessageDeleted     bool   `json:"-"`
+	MessageQuarantined bool   `json:"-"`
+}
+
+// AttachmentDAOInterface は送信されなかった添付画像のGC用のインターフェース
+type AttachmentDAOInterface interface {
+	ListOrphanAttachments(olderThan time.Time, limit int) ([]*Attachment, error)
+	DeleteOrphanAttachment(id int64, hash string, olderThan time.Time, removeObjects func()) (bool, error)
+}
+
+const attachmentColumns = "a.id, a.message_id, a.uploader_uid, a.image_hash, a.ext, a.size_bytes, a.created_at"
+
+func scanAttachment(row scanner, extra ...interface{}) (*Attachment, error) {
+	var a Attachment
+	var messageID sql.NullInt64
+	dest := append([]interface{}{&a.ID, &messageID, &a.UploaderUID, &a.Hash, &a.Ext, &a.SizeBytes, &a.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if messageID.Valid {
+		id := int(messageID.Int64)
+		a.MessageID = &id
+	}
+	return &a, nil
+}
+
+// CreateAttachment は画像を送信前の添付画像として登録する（ファイルはこの後に保存する）
+func (d *MessageDAO) CreateAttachment(uploaderUID, hash, ext string, sizeBytes int) (*Attachment, error) {
+	result, err := d.db.Exec(
+		"INSERT INTO message_attachments (uploader_uid, image_hash, ext, size_bytes) VALUES (?, ?, ?, ?)",
+		uploaderUID, hash, ext, sizeBytes,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert attachment: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment id: %w", err)
+	}
+	return &Attachment{
+		ID:          id,
+		UploaderUID: uploaderUID,
+		Hash:        hash,
+		Ext:         ext,
+		SizeBytes:   sizeBytes,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// GetAttachment は添付画像と添付先のメッセージの送信者・受信者・削除されたかを取得する（存在しない場合はsql.ErrNoRows）
+func (d *MessageDAO) GetAttachment(id int64) (*Attachment, error) {
+	query := `
+		SELECT ` + attachmentColumns + `, COALESCE(m.sender_uid, ''), COALESCE(m.receiver_uid, ''), m.deleted_at IS NOT NULL,
+			m.quarantined_at IS NOT NULL
+		FROM message_attachments a
+		LEFT JOIN messages m ON m.id = a.message_id
+		WHERE a.id = ?
+	`
+	var senderUID, receiverUID string
+	var deleted, quarantined bool
+	a, err := scanAttachment(d.db.QueryRow(query, id), &senderUID, &receiverUID, &deleted, &quarantined)
+	if err != nil {
+		return nil, err
+	}
+	a.SenderUID, a.ReceiverUID = senderUID, receiverUID
+	a.MessageDeleted, a.MessageQuarantined = deleted, quarantined
+	return a, nil
+}
+
+// attachToMessage は送信前の添付画像をメッセージに紐づける（アップロードした本人のもののみ）
+func attachToMessage(tx *sql.Tx, messageID int64, uploaderUID string, attachmentIDs []int64) error {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(attachmentIDs)), ",")
+	args := []interface{}{messageID, uploaderUID}
+	for _, id := range attachmentIDs {
+		args = append(args, id)
+	}
+	query := "UPDATE message_attachments SET message_id = ? WHERE uploader_uid = ? AND message_id IS NULL AND id IN (" + placeholders + ")"
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to attach images: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected != int64(len(attachmentIDs)) {
+		return ErrAttachmentUnavailable
+	}
+	return nil
+}
+
+// queryer は*sql.DBと*sql.Txの共通のメソッド
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// loadAttachments はメッセージの添付画像を取得して設定する
+func loadAttachments(q queryer, messages []*Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	byID := make(map[int]*Message, len(messages))
+	args := make([]interface{}, 0, len(messages))
+	for _, msg := range messages {
+		msg.Attachments = []*Attachment{}
+		byID[msg.ID] = msg
+		args = append(args, msg.ID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")
+	rows, err := q.Query("SELECT "+attachmentColumns+" FROM message_attachments a WHERE a.message_id IN ("+placeholders+") ORDER BY a.id", args...)
+	if err != nil {
+		return fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		if msg, ok := byID[*a.MessageID]; ok {
+			msg.Attachments = append(msg.Attachments, a)
+		}
+	}
+	return rows.Err()
+}
+
+// ListOrphanAttachments はメッセージに添付されないままolderThanより前に登録された添付画像を取得する
+func (d *MessageDAO) ListOrphanAttachments(olderThan time.Time, limit int) ([]*Attachment, error) {
+	query := "SELECT " + attachmentColumns + " FROM message_attachments a WHERE a.message_id IS NULL AND a.created_at < ? ORDER BY a.created_at LIMIT ?"
+	rows, err := d.db.Query(query, olderThan, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orphan attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []*Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// DeleteOrphanAttachment は削除直前にまだ添付されていないことを再確認してから削除する
+// 同じ画像の添付画像（送信前を含む）が他に残っていなければ、removeObjectsでストレージのファイルも消す
+// 一覧取得後にメッセージに添付された場合はfalseを返す
+//
+// 同じ画像の行をすべてロック（idx_image_hashのギャップロックを含む）してから確認・削除するので、
+// 同じ画像のアップロード（CreateAttachment）は削除が終わるまで待ち、その後にファイルを保存し直す
+func (d *MessageDAO) DeleteOrphanAttachment(id int64, hash string, olderThan time.Time, removeObjects func()) (bool, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT id, message_id IS NULL AND created_at < ? FROM message_attachments WHERE image_hash = ? FOR UPDATE", olderThan, hash)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock attachments: %w", err)
+	}
+	orphan, others := false, 0
+	for rows.Next() {
+		var rowID int64
+		var deletable bool
+		if err := rows.Scan(&rowID, &deletable); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		if rowID == id {
+			orphan = deletable
+		} else {
+			others++
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to lock attachments: %w", err)
+	}
+	if !orphan {
+		return false, nil
+	}
+
+	if _, err := tx.Exec("DELETE FROM message_attachments WHERE id = ?", id); err != nil {
+		return false, fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	if others == 0 {
+		removeObjects()
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit: %w", err)
+	}
+	return true, nil
+}
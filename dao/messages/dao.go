@@ -2,26 +2,103 @@ package messages
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 )
 
+// メッセージの種類（Message.Type）
+const (
+	TypeUser   = "user"
+	TypeSystem = "system" // 取引の節目を知らせるシステムメッセージ（SystemEventに種類が入る）
+)
+
+// システムメッセージの種類（Message.SystemEvent）
+const (
+	SystemEventPurchased     = "purchased"      // 購入された（購入者から出品者へ）
+	SystemEventShipped       = "shipped"        // 発送された（出品者から購入者へ）
+	SystemEventDelivered     = "delivered"      // 購入者が受け取りを報告した
+	SystemEventCompleted     = "completed"      // 受け取りが確認され取引が完了した
+	SystemEventCancelled     = "cancelled"      // 注文がキャンセルされた
+	SystemEventRefunded      = "refunded"       // 注文が返金された
+	SystemEventItemCancelled = "item_cancelled" // 出品が取り消された（問い合わせていた相手へ）
+)
+
 type Message struct {
-	ID          int       `json:"id"`
-	SenderUID   string    `json:"sender_uid"`
-	ReceiverUID string    `json:"receiver_uid"`
-	Content     string    `json:"content"`
-	IsRead      bool      `json:"is_read"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          int           `json:"id"`
+	SenderUID   string        `json:"sender_uid"`
+	ReceiverUID string        `json:"receiver_uid"`
+	ItemID      *int          `json:"item_id"`
+	Type        string        `json:"type"`
+	SystemEvent string        `json:"system_event,omitempty"`
+	Content     string        `json:"content"`
+	IsRead      bool          `json:"is_read"`
+	DeliveredAt *time.Time    `json:"delivered_at"`
+	EditedAt    *time.Time    `json:"edited_at"`  // 編集済みの印（編集していなければnull）
+	DeletedAt   *time.Time    `json:"deleted_at"` // 削除済みならcontentは空、attachmentsは空配列
+	Attachments []*Attachment `json:"attachments"`
+	CreatedAt   time.Time     `json:"created_at"`
+	// QuarantinedAt はフィルタで保留された日時（受信者には見せない。送信者に知らせないためJSONには含めない）
+	QuarantinedAt *time.Time `json:"-"`
+}
+
+// MessageEdit は編集前の本文の履歴
+type MessageEdit struct {
+	PreviousContent string    `json:"previous_content"`
+	EditedAt        time.Time `json:"edited_at"`
+}
+
+// UnreadSummary は未読のメッセージの件数（ヘッダーのバッジ用）
+type UnreadSummary struct {
+	Total         int `json:"total"`
+	Conversations int `json:"conversations"` // 未読があるスレッドの数
+}
+
+// MessageItem はスレッドの対象の商品
+type MessageItem struct {
+	ID        int
+	SellerUID string
 }
 
 // MessageDAOInterface はモック化のためのインターフェース
+// itemIDはスレッドの対象の商品（nilなら商品に紐づかないスレッド）
 type MessageDAOInterface interface {
-	GetMessagesByPartner(myUID, partnerUID string) ([]*Message, error)
-	CreateMessage(senderUID, receiverUID, content string) (*Message, error)
-	MarkAsRead(myUID, partnerUID string) error
+	GetMessagesByPartner(myUID, partnerUID string, itemID *int, beforeID, limit int) ([]*Message, error)
+	CreateMessage(senderUID, receiverUID, content string, itemID *int, attachmentIDs []int64, quarantined bool) (*Message, error)
+	CreateSystemMessage(senderUID, receiverUID string, itemID int, event, content string) (*Message, error)
+	GetItemThreadPartners(itemID int, sellerUID string) ([]string, error)
+	MarkAsRead(myUID, partnerUID string, itemID *int) (int64, error)
+	MarkAsDelivered(receiverUID string, messageID int) (*Message, error)
+	CountUnread(myUID, partnerUID string) (fromPartner int, total int, err error)
+	GetUnreadSummary(myUID string) (*UnreadSummary, error)
 	GetConversations(myUID string) ([]*Conversation, error)
+	IsBlockedBetween(uidA, uidB string) (bool, error)
+	IsSuspended(uid string) (bool, error)
+	GetMessageItem(itemID int) (*MessageItem, error)
+	CreateAttachment(uploaderUID, hash, ext string, sizeBytes int) (*Attachment, error)
+	GetAttachment(id int64) (*Attachment, error)
+	GetMessage(id int) (*Message, error)
+	EditMessage(id int, senderUID, content string, since time.Time) (bool, error)
+	DeleteMessage(id int, senderUID string, since time.Time) (bool, error)
+	ListMessageEdits(id int) ([]*MessageEdit, error)
 }
 
+// 既読は受信者のスレッドごとの既読ポインタ（message_read_pointers）で管理する
+// readPointerJoin で受信者のポインタを結合し、m.id <= last_read_message_id なら既読とする（削除されたメッセージは未読に数えない）
+// 保留を解除したメッセージはポインタより古いことがあるので、解除（released_at）の後に既読にする（read_at）までは未読とする
+// 削除されたメッセージの本文は当事者には返さない（messageColumns）。元の本文はモデレーター向けのGetMessageだけが返す
+// フィルタで保留されたメッセージは受信者には返さず、未読にも数えない（visibleCondition・unreadCondition）
+const (
+	readPointerJoin = `LEFT JOIN message_read_pointers p
+		ON p.reader_uid = m.receiver_uid AND p.partner_uid = m.sender_uid AND p.thread_item_id = COALESCE(m.item_id, 0)`
+	unreadState         = "(m.id > COALESCE(p.last_read_message_id, 0) OR (m.released_at IS NOT NULL AND (p.read_at IS NULL OR m.released_at > p.read_at)))"
+	unreadCondition     = unreadState + " AND m.deleted_at IS NULL AND m.quarantined_at IS NULL"
+	visibleCondition    = "(m.quarantined_at IS NULL OR m.sender_uid = ?)"
+	messageStateColumns = "NOT " + unreadState + ", m.delivered_at, m.edited_at, m.deleted_at, m.quarantined_at, m.created_at"
+	messageColumns      = "m.id, m.sender_uid, m.receiver_uid, m.item_id, m.type, m.system_event, IF(m.deleted_at IS NULL, m.content, ''), " + messageStateColumns
+	rawMessageColumns   = "m.id, m.sender_uid, m.receiver_uid, m.item_id, m.type, m.system_event, m.content, " + messageStateColumns
+)
+
 type MessageDAO struct {
 	db *sql.DB
 }
@@ -30,16 +107,24 @@ func NewMessageDAO(db *sql.DB) *MessageDAO {
 	return &MessageDAO{db: db}
 }
 
-// 相手とのメッセージ一覧を取得
-func (d *MessageDAO) GetMessagesByPartner(myUID, partnerUID string) ([]*Message, error) {
+// 相手とのスレッドのメッセージを新しい方からlimit件取得し、古い順にして返す
+// beforeIDを指定した場合はそれより前（idが小さい）のメッセージだけを対象にする（0なら最新から）
+func (d *MessageDAO) GetMessagesByPartner(myUID, partnerUID string, itemID *int, beforeID, limit int) ([]*Message, error) {
 	query := `
-		SELECT id, sender_uid, receiver_uid, content, is_read, created_at
-		FROM messages
-		WHERE (sender_uid = ? AND receiver_uid = ?)
-		   OR (sender_uid = ? AND receiver_uid = ?)
-		ORDER BY created_at ASC
-	`
-	rows, err := d.db.Query(query, myUID, partnerUID, partnerUID, myUID)
+		SELECT ` + messageColumns + `
+		FROM messages m ` + readPointerJoin + `
+		WHERE ((m.sender_uid = ? AND m.receiver_uid = ?)
+		   OR (m.sender_uid = ? AND m.receiver_uid = ?))
+		  AND m.item_id <=> ? AND ` + visibleCondition
+	args := []interface{}{myUID, partnerUID, partnerUID, myUID, nullableItemID(itemID), myUID}
+	if beforeID > 0 {
+		query += " AND m.id < ?"
+		args = append(args, beforeID)
+	}
+	query += " ORDER BY m.id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := d.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -47,21 +132,52 @@ func (d *MessageDAO) GetMessagesByPartner(myUID, partnerUID string) ([]*Message,
 
 	var messages []*Message
 	for rows.Next() {
-		var msg Message
-		err := rows.Scan(&msg.ID, &msg.SenderUID, &msg.ReceiverUID, &msg.Content, &msg.IsRead, &msg.CreatedAt)
+		msg, err := scanMessage(rows)
 		if err != nil {
 			return nil, err
 		}
-		messages = append(messages, &msg)
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
+	rows.Close()
 
-	return messages, rows.Err()
+	// 新しい順に取得したので古い順に並べ直す
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	// 削除されたメッセージの添付画像は返さない
+	visible := make([]*Message, 0, len(messages))
+	for _, msg := range messages {
+		msg.Attachments = []*Attachment{}
+		if msg.DeletedAt == nil {
+			visible = append(visible, msg)
+		}
+	}
+	if err := loadAttachments(d.db, visible); err != nil {
+		return nil, err
+	}
+	return messages, nil
 }
 
-// メッセージ送信
-func (d *MessageDAO) CreateMessage(senderUID, receiverUID, content string) (*Message, error) {
-	query := "INSERT INTO messages (sender_uid, receiver_uid, content) VALUES (?, ?, ?)"
-	result, err := d.db.Exec(query, senderUID, receiverUID, content)
+// メッセージ送信（attachmentIDsの添付画像も同じトランザクションで紐づける）
+// 添付画像が送信者のものでない・添付済みの場合はErrAttachmentUnavailable
+// quarantinedならフィルタで保留したメッセージとして保存する（受信者にはモデレーターが却下するまで見せない）
+func (d *MessageDAO) CreateMessage(senderUID, receiverUID, content string, itemID *int, attachmentIDs []int64, quarantined bool) (*Message, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var quarantinedAt *time.Time
+	if quarantined {
+		quarantinedAt = &now
+	}
+	query := "INSERT INTO messages (sender_uid, receiver_uid, item_id, content, quarantined_at) VALUES (?, ?, ?, ?, ?)"
+	result, err := tx.Exec(query, senderUID, receiverUID, nullableItemID(itemID), content, quarantinedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -71,60 +187,324 @@ func (d *MessageDAO) CreateMessage(senderUID, receiverUID, content string) (*Mes
 		return nil, err
 	}
 
+	message := &Message{
+		ID:            int(id),
+		SenderUID:     senderUID,
+		ReceiverUID:   receiverUID,
+		ItemID:        itemID,
+		Type:          TypeUser,
+		Content:       content,
+		IsRead:        false,
+		Attachments:   []*Attachment{},
+		CreatedAt:     now,
+		QuarantinedAt: quarantinedAt,
+	}
+	if len(attachmentIDs) > 0 {
+		if err := attachToMessage(tx, id, senderUID, attachmentIDs); err != nil {
+			return nil, err
+		}
+		if err := loadAttachments(tx, []*Message{message}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return message, nil
+}
+
+// システムメッセージを商品のスレッドに書き込む
+func (d *MessageDAO) CreateSystemMessage(senderUID, receiverUID string, itemID int, event, content string) (*Message, error) {
+	query := "INSERT INTO messages (sender_uid, receiver_uid, item_id, type, system_event, content) VALUES (?, ?, ?, ?, ?, ?)"
+	result, err := d.db.Exec(query, senderUID, receiverUID, itemID, TypeSystem, event, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create system message: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
 	return &Message{
 		ID:          int(id),
 		SenderUID:   senderUID,
 		ReceiverUID: receiverUID,
+		ItemID:      &itemID,
+		Type:        TypeSystem,
+		SystemEvent: event,
 		Content:     content,
-		IsRead:      false,
+		Attachments: []*Attachment{},
 		CreatedAt:   time.Now(),
 	}, nil
 }
 
-// 相手からのメッセージを既読にする
-func (d *MessageDAO) MarkAsRead(myUID, partnerUID string) error {
-	query := "UPDATE messages SET is_read = true WHERE sender_uid = ? AND receiver_uid = ? AND is_read = false"
-	_, err := d.db.Exec(query, partnerUID, myUID)
-	return err
+// GetItemThreadPartners は商品のスレッドで出品者とやり取りしている相手のuidを返す
+func (d *MessageDAO) GetItemThreadPartners(itemID int, sellerUID string) ([]string, error) {
+	query := `
+		SELECT DISTINCT CASE WHEN sender_uid = ? THEN receiver_uid ELSE sender_uid END
+		FROM messages
+		WHERE item_id = ? AND (sender_uid = ? OR receiver_uid = ?)
+	`
+	rows, err := d.db.Query(query, sellerUID, itemID, sellerUID, sellerUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query thread partners: %w", err)
+	}
+	defer rows.Close()
+
+	var partners []string
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, fmt.Errorf("failed to scan thread partner: %w", err)
+		}
+		partners = append(partners, uid)
+	}
+	return partners, rows.Err()
+}
+
+// GetMessage はメッセージを削除済みの本文や添付画像も含めて取得する（存在しない場合はsql.ErrNoRows）
+// 編集・削除できるかの確認と、モデレーターによる通報の確認に使う
+func (d *MessageDAO) GetMessage(id int) (*Message, error) {
+	msg, err := scanMessage(d.db.QueryRow("SELECT "+rawMessageColumns+" FROM messages m "+readPointerJoin+" WHERE m.id = ?", id))
+	if err != nil {
+		return nil, err
+	}
+	if err := loadAttachments(d.db, []*Message{msg}); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// messageEditableCondition は送信者が編集・削除できるメッセージの条件（送信者本人・削除されていない・ユーザーのメッセージ・sinceより後の送信）
+const messageEditableCondition = "id = ? AND sender_uid = ? AND deleted_at IS NULL AND type = '" + TypeUser + "' AND created_at >= ?"
+
+// EditMessage は編集前の本文を履歴に残してから本文を更新する（編集できないメッセージならfalse）
+func (d *MessageDAO) EditMessage(id int, senderUID, content string, since time.Time) (bool, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previous string
+	err = tx.QueryRow("SELECT content FROM messages WHERE "+messageEditableCondition+" FOR UPDATE", id, senderUID, since).Scan(&previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock message: %w", err)
+	}
+	if _, err := tx.Exec("INSERT INTO message_edits (message_id, previous_content) VALUES (?, ?)", id, previous); err != nil {
+		return false, fmt.Errorf("failed to record edit: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE messages SET content = ?, edited_at = CURRENT_TIMESTAMP WHERE id = ?", content, id); err != nil {
+		return false, fmt.Errorf("failed to update message: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
 }
 
-// Conversation はやり取りしている相手との会話情報
+// DeleteMessage はメッセージを論理削除する（削除できないメッセージならfalse）
+// 本文・添付画像・編集履歴は通報の確認のために残す
+func (d *MessageDAO) DeleteMessage(id int, senderUID string, since time.Time) (bool, error) {
+	result, err := d.db.Exec("UPDATE messages SET deleted_at = CURRENT_TIMESTAMP WHERE "+messageEditableCondition, id, senderUID, since)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete message: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// ListMessageEdits はメッセージの編集前の本文を古い順に返す
+func (d *MessageDAO) ListMessageEdits(id int) ([]*MessageEdit, error) {
+	rows, err := d.db.Query("SELECT previous_content, edited_at FROM message_edits WHERE message_id = ? ORDER BY id", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message edits: %w", err)
+	}
+	defer rows.Close()
+
+	edits := []*MessageEdit{}
+	for rows.Next() {
+		edit := &MessageEdit{}
+		if err := rows.Scan(&edit.PreviousContent, &edit.EditedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan message edit: %w", err)
+		}
+		edits = append(edits, edit)
+	}
+	return edits, rows.Err()
+}
+
+// どちらかがもう一方をブロックしているか
+func (d *MessageDAO) IsBlockedBetween(uidA, uidB string) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM blocks
+			WHERE (blocker_uid = ? AND blocked_uid = ?)
+			   OR (blocker_uid = ? AND blocked_uid = ?)
+		)
+	`
+	var blocked bool
+	if err := d.db.QueryRow(query, uidA, uidB, uidB, uidA).Scan(&blocked); err != nil {
+		return false, err
+	}
+	return blocked, nil
+}
+
+// ユーザーが利用停止中か（退会済みも含む）
+func (d *MessageDAO) IsSuspended(uid string) (bool, error) {
+	var suspended bool
+	query := "SELECT EXISTS(SELECT 1 FROM users WHERE uid = ? AND (suspended_at IS NOT NULL OR deleted_at IS NOT NULL))"
+	if err := d.db.QueryRow(query, uid).Scan(&suspended); err != nil {
+		return false, err
+	}
+	return suspended, nil
+}
+
+// スレッドの相手からのメッセージを最新まで既読にする（既読ポインタを進め、新たに既読になった件数を返す）
+// 既読のメッセージは配信済みでもある（delivered_atが空でも）
+func (d *MessageDAO) MarkAsRead(myUID, partnerUID string, itemID *int) (int64, error) {
+	query := `
+		SELECT COALESCE(MAX(m.id), 0), COUNT(*), CURRENT_TIMESTAMP(6)
+		FROM messages m ` + readPointerJoin + `
+		WHERE m.receiver_uid = ? AND m.sender_uid = ? AND m.item_id <=> ? AND ` + unreadCondition
+	var lastID, unread int64
+	var readAt time.Time
+	if err := d.db.QueryRow(query, myUID, partnerUID, nullableItemID(itemID)).Scan(&lastID, &unread, &readAt); err != nil {
+		return 0, fmt.Errorf("failed to count unread messages: %w", err)
+	}
+	if unread == 0 {
+		return 0, nil
+	}
+
+	// 同時に既読にした場合でもポインタが戻らないようにする
+	// read_atは数えた時点の日時にする（数えた後に保留を解除したメッセージは未読のまま）
+	_, err := d.db.Exec(`
+		INSERT INTO message_read_pointers (reader_uid, partner_uid, thread_item_id, last_read_message_id, read_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			last_read_message_id = GREATEST(last_read_message_id, VALUES(last_read_message_id)),
+			read_at = GREATEST(COALESCE(read_at, VALUES(read_at)), VALUES(read_at))
+	`, myUID, partnerUID, threadItemID(itemID), lastID, readAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update read pointer: %w", err)
+	}
+	return unread, nil
+}
+
+// 受信者の端末に届いたメッセージを配信済みにする（受信者以外・存在しない・保留中の場合はsql.ErrNoRows）
+func (d *MessageDAO) MarkAsDelivered(receiverUID string, messageID int) (*Message, error) {
+	query := "UPDATE messages SET delivered_at = COALESCE(delivered_at, CURRENT_TIMESTAMP) WHERE id = ? AND receiver_uid = ? AND quarantined_at IS NULL"
+	if _, err := d.db.Exec(query, messageID, receiverUID); err != nil {
+		return nil, err
+	}
+
+	return scanMessage(d.db.QueryRow(
+		"SELECT "+messageColumns+" FROM messages m "+readPointerJoin+" WHERE m.id = ? AND m.receiver_uid = ? AND m.quarantined_at IS NULL",
+		messageID, receiverUID,
+	))
+}
+
+// CountSentSince はsenderUIDがsince以降に送ったメッセージの数を返す（送信数の制限用。システムメッセージは数えない）
+func (d *MessageDAO) CountSentSince(senderUID string, since time.Time) (int, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM messages WHERE sender_uid = ? AND created_at >= ? AND type = ?"
+	if err := d.db.QueryRow(query, senderUID, since, TypeUser).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count sent messages: %w", err)
+	}
+	return count, nil
+}
+
+// GetMessageItem はスレッドの対象にする商品を取得する（存在しない・非表示の場合はsql.ErrNoRows）
+func (d *MessageDAO) GetMessageItem(itemID int) (*MessageItem, error) {
+	item := &MessageItem{}
+	err := d.db.QueryRow("SELECT id, uid FROM items WHERE id = ? AND hidden = false", itemID).Scan(&item.ID, &item.SellerUID)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// 未読件数（partnerUIDからの未読件数と、全体の未読件数）
+func (d *MessageDAO) CountUnread(myUID, partnerUID string) (int, int, error) {
+	query := `
+		SELECT COALESCE(SUM(m.sender_uid = ?), 0), COUNT(*)
+		FROM messages m ` + readPointerJoin + `
+		WHERE m.receiver_uid = ? AND ` + unreadCondition
+	var fromPartner, total int
+	if err := d.db.QueryRow(query, partnerUID, myUID).Scan(&fromPartner, &total); err != nil {
+		return 0, 0, err
+	}
+	return fromPartner, total, nil
+}
+
+// GetUnreadSummary は未読の件数と未読があるスレッドの数を取得する
+func (d *MessageDAO) GetUnreadSummary(myUID string) (*UnreadSummary, error) {
+	query := `
+		SELECT COUNT(*), COUNT(DISTINCT m.sender_uid, COALESCE(m.item_id, 0))
+		FROM messages m ` + readPointerJoin + `
+		WHERE m.receiver_uid = ? AND ` + unreadCondition
+	summary := &UnreadSummary{}
+	if err := d.db.QueryRow(query, myUID).Scan(&summary.Total, &summary.Conversations); err != nil {
+		return nil, fmt.Errorf("failed to count unread messages: %w", err)
+	}
+	return summary, nil
+}
+
+// Conversation はやり取りしている相手とのスレッド（相手と商品の組み合わせごと）の情報
 type Conversation struct {
-	PartnerUID     string    `json:"partner_uid"`
-	LastMessage    string    `json:"last_message"`
-	LastMessageAt  time.Time `json:"last_message_at"`
-	UnreadCount    int       `json:"unread_count"`
+	PartnerUID       string    `json:"partner_uid"`
+	ItemID           *int      `json:"item_id"`
+	ItemTitle        string    `json:"item_title,omitempty"`
+	ItemThumbnailURL string    `json:"item_thumbnail_url,omitempty"`
+	LastMessage      string    `json:"last_message"`
+	LastMessageAt    time.Time `json:"last_message_at"`
+	UnreadCount      int       `json:"unread_count"`
 }
 
-// やり取りしている相手一覧を取得
+// やり取りしているスレッドの一覧を取得（相手と商品の組み合わせごとに最新のメッセージ）
 func (d *MessageDAO) GetConversations(myUID string) ([]*Conversation, error) {
 	query := `
 		SELECT
-			partner_uid,
-			last_message,
-			last_message_at,
-			unread_count
+			sub.partner_uid,
+			sub.item_id,
+			COALESCE(i.title, ''),
+			COALESCE((
+				SELECT COALESCE(ii.thumbnail_url, ii.image_url) FROM item_images ii
+				WHERE ii.item_id = sub.item_id ORDER BY ii.id LIMIT 1
+			), ''),
+			sub.last_message,
+			sub.last_message_at,
+			COALESCE(u.unread_count, 0)
 		FROM (
 			SELECT
 				CASE
 					WHEN sender_uid = ? THEN receiver_uid
 					ELSE sender_uid
 				END as partner_uid,
-				content as last_message,
+				item_id,
+				IF(deleted_at IS NULL, content, '') as last_message,
 				created_at as last_message_at,
-				(SELECT COUNT(*) FROM messages m2
-				 WHERE m2.sender_uid = CASE WHEN m1.sender_uid = ? THEN m1.receiver_uid ELSE m1.sender_uid END
-				 AND m2.receiver_uid = ?
-				 AND m2.is_read = false) as unread_count,
 				ROW_NUMBER() OVER (
-					PARTITION BY CASE WHEN sender_uid = ? THEN receiver_uid ELSE sender_uid END
-					ORDER BY created_at DESC
+					PARTITION BY CASE WHEN sender_uid = ? THEN receiver_uid ELSE sender_uid END, item_id
+					ORDER BY created_at DESC, id DESC
 				) as rn
 			FROM messages m1
-			WHERE sender_uid = ? OR receiver_uid = ?
+			WHERE (sender_uid = ? OR receiver_uid = ?) AND (quarantined_at IS NULL OR sender_uid = ?)
 		) sub
-		WHERE rn = 1
-		ORDER BY last_message_at DESC
+		LEFT JOIN (
+			SELECT m.sender_uid AS partner_uid, COALESCE(m.item_id, 0) AS thread_item_id, COUNT(*) AS unread_count
+			FROM messages m ` + readPointerJoin + `
+			WHERE m.receiver_uid = ? AND ` + unreadCondition + `
+			GROUP BY m.sender_uid, COALESCE(m.item_id, 0)
+		) u ON u.partner_uid = sub.partner_uid AND u.thread_item_id = COALESCE(sub.item_id, 0)
+		LEFT JOIN items i ON i.id = sub.item_id
+		WHERE sub.rn = 1
+		ORDER BY sub.last_message_at DESC
 	`
 	rows, err := d.db.Query(query, myUID, myUID, myUID, myUID, myUID, myUID)
 	if err != nil {
@@ -135,12 +515,68 @@ func (d *MessageDAO) GetConversations(myUID string) ([]*Conversation, error) {
 	var conversations []*Conversation
 	for rows.Next() {
 		var conv Conversation
-		err := rows.Scan(&conv.PartnerUID, &conv.LastMessage, &conv.LastMessageAt, &conv.UnreadCount)
+		var itemID sql.NullInt64
+		err := rows.Scan(&conv.PartnerUID, &itemID, &conv.ItemTitle, &conv.ItemThumbnailURL,
+			&conv.LastMessage, &conv.LastMessageAt, &conv.UnreadCount)
 		if err != nil {
 			return nil, err
 		}
+		if itemID.Valid {
+			id := int(itemID.Int64)
+			conv.ItemID = &id
+		}
 		conversations = append(conversations, &conv)
 	}
 
 	return conversations, rows.Err()
 }
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMessage(row scanner) (*Message, error) {
+	var msg Message
+	var itemID sql.NullInt64
+	var systemEvent sql.NullString
+	var deliveredAt, editedAt, deletedAt, quarantinedAt sql.NullTime
+	err := row.Scan(&msg.ID, &msg.SenderUID, &msg.ReceiverUID, &itemID, &msg.Type, &systemEvent, &msg.Content,
+		&msg.IsRead, &deliveredAt, &editedAt, &deletedAt, &quarantinedAt, &msg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	msg.SystemEvent = systemEvent.String
+	if itemID.Valid {
+		id := int(itemID.Int64)
+		msg.ItemID = &id
+	}
+	if deliveredAt.Valid {
+		msg.DeliveredAt = &deliveredAt.Time
+	}
+	if editedAt.Valid {
+		msg.EditedAt = &editedAt.Time
+	}
+	if deletedAt.Valid {
+		msg.DeletedAt = &deletedAt.Time
+	}
+	if quarantinedAt.Valid {
+		msg.QuarantinedAt = &quarantinedAt.Time
+	}
+	return &msg, nil
+}
+
+// threadItemID は既読ポインタのスレッドの商品ID（商品に紐づかないスレッドは0）
+func threadItemID(itemID *int) int {
+	if itemID == nil {
+		return 0
+	}
+	return *itemID
+}
+
+// nullableItemID はスレッドの商品IDをSQLの引数にする（nilならNULL）
+func nullableItemID(itemID *int) interface{} {
+	if itemID == nil {
+		return nil
+	}
+	return *itemID
+}
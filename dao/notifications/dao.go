@@ -0,0 +1,232 @@
+package notifications
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Notification はアプリ内の通知
+// ActorNicknameとItemTitleは取得時に結合し、Textはusecaseで設定する
+type Notification struct {
+	ID            int64     `json:"id"`
+	UID           string    `json:"-"`
+	Type          string    `json:"type"`
+	ActorUID      string    `json:"actor_uid,omitempty"`
+	ActorNickname string    `json:"actor_nickname,omitempty"`
+	ItemID        *int      `json:"item_id"`
+	ItemTitle     string    `json:"item_title,omitempty"`
+	Text          string    `json:"text"`
+	IsRead        bool      `json:"is_read"`
+	CreatedAt     time.Time `json:"created_at"`
+	GroupKey      string    `json:"-"`
+}
+
+// NotificationDAOInterface はモック化のためのインターフェース
+type NotificationDAOInterface interface {
+	GetItemOwner(itemID int) (string, error)
+	Upsert(n *Notification, refresh bool) (id int64, changed bool, err error)
+	GetNotification(id int64) (*Notification, error)
+	List(uid string, unreadOnly bool, limit, offset int) ([]*Notification, error)
+	CountUnread(uid string) (int, error)
+	MarkAsRead(uid string, ids []int64) (int64, error)
+	MarkAllAsRead(uid string) (int64, error)
+	GetPreferences(uid string) (map[string]bool, error)
+	SetPreferences(uid string, prefs map[string]bool) error
+	GetEmailSettings(uid string) (*EmailSettings, error)
+	SetEmailSettings(uid string, s *EmailSettings) error
+	ClaimEmail(id int64, interval time.Duration) (bool, error)
+	ReleaseEmail(id int64) error
+}
+
+type NotificationDAO struct {
+	db *sql.DB
+}
+
+func NewNotificationDAO(db *sql.DB) *NotificationDAO {
+	return &NotificationDAO{db: db}
+}
+
+const notificationColumns = `
+	n.id, n.uid, n.type, COALESCE(n.actor_uid, ''), COALESCE(u.nickname, ''), n.item_id, COALESCE(i.title, ''),
+	n.read_at IS NOT NULL, n.created_at, n.group_key
+`
+
+const notificationJoins = `
+	LEFT JOIN users u ON u.uid = n.actor_uid
+	LEFT JOIN items i ON i.id = n.item_id
+`
+
+// GetItemOwner は商品の出品者のuidを返す（存在しない場合はsql.ErrNoRows）
+func (d *NotificationDAO) GetItemOwner(itemID int) (string, error) {
+	var uid string
+	if err := d.db.QueryRow("SELECT uid FROM items WHERE id = ?", itemID).Scan(&uid); err != nil {
+		return "", err
+	}
+	return uid, nil
+}
+
+// Upsert は通知を追加する。同じ相手・group_keyの通知があれば追加しない
+// refreshなら既存の通知を未読に戻し、きっかけになったユーザーと日時を更新する（メッセージの通知をスレッドごとにまとめる）
+// 通知のidと、追加・更新したか（同じ通知が既にあり何もしなかった場合はfalse）を返す
+func (d *NotificationDAO) Upsert(n *Notification, refresh bool) (int64, bool, error) {
+	// LAST_INSERT_ID(id) で既存の通知のidも返るようにする
+	onDuplicate := "id = LAST_INSERT_ID(id)"
+	if refresh {
+		onDuplicate += ", actor_uid = VALUES(actor_uid), read_at = NULL, created_at = CURRENT_TIMESTAMP"
+	}
+	query := `
+		INSERT INTO notifications (uid, type, actor_uid, item_id, group_key)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE ` + onDuplicate
+	result, err := d.db.Exec(query, n.UID, n.Type, nullIfEmpty(n.ActorUID), n.ItemID, n.GroupKey)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to insert notification: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get notification id: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	// 追加なら1、更新なら2、何も変わらなければ0
+	return id, affected > 0, nil
+}
+
+// GetNotification は通知を取得する（存在しない場合はsql.ErrNoRows）
+func (d *NotificationDAO) GetNotification(id int64) (*Notification, error) {
+	row := d.db.QueryRow("SELECT "+notificationColumns+" FROM notifications n "+notificationJoins+" WHERE n.id = ?", id)
+	return scanNotification(row)
+}
+
+// List は通知を新しい順に取得する（unreadOnlyなら未読のみ）
+func (d *NotificationDAO) List(uid string, unreadOnly bool, limit, offset int) ([]*Notification, error) {
+	query := "SELECT " + notificationColumns + " FROM notifications n " + notificationJoins + " WHERE n.uid = ?"
+	if unreadOnly {
+		query += " AND n.read_at IS NULL"
+	}
+	query += " ORDER BY n.created_at DESC, n.id DESC LIMIT ? OFFSET ?"
+	rows, err := d.db.Query(query, uid, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []*Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// CountUnread は未読の通知の数を返す
+func (d *NotificationDAO) CountUnread(uid string) (int, error) {
+	var count int
+	if err := d.db.QueryRow("SELECT COUNT(*) FROM notifications WHERE uid = ? AND read_at IS NULL", uid).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkAsRead はuidの通知のうちidsを既読にし、新たに既読にした件数を返す（他人の通知は無視する）
+func (d *NotificationDAO) MarkAsRead(uid string, ids []int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := []interface{}{uid}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	query := "UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE uid = ? AND read_at IS NULL AND id IN (" + placeholders + ")"
+	result, err := d.db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications as read: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// MarkAllAsRead はuidの未読の通知をすべて既読にし、その件数を返す
+func (d *NotificationDAO) MarkAllAsRead(uid string) (int64, error) {
+	result, err := d.db.Exec("UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE uid = ? AND read_at IS NULL", uid)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications as read: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetPreferences は種類ごとの受け取り設定を返す（設定していない種類は含まない）
+func (d *NotificationDAO) GetPreferences(uid string) (map[string]bool, error) {
+	rows, err := d.db.Query("SELECT type, enabled FROM notification_preferences WHERE uid = ?", uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	prefs := make(map[string]bool)
+	for rows.Next() {
+		var notificationType string
+		var enabled bool
+		if err := rows.Scan(&notificationType, &enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan notification preference: %w", err)
+		}
+		prefs[notificationType] = enabled
+	}
+	return prefs, rows.Err()
+}
+
+// SetPreferences は種類ごとの受け取り設定を保存する（prefsに含まない種類は変更しない）
+func (d *NotificationDAO) SetPreferences(uid string, prefs map[string]bool) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO notification_preferences (uid, type, enabled) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE enabled = VALUES(enabled)
+	`
+	for notificationType, enabled := range prefs {
+		if _, err := tx.Exec(query, uid, notificationType, enabled); err != nil {
+			return fmt.Errorf("failed to save notification preference: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanNotification(row scanner) (*Notification, error) {
+	var n Notification
+	var itemID sql.NullInt64
+	err := row.Scan(&n.ID, &n.UID, &n.Type, &n.ActorUID, &n.ActorNickname, &itemID, &n.ItemTitle,
+		&n.IsRead, &n.CreatedAt, &n.GroupKey)
+	if err != nil {
+		return nil, err
+	}
+	if itemID.Valid {
+		id := int(itemID.Int64)
+		n.ItemID = &id
+	}
+	return &n, nil
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,72 @@
+package notifications
+
+import (
+	"fmt"
+	"time"
+)
+
+// EmailSettings はメールの送信先と言語
+type EmailSettings struct {
+	Email    string `json:"email"`
+	Locale   string `json:"locale"`
+	Enabled  bool   `json:"enabled"`
+	Nickname string `json:"-"` // メールの宛名（usersから結合する）
+}
+
+// GetEmailSettings はメールの設定を返す（設定していない・退会済みの場合はsql.ErrNoRows）
+func (d *NotificationDAO) GetEmailSettings(uid string) (*EmailSettings, error) {
+	query := `
+		SELECT e.email, e.locale, e.enabled, COALESCE(u.nickname, '')
+		FROM email_settings e
+		JOIN users u ON u.uid = e.uid
+		WHERE e.uid = ? AND u.deleted_at IS NULL
+	`
+	var s EmailSettings
+	if err := d.db.QueryRow(query, uid).Scan(&s.Email, &s.Locale, &s.Enabled, &s.Nickname); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SetEmailSettings はメールの設定を保存する
+func (d *NotificationDAO) SetEmailSettings(uid string, s *EmailSettings) error {
+	query := `
+		INSERT INTO email_settings (uid, email, locale, enabled) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE email = VALUES(email), locale = VALUES(locale), enabled = VALUES(enabled)
+	`
+	if _, err := d.db.Exec(query, uid, s.Email, s.Locale, s.Enabled); err != nil {
+		return fmt.Errorf("failed to save email settings: %w", err)
+	}
+	return nil
+}
+
+// ClaimEmail は通知のメールを送る権利を取る（直近intervalに、intervalが0なら一度でもメールを送っていればfalse）
+// 複数インスタンスで同じ通知のメールを重複して送らないように、条件付きのUPDATEで取る
+func (d *NotificationDAO) ClaimEmail(id int64, interval time.Duration) (bool, error) {
+	query := "UPDATE notifications SET emailed_at = CURRENT_TIMESTAMP WHERE id = ? AND emailed_at IS NULL"
+	args := []interface{}{id}
+	if interval > 0 {
+		query = `
+			UPDATE notifications SET emailed_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND (emailed_at IS NULL OR emailed_at < CURRENT_TIMESTAMP - INTERVAL ? SECOND)
+		`
+		args = append(args, int64(interval.Seconds()))
+	}
+	result, err := d.db.Exec(query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim notification email: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// ReleaseEmail はClaimEmailで取った権利を戻す（メールを送信待ちに追加できなかった場合に、次の通知で送れるようにする）
+func (d *NotificationDAO) ReleaseEmail(id int64) error {
+	if _, err := d.db.Exec("UPDATE notifications SET emailed_at = NULL WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to release notification email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,340 @@
+package orders
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	auditDao "uttc-hackathon-backend/dao/audit"
+	"uttc-hackathon-backend/events"
+)
+
+// 注文の状態
+const (
+	StatusCreated   = "created"
+	StatusPaid      = "paid"
+	StatusShipped   = "shipped"
+	StatusDelivered = "delivered"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+	StatusRefunded  = "refunded"
+)
+
+// 支払い方法
+const (
+	PaymentCash    = "cash"
+	PaymentOnchain = "onchain"
+)
+
+var (
+	// ErrItemNotListed は商品が出品中でなく注文できない場合のエラー
+	ErrItemNotListed = errors.New("item is not listed")
+	// ErrStatusChanged は更新中に注文（または商品）の状態が変わっていた場合のエラー
+	ErrStatusChanged = errors.New("order status has changed")
+)
+
+// Order はordersテーブルの1行
+type Order struct {
+	ID            int64      `json:"id"`
+	ItemID        int        `json:"item_id"`
+	PurchaseID    int64      `json:"-"`
+	SellerUID     string     `json:"seller_uid"`
+	BuyerUID      string     `json:"buyer_uid"`
+	BuyerAddress  string     `json:"buyer_address"`
+	PaymentMethod string     `json:"payment_method"`
+	Status        string     `json:"status"`
+	Amount        int        `json:"amount"`
+	AmountWei     string     `json:"amount_wei"`
+	TxHash        string     `json:"tx_hash"`
+	CreatedAt     time.Time  `json:"created_at"`
+	PaidAt        *time.Time `json:"paid_at"`
+	ShippedAt     *time.Time `json:"shipped_at"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	CompletedAt   *time.Time `json:"completed_at"`
+	CancelledAt   *time.Time `json:"cancelled_at"`
+	RefundedAt    *time.Time `json:"refunded_at"`
+}
+
+// OrderItem は注文を作成するために必要な商品の情報
+type OrderItem struct {
+	ID          int
+	SellerUID   string
+	Status      string
+	Price       int
+	ChainItemID sql.NullInt64
+}
+
+// ItemChange は注文の状態変更と同じトランザクションで行う商品の状態の変更
+// 遷移の妥当性は呼び出し側（itemStatus.Lifecycle）で検証する
+type ItemChange struct {
+	From string
+	To   string
+}
+
+// OrderDAOInterface はモック化のためのインターフェース
+type OrderDAOInterface interface {
+	GetItem(itemID int) (*OrderItem, error)
+	FindItemIDByChainItemID(chainItemID int64) (int, error)
+	GetOrder(id int64) (*Order, error)
+	GetOrderByTxHash(txHash string) (*Order, error)
+	GetActiveOrderByItem(itemID int) (*Order, error)
+	ListOrders(uid, role string, limit, offset int) ([]*Order, error)
+	CreateOrder(order *Order, actor auditDao.Actor) (int64, error)
+	UpdateStatus(order *Order, to string, item *ItemChange, actor auditDao.Actor) error
+}
+
+type OrderDAO struct {
+	db  *sql.DB
+	bus *events.Bus
+}
+
+// NewOrderDAO は商品の状態の変化をbusへ送るOrderDAOを生成する（busはnilでもよい）
+func NewOrderDAO(db *sql.DB, bus *events.Bus) *OrderDAO {
+	return &OrderDAO{db: db, bus: bus}
+}
+
+const orderColumns = `
+	id, item_id, COALESCE(purchase_id, 0), seller_uid, COALESCE(buyer_uid, ''), COALESCE(buyer_address, ''),
+	payment_method, status, amount, COALESCE(amount_wei, ''), COALESCE(tx_hash, ''),
+	created_at, paid_at, shipped_at, delivered_at, completed_at, cancelled_at, refunded_at
+`
+
+// timestampColumns は状態ごとに遷移した日時を記録する列
+var timestampColumns = map[string]string{
+	StatusPaid:      "paid_at",
+	StatusShipped:   "shipped_at",
+	StatusDelivered: "delivered_at",
+	StatusCompleted: "completed_at",
+	StatusCancelled: "cancelled_at",
+	StatusRefunded:  "refunded_at",
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanOrder(row scanner) (*Order, error) {
+	o := &Order{}
+	var paidAt, shippedAt, deliveredAt, completedAt, cancelledAt, refundedAt sql.NullTime
+	err := row.Scan(&o.ID, &o.ItemID, &o.PurchaseID, &o.SellerUID, &o.BuyerUID, &o.BuyerAddress,
+		&o.PaymentMethod, &o.Status, &o.Amount, &o.AmountWei, &o.TxHash,
+		&o.CreatedAt, &paidAt, &shippedAt, &deliveredAt, &completedAt, &cancelledAt, &refundedAt)
+	if err != nil {
+		return nil, err
+	}
+	o.PaidAt = timePtr(paidAt)
+	o.ShippedAt = timePtr(shippedAt)
+	o.DeliveredAt = timePtr(deliveredAt)
+	o.CompletedAt = timePtr(completedAt)
+	o.CancelledAt = timePtr(cancelledAt)
+	o.RefundedAt = timePtr(refundedAt)
+	return o, nil
+}
+
+// GetItem は商品の出品者・状態・価格を取得する（存在しない場合はsql.ErrNoRows）
+func (d *OrderDAO) GetItem(itemID int) (*OrderItem, error) {
+	item := &OrderItem{}
+	err := d.db.QueryRow("SELECT id, uid, status, price, chain_item_id FROM items WHERE id = ?", itemID).
+		Scan(&item.ID, &item.SellerUID, &item.Status, &item.Price, &item.ChainItemID)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// FindItemIDByChainItemID はchain_item_idから商品IDを取得する（存在しない場合はsql.ErrNoRows）
+func (d *OrderDAO) FindItemIDByChainItemID(chainItemID int64) (int, error) {
+	var itemID int
+	if err := d.db.QueryRow("SELECT id FROM items WHERE chain_item_id = ?", chainItemID).Scan(&itemID); err != nil {
+		return 0, err
+	}
+	return itemID, nil
+}
+
+// GetOrder はidで注文を取得する（存在しない場合はsql.ErrNoRows）
+func (d *OrderDAO) GetOrder(id int64) (*Order, error) {
+	return scanOrder(d.db.QueryRow("SELECT "+orderColumns+" FROM orders WHERE id = ?", id))
+}
+
+// GetOrderByTxHash は購入トランザクションのハッシュで注文を取得する（存在しない場合はsql.ErrNoRows）
+func (d *OrderDAO) GetOrderByTxHash(txHash string) (*Order, error) {
+	return scanOrder(d.db.QueryRow("SELECT "+orderColumns+" FROM orders WHERE tx_hash = ?", txHash))
+}
+
+// GetActiveOrderByItem は商品の進行中・完了済みの最新の注文を取得する（キャンセル・返金済みは除く。ない場合はsql.ErrNoRows）
+func (d *OrderDAO) GetActiveOrderByItem(itemID int) (*Order, error) {
+	query := "SELECT " + orderColumns + " FROM orders WHERE item_id = ? AND status NOT IN ('cancelled', 'refunded') ORDER BY id DESC LIMIT 1"
+	return scanOrder(d.db.QueryRow(query, itemID))
+}
+
+// ListOrders はユーザーの注文を新しい順に取得する（roleはbuyerかseller）
+func (d *OrderDAO) ListOrders(uid, role string, limit, offset int) ([]*Order, error) {
+	column := "buyer_uid"
+	if role == "seller" {
+		column = "seller_uid"
+	}
+	query := "SELECT " + orderColumns + " FROM orders WHERE " + column + " = ? ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+	rows, err := d.db.Query(query, uid, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []*Order{}
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+// CreateOrder は商品を購入済みにし、購入記録と注文を同じトランザクションで作成する
+// 商品が出品中でない場合はErrItemNotListed。order.Statusまでの日時（paid_atなど）を記録する
+func (d *OrderDAO) CreateOrder(order *Order, actor auditDao.Actor) (int64, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"UPDATE items SET status = 'purchased', buyer_address = ? WHERE id = ? AND status = 'listed'",
+		nullIfEmpty(order.BuyerAddress), order.ItemID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update item status: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return 0, ErrItemNotListed
+	}
+
+	result, err = tx.Exec(
+		"INSERT INTO purchases (item_id, buyer_uid, buyer_address) VALUES (?, ?, ?)",
+		order.ItemID, order.BuyerUID, nullIfEmpty(order.BuyerAddress),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert purchase record: %w", err)
+	}
+	purchaseID, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get purchase id: %w", err)
+	}
+
+	paidAt := sql.NullTime{}
+	if order.PaidAt != nil {
+		paidAt = sql.NullTime{Time: *order.PaidAt, Valid: true}
+	}
+	result, err = tx.Exec(`
+		INSERT INTO orders (item_id, purchase_id, seller_uid, buyer_uid, buyer_address, payment_method, status, amount, amount_wei, tx_hash, paid_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, order.ItemID, purchaseID, order.SellerUID, nullIfEmpty(order.BuyerUID), nullIfEmpty(order.BuyerAddress),
+		order.PaymentMethod, order.Status, order.Amount, nullIfEmpty(order.AmountWei), nullIfEmpty(order.TxHash), paidAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert order: %w", err)
+	}
+	orderID, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get order id: %w", err)
+	}
+
+	after := map[string]interface{}{
+		"order_id": orderID, "status": order.Status, "payment_method": order.PaymentMethod,
+		"buyer_uid": order.BuyerUID, "buyer_address": order.BuyerAddress, "amount": order.Amount,
+	}
+	if err := auditDao.Record(tx, actor, "order.create", "item", strconv.Itoa(order.ItemID), map[string]interface{}{"status": "listed"}, after); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	d.bus.Publish(events.ItemStatusChanged, order.ItemID, []string{order.SellerUID, order.BuyerUID},
+		events.StatusChange{From: "listed", To: "purchased"})
+	return orderID, nil
+}
+
+// UpdateStatus は注文をorder.Statusからtoへ遷移させ、遷移した日時を記録する
+// itemがnilでなければ商品の状態もitem.Fromからitem.Toへ更新する
+// 遷移の妥当性は呼び出し側で検証する。更新中に注文・商品の状態が変わっていた場合はErrStatusChanged
+func (d *OrderDAO) UpdateStatus(order *Order, to string, item *ItemChange, actor auditDao.Actor) error {
+	column, ok := timestampColumns[to]
+	if !ok {
+		return fmt.Errorf("unknown order status: %s", to)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current string
+	if err := tx.QueryRow("SELECT status FROM orders WHERE id = ? FOR UPDATE", order.ID).Scan(&current); err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+	if current != order.Status {
+		return ErrStatusChanged
+	}
+
+	query := "UPDATE orders SET status = ?, " + column + " = CURRENT_TIMESTAMP WHERE id = ?"
+	if _, err := tx.Exec(query, to, order.ID); err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	if item != nil {
+		itemQuery := "UPDATE items SET status = ? WHERE id = ? AND status = ?"
+		if item.To == "listed" {
+			// 再出品する場合は購入者のアドレスを外す
+			itemQuery = "UPDATE items SET status = ?, buyer_address = NULL WHERE id = ? AND status = ?"
+		}
+		result, err := tx.Exec(itemQuery, item.To, order.ItemID, item.From)
+		if err != nil {
+			return fmt.Errorf("failed to update item status: %w", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if affected == 0 {
+			return ErrStatusChanged
+		}
+	}
+
+	before := map[string]interface{}{"order_id": order.ID, "status": order.Status}
+	after := map[string]interface{}{"order_id": order.ID, "status": to}
+	if item != nil {
+		before["item_status"] = item.From
+		after["item_status"] = item.To
+	}
+	if err := auditDao.Record(tx, actor, "order."+to, "item", strconv.Itoa(order.ItemID), before, after); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if item != nil {
+		d.bus.Publish(events.ItemStatusChanged, order.ItemID, []string{order.SellerUID, order.BuyerUID},
+			events.StatusChange{From: item.From, To: item.To})
+	}
+	return nil
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// nullIfEmpty は空文字列をNULLとして保存するための変換
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
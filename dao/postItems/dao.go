@@ -3,8 +3,19 @@ package postItems
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
+	auditDao "uttc-hackathon-backend/dao/audit"
 )
 
+// ItemImage は1枚の商品画像のサイズ別URL
+// MediumURL/ThumbnailURL/Hashは外部URLなど縮小版がない場合は空
+type ItemImage struct {
+	URL          string
+	MediumURL    string
+	ThumbnailURL string
+	Hash         string // imagesテーブルのコンテンツハッシュ（参照カウント用）
+}
+
 type ItemDAO struct {
 	db *sql.DB
 }
@@ -13,7 +24,7 @@ func NewItemDAO(db *sql.DB) *ItemDAO {
 	return &ItemDAO{db: db}
 }
 
-func (d *ItemDAO) InsertItem(title string, price int, explanation string, imageURLs []string, uid string, status string, category string) error {
+func (d *ItemDAO) InsertItem(title string, price int, explanation string, images []ItemImage, uid string, status string, category string) error {
 	// トランザクション開始
 	tx, err := d.db.Begin()
 	if err != nil {
@@ -38,11 +49,11 @@ func (d *ItemDAO) InsertItem(title string, price int, explanation string, imageU
 	}
 
 	// item_imagesテーブルに画像URLを挿入
-	if len(imageURLs) > 0 {
-		imageQuery := "INSERT INTO item_images (item_id, image_url) VALUES (?, ?)"
-		for _, url := range imageURLs {
-			if url != "" {
-				_, err := tx.Exec(imageQuery, itemID, url)
+	if len(images) > 0 {
+		imageQuery := "INSERT INTO item_images (item_id, image_url, medium_url, thumbnail_url, image_hash) VALUES (?, ?, ?, ?, ?)"
+		for _, img := range images {
+			if img.URL != "" {
+				_, err := tx.Exec(imageQuery, itemID, img.URL, nullIfEmpty(img.MediumURL), nullIfEmpty(img.ThumbnailURL), nullIfEmpty(img.Hash))
 				if err != nil {
 					return fmt.Errorf("failed to insert image URL into database: %w", err)
 				}
@@ -50,15 +61,43 @@ func (d *ItemDAO) InsertItem(title string, price int, explanation string, imageU
 		}
 	}
 
+	// 監査ログ（出品者本人による出品）
+	after := map[string]interface{}{"title": title, "price": price, "uid": uid, "status": status, "category": category}
+	if err := auditDao.Record(tx, auditDao.UserActor(uid), "item.create", "item", strconv.FormatInt(itemID, 10), nil, after); err != nil {
+		return err
+	}
+
 	// コミット
 	return tx.Commit()
 }
 
 // UpdateChainItemID は既存の商品にchain_item_idを関連付ける
-func (d *ItemDAO) UpdateChainItemID(itemID int64, chainItemID int64, sellerAddress string, tokenID int64) error {
+func (d *ItemDAO) UpdateChainItemID(itemID int64, chainItemID int64, sellerAddress string, tokenID int64, actor auditDao.Actor) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var beforeChainItemID, beforeTokenID sql.NullInt64
+	var beforeSeller sql.NullString
+	err = tx.QueryRow("SELECT chain_item_id, seller_address, token_id FROM items WHERE id = ? FOR UPDATE", itemID).
+		Scan(&beforeChainItemID, &beforeSeller, &beforeTokenID)
+	if err != nil {
+		return fmt.Errorf("failed to get item: %w", err)
+	}
+
 	query := "UPDATE items SET chain_item_id = ?, seller_address = ?, token_id = ? WHERE id = ?"
-	_, err := d.db.Exec(query, chainItemID, sellerAddress, tokenID, itemID)
-	return err
+	if _, err := tx.Exec(query, chainItemID, sellerAddress, tokenID, itemID); err != nil {
+		return err
+	}
+
+	before := map[string]interface{}{"chain_item_id": nullableInt(beforeChainItemID), "seller_address": beforeSeller.String, "token_id": nullableInt(beforeTokenID)}
+	after := map[string]interface{}{"chain_item_id": chainItemID, "seller_address": sellerAddress, "token_id": tokenID}
+	if err := auditDao.Record(tx, actor, "item.link_chain", "item", strconv.FormatInt(itemID, 10), before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // FindItemByUidAndTitle はuidとtitleで商品を検索（chain_item_idを関連付けるため）
@@ -84,7 +123,7 @@ func (d *ItemDAO) FindItemByChainItemID(chainItemID int64) (int64, error) {
 }
 
 // InsertItemWithChainID はchain_item_idを含めて商品を挿入
-func (d *ItemDAO) InsertItemWithChainID(title string, price int, explanation string, imageURLs []string, uid string, status string, category string, chainItemID int64, sellerAddress string, tokenID int64) error {
+func (d *ItemDAO) InsertItemWithChainID(title string, price int, explanation string, images []ItemImage, uid string, status string, category string, chainItemID int64, sellerAddress string, tokenID int64, actor auditDao.Actor) error {
 	// トランザクション開始
 	tx, err := d.db.Begin()
 	if err != nil {
@@ -109,16 +148,16 @@ func (d *ItemDAO) InsertItemWithChainID(title string, price int, explanation str
 	}
 
 	// item_imagesテーブルに画像URLを挿入
-	if len(imageURLs) > 0 {
+	if len(images) > 0 {
 		// chain_item_idカラムがある場合は含める、ない場合はitem_idのみ
-		imageQuery := "INSERT INTO item_images (item_id, image_url, chain_item_id) VALUES (?, ?, ?)"
-		for _, url := range imageURLs {
-			if url != "" {
-				_, err := tx.Exec(imageQuery, itemID, url, chainItemID)
+		imageQuery := "INSERT INTO item_images (item_id, image_url, medium_url, thumbnail_url, image_hash, chain_item_id) VALUES (?, ?, ?, ?, ?, ?)"
+		for _, img := range images {
+			if img.URL != "" {
+				_, err := tx.Exec(imageQuery, itemID, img.URL, nullIfEmpty(img.MediumURL), nullIfEmpty(img.ThumbnailURL), nullIfEmpty(img.Hash), chainItemID)
 				if err != nil {
 					// chain_item_idカラムがない可能性があるので、item_idのみで再試行
-					imageQueryFallback := "INSERT INTO item_images (item_id, image_url) VALUES (?, ?)"
-					_, err2 := tx.Exec(imageQueryFallback, itemID, url)
+					imageQueryFallback := "INSERT INTO item_images (item_id, image_url, medium_url, thumbnail_url, image_hash) VALUES (?, ?, ?, ?, ?)"
+					_, err2 := tx.Exec(imageQueryFallback, itemID, img.URL, nullIfEmpty(img.MediumURL), nullIfEmpty(img.ThumbnailURL), nullIfEmpty(img.Hash))
 					if err2 != nil {
 						return fmt.Errorf("failed to insert image URL: %w (original error: %v)", err2, err)
 					}
@@ -127,6 +166,28 @@ func (d *ItemDAO) InsertItemWithChainID(title string, price int, explanation str
 		}
 	}
 
+	// 監査ログ（onchainイベントによる出品）
+	after := map[string]interface{}{
+		"title": title, "price": price, "uid": uid, "status": status, "category": category,
+		"chain_item_id": chainItemID, "seller_address": sellerAddress, "token_id": tokenID,
+	}
+	if err := auditDao.Record(tx, actor, "item.create", "item", strconv.FormatInt(itemID, 10), nil, after); err != nil {
+		return err
+	}
+
 	// コミット
 	return tx.Commit()
 }
+
+// nullableInt は監査ログ用にNULLをnilに変換する
+func nullableInt(v sql.NullInt64) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.Int64
+}
+
+// nullIfEmpty は空文字列をNULLとして保存するための変換
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -2,6 +2,8 @@ package postUser
 
 import (
 	"database/sql"
+	"fmt"
+	auditDao "uttc-hackathon-backend/dao/audit"
 )
 
 type UserDAO struct {
@@ -12,8 +14,22 @@ func NewUserDAO(db *sql.DB) *UserDAO {
 	return &UserDAO{db: db}
 }
 
-func (d *UserDAO) InsertUser(uid string, nickname string, sex string, birthyear int, birthdate int) error {
+// InsertUser はユーザーを登録する。birthdateはDATE列（未設定はNULL）
+func (d *UserDAO) InsertUser(uid string, nickname string, sex string, birthyear int, birthdate sql.NullTime) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := "INSERT INTO users (uid, nickname, sex, birthyear, birthdate) VALUES (?, ?, ?, ?, ?)"
-	_, err := d.db.Exec(query, uid, nickname, sex, birthyear, birthdate)
-	return err
+	if _, err := tx.Exec(query, uid, nickname, sex, birthyear, birthdate); err != nil {
+		return err
+	}
+
+	after := map[string]interface{}{"nickname": nickname, "sex": sex, "birthyear": birthyear}
+	if err := auditDao.Record(tx, auditDao.UserActor(uid), "user.create", "user", uid, nil, after); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
@@ -2,21 +2,27 @@ package purchaseItem
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
-	"strings"
+	"strconv"
 	"time"
+	auditDao "uttc-hackathon-backend/dao/audit"
+	"uttc-hackathon-backend/events"
 )
 
+// ErrStatusChanged は更新中に商品の状態が変わっていた場合のエラー
+var ErrStatusChanged = errors.New("item status has changed")
+
 // PurchaseDAOInterface はモック化のためのインターフェース
 type PurchaseDAOInterface interface {
-	UpdatePurchaseStatus(itemID int, buyerUID string, buyerAddress string) error
 	GetPurchasedItems(buyerUID string, buyerAddress string) ([]*PurchasedItem, error)
 	GetUIDByWalletAddress(walletAddress string) (string, error)
 }
 
 type PurchaseDAO struct {
-	db *sql.DB
+	db  *sql.DB
+	bus *events.Bus
 }
 
 type PurchasedItem struct {
@@ -30,95 +36,9 @@ type PurchasedItem struct {
 	PurchasedAt time.Time `json:"purchased_at"`
 }
 
-func NewPurchaseDAO(db *sql.DB) *PurchaseDAO {
-	return &PurchaseDAO{db: db}
-}
-
-func (d *PurchaseDAO) UpdatePurchaseStatus(itemID int, buyerUID string, buyerAddress string) error {
-	tx, err := d.db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
-	// 商品の出品者UIDを取得して、購入者UIDと比較
-	var sellerUID string
-	err = tx.QueryRow("SELECT uid FROM items WHERE id = ?", itemID).Scan(&sellerUID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("item not found: itemID=%d", itemID)
-		}
-		return fmt.Errorf("failed to get seller uid: %w", err)
-	}
-
-	// 出品者が自分の商品を購入できないようにする
-	if sellerUID == buyerUID {
-		return fmt.Errorf("seller cannot purchase their own item")
-	}
-
-	// itemsテーブルのstatusとbuyer_addressを更新
-	// statusが'listed'または'purchased'の場合に更新（重複イベントに対応）
-	updateQuery := "UPDATE items SET status = 'purchased', buyer_address = ? WHERE id = ? AND status IN ('listed', 'purchased')"
-	result, err := tx.Exec(updateQuery, buyerAddress, itemID)
-	if err != nil {
-		if strings.Contains(err.Error(), "Unknown column 'buyer_address'") {
-			return fmt.Errorf("buyer_address column does not exist in items table. Please run the migration script: add_buyer_address_column_safe.sql. Original error: %w", err)
-		}
-		return fmt.Errorf("failed to update purchase status: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	// 更新されなかった場合、既に完了済みか確認
-	if rowsAffected == 0 {
-		var currentStatus string
-		if err := tx.QueryRow("SELECT status FROM items WHERE id = ?", itemID).Scan(&currentStatus); err != nil {
-			if err == sql.ErrNoRows {
-				return fmt.Errorf("item not found: itemID=%d", itemID)
-			}
-			return fmt.Errorf("failed to check current status: %w", err)
-		}
-		// 既に完了済みの場合は正常終了
-		if currentStatus == "completed" || currentStatus == "cancelled" {
-			return tx.Commit()
-		}
-		return fmt.Errorf("item status is '%s', cannot update to purchased", currentStatus)
-	}
-
-	// purchasesテーブルに購入情報を挿入（重複チェック付き）
-	var insertQuery string
-	if buyerAddress != "" {
-		insertQuery = `
-			INSERT INTO purchases (item_id, buyer_uid, buyer_address) 
-			SELECT ?, ?, ? 
-			WHERE NOT EXISTS (
-				SELECT 1 FROM purchases 
-				WHERE item_id = ? AND buyer_address = ?
-			)
-		`
-		_, err = tx.Exec(insertQuery, itemID, buyerUID, buyerAddress, itemID, buyerAddress)
-	} else {
-		insertQuery = `
-			INSERT INTO purchases (item_id, buyer_uid, buyer_address) 
-			SELECT ?, ?, ? 
-			WHERE NOT EXISTS (
-				SELECT 1 FROM purchases 
-				WHERE item_id = ? AND buyer_uid = ? AND (buyer_address IS NULL OR buyer_address = '')
-			)
-		`
-		_, err = tx.Exec(insertQuery, itemID, buyerUID, buyerAddress, itemID, buyerUID)
-	}
-	if err != nil {
-		return fmt.Errorf("failed to insert purchase record: %w", err)
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-	return nil
+// NewPurchaseDAO は商品の状態の変化をbusへ送るPurchaseDAOを生成する（busはnilでもよい）
+func NewPurchaseDAO(db *sql.DB, bus *events.Bus) *PurchaseDAO {
+	return &PurchaseDAO{db: db, bus: bus}
 }
 
 // GetUIDByWalletAddress はウォレットアドレスからUIDを取得
@@ -227,47 +147,57 @@ func (d *PurchaseDAO) GetPurchasedItems(buyerUID string, buyerAddress string) ([
 	return items, nil
 }
 
-// UpdateToCompleted は商品受け取り確認時にステータスをcompletedに更新
-func (d *PurchaseDAO) UpdateToCompleted(chainItemID int64) error {
-	query := `UPDATE items SET status = 'completed' WHERE chain_item_id = ? AND status = 'purchased'`
-	result, err := d.db.Exec(query, chainItemID)
+// GetItemStatusByChainID はchain_item_idの商品のidと状態を取得する（存在しない場合はsql.ErrNoRows）
+func (d *PurchaseDAO) GetItemStatusByChainID(chainItemID int64) (int, string, error) {
+	var itemID int
+	var status string
+	err := d.db.QueryRow("SELECT id, status FROM items WHERE chain_item_id = ?", chainItemID).Scan(&itemID, &status)
 	if err != nil {
-		return fmt.Errorf("failed to update status to completed: %w", err)
+		return 0, "", err
 	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	if rowsAffected == 0 {
-		log.Printf("No rows updated for chain_item_id=%d (may already be completed)", chainItemID)
-	} else {
-		log.Printf("Updated status to completed for chain_item_id=%d", chainItemID)
-	}
-
-	return nil
+	return itemID, status, nil
 }
 
-// UpdateToCancelled は商品キャンセル時にステータスをcancelledに更新
-func (d *PurchaseDAO) UpdateToCancelled(chainItemID int64) error {
-	query := `UPDATE items SET status = 'cancelled' WHERE chain_item_id = ? AND status = 'listed'`
-	result, err := d.db.Exec(query, chainItemID)
+// UpdateStatusByChainID はchain_item_idの商品をfromからtoへ遷移させ、同じトランザクションで監査ログを残す
+// 遷移の妥当性は呼び出し側（itemStatus.Lifecycle）で検証する。更新中に状態が変わっていた場合はErrStatusChanged
+func (d *PurchaseDAO) UpdateStatusByChainID(chainItemID int64, from string, to string, action string, actor auditDao.Actor) error {
+	tx, err := d.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to update status to cancelled: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	// 配信先は出品者と、取引中の注文の購入者（購入前なら出品者だけ）
+	var itemID int64
+	var status, sellerUID, buyerUID string
+	err = tx.QueryRow(`
+		SELECT i.id, i.status, i.uid, COALESCE((
+			SELECT o.buyer_uid FROM orders o
+			WHERE o.item_id = i.id AND o.status NOT IN ('cancelled', 'refunded')
+			ORDER BY o.id DESC LIMIT 1
+		), '')
+		FROM items i WHERE i.chain_item_id = ? FOR UPDATE
+	`, chainItemID).Scan(&itemID, &status, &sellerUID, &buyerUID)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to get item: %w", err)
+	}
+	if status != from {
+		return ErrStatusChanged
 	}
 
-	if rowsAffected == 0 {
-		log.Printf("No rows updated for chain_item_id=%d (may already be cancelled or purchased)", chainItemID)
-	} else {
-		log.Printf("Updated status to cancelled for chain_item_id=%d", chainItemID)
+	if _, err := tx.Exec("UPDATE items SET status = ? WHERE id = ?", to, itemID); err != nil {
+		return fmt.Errorf("failed to update item status: %w", err)
+	}
+	before := map[string]interface{}{"status": status}
+	after := map[string]interface{}{"status": to}
+	if err := auditDao.Record(tx, actor, action, "item", strconv.FormatInt(itemID, 10), before, after); err != nil {
+		return err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	d.bus.Publish(events.ItemStatusChanged, int(itemID), []string{sellerUID, buyerUID}, events.StatusChange{From: status, To: to})
 	return nil
 }
 
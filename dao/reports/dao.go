@@ -0,0 +1,258 @@
+package reports
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	auditDao "uttc-hackathon-backend/dao/audit"
+)
+
+// Report は商品・メッセージ・ユーザーへの通報
+type Report struct {
+	ID          int        `json:"id"`
+	ReporterUID string     `json:"reporter_uid"`
+	TargetType  string     `json:"target_type"` // item, message, user
+	TargetID    string     `json:"target_id"`
+	Reason      string     `json:"reason"`
+	Detail      string     `json:"detail"`
+	Status      string     `json:"status"` // open, resolved, dismissed
+	Action      string     `json:"action,omitempty"`
+	ResolvedBy  string     `json:"resolved_by,omitempty"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	// TargetOpenReports は同じ対象への未対応の通報数（モデレーションキューの優先度の目安）
+	TargetOpenReports int `json:"target_open_reports"`
+}
+
+// Resolution は通報への対応内容。対象への未対応の通報をまとめてクローズする
+type Resolution struct {
+	TargetType   string
+	TargetID     string
+	Status       string // resolved, dismissed
+	Action       string
+	ReportID     int // 対応のきっかけになった通報（監査ログ用）
+	ModeratorUID string
+	HideItemID   int    // 0以外なら商品を非表示にする
+	SuspendUID   string // 空でなければユーザーを利用停止にする
+	// ReleaseMessageID が0以外なら、フィルタで保留していたメッセージを受信者に見せる
+	ReleaseMessageID int
+}
+
+// ReportDAOInterface はモック化のためのインターフェース
+type ReportDAOInterface interface {
+	UserExists(uid string) (bool, error)
+	GetItemOwner(itemID int) (string, error)
+	GetMessageParticipants(messageID int) (senderUID, receiverUID string, err error)
+	HasOpenReport(reporterUID, targetType, targetID string) (bool, error)
+	CreateReport(report *Report) (*Report, error)
+	GetReport(id int) (*Report, error)
+	ListReports(status string, limit, offset int) ([]*Report, error)
+	Resolve(res *Resolution) (closed int64, released bool, err error)
+}
+
+type ReportDAO struct {
+	db *sql.DB
+}
+
+func NewReportDAO(db *sql.DB) *ReportDAO {
+	return &ReportDAO{db: db}
+}
+
+// UserExists はusersテーブルにuidが登録されているかを返す
+func (d *ReportDAO) UserExists(uid string) (bool, error) {
+	var exists bool
+	if err := d.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE uid = ?)", uid).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check user: %w", err)
+	}
+	return exists, nil
+}
+
+// GetItemOwner は商品の出品者のuidを返す（存在しない場合はsql.ErrNoRows）
+func (d *ReportDAO) GetItemOwner(itemID int) (string, error) {
+	var uid string
+	if err := d.db.QueryRow("SELECT uid FROM items WHERE id = ?", itemID).Scan(&uid); err != nil {
+		return "", err
+	}
+	return uid, nil
+}
+
+// GetMessageParticipants はメッセージの送信者と受信者を返す（存在しない場合はsql.ErrNoRows）
+func (d *ReportDAO) GetMessageParticipants(messageID int) (string, string, error) {
+	var sender, receiver string
+	query := "SELECT sender_uid, receiver_uid FROM messages WHERE id = ?"
+	if err := d.db.QueryRow(query, messageID).Scan(&sender, &receiver); err != nil {
+		return "", "", err
+	}
+	return sender, receiver, nil
+}
+
+// HasOpenReport は同じ人が同じ対象を通報していて未対応のものがあるかを返す
+func (d *ReportDAO) HasOpenReport(reporterUID, targetType, targetID string) (bool, error) {
+	var exists bool
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM reports
+			WHERE reporter_uid = ? AND target_type = ? AND target_id = ? AND status = 'open'
+		)
+	`
+	if err := d.db.QueryRow(query, reporterUID, targetType, targetID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check report: %w", err)
+	}
+	return exists, nil
+}
+
+func (d *ReportDAO) CreateReport(report *Report) (*Report, error) {
+	query := "INSERT INTO reports (reporter_uid, target_type, target_id, reason, detail) VALUES (?, ?, ?, ?, ?)"
+	result, err := d.db.Exec(query, report.ReporterUID, report.TargetType, report.TargetID, report.Reason, report.Detail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert report: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report id: %w", err)
+	}
+	return d.GetReport(int(id))
+}
+
+const reportColumns = `
+	r.id, r.reporter_uid, r.target_type, r.target_id, r.reason, r.detail, r.status,
+	r.action, r.resolved_by, r.resolved_at, r.created_at,
+	(SELECT COUNT(*) FROM reports o
+	 WHERE o.target_type = r.target_type AND o.target_id = r.target_id AND o.status = 'open')
+`
+
+// GetReport は通報を取得する（存在しない場合はsql.ErrNoRows）
+func (d *ReportDAO) GetReport(id int) (*Report, error) {
+	row := d.db.QueryRow("SELECT "+reportColumns+" FROM reports r WHERE r.id = ?", id)
+	return scanReport(row)
+}
+
+// ListReports は指定した状態の通報を古い順に取得する（モデレーションキュー）
+func (d *ReportDAO) ListReports(status string, limit, offset int) ([]*Report, error) {
+	query := "SELECT " + reportColumns + " FROM reports r WHERE r.status = ? ORDER BY r.created_at ASC, r.id ASC LIMIT ? OFFSET ?"
+	rows, err := d.db.Query(query, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []*Report{}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// Resolve は商品の非表示・ユーザーの利用停止・保留したメッセージの公開を行い、対象への未対応の通報をまとめてクローズする
+// クローズした通報の件数と、メッセージを公開したか（保留中だったか）を返す
+func (d *ReportDAO) Resolve(res *Resolution) (int64, bool, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if res.HideItemID != 0 {
+		if _, err := tx.Exec("UPDATE items SET hidden = TRUE WHERE id = ?", res.HideItemID); err != nil {
+			return 0, false, fmt.Errorf("failed to hide item: %w", err)
+		}
+	}
+	if res.SuspendUID != "" {
+		query := "UPDATE users SET suspended_at = COALESCE(suspended_at, CURRENT_TIMESTAMP) WHERE uid = ?"
+		if _, err := tx.Exec(query, res.SuspendUID); err != nil {
+			return 0, false, fmt.Errorf("failed to suspend user: %w", err)
+		}
+	}
+
+	var released int64
+	if res.ReleaseMessageID != 0 {
+		// 公開した日時を残し、既読ポインタより古いメッセージでも未読として数える
+		query := "UPDATE messages SET quarantined_at = NULL, released_at = CURRENT_TIMESTAMP(6) WHERE id = ? AND quarantined_at IS NOT NULL"
+		result, err := tx.Exec(query, res.ReleaseMessageID)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to release message: %w", err)
+		}
+		if released, err = result.RowsAffected(); err != nil {
+			return 0, false, fmt.Errorf("failed to get affected rows: %w", err)
+		}
+	}
+
+	query := `
+		UPDATE reports
+		SET status = ?, action = ?, resolved_by = ?, resolved_at = CURRENT_TIMESTAMP
+		WHERE target_type = ? AND target_id = ? AND status = 'open'
+	`
+	result, err := tx.Exec(query, res.Status, nullIfEmpty(res.Action), res.ModeratorUID, res.TargetType, res.TargetID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to close reports: %w", err)
+	}
+	closed, err := result.RowsAffected()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	action := res.Action
+	if action == "" {
+		action = "dismiss"
+	}
+	after := map[string]interface{}{"status": res.Status, "closed_reports": closed}
+	if res.HideItemID != 0 {
+		after["hidden_item_id"] = res.HideItemID
+	}
+	if res.SuspendUID != "" {
+		after["suspended_uid"] = res.SuspendUID
+	}
+	if released > 0 {
+		after["released_message_id"] = res.ReleaseMessageID
+	}
+	err = auditDao.Insert(tx, &auditDao.Event{
+		ActorType:  auditDao.ActorAdmin,
+		ActorUID:   res.ModeratorUID,
+		Source:     auditDao.SourceModeration,
+		Action:     "report." + action,
+		EntityType: res.TargetType,
+		EntityID:   res.TargetID,
+		Before:     auditDao.Values(map[string]interface{}{"status": "open", "report_id": res.ReportID}),
+		After:      auditDao.Values(after),
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, false, fmt.Errorf("failed to commit: %w", err)
+	}
+	return closed, released > 0, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanReport(row rowScanner) (*Report, error) {
+	var r Report
+	var detail, action, resolvedBy sql.NullString
+	var resolvedAt sql.NullTime
+	err := row.Scan(&r.ID, &r.ReporterUID, &r.TargetType, &r.TargetID, &r.Reason, &detail, &r.Status,
+		&action, &resolvedBy, &resolvedAt, &r.CreatedAt, &r.TargetOpenReports)
+	if err != nil {
+		return nil, err
+	}
+	r.Detail = detail.String
+	r.Action = action.String
+	r.ResolvedBy = resolvedBy.String
+	if resolvedAt.Valid {
+		r.ResolvedAt = &resolvedAt.Time
+	}
+	return &r, nil
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
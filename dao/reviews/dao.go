@@ -0,0 +1,156 @@
+package reviews
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrDuplicateReview は同じ取引で既にレビュー済みの場合のエラー
+var ErrDuplicateReview = errors.New("review already exists")
+
+// mysqlDuplicateEntry は一意制約違反のエラー番号
+const mysqlDuplicateEntry = 1062
+
+type Review struct {
+	ID          int       `json:"id"`
+	ItemID      int       `json:"item_id"`
+	ReviewerUID string    `json:"reviewer_uid"`
+	RevieweeUID string    `json:"reviewee_uid"`
+	Role        string    `json:"role"` // レビューした人の立場（buyer / seller）
+	Rating      int       `json:"rating"`
+	Comment     string    `json:"comment"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Transaction はレビュー対象の取引（商品の出品者と購入者）
+type Transaction struct {
+	ItemID    int
+	SellerUID string
+	BuyerUID  string // 購入記録がない、または購入者が未登録の場合は空
+	Status    string
+}
+
+// RatingSummary はユーザーが受け取った評価の集計
+type RatingSummary struct {
+	Average *float64 `json:"average"` // レビューがない場合はnull
+	Count   int      `json:"count"`
+}
+
+// ReviewDAOInterface はモック化のためのインターフェース
+type ReviewDAOInterface interface {
+	GetTransaction(itemID int) (*Transaction, error)
+	CreateReview(review *Review) error
+	GetReviewsByReviewee(uid string, limit, offset int) ([]*Review, error)
+	GetReviewsByItem(itemID int) ([]*Review, error)
+	GetRatingSummary(uid string) (*RatingSummary, error)
+}
+
+type ReviewDAO struct {
+	db *sql.DB
+}
+
+func NewReviewDAO(db *sql.DB) *ReviewDAO {
+	return &ReviewDAO{db: db}
+}
+
+// GetTransaction は商品の出品者と、最新の購入記録の購入者を取得する（商品がない場合はsql.ErrNoRows）
+// オンチェーン購入で購入時にUIDが分からなかった場合は、ウォレットアドレスから購入者を引き直す
+func (d *ReviewDAO) GetTransaction(itemID int) (*Transaction, error) {
+	query := `
+		SELECT i.id, i.uid, i.status,
+			COALESCE(NULLIF(p.buyer_uid, ''), (SELECT u.uid FROM users u WHERE u.wallet_address = p.buyer_address LIMIT 1))
+		FROM items i
+		LEFT JOIN purchases p ON p.id = (SELECT MAX(id) FROM purchases WHERE item_id = i.id)
+		WHERE i.id = ?
+	`
+	var tx Transaction
+	var status, buyerUID sql.NullString
+	if err := d.db.QueryRow(query, itemID).Scan(&tx.ItemID, &tx.SellerUID, &status, &buyerUID); err != nil {
+		return nil, err
+	}
+	tx.Status = status.String
+	tx.BuyerUID = buyerUID.String
+	return &tx, nil
+}
+
+// CreateReview はレビューを登録する（同じ商品・レビュアーの組はErrDuplicateReview）
+func (d *ReviewDAO) CreateReview(review *Review) error {
+	query := `
+		INSERT INTO reviews (item_id, reviewer_uid, reviewee_uid, role, rating, comment)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	result, err := d.db.Exec(query, review.ItemID, review.ReviewerUID, review.RevieweeUID, review.Role, review.Rating, review.Comment)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			return ErrDuplicateReview
+		}
+		return fmt.Errorf("failed to insert review: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	review.ID = int(id)
+	review.CreatedAt = time.Now()
+	return nil
+}
+
+// GetReviewsByReviewee はユーザーが受け取ったレビューを新しい順に取得する
+func (d *ReviewDAO) GetReviewsByReviewee(uid string, limit, offset int) ([]*Review, error) {
+	query := `
+		SELECT id, item_id, reviewer_uid, reviewee_uid, role, rating, comment, created_at
+		FROM reviews WHERE reviewee_uid = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`
+	return d.queryReviews(query, uid, limit, offset)
+}
+
+// GetReviewsByItem は取引（商品）に付いたレビューを取得する（最大2件）
+func (d *ReviewDAO) GetReviewsByItem(itemID int) ([]*Review, error) {
+	query := `
+		SELECT id, item_id, reviewer_uid, reviewee_uid, role, rating, comment, created_at
+		FROM reviews WHERE item_id = ?
+		ORDER BY id
+	`
+	return d.queryReviews(query, itemID)
+}
+
+func (d *ReviewDAO) queryReviews(query string, args ...interface{}) ([]*Review, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := []*Review{}
+	for rows.Next() {
+		var r Review
+		var comment sql.NullString
+		if err := rows.Scan(&r.ID, &r.ItemID, &r.ReviewerUID, &r.RevieweeUID, &r.Role, &r.Rating, &comment, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan review row: %w", err)
+		}
+		r.Comment = comment.String
+		reviews = append(reviews, &r)
+	}
+	return reviews, rows.Err()
+}
+
+// GetRatingSummary はユーザーが受け取った評価の平均と件数を取得する
+func (d *ReviewDAO) GetRatingSummary(uid string) (*RatingSummary, error) {
+	var summary RatingSummary
+	var average sql.NullFloat64
+	err := d.db.QueryRow("SELECT AVG(rating), COUNT(*) FROM reviews WHERE reviewee_uid = ?", uid).Scan(&average, &summary.Count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rating summary: %w", err)
+	}
+	if average.Valid {
+		summary.Average = &average.Float64
+	}
+	return &summary, nil
+}
@@ -0,0 +1,218 @@
+package shipping
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+	auditDao "uttc-hackathon-backend/dao/audit"
+)
+
+// Address はshipping_addressesテーブルの1行（住所本体は暗号化したまま扱う）
+type Address struct {
+	ID        int64
+	UID       string
+	Label     string
+	Encrypted []byte
+	IsDefault bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Shipment は商品の最新の購入記録と発送情報
+type Shipment struct {
+	PurchaseID       int64
+	ItemID           int
+	SellerUID        string
+	BuyerUID         string
+	ItemStatus       string
+	EncryptedAddress []byte // 未指定の場合はnil
+	Carrier          string
+	TrackingNumber   string
+	ShippedAt        sql.NullTime
+}
+
+// ShippingDAOInterface はモック化のためのインターフェース
+type ShippingDAOInterface interface {
+	ListAddresses(uid string) ([]*Address, error)
+	GetAddress(id int64) (*Address, error)
+	CreateAddress(address *Address) (int64, error)
+	UpdateAddress(address *Address) error
+	DeleteAddress(id int64) error
+	GetShipment(itemID int) (*Shipment, error)
+	AttachAddress(shipment *Shipment, encrypted []byte, actor auditDao.Actor) error
+	SetTracking(shipment *Shipment, carrier, trackingNumber string, actor auditDao.Actor) error
+}
+
+type ShippingDAO struct {
+	db *sql.DB
+}
+
+func NewShippingDAO(db *sql.DB) *ShippingDAO {
+	return &ShippingDAO{db: db}
+}
+
+// ListAddresses はユーザーの住所をデフォルト、登録順に取得する
+func (d *ShippingDAO) ListAddresses(uid string) ([]*Address, error) {
+	query := `
+		SELECT id, uid, label, encrypted_address, is_default, created_at, updated_at
+		FROM shipping_addresses WHERE uid = ?
+		ORDER BY is_default DESC, id
+	`
+	rows, err := d.db.Query(query, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query addresses: %w", err)
+	}
+	defer rows.Close()
+
+	addresses := []*Address{}
+	for rows.Next() {
+		a := &Address{}
+		if err := rows.Scan(&a.ID, &a.UID, &a.Label, &a.Encrypted, &a.IsDefault, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan address: %w", err)
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+// GetAddress はidで住所を取得する（存在しない場合はsql.ErrNoRows）
+func (d *ShippingDAO) GetAddress(id int64) (*Address, error) {
+	query := `
+		SELECT id, uid, label, encrypted_address, is_default, created_at, updated_at
+		FROM shipping_addresses WHERE id = ?
+	`
+	a := &Address{}
+	err := d.db.QueryRow(query, id).Scan(&a.ID, &a.UID, &a.Label, &a.Encrypted, &a.IsDefault, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// CreateAddress は住所を登録する。デフォルトにする場合は同じユーザーの他の住所のデフォルトを外す
+func (d *ShippingDAO) CreateAddress(address *Address) (int64, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if address.IsDefault {
+		if _, err := tx.Exec("UPDATE shipping_addresses SET is_default = FALSE WHERE uid = ?", address.UID); err != nil {
+			return 0, fmt.Errorf("failed to reset default address: %w", err)
+		}
+	}
+	result, err := tx.Exec(
+		"INSERT INTO shipping_addresses (uid, label, encrypted_address, is_default) VALUES (?, ?, ?, ?)",
+		address.UID, address.Label, address.Encrypted, address.IsDefault,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert address: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get address id: %w", err)
+	}
+	return id, tx.Commit()
+}
+
+// UpdateAddress は住所を更新する。デフォルトにする場合は同じユーザーの他の住所のデフォルトを外す
+func (d *ShippingDAO) UpdateAddress(address *Address) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if address.IsDefault {
+		if _, err := tx.Exec("UPDATE shipping_addresses SET is_default = FALSE WHERE uid = ? AND id <> ?", address.UID, address.ID); err != nil {
+			return fmt.Errorf("failed to reset default address: %w", err)
+		}
+	}
+	_, err = tx.Exec(
+		"UPDATE shipping_addresses SET label = ?, encrypted_address = ?, is_default = ? WHERE id = ?",
+		address.Label, address.Encrypted, address.IsDefault, address.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update address: %w", err)
+	}
+	return tx.Commit()
+}
+
+// DeleteAddress は住所を削除する（購入に指定した配送先はコピーなので影響しない）
+func (d *ShippingDAO) DeleteAddress(id int64) error {
+	if _, err := d.db.Exec("DELETE FROM shipping_addresses WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete address: %w", err)
+	}
+	return nil
+}
+
+// GetShipment は商品の出品者と最新の購入記録を取得する（購入記録がない場合はsql.ErrNoRows）
+// オンチェーン購入で購入時にUIDが分からなかった場合は、ウォレットアドレスから購入者を引き直す
+func (d *ShippingDAO) GetShipment(itemID int) (*Shipment, error) {
+	query := `
+		SELECT p.id, i.id, i.uid, i.status,
+			COALESCE(NULLIF(p.buyer_uid, ''), (SELECT u.uid FROM users u WHERE u.wallet_address = p.buyer_address LIMIT 1)),
+			p.shipping_address_encrypted, p.carrier, p.tracking_number, p.shipped_at
+		FROM items i
+		JOIN purchases p ON p.id = (SELECT MAX(id) FROM purchases WHERE item_id = i.id)
+		WHERE i.id = ?
+	`
+	s := &Shipment{}
+	var status, buyerUID, carrier, trackingNumber sql.NullString
+	err := d.db.QueryRow(query, itemID).Scan(&s.PurchaseID, &s.ItemID, &s.SellerUID, &status, &buyerUID,
+		&s.EncryptedAddress, &carrier, &trackingNumber, &s.ShippedAt)
+	if err != nil {
+		return nil, err
+	}
+	s.ItemStatus = status.String
+	s.BuyerUID = buyerUID.String
+	s.Carrier = carrier.String
+	s.TrackingNumber = trackingNumber.String
+	return s, nil
+}
+
+// AttachAddress は購入に配送先を設定する（監査ログには住所を残さない）
+func (d *ShippingDAO) AttachAddress(shipment *Shipment, encrypted []byte, actor auditDao.Actor) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE purchases SET shipping_address_encrypted = ? WHERE id = ?", encrypted, shipment.PurchaseID); err != nil {
+		return fmt.Errorf("failed to attach address: %w", err)
+	}
+	before := map[string]interface{}{"address_attached": shipment.EncryptedAddress != nil}
+	after := map[string]interface{}{"address_attached": true, "purchase_id": shipment.PurchaseID}
+	if err := auditDao.Record(tx, actor, "purchase.attach_address", "item", strconv.Itoa(shipment.ItemID), before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetTracking は配送業者と追跡番号を登録する（発送日時は最初の登録時のまま）
+// 支払い済み（paid）の注文は同じトランザクションで発送済み（shipped）にする
+func (d *ShippingDAO) SetTracking(shipment *Shipment, carrier, trackingNumber string, actor auditDao.Actor) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE purchases SET carrier = ?, tracking_number = ?, shipped_at = COALESCE(shipped_at, CURRENT_TIMESTAMP) WHERE id = ?"
+	if _, err := tx.Exec(query, carrier, trackingNumber, shipment.PurchaseID); err != nil {
+		return fmt.Errorf("failed to set tracking number: %w", err)
+	}
+	orderQuery := "UPDATE orders SET status = 'shipped', shipped_at = COALESCE(shipped_at, CURRENT_TIMESTAMP) WHERE purchase_id = ? AND status = 'paid'"
+	if _, err := tx.Exec(orderQuery, shipment.PurchaseID); err != nil {
+		return fmt.Errorf("failed to mark order as shipped: %w", err)
+	}
+	before := map[string]interface{}{"carrier": shipment.Carrier, "tracking_number": shipment.TrackingNumber}
+	after := map[string]interface{}{"carrier": carrier, "tracking_number": trackingNumber, "purchase_id": shipment.PurchaseID}
+	if err := auditDao.Record(tx, actor, "purchase.ship", "item", strconv.Itoa(shipment.ItemID), before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
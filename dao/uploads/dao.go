@@ -0,0 +1,110 @@
+package uploads
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// UploadSession はクライアントがストレージへ直接アップロードする際の発行済みトークン
+// status: pending（URL発行済み）→ completed（検証済み・imagesに登録済み）/ failed（検証失敗）
+type UploadSession struct {
+	Token       string
+	UID         string
+	StagingKey  string
+	ContentType string
+	SizeBytes   int
+	SHA256      string
+	Status      string
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+}
+
+// UploadSessionDAOInterface はモック化のためのインターフェース
+type UploadSessionDAOInterface interface {
+	Create(session *UploadSession) error
+	FindByToken(token string) (*UploadSession, error)
+	UpdateStatus(token string, fromStatus string, toStatus string) (bool, error)
+	ListExpired(before time.Time, limit int) ([]*UploadSession, error)
+	Delete(token string) error
+}
+
+type UploadSessionDAO struct {
+	db *sql.DB
+}
+
+func NewUploadSessionDAO(db *sql.DB) *UploadSessionDAO {
+	return &UploadSessionDAO{db: db}
+}
+
+const selectColumns = `token, uid, staging_key, content_type, size_bytes, sha256, status, expires_at, created_at`
+
+func scanSession(scanner interface{ Scan(...interface{}) error }) (*UploadSession, error) {
+	var s UploadSession
+	if err := scanner.Scan(&s.Token, &s.UID, &s.StagingKey, &s.ContentType, &s.SizeBytes, &s.SHA256, &s.Status, &s.ExpiresAt, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Create はpending状態のアップロードセッションを登録する
+func (d *UploadSessionDAO) Create(session *UploadSession) error {
+	query := `
+		INSERT INTO upload_sessions (token, uid, staging_key, content_type, size_bytes, sha256, status, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, 'pending', ?)
+	`
+	_, err := d.db.Exec(query, session.Token, session.UID, session.StagingKey, session.ContentType, session.SizeBytes, session.SHA256, session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert upload session: %w", err)
+	}
+	session.Status = "pending"
+	return nil
+}
+
+// FindByToken はトークンでセッションを取得する（存在しない場合はsql.ErrNoRows）
+func (d *UploadSessionDAO) FindByToken(token string) (*UploadSession, error) {
+	query := "SELECT " + selectColumns + " FROM upload_sessions WHERE token = ?"
+	return scanSession(d.db.QueryRow(query, token))
+}
+
+// UpdateStatus はセッションの状態がfromStatusの場合のみtoStatusに更新する
+// 同じトークンで完了処理が同時に走った場合、片方だけがtrueになる
+func (d *UploadSessionDAO) UpdateStatus(token string, fromStatus string, toStatus string) (bool, error) {
+	result, err := d.db.Exec("UPDATE upload_sessions SET status = ? WHERE token = ? AND status = ?", toStatus, token, fromStatus)
+	if err != nil {
+		return false, fmt.Errorf("failed to update upload session status: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// ListExpired はbefore以前に期限切れになったセッションを取得する（GC用）
+func (d *UploadSessionDAO) ListExpired(before time.Time, limit int) ([]*UploadSession, error) {
+	query := "SELECT " + selectColumns + " FROM upload_sessions WHERE expires_at < ? ORDER BY expires_at LIMIT ?"
+	rows, err := d.db.Query(query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expired upload sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*UploadSession
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan upload session row: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// Delete はセッションを削除する
+func (d *UploadSessionDAO) Delete(token string) error {
+	if _, err := d.db.Exec("DELETE FROM upload_sessions WHERE token = ?", token); err != nil {
+		return fmt.Errorf("failed to delete upload session: %w", err)
+	}
+	return nil
+}
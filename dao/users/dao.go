@@ -0,0 +1,528 @@
+package users
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	auditDao "uttc-hackathon-backend/dao/audit"
+)
+
+// User はusersテーブルの1行
+type User struct {
+	UID                string
+	Nickname           string
+	AvatarURL          string
+	AvatarThumbnailURL string
+	Bio                string
+	Sex                string
+	Birthdate          sql.NullTime
+	WalletAddress      string
+	CreatedAt          time.Time
+	DeletedAt          sql.NullTime // 退会済みの場合に入る
+}
+
+// ListingCounts はユーザーの出品数（状態別）
+type ListingCounts struct {
+	Total     int `json:"total"`
+	Listed    int `json:"listed"`
+	Purchased int `json:"purchased"`
+	Completed int `json:"completed"`
+}
+
+// RatingSummary はユーザーが受け取った評価の集計
+type RatingSummary struct {
+	Average *float64 `json:"average"` // レビューがない場合はnull
+	Count   int      `json:"count"`
+}
+
+// FollowCounts はフォロワー数とフォロー数
+type FollowCounts struct {
+	Followers int
+	Following int
+}
+
+// ProfileUpdate はプロフィールの部分更新。nilのフィールドは更新しない
+type ProfileUpdate struct {
+	Nickname  *string
+	Bio       *string
+	Sex       *string
+	Birthdate *sql.NullTime
+}
+
+// Avatar はアバター画像（imagesテーブルのハッシュで参照し、GCの対象外にする）
+type Avatar struct {
+	URL          string
+	ThumbnailURL string
+	Hash         string
+}
+
+// ExportItem はエクスポートする出品
+type ExportItem struct {
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	Price       int       `json:"price"`
+	Explanation string    `json:"explanation"`
+	Status      string    `json:"status"`
+	Category    string    `json:"category"`
+	ChainItemID *int64    `json:"chain_item_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ExportPurchase はエクスポートする購入履歴
+type ExportPurchase struct {
+	ItemID       int       `json:"item_id"`
+	Title        string    `json:"title"`
+	Price        int       `json:"price"`
+	SellerUID    string    `json:"seller_uid"`
+	Status       string    `json:"status"`
+	BuyerAddress string    `json:"buyer_address"`
+	PurchasedAt  time.Time `json:"purchased_at"`
+}
+
+// ExportLike はエクスポートするいいね
+type ExportLike struct {
+	ItemID    int       `json:"item_id"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportMessage はエクスポートするメッセージ（送信・受信の両方）
+type ExportMessage struct {
+	ID          int       `json:"id"`
+	SenderUID   string    `json:"sender_uid"`
+	ReceiverUID string    `json:"receiver_uid"`
+	ItemID      *int      `json:"item_id"`
+	Content     string    `json:"content"`
+	IsRead      bool      `json:"is_read"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ExportData はユーザーに関するデータ（プロフィール以外）
+type ExportData struct {
+	Listings  []*ExportItem
+	Purchases []*ExportPurchase
+	Likes     []*ExportLike
+	Messages  []*ExportMessage
+}
+
+// DeletedNickname は退会したユーザーの表示名
+const DeletedNickname = "退会したユーザー"
+
+// UserDAOInterface はモック化のためのインターフェース
+type UserDAOInterface interface {
+	GetUserByUID(uid string) (*User, error)
+	GetListingCounts(uid string) (*ListingCounts, error)
+	GetRatingSummary(uid string) (*RatingSummary, error)
+	GetFollowCounts(uid string) (*FollowCounts, error)
+	UpdateProfile(uid string, update *ProfileUpdate) error
+	UpdateAvatar(uid string, avatar *Avatar) error
+	GetExportData(uid string) (*ExportData, error)
+	CountBlockingTransactions(uid string) (int, error)
+	DeleteAccount(uid string) error
+}
+
+type UserDAO struct {
+	db *sql.DB
+}
+
+func NewUserDAO(db *sql.DB) *UserDAO {
+	return &UserDAO{db: db}
+}
+
+// GetUserByUID はuidでユーザーを取得する（存在しない場合はsql.ErrNoRows）
+func (d *UserDAO) GetUserByUID(uid string) (*User, error) {
+	query := `
+		SELECT uid, nickname, avatar_url, avatar_thumbnail_url, bio, sex, birthdate, wallet_address, created_at, deleted_at
+		FROM users WHERE uid = ?
+	`
+	var user User
+	var nickname, avatarURL, avatarThumbnailURL, bio, sex, walletAddress sql.NullString
+	err := d.db.QueryRow(query, uid).Scan(&user.UID, &nickname, &avatarURL, &avatarThumbnailURL, &bio, &sex, &user.Birthdate, &walletAddress, &user.CreatedAt, &user.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	user.Nickname = nickname.String
+	user.AvatarURL = avatarURL.String
+	user.AvatarThumbnailURL = avatarThumbnailURL.String
+	user.Bio = bio.String
+	user.Sex = sex.String
+	user.WalletAddress = walletAddress.String
+	return &user, nil
+}
+
+// GetListingCounts は出品者としての商品数を状態別に集計する
+func (d *UserDAO) GetListingCounts(uid string) (*ListingCounts, error) {
+	query := `
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(status = 'listed'), 0),
+			COALESCE(SUM(status = 'purchased'), 0),
+			COALESCE(SUM(status = 'completed'), 0)
+		FROM items WHERE uid = ?
+	`
+	var counts ListingCounts
+	if err := d.db.QueryRow(query, uid).Scan(&counts.Total, &counts.Listed, &counts.Purchased, &counts.Completed); err != nil {
+		return nil, fmt.Errorf("failed to count listings: %w", err)
+	}
+	return &counts, nil
+}
+
+// GetRatingSummary は取引相手から受け取った評価の平均と件数を集計する
+func (d *UserDAO) GetRatingSummary(uid string) (*RatingSummary, error) {
+	var summary RatingSummary
+	var average sql.NullFloat64
+	err := d.db.QueryRow("SELECT AVG(rating), COUNT(*) FROM reviews WHERE reviewee_uid = ?", uid).Scan(&average, &summary.Count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rating summary: %w", err)
+	}
+	if average.Valid {
+		summary.Average = &average.Float64
+	}
+	return &summary, nil
+}
+
+// GetFollowCounts はフォロワー数とフォロー数を取得する
+func (d *UserDAO) GetFollowCounts(uid string) (*FollowCounts, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM follows WHERE followee_uid = ?),
+			(SELECT COUNT(*) FROM follows WHERE follower_uid = ?)
+	`
+	var counts FollowCounts
+	if err := d.db.QueryRow(query, uid, uid).Scan(&counts.Followers, &counts.Following); err != nil {
+		return nil, fmt.Errorf("failed to count follows: %w", err)
+	}
+	return &counts, nil
+}
+
+// UpdateProfile は指定されたフィールドだけを更新する（変更前後の値を同じトランザクションで監査ログに残す）
+func (d *UserDAO) UpdateProfile(uid string, update *ProfileUpdate) error {
+	var sets []string
+	var args []interface{}
+	after := map[string]interface{}{}
+	if update.Nickname != nil {
+		sets = append(sets, "nickname = ?")
+		args = append(args, *update.Nickname)
+		after["nickname"] = *update.Nickname
+	}
+	if update.Bio != nil {
+		sets = append(sets, "bio = ?")
+		args = append(args, *update.Bio)
+		after["bio"] = *update.Bio
+	}
+	if update.Sex != nil {
+		sets = append(sets, "sex = ?")
+		args = append(args, *update.Sex)
+		after["sex"] = *update.Sex
+	}
+	if update.Birthdate != nil {
+		sets = append(sets, "birthdate = ?")
+		args = append(args, *update.Birthdate)
+		after["birthdate"] = dateValue(*update.Birthdate)
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var nickname, bio, sex sql.NullString
+	var birthdate sql.NullTime
+	err = tx.QueryRow("SELECT nickname, bio, sex, birthdate FROM users WHERE uid = ? FOR UPDATE", uid).
+		Scan(&nickname, &bio, &sex, &birthdate)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	current := map[string]interface{}{
+		"nickname":  nickname.String,
+		"bio":       bio.String,
+		"sex":       sex.String,
+		"birthdate": dateValue(birthdate),
+	}
+	before := map[string]interface{}{}
+	for k := range after {
+		before[k] = current[k]
+	}
+
+	args = append(args, uid)
+	query := "UPDATE users SET " + strings.Join(sets, ", ") + " WHERE uid = ?"
+	if _, err := tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to update profile: %w", err)
+	}
+	if err := auditDao.Record(tx, auditDao.UserActor(uid), "user.update_profile", "user", uid, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateAvatar はアバター画像を設定する。avatarがnilの場合は削除する
+func (d *UserDAO) UpdateAvatar(uid string, avatar *Avatar) error {
+	if avatar == nil {
+		avatar = &Avatar{}
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var beforeURL sql.NullString
+	if err := tx.QueryRow("SELECT avatar_url FROM users WHERE uid = ? FOR UPDATE", uid).Scan(&beforeURL); err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	query := "UPDATE users SET avatar_url = ?, avatar_thumbnail_url = ?, avatar_hash = ? WHERE uid = ?"
+	_, err = tx.Exec(query, nullIfEmpty(avatar.URL), nullIfEmpty(avatar.ThumbnailURL), nullIfEmpty(avatar.Hash), uid)
+	if err != nil {
+		return fmt.Errorf("failed to update avatar: %w", err)
+	}
+
+	before := map[string]interface{}{"avatar_url": beforeURL.String}
+	after := map[string]interface{}{"avatar_url": avatar.URL}
+	if err := auditDao.Record(tx, auditDao.UserActor(uid), "user.update_avatar", "user", uid, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetExportData は本人の出品・購入・いいね・メッセージを取得する（データエクスポート用）
+func (d *UserDAO) GetExportData(uid string) (*ExportData, error) {
+	data := &ExportData{
+		Listings:  []*ExportItem{},
+		Purchases: []*ExportPurchase{},
+		Likes:     []*ExportLike{},
+		Messages:  []*ExportMessage{},
+	}
+
+	rows, err := d.db.Query(`
+		SELECT id, title, price, explanation, status, category, chain_item_id, created_at
+		FROM items WHERE uid = ? ORDER BY created_at
+	`, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query listings: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		item := &ExportItem{}
+		var explanation, category sql.NullString
+		var chainItemID sql.NullInt64
+		if err := rows.Scan(&item.ID, &item.Title, &item.Price, &explanation, &item.Status, &category, &chainItemID, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan listing: %w", err)
+		}
+		item.Explanation = explanation.String
+		item.Category = category.String
+		if chainItemID.Valid {
+			item.ChainItemID = &chainItemID.Int64
+		}
+		data.Listings = append(data.Listings, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate listings: %w", err)
+	}
+
+	// onchainで購入した場合はbuyer_uidが空のことがあるため、登録済みのウォレットアドレスでも探す
+	rows, err = d.db.Query(`
+		SELECT p.item_id, i.title, i.price, i.uid, i.status, COALESCE(p.buyer_address, ''), p.purchased_at
+		FROM purchases p
+		JOIN items i ON i.id = p.item_id
+		WHERE p.buyer_uid = ?
+		   OR (p.buyer_address <> '' AND p.buyer_address = (SELECT wallet_address FROM users WHERE uid = ?))
+		ORDER BY p.purchased_at
+	`, uid, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query purchases: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		p := &ExportPurchase{}
+		if err := rows.Scan(&p.ItemID, &p.Title, &p.Price, &p.SellerUID, &p.Status, &p.BuyerAddress, &p.PurchasedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan purchase: %w", err)
+		}
+		data.Purchases = append(data.Purchases, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate purchases: %w", err)
+	}
+
+	rows, err = d.db.Query(`
+		SELECT l.item_id, i.title, l.created_at
+		FROM likes l
+		JOIN items i ON i.id = l.item_id
+		WHERE l.uid = ?
+		ORDER BY l.created_at
+	`, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query likes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		l := &ExportLike{}
+		if err := rows.Scan(&l.ItemID, &l.Title, &l.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan like: %w", err)
+		}
+		data.Likes = append(data.Likes, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate likes: %w", err)
+	}
+
+	rows, err = d.db.Query(`
+		SELECT m.id, m.sender_uid, m.receiver_uid, m.item_id, IF(m.deleted_at IS NULL, m.content, ''),
+			NOT (m.id > COALESCE(p.last_read_message_id, 0) OR (m.released_at IS NOT NULL AND (p.read_at IS NULL OR m.released_at > p.read_at))),
+			m.created_at
+		FROM messages m
+		LEFT JOIN message_read_pointers p
+			ON p.reader_uid = m.receiver_uid AND p.partner_uid = m.sender_uid AND p.thread_item_id = COALESCE(m.item_id, 0)
+		WHERE (m.sender_uid = ? OR m.receiver_uid = ?) AND (m.quarantined_at IS NULL OR m.sender_uid = ?)
+		ORDER BY m.created_at, m.id
+	`, uid, uid, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		m := &ExportMessage{}
+		var itemID sql.NullInt64
+		if err := rows.Scan(&m.ID, &m.SenderUID, &m.ReceiverUID, &itemID, &m.Content, &m.IsRead, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		if itemID.Valid {
+			id := int(itemID.Int64)
+			m.ItemID = &id
+		}
+		data.Messages = append(data.Messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate messages: %w", err)
+	}
+
+	return data, nil
+}
+
+// CountBlockingTransactions は退会の妨げになる商品の数を返す
+// 取引中（purchased）の商品（出品者・購入者のどちらでも）と、onchainに出品中の商品が対象
+func (d *UserDAO) CountBlockingTransactions(uid string) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM items
+		WHERE (status = 'purchased' AND (uid = ? OR id IN (SELECT item_id FROM purchases WHERE buyer_uid = ?)))
+		   OR (status = 'listed' AND uid = ? AND chain_item_id IS NOT NULL)
+	`
+	var count int
+	if err := d.db.QueryRow(query, uid, uid, uid).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count open transactions: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteAccount は退会処理として個人情報を消去する
+// 取引相手の履歴（購入・メッセージ・評価）とonchainの参照（items.seller_address/buyer_address）はそのまま残し、
+// usersの行もuidを残して匿名化する。出品中の商品は非表示にし、いいね・フォロー・自分がしたブロック・住所・通知・メールの送信先は削除する
+func (d *UserDAO) DeleteAccount(uid string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var deletedAt sql.NullTime
+	if err := tx.QueryRow("SELECT deleted_at FROM users WHERE uid = ? FOR UPDATE", uid).Scan(&deletedAt); err != nil {
+		return err
+	}
+	if deletedAt.Valid {
+		return nil
+	}
+
+	result, err := tx.Exec("UPDATE items SET hidden = TRUE WHERE uid = ? AND status = 'listed'", uid)
+	if err != nil {
+		return fmt.Errorf("failed to hide listings: %w", err)
+	}
+	hidden, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	// いいね数を戻してからいいねを削除
+	_, err = tx.Exec(`
+		UPDATE items i JOIN likes l ON l.item_id = i.id
+		SET i.like_count = GREATEST(i.like_count - 1, 0)
+		WHERE l.uid = ?
+	`, uid)
+	if err != nil {
+		return fmt.Errorf("failed to update like counts: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM likes WHERE uid = ?", uid); err != nil {
+		return fmt.Errorf("failed to delete likes: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM follows WHERE follower_uid = ? OR followee_uid = ?", uid, uid); err != nil {
+		return fmt.Errorf("failed to delete follows: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM blocks WHERE blocker_uid = ?", uid); err != nil {
+		return fmt.Errorf("failed to delete blocks: %w", err)
+	}
+
+	// 登録済みの住所と、終了した取引の配送先を削除（取引中の商品があると退会できないので全て終了済み）
+	if _, err := tx.Exec("DELETE FROM shipping_addresses WHERE uid = ?", uid); err != nil {
+		return fmt.Errorf("failed to delete shipping addresses: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE purchases SET shipping_address_encrypted = NULL WHERE buyer_uid = ?", uid); err != nil {
+		return fmt.Errorf("failed to clear shipping addresses: %w", err)
+	}
+
+	// 通知とメールの送信先
+	if _, err := tx.Exec("DELETE FROM email_settings WHERE uid = ?", uid); err != nil {
+		return fmt.Errorf("failed to delete email settings: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM notification_preferences WHERE uid = ?", uid); err != nil {
+		return fmt.Errorf("failed to delete notification preferences: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM notifications WHERE uid = ?", uid); err != nil {
+		return fmt.Errorf("failed to delete notifications: %w", err)
+	}
+
+	// アバター画像は参照がなくなるのでGCで削除される
+	_, err = tx.Exec(`
+		UPDATE users SET
+			nickname = ?, avatar_url = NULL, avatar_thumbnail_url = NULL, avatar_hash = NULL, bio = NULL,
+			sex = NULL, birthyear = NULL, birthdate = NULL, wallet_address = NULL,
+			role = 'user', deleted_at = CURRENT_TIMESTAMP
+		WHERE uid = ?
+	`, DeletedNickname, uid)
+	if err != nil {
+		return fmt.Errorf("failed to anonymize user: %w", err)
+	}
+
+	// 監査ログに残したプロフィールの値も消去する（操作があった事実は残す）
+	_, err = tx.Exec(`
+		UPDATE audit_events SET before_value = NULL, after_value = NULL
+		WHERE entity_type = 'user' AND entity_id = ?
+		  AND action IN ('user.create', 'user.update_profile', 'user.update_avatar')
+	`, uid)
+	if err != nil {
+		return fmt.Errorf("failed to scrub audit events: %w", err)
+	}
+	after := map[string]interface{}{"hidden_items": hidden}
+	if err := auditDao.Record(tx, auditDao.UserActor(uid), "user.delete", "user", uid, nil, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// dateValue は監査ログ用に日付をYYYY-MM-DD（未設定はnil）にする
+func dateValue(t sql.NullTime) interface{} {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time.Format("2006-01-02")
+}
+
+// nullIfEmpty は空文字をNULLとして保存する
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,181 @@
+package events
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"uttc-hackathon-backend/realtime"
+)
+
+// 商品に関するイベントの種類
+const (
+	// ItemStatusChanged は商品の状態が変わった（data: StatusChange）
+	ItemStatusChanged = "item.status_changed"
+	// ItemLikeCountChanged は商品のいいね数が変わった（data: LikeCountChange）
+	ItemLikeCountChanged = "item.like_count_changed"
+)
+
+// typePrefix はBusが扱うイベントの種類の接頭辞
+// BackendをHubと共有するため、これ以外のイベント（メッセージなど）は無視する
+const typePrefix = "item."
+
+// subscriberBufferSize は購読者ごとの受信待ちのイベント数の上限（溢れた購読者は打ち切る）
+const subscriberBufferSize = 32
+
+// Event は商品に関するイベント
+type Event struct {
+	Type   string          `json:"type"`
+	ItemID int             `json:"item_id"`
+	UIDs   []string        `json:"uids,omitempty"` // 関係するユーザー（出品者・購入者）。マイページへの配信先
+	Data   json.RawMessage `json:"data"`
+	At     time.Time       `json:"at"`
+}
+
+// StatusChange は ItemStatusChanged のdata
+type StatusChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// LikeCountChange は ItemLikeCountChanged のdata
+type LikeCountChange struct {
+	LikeCount int `json:"like_count"`
+}
+
+// Bus はDAOの書き込みで発生したイベントを購読者（SSEの接続など）へ届けるイベントバス
+// realtime.Backendを介して配信するので、別のインスタンスでの書き込みも届く
+type Bus struct {
+	backend realtime.Backend
+
+	mu   sync.RWMutex
+	subs map[*subscription]struct{}
+}
+
+type subscription struct {
+	filter func(Event) bool
+	ch     chan Event
+	once   sync.Once
+}
+
+func (s *subscription) close() {
+	s.once.Do(func() { close(s.ch) })
+}
+
+// NewBus はbackendを介してイベントを配信するBusを生成する
+func NewBus(backend realtime.Backend) *Bus {
+	b := &Bus{
+		backend: backend,
+		subs:    make(map[*subscription]struct{}),
+	}
+	backend.Subscribe(b.dispatch)
+	return b
+}
+
+// Publish はイベントを全インスタンスの購読者へ送る（DAOはコミットした後に呼ぶ）
+// 配信は補助的なものなので、失敗してもログに残すだけにする。nilのBusでは何もしない
+func (b *Bus) Publish(eventType string, itemID int, uids []string, data interface{}) {
+	if b == nil {
+		return
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("WARNING: failed to encode %s event: %v", eventType, err)
+		return
+	}
+	// 購入者が未登録のウォレットの場合などはuidが空なので除く
+	var recipients []string
+	for _, uid := range uids {
+		if uid != "" {
+			recipients = append(recipients, uid)
+		}
+	}
+	body, err := json.Marshal(Event{Type: eventType, ItemID: itemID, UIDs: recipients, Data: payload, At: time.Now()})
+	if err != nil {
+		log.Printf("WARNING: failed to encode %s event: %v", eventType, err)
+		return
+	}
+	// 配信先のuidはHubに渡さない（WebSocketには流さない）
+	if err := b.backend.Publish(realtime.Event{Type: eventType, Data: body}); err != nil {
+		log.Printf("WARNING: failed to publish %s event: %v", eventType, err)
+	}
+}
+
+// Subscribe はfilterに合うイベントを受け取るチャネルと、購読をやめる関数を返す
+// 受け取りが追いつかない場合はチャネルが閉じられるので、呼び出し側は接続を終えて取り直す
+func (b *Bus) Subscribe(filter func(Event) bool) (<-chan Event, func()) {
+	s := &subscription{filter: filter, ch: make(chan Event, subscriberBufferSize)}
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		delete(b.subs, s)
+		s.close()
+		b.mu.Unlock()
+	}
+	return s.ch, unsubscribe
+}
+
+// dispatch はBackendから受け取ったイベントをこのインスタンスの購読者へ送る
+func (b *Bus) dispatch(re realtime.Event) {
+	if !strings.HasPrefix(re.Type, typePrefix) {
+		return
+	}
+	var event Event
+	if err := json.Unmarshal(re.Data, &event); err != nil {
+		log.Printf("WARNING: skipping malformed %s event: %v", re.Type, err)
+		return
+	}
+
+	b.mu.RLock()
+	var slow []*subscription
+	for s := range b.subs {
+		if !s.filter(event) {
+			continue
+		}
+		select {
+		case s.ch <- event:
+		default:
+			slow = append(slow, s)
+		}
+	}
+	b.mu.RUnlock()
+
+	// chへの書き込みは読み取りロック中に行うので、閉じるのは書き込みロックを取ってから
+	if len(slow) > 0 {
+		b.mu.Lock()
+		for _, s := range slow {
+			delete(b.subs, s)
+			s.close()
+		}
+		b.mu.Unlock()
+	}
+}
+
+// subscribers は購読者の数（テスト用）
+func (b *Bus) subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs)
+}
+
+// ForItem は商品のイベントだけを通すfilter
+func ForItem(itemID int) func(Event) bool {
+	return func(e Event) bool { return e.ItemID == itemID }
+}
+
+// ForUser はuidが出品者・購入者として関係するイベントだけを通すfilter
+func ForUser(uid string) func(Event) bool {
+	return func(e Event) bool {
+		for _, u := range e.UIDs {
+			if u == uid {
+				return true
+			}
+		}
+		return false
+	}
+}
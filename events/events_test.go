@@ -0,0 +1,116 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"uttc-hackathon-backend/realtime"
+)
+
+func receive(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case e, ok := <-ch:
+		if !ok {
+			t.Fatal("channel was closed")
+		}
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return Event{}
+}
+
+func expectNone(t *testing.T, ch <-chan Event) {
+	t.Helper()
+	select {
+	case e := <-ch:
+		t.Fatalf("expected no event, got %s item=%d", e.Type, e.ItemID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestBus_Filters 商品・ユーザーのfilterに合うイベントだけが届く
+func TestBus_Filters(t *testing.T) {
+	bus := NewBus(realtime.NewMemoryBackend())
+	item1, unsub1 := bus.Subscribe(ForItem(1))
+	defer unsub1()
+	item2, unsub2 := bus.Subscribe(ForItem(2))
+	defer unsub2()
+	seller, unsub3 := bus.Subscribe(ForUser("seller"))
+	defer unsub3()
+
+	bus.Publish(ItemStatusChanged, 1, []string{"seller", "buyer"}, StatusChange{From: "listed", To: "purchased"})
+
+	e := receive(t, item1)
+	var change StatusChange
+	if err := json.Unmarshal(e.Data, &change); err != nil {
+		t.Fatalf("failed to decode data: %v", err)
+	}
+	if e.Type != ItemStatusChanged || change.From != "listed" || change.To != "purchased" || e.At.IsZero() {
+		t.Errorf("unexpected event: %+v %+v", e, change)
+	}
+	if e := receive(t, seller); e.ItemID != 1 {
+		t.Errorf("expected item 1, got %d", e.ItemID)
+	}
+	expectNone(t, item2)
+}
+
+// TestBus_SharedBackend 同じBackendを共有するBus（別インスタンス）の購読者にも届き、Hub向けのイベントは無視する
+func TestBus_SharedBackend(t *testing.T) {
+	backend := realtime.NewMemoryBackend()
+	busA := NewBus(backend)
+	busB := NewBus(backend)
+	ch, unsub := busB.Subscribe(ForItem(3))
+	defer unsub()
+
+	backend.Publish(realtime.Event{UIDs: []string{"alice"}, Type: "message.created", Data: json.RawMessage(`{"item_id":3}`)})
+	busA.Publish(ItemLikeCountChanged, 3, []string{"seller"}, LikeCountChange{LikeCount: 5})
+
+	e := receive(t, ch)
+	if e.Type != ItemLikeCountChanged || string(e.Data) != `{"like_count":5}` {
+		t.Errorf("unexpected event: %s %s", e.Type, e.Data)
+	}
+	expectNone(t, ch)
+}
+
+// TestBus_SlowSubscriber 受け取りが追いつかない購読者は打ち切られる
+func TestBus_SlowSubscriber(t *testing.T) {
+	bus := NewBus(realtime.NewMemoryBackend())
+	_, unsubSlow := bus.Subscribe(ForItem(1))
+	defer unsubSlow()
+	fast, unsubFast := bus.Subscribe(ForItem(2))
+	defer unsubFast()
+
+	for i := 0; i <= subscriberBufferSize; i++ {
+		bus.Publish(ItemLikeCountChanged, 1, nil, LikeCountChange{LikeCount: i})
+	}
+	if got := bus.subscribers(); got != 1 {
+		t.Errorf("expected slow subscriber to be dropped, got %d subscribers", got)
+	}
+
+	bus.Publish(ItemLikeCountChanged, 2, nil, LikeCountChange{LikeCount: 1})
+	receive(t, fast)
+}
+
+// TestBus_Unsubscribe 購読をやめるとチャネルが閉じられ、二重に呼んでも問題ない
+func TestBus_Unsubscribe(t *testing.T) {
+	bus := NewBus(realtime.NewMemoryBackend())
+	ch, unsub := bus.Subscribe(ForItem(1))
+	unsub()
+	unsub()
+	if _, ok := <-ch; ok {
+		t.Error("expected channel to be closed")
+	}
+	if got := bus.subscribers(); got != 0 {
+		t.Errorf("expected 0 subscribers, got %d", got)
+	}
+	bus.Publish(ItemStatusChanged, 1, nil, StatusChange{From: "listed", To: "cancelled"})
+}
+
+// TestBus_Nil BusなしのDAOから呼ばれても何もしない
+func TestBus_Nil(t *testing.T) {
+	var bus *Bus
+	bus.Publish(ItemStatusChanged, 1, nil, StatusChange{})
+}
@@ -1,10 +1,12 @@
 module uttc-hackathon-backend
 
-go 1.23
+go 1.23.0
 
 require (
 	github.com/ethereum/go-ethereum v1.14.12
 	github.com/go-sql-driver/mysql v1.9.3
+	github.com/gorilla/websocket v1.4.2
+	golang.org/x/image v0.25.0
 )
 
 require (
@@ -21,7 +23,6 @@ require (
 	github.com/ethereum/c-kzg-4844 v1.0.0 // indirect
 	github.com/ethereum/go-verkle v0.1.1-0.20240829091221-dffa7562dbe9 // indirect
 	github.com/go-ole/go-ole v1.3.0 // indirect
-	github.com/gorilla/websocket v1.4.2 // indirect
 	github.com/holiman/uint256 v1.3.1 // indirect
 	github.com/mmcloughlin/addchain v0.4.0 // indirect
 	github.com/shirou/gopsutil v3.21.4-0.20210419000835-c7a38de76ee5+incompatible // indirect
@@ -0,0 +1,287 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"uttc-hackathon-backend/auth"
+	auditDao "uttc-hackathon-backend/dao/audit"
+	"uttc-hackathon-backend/usecase/admin"
+	"uttc-hackathon-backend/usecase/itemStatus"
+)
+
+// 管理者APIはすべて auth.RequireRole を通してルーティングすること（操作者はcontextから取得する）
+type AdminHandler struct {
+	adminUc *admin.AdminUsecase
+}
+
+func NewAdminHandler(u *admin.AdminUsecase) *AdminHandler {
+	return &AdminHandler{adminUc: u}
+}
+
+type ForceStatusRequest struct {
+	ItemID int    `json:"item_id"`
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+type SuspendRequest struct {
+	UID    string `json:"uid"`
+	Reason string `json:"reason"`
+}
+
+type SetRoleRequest struct {
+	UID    string `json:"uid"`
+	Role   string `json:"role"`
+	Reason string `json:"reason"`
+}
+
+// GET /api/v1/admin/items?status=listed&hidden=true&page=1&limit=20 - 商品一覧（非表示の商品も含む）
+func (h *AdminHandler) ListItems(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var hidden *bool
+	if v := r.URL.Query().Get("hidden"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			writeJSONError(w, "hidden must be true or false", http.StatusBadRequest)
+			return
+		}
+		hidden = &parsed
+	}
+
+	page, limit := pagination(r)
+	items, err := h.adminUc.ListItems(r.URL.Query().Get("status"), hidden, page, limit)
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to get items")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
+
+// POST /api/v1/admin/items/status - 商品の状態を強制的に変更（admin）
+func (h *AdminHandler) ForceItemStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var req ForceStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.ItemID == 0 || req.Status == "" {
+		writeJSONError(w, "item_id, status and reason are required", http.StatusBadRequest)
+		return
+	}
+
+	change, err := h.adminUc.ForceItemStatus(actor.UID, req.ItemID, req.Status, req.Reason)
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to update item status")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(change)
+}
+
+// POST /api/v1/admin/users/suspend - ユーザーを利用停止
+// DELETE /api/v1/admin/users/suspend - 利用停止を解除
+func (h *AdminHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var req SuspendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.UID == "" {
+		writeJSONError(w, "uid and reason are required", http.StatusBadRequest)
+		return
+	}
+
+	suspended := r.Method == http.MethodPost
+	if err := h.adminUc.SetSuspended(actor.UID, actor.Role, req.UID, suspended, req.Reason); err != nil {
+		writeUsecaseError(w, err, "Failed to update suspension")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"uid": req.UID, "suspended": suspended})
+}
+
+// PUT /api/v1/admin/users/role - ユーザーのロールを変更（admin）
+func (h *AdminHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var req SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.UID == "" || req.Role == "" {
+		writeJSONError(w, "uid, role and reason are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.adminUc.SetRole(actor.UID, req.UID, req.Role, req.Reason); err != nil {
+		writeUsecaseError(w, err, "Failed to update role")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"uid": req.UID, "role": req.Role})
+}
+
+// GET /api/v1/admin/chain-events?chain_item_id=1&page=1&limit=20 - onchainイベントの履歴
+func (h *AdminHandler) ListChainEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var chainItemID int64
+	if v := r.URL.Query().Get("chain_item_id"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed <= 0 {
+			writeJSONError(w, "Invalid chain_item_id", http.StatusBadRequest)
+			return
+		}
+		chainItemID = parsed
+	}
+
+	page, limit := pagination(r)
+	events, err := h.adminUc.ListChainEvents(chainItemID, page, limit)
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to get chain events")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"events": events})
+}
+
+// GET /api/v1/admin/audit-events?entity_type=item&entity_id=1&actor_uid=xxx&action=xxx - 監査ログ（admin）
+func (h *AdminHandler) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := auditDao.Filter{
+		EntityType: q.Get("entity_type"),
+		EntityID:   q.Get("entity_id"),
+		ActorUID:   q.Get("actor_uid"),
+		Action:     q.Get("action"),
+	}
+	page, limit := pagination(r)
+	events, err := h.adminUc.ListAuditEvents(filter, page, limit)
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to get audit events")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"events": events})
+}
+
+// GET /api/v1/admin/items/{id}/audit-events?page=1&limit=20 - 商品ごとの変更履歴（moderator）
+func (h *AdminHandler) ItemAuditEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/admin/items/"), "/")
+	idStr, sub, _ := strings.Cut(rest, "/")
+	if sub != "audit-events" {
+		writeJSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	itemID, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeJSONError(w, "Invalid item id", http.StatusBadRequest)
+		return
+	}
+
+	page, limit := pagination(r)
+	events, err := h.adminUc.GetItemHistory(itemID, page, limit)
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to get item history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"item_id": itemID, "events": events})
+}
+
+// pagination はpage/limitクエリを読み取る（デフォルト 1/20、limitは最大100）
+func pagination(r *http.Request) (int, int) {
+	page := 1
+	limit := 20
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+	return page, limit
+}
+
+// writeUsecaseError はユースケースのエラーをHTTPステータスに変換する
+func writeUsecaseError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, admin.ErrInvalidInput), errors.Is(err, admin.ErrCannotTargetSelf):
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, admin.ErrItemNotFound), errors.Is(err, admin.ErrUserNotFound):
+		writeJSONError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, admin.ErrForbidden):
+		writeJSONError(w, err.Error(), http.StatusForbidden)
+	case itemStatus.IsConflict(err), errors.Is(err, admin.ErrActiveOrder):
+		writeJSONError(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		writeJSONError(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"uttc-hackathon-backend/usecase/blockchain"
+	"uttc-hackathon-backend/usecase/itemStatus"
 )
 
 type BlockchainHandler struct {
@@ -101,7 +102,7 @@ func (h *BlockchainHandler) HandleItemPurchased(w http.ResponseWriter, r *http.R
 
 	if err := h.blockchainUC.HandleItemPurchased(req.ChainItemID, req.Buyer, req.PriceWei, req.TokenID, req.TxHash); err != nil {
 		log.Printf("Error processing ItemPurchased event: %v", err)
-		writeJSONError(w, fmt.Sprintf("Failed to process item purchased event: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, fmt.Sprintf("Failed to process item purchased event: %v", err), eventErrorStatus(err))
 		return
 	}
 
@@ -138,7 +139,7 @@ func (h *BlockchainHandler) HandleReceiptConfirmed(w http.ResponseWriter, r *htt
 
 	if err := h.blockchainUC.HandleReceiptConfirmed(req.ChainItemID, req.Buyer, req.Seller, req.PriceWei, req.TxHash); err != nil {
 		log.Printf("Error processing ReceiptConfirmed event: %v", err)
-		writeJSONError(w, fmt.Sprintf("Failed to process receipt confirmed event: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, fmt.Sprintf("Failed to process receipt confirmed event: %v", err), eventErrorStatus(err))
 		return
 	}
 
@@ -173,7 +174,7 @@ func (h *BlockchainHandler) HandleItemCancelled(w http.ResponseWriter, r *http.R
 
 	if err := h.blockchainUC.HandleItemCancelled(req.ChainItemID, req.Seller, req.TxHash); err != nil {
 		log.Printf("Error processing ItemCancelled event: %v", err)
-		writeJSONError(w, fmt.Sprintf("Failed to process item cancelled event: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, fmt.Sprintf("Failed to process item cancelled event: %v", err), eventErrorStatus(err))
 		return
 	}
 
@@ -183,6 +184,14 @@ func (h *BlockchainHandler) HandleItemCancelled(w http.ResponseWriter, r *http.R
 	json.NewEncoder(w).Encode(map[string]string{"message": "Item cancelled event processed successfully"})
 }
 
+// eventErrorStatus は商品の状態から遷移できないイベントを409、それ以外を500とする
+func eventErrorStatus(err error) int {
+	if itemStatus.IsConflict(err) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
 func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
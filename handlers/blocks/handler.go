@@ -0,0 +1,142 @@
+package blocks
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"uttc-hackathon-backend/auth"
+	"uttc-hackathon-backend/usecase/blocks"
+)
+
+type BlockHandler struct {
+	blockUc *blocks.BlockUsecase
+}
+
+func NewBlockHandler(u *blocks.BlockUsecase) *BlockHandler {
+	return &BlockHandler{blockUc: u}
+}
+
+type BlockRequest struct {
+	BlockedUID string `json:"blocked_uid"`
+}
+
+// POST /api/v1/blocks - ブロック
+// DELETE /api/v1/blocks - ブロック解除
+// GET /api/v1/blocks - 自分がブロック中のユーザー一覧
+// ブロックする側はIDトークンのユーザー
+func (h *BlockHandler) HandleBlock(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost, http.MethodDelete:
+		h.updateBlock(w, r)
+	case http.MethodGet:
+		h.listBlocks(w, r)
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *BlockHandler) updateBlock(w http.ResponseWriter, r *http.Request) {
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	var req BlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.BlockedUID == "" {
+		writeJSONError(w, "blocked_uid is required", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		if err := h.blockUc.Block(actor.UID, req.BlockedUID); err != nil {
+			switch {
+			case errors.Is(err, blocks.ErrCannotBlockSelf):
+				writeJSONError(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, blocks.ErrUserNotFound):
+				writeJSONError(w, err.Error(), http.StatusNotFound)
+			default:
+				writeJSONError(w, "Failed to block", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Blocked successfully"})
+		return
+	}
+
+	if err := h.blockUc.Unblock(actor.UID, req.BlockedUID); err != nil {
+		writeJSONError(w, "Failed to unblock", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Unblocked successfully"})
+}
+
+func (h *BlockHandler) listBlocks(w http.ResponseWriter, r *http.Request) {
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	page := 1
+	limit := 20
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	users, err := h.blockUc.GetBlockedUsers(actor.UID, page, limit)
+	if err != nil {
+		writeJSONError(w, "Failed to get blocked users", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"users": users})
+}
+
+// GET /api/v1/blocks/status?blocked_uid=yyy - 自分がブロックしているかを取得
+func (h *BlockHandler) GetBlockStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	blockedUID := r.URL.Query().Get("blocked_uid")
+	if blockedUID == "" {
+		writeJSONError(w, "blocked_uid is required", http.StatusBadRequest)
+		return
+	}
+
+	blocking, err := h.blockUc.IsBlocking(actor.UID, blockedUID)
+	if err != nil {
+		writeJSONError(w, "Failed to get block status", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"blocking": blocking})
+}
+
+func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
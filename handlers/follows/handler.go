@@ -0,0 +1,152 @@
+package follows
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"uttc-hackathon-backend/auth"
+	"uttc-hackathon-backend/usecase/follows"
+)
+
+type FollowHandler struct {
+	followUc *follows.FollowUsecase
+}
+
+func NewFollowHandler(u *follows.FollowUsecase) *FollowHandler {
+	return &FollowHandler{followUc: u}
+}
+
+type FollowRequest struct {
+	FolloweeUID string `json:"followee_uid"`
+}
+
+// POST /api/v1/follows - フォロー
+// DELETE /api/v1/follows - フォロー解除
+// （フォローする側はIDトークンのユーザー。main.goでPOST・DELETEだけrequireUserを通す）
+// GET /api/v1/follows?uid=xxx&type=followers|following - フォロワー・フォロー中の一覧
+func (h *FollowHandler) HandleFollow(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost, http.MethodDelete:
+		h.updateFollow(w, r)
+	case http.MethodGet:
+		h.listFollows(w, r)
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *FollowHandler) updateFollow(w http.ResponseWriter, r *http.Request) {
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	var req FollowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.FolloweeUID == "" {
+		writeJSONError(w, "followee_uid is required", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		if err := h.followUc.Follow(actor.UID, req.FolloweeUID); err != nil {
+			switch {
+			case errors.Is(err, follows.ErrCannotFollowSelf):
+				writeJSONError(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, follows.ErrUserNotFound):
+				writeJSONError(w, err.Error(), http.StatusNotFound)
+			default:
+				writeJSONError(w, "Failed to follow", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Followed successfully"})
+		return
+	}
+
+	if err := h.followUc.Unfollow(actor.UID, req.FolloweeUID); err != nil {
+		writeJSONError(w, "Failed to unfollow", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Unfollowed successfully"})
+}
+
+func (h *FollowHandler) listFollows(w http.ResponseWriter, r *http.Request) {
+	uid := r.URL.Query().Get("uid")
+	if uid == "" {
+		writeJSONError(w, "uid is required", http.StatusBadRequest)
+		return
+	}
+
+	page := 1
+	limit := 20
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	var err error
+	var users interface{}
+	switch r.URL.Query().Get("type") {
+	case "", "followers":
+		users, err = h.followUc.GetFollowers(uid, page, limit)
+	case "following":
+		users, err = h.followUc.GetFollowing(uid, page, limit)
+	default:
+		writeJSONError(w, "type must be followers or following", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeJSONError(w, "Failed to get follows", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"users": users})
+}
+
+// GET /api/v1/follows/status?followee_uid=yyy - IDトークンのユーザーがフォローしているかを取得
+func (h *FollowHandler) GetFollowStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	followeeUID := r.URL.Query().Get("followee_uid")
+	if followeeUID == "" {
+		writeJSONError(w, "followee_uid is required", http.StatusBadRequest)
+		return
+	}
+
+	following, err := h.followUc.IsFollowing(actor.UID, followeeUID)
+	if err != nil {
+		writeJSONError(w, "Failed to get follow status", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"following": following})
+}
+
+func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"uttc-hackathon-backend/auth"
 	"uttc-hackathon-backend/usecase/getItems"
 )
 
@@ -26,10 +27,12 @@ func (h *ItemHandler) GetItems(w http.ResponseWriter, r *http.Request) {
 
 	category := r.URL.Query().Get("category")
 	uid := r.URL.Query().Get("uid")
+	// 閲覧者はIDトークンのユーザー（auth.OptionalUser。ブロックされている出品者の商品を除き、自分の非表示の商品は表示するため）
+	viewerUID := auth.ViewerUID(r.Context())
 
 	// uidが指定されている場合はuidで検索
 	if uid != "" {
-		items, err := h.getItemUc.GetItemsByUid(uid)
+		items, err := h.getItemUc.GetItemsByUid(uid, viewerUID)
 		if err != nil {
 			writeJSONError(w, "Items not found", http.StatusNotFound)
 			return
@@ -63,7 +66,7 @@ func (h *ItemHandler) GetItems(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	items, err := h.getItemUc.GetItemsByCategory(category, page, limit)
+	items, err := h.getItemUc.GetItemsByCategory(category, viewerUID, page, limit)
 	if err != nil {
 		writeJSONError(w, "Items not found", http.StatusNotFound)
 		return
@@ -101,7 +104,7 @@ func (h *ItemHandler) GetItemByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	item, err := h.getItemUc.GetItemByID(id)
+	item, err := h.getItemUc.GetItemByID(id, auth.ViewerUID(r.Context()))
 	if err != nil {
 		writeJSONError(w, "Item not found", http.StatusNotFound)
 		return
@@ -126,7 +129,7 @@ func (h *ItemHandler) GetLatestItems(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	items, err := h.getItemUc.GetLatestItems(limit)
+	items, err := h.getItemUc.GetLatestItems(limit, auth.ViewerUID(r.Context()))
 	if err != nil {
 		// エラーログを出力（デバッグ用）
 		log.Printf("Error in GetLatestItems: %v", err)
@@ -141,6 +144,48 @@ func (h *ItemHandler) GetLatestItems(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetFeed はIDトークンのユーザーがフォロー中の出品者の新着出品を返す
+// GET /api/v1/feed?page=1&limit=20
+func (h *ItemHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	uid := actor.UID
+
+	page := 1
+	limit := 20
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 50 {
+			limit = parsed
+		}
+	}
+
+	items, err := h.getItemUc.GetFeed(uid, page, limit)
+	if err != nil {
+		log.Printf("Error in GetFeed: %v", err)
+		writeJSONError(w, "Failed to get feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(items); err != nil {
+		log.Printf("Error encoding JSON in GetFeed: %v", err)
+		writeJSONError(w, "JSON encode error", http.StatusInternalServerError)
+	}
+}
+
 func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
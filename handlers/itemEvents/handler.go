@@ -0,0 +1,129 @@
+package itemEvents
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"uttc-hackathon-backend/auth"
+	"uttc-hackathon-backend/events"
+)
+
+const (
+	// heartbeatInterval はコメント行を送る間隔（プロキシにアイドル接続として切られないようにする）
+	heartbeatInterval = 25 * time.Second
+	// retryMillis は切断されたときにブラウザ（EventSource）が再接続するまでの時間
+	retryMillis = 3000
+)
+
+type ItemEventHandler struct {
+	bus *events.Bus
+}
+
+func NewItemEventHandler(bus *events.Bus) *ItemEventHandler {
+	return &ItemEventHandler{bus: bus}
+}
+
+// sseEvent はクライアントへ送るイベントのdata（関係するユーザーのuidは含めない）
+type sseEvent struct {
+	ItemID int             `json:"item_id"`
+	Data   json.RawMessage `json:"data"`
+	At     time.Time       `json:"at"`
+}
+
+// GET /api/v1/items/{id}/events - 商品ページ向けのイベント（Server-Sent Events）
+func (h *ItemEventHandler) ItemEvents(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/v1/items/")
+	idStr, action, found := strings.Cut(rest, "/")
+	if !found || action != "events" {
+		writeJSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	itemID, err := strconv.Atoi(idStr)
+	if err != nil || itemID <= 0 {
+		writeJSONError(w, "Invalid item id", http.StatusBadRequest)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.stream(w, r, events.ForItem(itemID))
+}
+
+// GET /api/v1/users/me/events?access_token=xxx - マイページ向けのイベント（出品・購入した商品の変化）
+func (h *ItemEventHandler) MyEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	h.stream(w, r, events.ForUser(actor.UID))
+}
+
+// stream はクライアントが切断するまでfilterに合うイベントを送り続ける
+// 購読が打ち切られた（受け取りが追いつかない）場合は接続を閉じ、EventSourceの再接続に任せる
+func (h *ItemEventHandler) stream(w http.ResponseWriter, r *http.Request, filter func(events.Event) bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ch, unsubscribe := h.bus.Subscribe(filter)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// nginxなどのプロキシでバッファリングさせない
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", retryMillis)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent はイベントをSSEの形式（event: 種類、data: JSON）で書き込む
+func writeEvent(w http.ResponseWriter, event events.Event) error {
+	data, err := json.Marshal(sseEvent{ItemID: event.ItemID, Data: event.Data, At: event.At})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
+func writeJSONError(w http.ResponseWriter, message string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -2,35 +2,66 @@ package messages
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"uttc-hackathon-backend/auth"
 	dao "uttc-hackathon-backend/dao/messages"
+	"uttc-hackathon-backend/realtime"
 	uc "uttc-hackathon-backend/usecase/messages"
 )
 
 type MessageHandler struct {
 	usecase *uc.MessageUsecase
+	hub     *realtime.Hub
 }
 
-func NewMessageHandler(usecase *uc.MessageUsecase) *MessageHandler {
-	return &MessageHandler{usecase: usecase}
+func NewMessageHandler(usecase *uc.MessageUsecase, hub *realtime.Hub) *MessageHandler {
+	return &MessageHandler{usecase: usecase, hub: hub}
 }
 
+// item_idは商品のスレッド（省略すると商品に紐づかないスレッド）
+// attachment_idsは POST /messages/attachments でアップロードした画像（添付があれば本文は省略できる）
 type SendMessageRequest struct {
-	SenderUID   string `json:"sender_uid"`
-	ReceiverUID string `json:"receiver_uid"`
-	Content     string `json:"content"`
+	SenderUID     string  `json:"sender_uid"` // 省略可（指定する場合はIDトークンのユーザーと同じ）
+	ReceiverUID   string  `json:"receiver_uid"`
+	ItemID        *int    `json:"item_id"`
+	Content       string  `json:"content"`
+	AttachmentIDs []int64 `json:"attachment_ids"`
+}
+
+type ContactSellerRequest struct {
+	SenderUID string `json:"sender_uid"` // 省略可（指定する場合はIDトークンのユーザーと同じ）
+	ItemID    int    `json:"item_id"`
+	Content   string `json:"content"`
 }
 
 type MarkReadRequest struct {
 	MyUID      string `json:"my_uid"`
 	PartnerUID string `json:"partner_uid"`
+	ItemID     *int   `json:"item_id"`
+}
+
+// DeliveredData はWebSocketでクライアントから送られる配信確認 {"type": "delivered", "data": {"message_id": 1}}
+type DeliveredData struct {
+	MessageID int `json:"message_id"`
+}
+
+// ReadData はWebSocketでクライアントから送られる既読 {"type": "read", "data": {"partner_uid": "yyy", "item_id": 1}}
+type ReadData struct {
+	PartnerUID string `json:"partner_uid"`
+	ItemID     *int   `json:"item_id"`
 }
 
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
-// GET /messages?my_uid=xxx&partner_uid=yyy
+// GET /messages?my_uid=xxx&partner_uid=yyy&item_id=1&before=100&limit=50（item_idを省略すると商品に紐づかないスレッド）
+// before・limitを省略するとスレッドのすべてのメッセージを、指定すると最新（beforeを指定した場合はそれより前）のlimit件を古い順の配列で返す
+// ページを指定した場合、前のページがあればX-Next-Cursorヘッダーにbeforeに指定するカーソルを返す
 func (h *MessageHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.Header().Set("Content-Type", "application/json")
@@ -57,7 +88,37 @@ func (h *MessageHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	messages, err := h.usecase.GetMessages(myUID, partnerUID)
+	itemID, err := parseItemID(r.URL.Query().Get("item_id"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid item_id"})
+		return
+	}
+
+	before, limit, err := parseCursor(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// ページを指定しない場合は、これまでどおりスレッドのすべてのメッセージを返す
+	if before == 0 && limit == 0 {
+		messages, err := h.usecase.GetThread(myUID, partnerUID, itemID)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to get messages"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(messages)
+		return
+	}
+
+	page, err := h.usecase.GetMessages(myUID, partnerUID, itemID, before, limit)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -65,12 +126,105 @@ func (h *MessageHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if messages == nil {
-		messages = []*dao.Message{}
+	w.Header().Set("Content-Type", "application/json")
+	if page.NextCursor != nil {
+		w.Header().Set("X-Next-Cursor", strconv.Itoa(*page.NextCursor))
+	}
+	json.NewEncoder(w).Encode(page.Messages)
+}
+
+// GET /api/v1/messages?partner_uid=yyy&item_id=1&before=100&limit=50 - メッセージ履歴をさかのぼって取得する
+// {"messages": [...古い順], "next_cursor": 51} を返す。next_cursorをbeforeに指定すると前のページ（nullなら最初まで取得済み）
+func (h *MessageHandler) History(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Method not allowed"})
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "forbidden"})
+		return
+	}
+
+	partnerUID := r.URL.Query().Get("partner_uid")
+	if partnerUID == "" || partnerUID == actor.UID {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "partner_uid is required and must be different from uid"})
+		return
+	}
+	itemID, err := parseItemID(r.URL.Query().Get("item_id"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid item_id"})
+		return
+	}
+	before, limit, err := parseCursor(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	page, err := h.usecase.GetMessages(actor.UID, partnerUID, itemID, before, limit)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to get messages"})
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(messages)
+	json.NewEncoder(w).Encode(page)
+}
+
+// GET /api/v1/messages/unread-count - ヘッダーのバッジ用の未読件数 {"total": 3, "conversations": 2}
+func (h *MessageHandler) UnreadCount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Method not allowed"})
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "forbidden"})
+		return
+	}
+
+	summary, err := h.usecase.GetUnreadSummary(actor.UID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to count unread messages"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// parseCursor はメッセージ履歴のページング（before・limit）を読み取る（省略時は0）
+func parseCursor(r *http.Request) (before, limit int, err error) {
+	if s := r.URL.Query().Get("before"); s != "" {
+		if before, err = strconv.Atoi(s); err != nil || before <= 0 {
+			return 0, 0, errors.New("invalid before")
+		}
+	}
+	if s := r.URL.Query().Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit <= 0 {
+			return 0, 0, errors.New("invalid limit")
+		}
+	}
+	return before, limit, nil
 }
 
 // POST /messages
@@ -82,6 +236,14 @@ func (h *MessageHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "forbidden"})
+		return
+	}
+
 	var req SendMessageRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		w.Header().Set("Content-Type", "application/json")
@@ -89,11 +251,19 @@ func (h *MessageHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
 		return
 	}
+	// 送信者はIDトークンのユーザー。互換性のためsender_uidも受け付けるが、違うユーザーなら拒否する
+	if req.SenderUID != "" && req.SenderUID != actor.UID {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "sender_uid does not match the authenticated user"})
+		return
+	}
+	req.SenderUID = actor.UID
 
-	if req.SenderUID == "" || req.ReceiverUID == "" || req.Content == "" {
+	if req.ReceiverUID == "" || (req.Content == "" && len(req.AttachmentIDs) == 0) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "sender_uid, receiver_uid, and content are required"})
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "receiver_uid, and content or attachment_ids are required"})
 		return
 	}
 
@@ -105,19 +275,299 @@ func (h *MessageHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	message, err := h.usecase.SendMessage(req.SenderUID, req.ReceiverUID, req.Content)
+	message, err := h.usecase.SendMessage(req.SenderUID, req.ReceiverUID, req.Content, req.ItemID, req.AttachmentIDs)
+	if err != nil {
+		writeSendError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(message)
+}
+
+// POST /messages/contact - 商品ページから出品者へ、その商品のスレッドでメッセージを送る
+func (h *MessageHandler) ContactSeller(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Method not allowed"})
+		return
+	}
+
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "forbidden"})
+		return
+	}
+
+	var req ContactSellerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+	// 送信者はIDトークンのユーザー。互換性のためsender_uidも受け付けるが、違うユーザーなら拒否する
+	if req.SenderUID != "" && req.SenderUID != actor.UID {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "sender_uid does not match the authenticated user"})
+		return
+	}
+	req.SenderUID = actor.UID
+
+	if req.ItemID <= 0 || req.Content == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "item_id and content are required"})
+		return
+	}
+
+	message, err := h.usecase.ContactSeller(req.SenderUID, req.ItemID, req.Content)
 	if err != nil {
+		writeSendError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(message)
+}
+
+// POST /messages/attachments (multipart/form-data, image) - 添付画像をアップロードする
+// 返ったidを POST /messages/send の attachment_ids に指定して送る
+func (h *MessageHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Method not allowed"})
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "forbidden"})
+		return
+	}
+
+	r.ParseMultipartForm(10 << 20) // 10MB max
+
+	file, fileHeader, err := r.FormFile("image")
+	if err != nil && err != http.ErrMissingFile {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to send message"})
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Error retrieving file"})
+		return
+	}
+
+	attachment, err := h.usecase.UploadAttachment(actor.UID, file, fileHeader)
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to upload attachment"
+		switch {
+		case errors.Is(err, uc.ErrInvalidAttachment):
+			status, message = http.StatusBadRequest, err.Error()
+		case errors.Is(err, uc.ErrSuspended):
+			status, message = http.StatusForbidden, err.Error()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: message})
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(attachment)
+}
+
+// GET /messages/attachments/{id}?size=thumb|medium|full - 添付画像の署名付きURLへリダイレクトする
+// メッセージに含まれるURLの期限が切れた後の取り直しに使う。見られるのは会話の二人だけ
+func (h *MessageHandler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Method not allowed"})
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "forbidden"})
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/messages/attachments/"), 10, 64)
+	if err != nil || id <= 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid attachment id"})
+		return
+	}
+
+	url, err := h.usecase.AttachmentURL(actor.UID, id, r.URL.Query().Get("size"))
+	if errors.Is(err, uc.ErrAttachmentNotFound) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to get attachment"})
+		return
+	}
+
+	// 署名付きURLは期限があるのでキャッシュさせない
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// EditMessageRequest は PATCH /api/v1/messages/{id} のリクエスト
+type EditMessageRequest struct {
+	Content string `json:"content"`
+}
+
+// PATCH /api/v1/messages/{id} - 自分が送ったメッセージを編集する（送信から15分以内）
+// DELETE /api/v1/messages/{id} - 自分が送ったメッセージを削除する（送信から15分以内）
+func (h *MessageHandler) MessageByID(w http.ResponseWriter, r *http.Request) {
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "forbidden"})
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/v1/messages/"))
+	if err != nil || id <= 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid message id"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		var req EditMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid request body"})
+			return
+		}
+		message, err := h.usecase.EditMessage(actor.UID, id, req.Content)
+		if err != nil {
+			writeEditError(w, err, "Failed to edit message")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(message)
+	case http.MethodDelete:
+		if err := h.usecase.DeleteMessage(actor.UID, id); err != nil {
+			writeEditError(w, err, "Failed to delete message")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Method not allowed"})
+	}
+}
+
+// GET /api/v1/admin/messages/{id} - 通報されたメッセージを確認する（モデレーター以上）
+// 削除されていても本文と添付画像を返し、編集履歴（編集前の本文）も含める
+func (h *MessageHandler) ModerationMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Method not allowed"})
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/v1/admin/messages/"))
+	if err != nil || id <= 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid message id"})
+		return
+	}
+
+	message, err := h.usecase.GetMessageForModeration(id)
+	if errors.Is(err, uc.ErrMessageNotFound) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to get message"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(message)
 }
 
+// writeEditError は編集・削除できなかった理由に応じたステータスでエラーを返す
+func writeEditError(w http.ResponseWriter, err error, fallback string) {
+	status := http.StatusInternalServerError
+	message := fallback
+	switch {
+	case errors.Is(err, uc.ErrMessageNotFound):
+		status, message = http.StatusNotFound, err.Error()
+	case errors.Is(err, uc.ErrMessageNotEditable), errors.Is(err, uc.ErrBlocked), errors.Is(err, uc.ErrSuspended):
+		status, message = http.StatusForbidden, err.Error()
+	case errors.Is(err, uc.ErrEmptyMessage), errors.Is(err, uc.ErrMessageTooLong), errors.Is(err, uc.ErrMessageRejected):
+		status, message = http.StatusBadRequest, err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: message})
+}
+
+// writeSendError は送信できなかった理由に応じたステータスでエラーを返す
+func writeSendError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	message := "Failed to send message"
+	switch {
+	case errors.Is(err, uc.ErrBlocked), errors.Is(err, uc.ErrSuspended):
+		status, message = http.StatusForbidden, err.Error()
+	case errors.Is(err, uc.ErrItemNotFound), errors.Is(err, uc.ErrAttachmentNotFound):
+		status, message = http.StatusNotFound, err.Error()
+	case errors.Is(err, uc.ErrItemNotInThread), errors.Is(err, uc.ErrOwnItem),
+		errors.Is(err, uc.ErrEmptyMessage), errors.Is(err, uc.ErrTooManyAttachments),
+		errors.Is(err, uc.ErrMessageTooLong), errors.Is(err, uc.ErrMessageRejected):
+		status, message = http.StatusBadRequest, err.Error()
+	case errors.Is(err, uc.ErrRateLimited):
+		status, message = http.StatusTooManyRequests, err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: message})
+}
+
+// parseItemID はスレッドの商品IDを読み取る（空ならnil）
+func parseItemID(s string) (*int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	id, err := strconv.Atoi(s)
+	if err != nil || id <= 0 {
+		return nil, errors.New("invalid item_id")
+	}
+	return &id, nil
+}
+
 // PUT /messages/read
 func (h *MessageHandler) MarkAsRead(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
@@ -142,7 +592,7 @@ func (h *MessageHandler) MarkAsRead(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.usecase.MarkAsRead(req.MyUID, req.PartnerUID)
+	err := h.usecase.MarkAsRead(req.MyUID, req.PartnerUID, req.ItemID)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -186,3 +636,44 @@ func (h *MessageHandler) GetConversations(w http.ResponseWriter, r *http.Request
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(conversations)
 }
+
+// GET /ws/messages?access_token=xxx - WebSocketで新着メッセージ・配信確認・既読・未読件数を受け取る
+// イベントは {"type": "message.created", "data": {...}} の形式で届く
+func (h *MessageHandler) Realtime(w http.ResponseWriter, r *http.Request) {
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "forbidden"})
+		return
+	}
+
+	err := h.hub.Serve(w, r, actor.UID, func(msg realtime.ClientMessage) {
+		h.handleClientMessage(actor.UID, msg)
+	})
+	if err != nil {
+		log.Printf("websocket upgrade failed for uid=%s: %v", actor.UID, err)
+	}
+}
+
+// handleClientMessage はWebSocketでクライアントから送られた配信確認・既読を処理する
+func (h *MessageHandler) handleClientMessage(uid string, msg realtime.ClientMessage) {
+	switch msg.Type {
+	case "delivered":
+		var data DeliveredData
+		if err := json.Unmarshal(msg.Data, &data); err != nil || data.MessageID <= 0 {
+			return
+		}
+		if err := h.usecase.MarkAsDelivered(uid, data.MessageID); err != nil && !errors.Is(err, uc.ErrMessageNotFound) {
+			log.Printf("failed to mark message %d as delivered: %v", data.MessageID, err)
+		}
+	case "read":
+		var data ReadData
+		if err := json.Unmarshal(msg.Data, &data); err != nil || data.PartnerUID == "" || data.PartnerUID == uid {
+			return
+		}
+		if err := h.usecase.MarkAsRead(uid, data.PartnerUID, data.ItemID); err != nil {
+			log.Printf("failed to mark messages from %s as read: %v", data.PartnerUID, err)
+		}
+	}
+}
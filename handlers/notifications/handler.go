@@ -0,0 +1,210 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"uttc-hackathon-backend/auth"
+	dao "uttc-hackathon-backend/dao/notifications"
+	"uttc-hackathon-backend/usecase/notifications"
+)
+
+type NotificationHandler struct {
+	notificationUc *notifications.NotificationUsecase
+}
+
+func NewNotificationHandler(u *notifications.NotificationUsecase) *NotificationHandler {
+	return &NotificationHandler{notificationUc: u}
+}
+
+type MarkReadRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+type EmailSettingsRequest struct {
+	Email   string `json:"email"`
+	Locale  string `json:"locale"`
+	Enabled bool   `json:"enabled"`
+}
+
+// GET /api/v1/notifications?page=1&limit=20&unread=true - 自分の通知を新しい順に取得
+func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	page := 1
+	limit := 20
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	result, err := h.notificationUc.List(actor.UID, unreadOnly, page, limit)
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to get notifications")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// POST /api/v1/notifications/read - 指定した通知を既読にする
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var req MarkReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) == 0 {
+		writeJSONError(w, "ids are required", http.StatusBadRequest)
+		return
+	}
+
+	unread, err := h.notificationUc.MarkRead(actor.UID, req.IDs)
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to mark notifications as read")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"unread_count": unread})
+}
+
+// POST /api/v1/notifications/read-all - すべての通知を既読にする
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	unread, err := h.notificationUc.MarkAllRead(actor.UID)
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to mark notifications as read")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"unread_count": unread})
+}
+
+// GET /api/v1/notifications/preferences - 種類ごとの受け取り設定を取得
+// PUT /api/v1/notifications/preferences - 受け取り設定を変更（{"like": false} のように変更する種類だけ送る）
+func (h *NotificationHandler) Preferences(w http.ResponseWriter, r *http.Request) {
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var prefs map[string]bool
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		prefs, err = h.notificationUc.GetPreferences(actor.UID)
+	case http.MethodPut:
+		var req map[string]bool
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		prefs, err = h.notificationUc.SetPreferences(actor.UID, req)
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to update notification preferences")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"preferences": prefs})
+}
+
+// GET /api/v1/notifications/email - メールの送信先・言語を取得
+// PUT /api/v1/notifications/email - メールの送信先・言語・送るかどうかを変更
+func (h *NotificationHandler) Email(w http.ResponseWriter, r *http.Request) {
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var settings *dao.EmailSettings
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		settings, err = h.notificationUc.GetEmailSettings(actor.UID)
+	case http.MethodPut:
+		var req EmailSettingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		settings, err = h.notificationUc.SetEmailSettings(actor.UID, &dao.EmailSettings{
+			Email:   req.Email,
+			Locale:  req.Locale,
+			Enabled: req.Enabled,
+		})
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to update email settings")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// writeUsecaseError はユースケースのエラーをHTTPステータスに変換する
+func writeUsecaseError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, notifications.ErrInvalidType), errors.Is(err, notifications.ErrInvalidEmail),
+		errors.Is(err, notifications.ErrInvalidLocale):
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		writeJSONError(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,177 @@
+package orders
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"uttc-hackathon-backend/auth"
+	"uttc-hackathon-backend/usecase/itemStatus"
+	"uttc-hackathon-backend/usecase/orders"
+)
+
+type OrderHandler struct {
+	orderUc *orders.OrderUsecase
+}
+
+func NewOrderHandler(u *orders.OrderUsecase) *OrderHandler {
+	return &OrderHandler{orderUc: u}
+}
+
+type UpdateStatusRequest struct {
+	Status string `json:"status"`
+}
+
+type RefundRequest struct {
+	OrderID int64 `json:"order_id"`
+}
+
+// GET /api/v1/orders?role=buyer|seller&page=1&limit=20 - 自分の注文一覧
+func (h *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	role := r.URL.Query().Get("role")
+	if role == "" {
+		role = "buyer"
+	}
+
+	page, limit := pagination(r)
+	list, err := h.orderUc.ListOrders(actor.UID, role, page, limit)
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to get orders")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"orders": list})
+}
+
+// GET /api/v1/orders/{id} - 注文の詳細（出品者・購入者のみ）
+// POST /api/v1/orders/{id}/status - 注文の状態を変更 {"status": "cancelled"}
+func (h *OrderHandler) HandleOrder(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/orders/"), "/")
+	idStr, sub, _ := strings.Cut(rest, "/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeJSONError(w, "Invalid order id", http.StatusBadRequest)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	switch {
+	case sub == "" && r.Method == http.MethodGet:
+		order, err := h.orderUc.GetOrder(id, actor.UID)
+		if err != nil {
+			writeUsecaseError(w, err, "Failed to get order")
+			return
+		}
+		writeJSON(w, http.StatusOK, order)
+	case sub == "status" && r.Method == http.MethodPost:
+		var req UpdateStatusRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Status == "" {
+			writeJSONError(w, "status is required", http.StatusBadRequest)
+			return
+		}
+		order, err := h.orderUc.UpdateStatusByUser(id, actor.UID, req.Status)
+		if err != nil {
+			writeUsecaseError(w, err, "Failed to update order status")
+			return
+		}
+		writeJSON(w, http.StatusOK, order)
+	case sub == "" || sub == "status":
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		writeJSONError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// POST /api/v1/admin/orders/refund - 注文を返金済みにする（admin） {"order_id": 1}
+func (h *OrderHandler) Refund(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var req RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.OrderID <= 0 {
+		writeJSONError(w, "order_id is required", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.orderUc.Refund(req.OrderID, actor.UID)
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to refund order")
+		return
+	}
+	writeJSON(w, http.StatusOK, order)
+}
+
+// pagination はpage/limitクエリを読み取る（デフォルト 1/20、limitは最大100）
+func pagination(r *http.Request) (int, int) {
+	page := 1
+	limit := 20
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+	return page, limit
+}
+
+// writeUsecaseError はユースケースのエラーをHTTPステータスに変換する
+func writeUsecaseError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, orders.ErrInvalidInput), errors.Is(err, orders.ErrCannotBuyOwnItem):
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, orders.ErrOrderNotFound), errors.Is(err, orders.ErrItemNotFound):
+		writeJSONError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, orders.ErrForbidden):
+		writeJSONError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, orders.ErrInvalidTransition), errors.Is(err, orders.ErrItemNotAvailable), itemStatus.IsConflict(err):
+		writeJSONError(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		writeJSONError(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
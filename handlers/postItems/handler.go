@@ -2,6 +2,7 @@ package postItems
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"uttc-hackathon-backend/usecase/postItems"
@@ -27,6 +28,7 @@ func (h *ItemHandler) CreateItem(w http.ResponseWriter, r *http.Request) {
 	explanation := r.PostForm.Get("explanation")
 	priceStr := r.PostForm.Get("price")
 	file, fileHeader, err := r.FormFile("image")
+	uploadToken := r.PostForm.Get("upload_token") // 直接アップロードで検証済みの画像を使う場合
 	uid := r.PostForm.Get("sellerUid")
 	status := r.PostForm.Get("status")
 	category := r.PostForm.Get("category")
@@ -65,9 +67,14 @@ func (h *ItemHandler) CreateItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, _, err := h.postItemsUc.CreateItem(title, explanation, priceStr, file, fileHeader, uid, status, category)
+	response, _, err := h.postItemsUc.CreateItem(title, explanation, priceStr, file, fileHeader, uploadToken, uid, status, category)
 	if err != nil {
 		fmt.Printf("Error creating item - Title: %s, UID: %s, Error: %v\n", title, uid, err)
+		if errors.Is(err, postItems.ErrInvalidImage) || errors.Is(err, postItems.ErrUploadNotFound) || errors.Is(err, postItems.ErrUploadNotCompleted) ||
+			errors.Is(err, postItems.ErrUploadExpired) {
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		// エラーメッセージを詳細に返す（開発環境用）
 		errorMsg := fmt.Sprintf("Failed to create item: %v", err)
 		writeJSONError(w, errorMsg, http.StatusInternalServerError)
@@ -0,0 +1,120 @@
+package postItems
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"uttc-hackathon-backend/auth"
+	"uttc-hackathon-backend/usecase/postItems"
+)
+
+type presignUploadRequest struct {
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+	SHA256      string `json:"sha256"`
+}
+
+type completeUploadRequest struct {
+	UploadToken string `json:"upload_token"`
+}
+
+// PresignUpload はストレージへ直接PUTするための署名付きURLとアップロードトークンを発行する
+// POST /api/v1/uploads/presign（セッションはIDトークンのユーザーに紐づける）
+func (h *ItemHandler) PresignUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var req presignUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	upload, err := h.postItemsUc.PresignUpload(actor.UID, req.ContentType, req.Size, req.SHA256)
+	if err != nil {
+		fmt.Printf("Error presigning upload: %v\n", err)
+		if errors.Is(err, postItems.ErrInvalidImage) {
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSONError(w, "Failed to create upload url", http.StatusInternalServerError)
+		return
+	}
+	// ローカルストレージの場合は相対パスなのでベースURLを付与
+	upload.UploadURL = absoluteURL(requestBaseURL(r), upload.UploadURL)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(upload)
+}
+
+// CompleteUpload はアップロードされた画像を検証し、出品に使えるURLを返す
+// POST /api/v1/uploads/complete（IDトークンのユーザーが発行したセッションのみ）
+func (h *ItemHandler) CompleteUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var req completeUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.UploadToken == "" {
+		writeJSONError(w, "upload_token is required", http.StatusBadRequest)
+		return
+	}
+
+	image, err := h.postItemsUc.CompleteUpload(actor.UID, req.UploadToken)
+	if err != nil {
+		fmt.Printf("Error completing upload: %v\n", err)
+		switch {
+		case errors.Is(err, postItems.ErrUploadNotFound):
+			writeJSONError(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, postItems.ErrUploadExpired):
+			writeJSONError(w, err.Error(), http.StatusGone)
+		case errors.Is(err, postItems.ErrInvalidImage):
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+		default:
+			writeJSONError(w, "Failed to complete upload", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	baseURL := requestBaseURL(r)
+	imageURL := absoluteURL(baseURL, image.URL)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"upload_token":  req.UploadToken,
+		"image_url":     imageURL,
+		"image_urls":    []string{imageURL},
+		"medium_url":    absoluteURL(baseURL, image.MediumURL),
+		"thumbnail_url": absoluteURL(baseURL, image.ThumbnailURL),
+	})
+}
+
+// requestBaseURL は環境変数BACKEND_BASE_URL、なければリクエストからベースURLを構築する
+func requestBaseURL(r *http.Request) string {
+	if baseURL := os.Getenv("BACKEND_BASE_URL"); baseURL != "" {
+		return baseURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, r.Host)
+}
@@ -2,9 +2,11 @@ package postItems
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
+	"strings"
+	"uttc-hackathon-backend/usecase/postItems"
 )
 
 // UploadImage は画像のみをアップロードしてURLを返す
@@ -27,30 +29,39 @@ func (h *ItemHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	// 画像をアップロードしてURLを取得
-	imagePath, err := h.postItemsUc.UploadImage(file, fileHeader)
+	// 画像を検証・加工してアップロードし、サイズ別のURLを取得
+	image, err := h.postItemsUc.UploadImage(file, fileHeader)
 	if err != nil {
 		fmt.Printf("Error uploading image: %v\n", err)
+		if errors.Is(err, postItems.ErrInvalidImage) {
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		writeJSONError(w, "Failed to upload image", http.StatusInternalServerError)
 		return
 	}
 
 	// フルURLを構築
 	// 環境変数からベースURLを取得、なければリクエストから構築
-	baseURL := os.Getenv("BACKEND_BASE_URL")
-	if baseURL == "" {
-		scheme := "http"
-		if r.TLS != nil {
-			scheme = "https"
-		}
-		baseURL = fmt.Sprintf("%s://%s", scheme, r.Host)
-	}
-	imageURL := fmt.Sprintf("%s/%s", baseURL, imagePath)
+	baseURL := requestBaseURL(r)
+	imageURL := absoluteURL(baseURL, image.URL)
+	mediumURL := absoluteURL(baseURL, image.MediumURL)
+	thumbnailURL := absoluteURL(baseURL, image.ThumbnailURL)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"image_url": imageURL,
-		"image_urls": []string{imageURL},
+		"image_url":     imageURL,
+		"image_urls":    []string{imageURL},
+		"medium_url":    mediumURL,
+		"thumbnail_url": thumbnailURL,
 	})
 }
 
+// absoluteURL はローカルストレージの相対パスにベースURLを付与する
+// S3等のストレージは既に絶対URLなのでそのまま返す
+func absoluteURL(baseURL, path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return fmt.Sprintf("%s/%s", baseURL, path)
+}
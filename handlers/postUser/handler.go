@@ -2,9 +2,11 @@ package postUser
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"uttc-hackathon-backend/usecase/postUser"
+	"uttc-hackathon-backend/usecase/users"
 )
 
 type UserHandler struct {
@@ -20,7 +22,24 @@ type RegisterRequest struct {
 	Nickname  string `json:"nickname"`
 	Sex       string `json:"sex"`
 	Birthyear int    `json:"birthyear"`
-	Birthdate int    `json:"birthdate"`
+	// "YYYY-MM-DD" の文字列。旧クライアントの数値（MMDD）も受け付ける
+	Birthdate json.RawMessage `json:"birthdate"`
+}
+
+// birthdateString は文字列・旧形式の数値どちらのbirthdateも "YYYY-MM-DD" に揃える
+func (req *RegisterRequest) birthdateString() (string, error) {
+	if len(req.Birthdate) == 0 || string(req.Birthdate) == "null" {
+		return "", nil
+	}
+	var s string
+	if err := json.Unmarshal(req.Birthdate, &s); err == nil {
+		return s, nil
+	}
+	var mmdd int
+	if err := json.Unmarshal(req.Birthdate, &mmdd); err != nil {
+		return "", fmt.Errorf("birthdate must be a string in YYYY-MM-DD format")
+	}
+	return users.LegacyBirthdate(req.Birthyear, mmdd), nil
 }
 
 func (h *UserHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
@@ -45,8 +64,24 @@ func (h *UserHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.postUserUc.RegisterUser(req.Uid, req.Nickname, req.Sex, req.Birthyear, req.Birthdate)
+	birthdate, err := req.birthdateString()
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.postUserUc.RegisterUser(req.Uid, req.Nickname, req.Sex, req.Birthyear, birthdate)
 	if err != nil {
+		var verr *users.ValidationError
+		if errors.As(err, &verr) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"message": verr.Error(),
+				"fields":  verr.Fields,
+			})
+			return
+		}
 		writeJSONError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -1,13 +1,15 @@
 package purchaseItem
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	ordersDao "uttc-hackathon-backend/dao/orders"
 	dao "uttc-hackathon-backend/dao/purchaseItem"
+	ordersUc "uttc-hackathon-backend/usecase/orders"
 	uc "uttc-hackathon-backend/usecase/purchaseItem"
 )
 
@@ -24,7 +26,8 @@ type PurchaseRequest struct {
 }
 
 type PurchaseResponse struct {
-	Message string `json:"message"`
+	Message string           `json:"message"`
+	Order   *ordersDao.Order `json:"order,omitempty"`
 }
 
 type ErrorResponse struct {
@@ -81,17 +84,20 @@ func (h *PurchaseHandler) PurchaseItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.usecase.PurchaseItem(itemID, req.BuyerUID)
+	order, err := h.usecase.PurchaseItem(itemID, req.BuyerUID)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
-		errMsg := err.Error()
-		if err == sql.ErrNoRows {
+		if errors.Is(err, ordersUc.ErrItemNotFound) {
 			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(ErrorResponse{Error: "Item not found or already purchased"})
-		} else if strings.Contains(errMsg, "seller cannot purchase their own item") {
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Item not found"})
+		} else if errors.Is(err, ordersUc.ErrItemNotAvailable) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Item is not available for purchase"})
+		} else if errors.Is(err, ordersUc.ErrCannotBuyOwnItem) {
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(ErrorResponse{Error: "Seller cannot purchase their own item"})
 		} else {
+			log.Printf("[PurchaseItem] Error from usecase: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to purchase item"})
 		}
@@ -100,7 +106,7 @@ func (h *PurchaseHandler) PurchaseItem(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(PurchaseResponse{Message: "Purchase successful"})
+	json.NewEncoder(w).Encode(PurchaseResponse{Message: "Purchase successful", Order: order})
 }
 
 // GET /purchases?buyer_uid=xxx&buyer_address=xxx
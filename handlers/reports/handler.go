@@ -0,0 +1,153 @@
+package reports
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"uttc-hackathon-backend/auth"
+	"uttc-hackathon-backend/usecase/reports"
+)
+
+type ReportHandler struct {
+	reportUc *reports.ReportUsecase
+}
+
+func NewReportHandler(u *reports.ReportUsecase) *ReportHandler {
+	return &ReportHandler{reportUc: u}
+}
+
+type CreateReportRequest struct {
+	TargetType string `json:"target_type"`
+	TargetID   string `json:"target_id"`
+	Reason     string `json:"reason"`
+	Detail     string `json:"detail"`
+}
+
+type ResolveReportRequest struct {
+	ReportID int    `json:"report_id"`
+	Action   string `json:"action"`
+}
+
+// POST /api/v1/reports - 商品・メッセージ・ユーザーを通報（通報者はIDトークンのユーザー）
+func (h *ReportHandler) CreateReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var req CreateReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.TargetType == "" || req.TargetID == "" || req.Reason == "" {
+		writeJSONError(w, "target_type, target_id and reason are required", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.reportUc.CreateReport(actor.UID, req.TargetType, req.TargetID, req.Reason, req.Detail)
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to create report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// GET /api/v1/admin/reports?status=open&page=1&limit=20 - モデレーションキュー（moderator以上）
+func (h *ReportHandler) ListQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page := 1
+	limit := 20
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	queue, err := h.reportUc.ListQueue(r.URL.Query().Get("status"), page, limit)
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to get reports")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"reports": queue})
+}
+
+// POST /api/v1/admin/reports/resolve - 通報に対応（hide_item, suspend_user, dismiss）（moderator以上）
+func (h *ReportHandler) ResolveReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var req ResolveReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.ReportID == 0 || req.Action == "" {
+		writeJSONError(w, "report_id and action are required", http.StatusBadRequest)
+		return
+	}
+
+	closed, err := h.reportUc.Resolve(actor.UID, req.ReportID, req.Action)
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to resolve report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":        "Report resolved",
+		"closed_reports": closed,
+	})
+}
+
+// writeUsecaseError はユースケースのエラーをHTTPステータスに変換する
+func writeUsecaseError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, reports.ErrInvalidReport), errors.Is(err, reports.ErrInvalidAction),
+		errors.Is(err, reports.ErrCannotReportSelf):
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, reports.ErrNotParticipant):
+		writeJSONError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, reports.ErrTargetNotFound), errors.Is(err, reports.ErrReportNotFound):
+		writeJSONError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, reports.ErrDuplicateReport), errors.Is(err, reports.ErrReportClosed):
+		writeJSONError(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		writeJSONError(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,132 @@
+package reviews
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"uttc-hackathon-backend/auth"
+	"uttc-hackathon-backend/usecase/reviews"
+)
+
+type ReviewHandler struct {
+	reviewUc *reviews.ReviewUsecase
+}
+
+func NewReviewHandler(u *reviews.ReviewUsecase) *ReviewHandler {
+	return &ReviewHandler{reviewUc: u}
+}
+
+type CreateReviewRequest struct {
+	ItemID  int    `json:"item_id"`
+	Rating  int    `json:"rating"`
+	Comment string `json:"comment"`
+}
+
+// POST /api/v1/reviews - レビュー投稿（投稿者はIDトークンのユーザー。main.goでPOSTだけrequireUserを通す）
+// GET /api/v1/reviews?uid=xxx - ユーザーが受け取ったレビュー一覧と評価の集計
+// GET /api/v1/reviews?item_id=xxx - 取引に付いたレビュー
+func (h *ReviewHandler) HandleReviews(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.createReview(w, r)
+	case http.MethodGet:
+		h.getReviews(w, r)
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *ReviewHandler) createReview(w http.ResponseWriter, r *http.Request) {
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	var req CreateReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.ItemID == 0 {
+		writeJSONError(w, "item_id is required", http.StatusBadRequest)
+		return
+	}
+
+	review, err := h.reviewUc.CreateReview(req.ItemID, actor.UID, req.Rating, req.Comment)
+	if err != nil {
+		switch {
+		case errors.Is(err, reviews.ErrInvalidReview):
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, reviews.ErrItemNotFound):
+			writeJSONError(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, reviews.ErrNotParticipant):
+			writeJSONError(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, reviews.ErrNotCompleted), errors.Is(err, reviews.ErrAlreadyReviewed):
+			writeJSONError(w, err.Error(), http.StatusConflict)
+		default:
+			fmt.Printf("Error creating review: %v\n", err)
+			writeJSONError(w, "Failed to create review", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(review)
+}
+
+func (h *ReviewHandler) getReviews(w http.ResponseWriter, r *http.Request) {
+	if itemIDStr := r.URL.Query().Get("item_id"); itemIDStr != "" {
+		itemID, err := strconv.Atoi(itemIDStr)
+		if err != nil {
+			writeJSONError(w, "Invalid item_id", http.StatusBadRequest)
+			return
+		}
+		list, err := h.reviewUc.GetReviewsForItem(itemID)
+		if err != nil {
+			writeJSONError(w, "Failed to get reviews", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"reviews": list})
+		return
+	}
+
+	uid := r.URL.Query().Get("uid")
+	if uid == "" {
+		writeJSONError(w, "uid or item_id is required", http.StatusBadRequest)
+		return
+	}
+
+	page := 1
+	limit := 20
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	list, summary, err := h.reviewUc.GetReviewsForUser(uid, page, limit)
+	if err != nil {
+		writeJSONError(w, "Failed to get reviews", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"reviews": list,
+		"rating":  summary,
+	})
+}
+
+func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
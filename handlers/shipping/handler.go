@@ -0,0 +1,209 @@
+package shipping
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"uttc-hackathon-backend/auth"
+	"uttc-hackathon-backend/usecase/shipping"
+)
+
+type ShippingHandler struct {
+	shippingUc *shipping.ShippingUsecase
+}
+
+func NewShippingHandler(u *shipping.ShippingUsecase) *ShippingHandler {
+	return &ShippingHandler{shippingUc: u}
+}
+
+type AttachAddressRequest struct {
+	ItemID    int   `json:"item_id"`
+	AddressID int64 `json:"address_id"`
+}
+
+type TrackingRequest struct {
+	ItemID         int    `json:"item_id"`
+	Carrier        string `json:"carrier"`
+	TrackingNumber string `json:"tracking_number"`
+}
+
+// GET /api/v1/addresses - 自分の登録済みの住所一覧
+// POST /api/v1/addresses - 住所の登録
+func (h *ShippingHandler) HandleAddresses(w http.ResponseWriter, r *http.Request) {
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	uid := actor.UID
+
+	switch r.Method {
+	case http.MethodGet:
+		addresses, err := h.shippingUc.ListAddresses(uid)
+		if err != nil {
+			writeUsecaseError(w, err, "Failed to get addresses")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"addresses": addresses})
+	case http.MethodPost:
+		var input shipping.AddressInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		address, err := h.shippingUc.CreateAddress(uid, input)
+		if err != nil {
+			writeUsecaseError(w, err, "Failed to create address")
+			return
+		}
+		writeJSON(w, http.StatusCreated, address)
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// PUT /api/v1/addresses/{id} - 自分の住所の更新
+// DELETE /api/v1/addresses/{id} - 自分の住所の削除
+func (h *ShippingHandler) HandleAddress(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/addresses/"), "/"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "Invalid address id", http.StatusBadRequest)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	uid := actor.UID
+
+	switch r.Method {
+	case http.MethodPut:
+		var input shipping.AddressInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		address, err := h.shippingUc.UpdateAddress(uid, id, input)
+		if err != nil {
+			writeUsecaseError(w, err, "Failed to update address")
+			return
+		}
+		writeJSON(w, http.StatusOK, address)
+	case http.MethodDelete:
+		if err := h.shippingUc.DeleteAddress(uid, id); err != nil {
+			writeUsecaseError(w, err, "Failed to delete address")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": id, "deleted": true})
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /api/v1/shipments?item_id=1 - 取引の配送情報（出品者・購入者のみ）
+func (h *ShippingHandler) GetShipment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	itemID, err := strconv.Atoi(r.URL.Query().Get("item_id"))
+	if err != nil {
+		writeJSONError(w, "Invalid item_id", http.StatusBadRequest)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	shipment, err := h.shippingUc.GetShipment(itemID, actor.UID)
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to get shipment")
+		return
+	}
+	writeJSON(w, http.StatusOK, shipment)
+}
+
+// PUT /api/v1/shipments/address - 購入者が配送先を指定 {"item_id": 1, "address_id": 2}
+func (h *ShippingHandler) AttachAddress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	var req AttachAddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	shipment, err := h.shippingUc.AttachAddress(req.ItemID, actor.UID, req.AddressID)
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to set shipping address")
+		return
+	}
+	writeJSON(w, http.StatusOK, shipment)
+}
+
+// PUT /api/v1/shipments/tracking - 出品者が追跡番号を登録 {"item_id": 1, "carrier": "ヤマト運輸", "tracking_number": "1234-5678-9012"}
+func (h *ShippingHandler) SetTracking(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	var req TrackingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	shipment, err := h.shippingUc.SetTracking(req.ItemID, actor.UID, req.Carrier, req.TrackingNumber)
+	if err != nil {
+		writeUsecaseError(w, err, "Failed to set tracking number")
+		return
+	}
+	writeJSON(w, http.StatusOK, shipment)
+}
+
+// writeUsecaseError はユースケースのエラーをHTTPステータスに変換する
+func writeUsecaseError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, shipping.ErrInvalidInput), errors.Is(err, shipping.ErrTooManyAddresses):
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, shipping.ErrAddressNotFound), errors.Is(err, shipping.ErrPurchaseNotFound):
+		writeJSONError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, shipping.ErrForbidden), errors.Is(err, shipping.ErrNotBuyer), errors.Is(err, shipping.ErrNotSeller):
+		writeJSONError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, shipping.ErrNotInTransit), errors.Is(err, shipping.ErrAlreadyShipped), errors.Is(err, shipping.ErrAddressRequired):
+		writeJSONError(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		writeJSONError(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
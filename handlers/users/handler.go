@@ -0,0 +1,209 @@
+package users
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"uttc-hackathon-backend/auth"
+	"uttc-hackathon-backend/usecase/users"
+)
+
+type UserHandler struct {
+	userUc *users.UserUsecase
+}
+
+func NewUserHandler(u *users.UserUsecase) *UserHandler {
+	return &UserHandler{userUc: u}
+}
+
+// Me は本人のプロフィールの取得・更新（本人はIDトークンのユーザー）
+// GET /api/v1/users/me
+// PATCH /api/v1/users/me  {"nickname": "...", "sex": "...", "birthdate": "YYYY-MM-DD"}
+// DELETE /api/v1/users/me  退会（個人情報を消去し、取引の記録は匿名化して残す）
+func (h *UserHandler) Me(w http.ResponseWriter, r *http.Request) {
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	uid := actor.UID
+
+	switch r.Method {
+	case http.MethodGet:
+		profile, err := h.userUc.GetMyProfile(uid)
+		if err != nil {
+			writeUsecaseError(w, err)
+			return
+		}
+		writeJSON(w, profile)
+	case http.MethodPatch:
+		var input users.UpdateProfileInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeJSONError(w, "Invalid JSON format", http.StatusBadRequest)
+			return
+		}
+		profile, err := h.userUc.UpdateMyProfile(uid, input)
+		if err != nil {
+			writeUsecaseError(w, err)
+			return
+		}
+		writeJSON(w, profile)
+	case http.MethodDelete:
+		if err := h.userUc.DeleteMyAccount(uid); err != nil {
+			writeUsecaseError(w, err)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"uid": uid, "deleted": true})
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Avatar は本人（IDトークンのユーザー）のアバター画像のアップロード・削除
+// POST /api/v1/users/me/avatar  (multipart/form-data, image)
+// DELETE /api/v1/users/me/avatar
+func (h *UserHandler) Avatar(w http.ResponseWriter, r *http.Request) {
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	uid := actor.UID
+
+	switch r.Method {
+	case http.MethodPost:
+		r.ParseMultipartForm(10 << 20) // 10MB max
+
+		file, fileHeader, err := r.FormFile("image")
+		if err != nil && err != http.ErrMissingFile {
+			writeJSONError(w, "Error retrieving file", http.StatusInternalServerError)
+			return
+		}
+		defer func() {
+			if file != nil {
+				file.Close()
+			}
+		}()
+
+		profile, err := h.userUc.UploadAvatar(uid, file, fileHeader)
+		if err != nil {
+			writeUsecaseError(w, err)
+			return
+		}
+		writeJSON(w, profile)
+	case http.MethodDelete:
+		profile, err := h.userUc.DeleteAvatar(uid)
+		if err != nil {
+			writeUsecaseError(w, err)
+			return
+		}
+		writeJSON(w, profile)
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Export は本人（IDトークンのユーザー）のデータをダウンロードする
+// GET /api/v1/users/me/export?format=json|zip （デフォルトはjson）
+func (h *UserHandler) Export(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	actor, ok := auth.ActorFromContext(r.Context())
+	if !ok {
+		writeJSONError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	uid := actor.UID
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		writeJSONError(w, "format must be json or zip", http.StatusBadRequest)
+		return
+	}
+
+	export, err := h.userUc.ExportMyData(uid)
+	if err != nil {
+		writeUsecaseError(w, err)
+		return
+	}
+
+	filename := "export-" + export.ExportedAt.Format("20060102-150405")
+	if format == "zip" {
+		// 書き込み途中で失敗した場合はステータスを変えられないため、先にメモリ上で作成する
+		var buf bytes.Buffer
+		if err := users.WriteExportZip(&buf, export); err != nil {
+			writeUsecaseError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+		w.Write(buf.Bytes())
+		return
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+	writeJSON(w, export)
+}
+
+// GetUser は公開プロフィールを取得する
+// GET /api/v1/users/{uid}
+func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	uid := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/users/"), "/")
+	if uid == "me" {
+		// 本人のプロフィールは本人確認をする /api/v1/users/me だけで扱う
+		writeJSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if uid == "" || strings.Contains(uid, "/") {
+		writeJSONError(w, "uid is required", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := h.userUc.GetPublicProfile(uid)
+	if err != nil {
+		writeUsecaseError(w, err)
+		return
+	}
+	writeJSON(w, profile)
+}
+
+// writeUsecaseError はusecaseのエラーをHTTPステータスに変換する
+func writeUsecaseError(w http.ResponseWriter, err error) {
+	var verr *users.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":  "validation failed",
+			"fields": verr.Fields,
+		})
+	case errors.Is(err, users.ErrUserNotFound):
+		writeJSONError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, users.ErrActiveTransactions):
+		writeJSONError(w, err.Error(), http.StatusConflict)
+	default:
+		fmt.Printf("Error in users handler: %v\n", err)
+		writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		writeJSONError(w, "JSON encode error", http.StatusInternalServerError)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
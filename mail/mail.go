@@ -0,0 +1,68 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/textproto"
+	"os"
+	"strings"
+)
+
+// Message は送信するメール（本文はプレーンテキスト）
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender はメールを送信する
+type Sender interface {
+	Send(msg *Message) error
+}
+
+// PermanentError は再送しても成功しないエラー（宛先の誤りなど）。Queueは再送しない
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// IsPermanent は再送しても成功しないエラーか（PermanentError、またはSMTPの5xx応答）
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	if errors.As(err, &permanent) {
+		return true
+	}
+	var protoErr *textproto.Error
+	return errors.As(err, &protoErr) && protoErr.Code >= 500
+}
+
+// NewSenderFromEnv は環境変数 MAIL_BACKEND に応じてSenderを生成する
+//
+//	log (デフォルト): 送信せずにログに出す（開発用）
+//	smtp: SMTP_ADDR のSMTPサーバーから MAIL_FROM の差出人で送信する
+func NewSenderFromEnv() (Sender, error) {
+	backend := strings.ToLower(os.Getenv("MAIL_BACKEND"))
+	switch backend {
+	case "", "log":
+		return &LogSender{}, nil
+	case "smtp":
+		return NewSMTPSender(SMTPConfig{
+			Addr:     os.Getenv("SMTP_ADDR"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		})
+	}
+	return nil, fmt.Errorf("unknown MAIL_BACKEND: %s", backend)
+}
+
+// LogSender は送信せずに宛先と件名をログに出すSender（開発用）
+type LogSender struct{}
+
+func (s *LogSender) Send(msg *Message) error {
+	log.Printf("mail (not sent): to=%s subject=%q", msg.To, msg.Subject)
+	return nil
+}
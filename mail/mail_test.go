@@ -0,0 +1,211 @@
+package mail
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"uttc-hackathon-backend/mail/mailtest"
+)
+
+func newTestServer(t *testing.T) *mailtest.Server {
+	t.Helper()
+	server, err := mailtest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start SMTP server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func newTestSender(t *testing.T, server *mailtest.Server, username string) *SMTPSender {
+	t.Helper()
+	sender, err := NewSMTPSender(SMTPConfig{
+		Addr:     server.Addr(),
+		Username: username,
+		Password: "secret",
+		From:     "ethershop <noreply@example.com>",
+	})
+	if err != nil {
+		t.Fatalf("failed to create sender: %v", err)
+	}
+	return sender
+}
+
+// TestSMTPSender_Send 件名・本文をUTF-8でエンコードし、認証して送信する
+func TestSMTPSender_Send(t *testing.T) {
+	server := newTestServer(t)
+	sender := newTestSender(t, server, "smtp-user")
+
+	body := "「カメラ」が購入されました。\n" + strings.Repeat("長い本文", 40)
+	err := sender.Send(&Message{To: "Seller <seller@example.com>", Subject: "商品が売れました", Body: body})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+	got := messages[0]
+	if got.From != "noreply@example.com" || len(got.To) != 1 || got.To[0] != "seller@example.com" {
+		t.Errorf("unexpected envelope: from=%s to=%v", got.From, got.To)
+	}
+	if got.Username != "smtp-user" {
+		t.Errorf("expected to authenticate as smtp-user, got %q", got.Username)
+	}
+	if subject, _ := got.Subject(); subject != "商品が売れました" {
+		t.Errorf("unexpected subject: %q", subject)
+	}
+	if decoded, _ := got.Body(); decoded != body {
+		t.Errorf("unexpected body: %q", decoded)
+	}
+	for _, line := range strings.Split(got.Data, "\r\n") {
+		if len(line) > 998 {
+			t.Errorf("line too long: %d", len(line))
+		}
+	}
+}
+
+// TestSMTPSender_Errors 宛先の誤り・5xx応答は再送しないエラー、4xx応答は再送するエラーになる
+func TestSMTPSender_Errors(t *testing.T) {
+	server := newTestServer(t)
+	sender := newTestSender(t, server, "")
+
+	err := sender.Send(&Message{To: "not an address", Subject: "s", Body: "b"})
+	if err == nil || !IsPermanent(err) {
+		t.Errorf("expected permanent error for invalid recipient, got %v", err)
+	}
+
+	server.Fail(550, 1)
+	if err := sender.Send(&Message{To: "a@example.com", Subject: "s", Body: "b"}); err == nil || !IsPermanent(err) {
+		t.Errorf("expected permanent error for 550, got %v", err)
+	}
+	server.Fail(451, 1)
+	if err := sender.Send(&Message{To: "a@example.com", Subject: "s", Body: "b"}); err == nil || IsPermanent(err) {
+		t.Errorf("expected temporary error for 451, got %v", err)
+	}
+	if len(server.Messages()) != 0 {
+		t.Errorf("expected no messages, got %d", len(server.Messages()))
+	}
+}
+
+// TestNewSMTPSender_InvalidConfig アドレス・差出人が不正なら生成しない
+func TestNewSMTPSender_InvalidConfig(t *testing.T) {
+	if _, err := NewSMTPSender(SMTPConfig{Addr: "localhost", From: "noreply@example.com"}); err == nil {
+		t.Error("expected error for address without port")
+	}
+	if _, err := NewSMTPSender(SMTPConfig{Addr: "localhost:25", From: ""}); err == nil {
+		t.Error("expected error for empty from")
+	}
+}
+
+// TestQueue_Retry 一時的なエラーは間隔を空けて再送する
+func TestQueue_Retry(t *testing.T) {
+	server := newTestServer(t)
+	server.Fail(451, 2)
+	queue := NewQueue(newTestSender(t, server, ""), QueueConfig{Workers: 1, MaxAttempts: 3, Backoff: time.Millisecond})
+	defer queue.Close()
+
+	if err := queue.Enqueue(&Message{To: "a@example.com", Subject: "s", Body: "b"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if messages := server.WaitForMessages(1, 2*time.Second); len(messages) != 1 {
+		t.Fatalf("expected message to be delivered after retries, got %d", len(messages))
+	}
+}
+
+// countingSender は送信の試行回数を数え、常にerrを返す
+type countingSender struct {
+	mu    sync.Mutex
+	calls int
+	err   error
+}
+
+func (s *countingSender) Send(msg *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	return s.err
+}
+
+func (s *countingSender) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// TestQueue_GiveUp 再送しても成功しないエラーは再送せず、一時的なエラーも試行回数で諦める
+func TestQueue_GiveUp(t *testing.T) {
+	permanent := &countingSender{err: &PermanentError{Err: errors.New("no such user")}}
+	queue := NewQueue(permanent, QueueConfig{Workers: 1, MaxAttempts: 3, Backoff: time.Millisecond})
+	_ = queue.Enqueue(&Message{To: "a@example.com"})
+	queue.Close()
+	if permanent.count() != 1 {
+		t.Errorf("expected 1 attempt for permanent error, got %d", permanent.count())
+	}
+
+	temporary := &countingSender{err: errors.New("connection refused")}
+	queue = NewQueue(temporary, QueueConfig{Workers: 1, MaxAttempts: 3, Backoff: time.Millisecond})
+	_ = queue.Enqueue(&Message{To: "a@example.com"})
+	deadline := time.Now().Add(2 * time.Second)
+	for temporary.count() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	queue.Close()
+	if temporary.count() != 3 {
+		t.Errorf("expected 3 attempts, got %d", temporary.count())
+	}
+}
+
+// TestQueue_Close 停止時に送信待ちのメールを送信し、停止後は追加できない
+func TestQueue_Close(t *testing.T) {
+	server := newTestServer(t)
+	queue := NewQueue(newTestSender(t, server, ""), QueueConfig{Workers: 1})
+	for i := 0; i < 3; i++ {
+		if err := queue.Enqueue(&Message{To: "a@example.com", Subject: "s", Body: "b"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	queue.Close()
+	if len(server.Messages()) != 3 {
+		t.Errorf("expected queued messages to be sent on close, got %d", len(server.Messages()))
+	}
+	if err := queue.Enqueue(&Message{To: "a@example.com"}); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("expected ErrQueueClosed, got %v", err)
+	}
+	queue.Close()
+}
+
+// TestQueue_Full 送信待ちが上限に達したら追加しない
+func TestQueue_Full(t *testing.T) {
+	block := make(chan struct{})
+	sender := &blockingSender{release: block, started: make(chan struct{})}
+	queue := NewQueue(sender, QueueConfig{Workers: 1, Size: 1})
+	defer queue.Close()
+	defer close(block)
+
+	_ = queue.Enqueue(&Message{To: "a@example.com"}) // 送信中
+	<-sender.started
+	if err := queue.Enqueue(&Message{To: "b@example.com"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := queue.Enqueue(&Message{To: "c@example.com"}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+}
+
+// blockingSender はreleaseが閉じられるまで送信を終えない
+type blockingSender struct {
+	release chan struct{}
+	started chan struct{}
+	once    sync.Once
+}
+
+func (s *blockingSender) Send(msg *Message) error {
+	s.once.Do(func() { close(s.started) })
+	<-s.release
+	return nil
+}
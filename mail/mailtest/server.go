@@ -0,0 +1,233 @@
+// Package mailtest はテスト用のSMTPサーバー（同じプロセス内で動く）
+package mailtest
+
+import (
+	"encoding/base64"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Received はServerが受け取ったメール
+type Received struct {
+	From     string
+	To       []string
+	Data     string // ヘッダーを含むメールの内容
+	Username string // AUTH PLAINで認証したユーザー（認証していなければ空）
+}
+
+// Subject はMIMEエンコードを戻した件名
+func (r *Received) Subject() (string, error) {
+	msg, err := mail.ReadMessage(strings.NewReader(r.Data))
+	if err != nil {
+		return "", err
+	}
+	return new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+}
+
+// Body はbase64を戻した本文
+func (r *Received) Body() (string, error) {
+	msg, err := mail.ReadMessage(strings.NewReader(r.Data))
+	if err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(msg.Body)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(msg.Header.Get("Content-Transfer-Encoding"), "base64") {
+		return string(data), nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.NewReplacer("\r", "", "\n", "").Replace(string(data)))
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+// Server はEHLO・AUTH PLAIN・MAIL・RCPT・DATA・QUITだけを扱うSMTPサーバー
+// Failで送信を失敗させられる
+type Server struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	received []*Received
+	failures []failure
+	wg       sync.WaitGroup
+}
+
+// failure はMAIL FROMに対して返すエラー応答
+type failure struct {
+	code    int
+	message string
+}
+
+// NewServer は127.0.0.1の空いているポートでServerを起動する
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{listener: listener}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr はServerのアドレス（host:port）
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Fail は次のtimes回の送信をcodeの応答（4xxなら一時的、5xxなら恒久的なエラー）で失敗させる
+func (s *Server) Fail(code int, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < times; i++ {
+		s.failures = append(s.failures, failure{code: code, message: "simulated failure"})
+	}
+}
+
+// Messages は受け取ったメールを返す
+func (s *Server) Messages() []*Received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Received(nil), s.received...)
+}
+
+// WaitForMessages はn通受け取るまで待つ（timeoutを過ぎたらそれまでに受け取ったメールを返す）
+func (s *Server) WaitForMessages(n int, timeout time.Duration) []*Received {
+	deadline := time.Now().Add(timeout)
+	for {
+		messages := s.Messages()
+		if len(messages) >= n || time.Now().After(deadline) {
+			return messages
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// Close はServerを停止する
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(textproto.NewConn(conn))
+		}()
+	}
+}
+
+func (s *Server) handle(c *textproto.Conn) {
+	c.PrintfLine("220 mailtest ESMTP")
+	current := &Received{}
+	var username string
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			c.PrintfLine("250-mailtest")
+			c.PrintfLine("250-8BITMIME")
+			c.PrintfLine("250 AUTH PLAIN")
+		case "HELO", "NOOP":
+			c.PrintfLine("250 OK")
+		case "AUTH":
+			user, ok := plainUser(arg)
+			if !ok {
+				c.PrintfLine("535 authentication failed")
+				continue
+			}
+			username = user
+			c.PrintfLine("235 authenticated")
+		case "MAIL":
+			if f, ok := s.nextFailure(); ok {
+				c.PrintfLine("%d %s", f.code, f.message)
+				continue
+			}
+			current = &Received{From: address(arg), Username: username}
+			c.PrintfLine("250 OK")
+		case "RCPT":
+			current.To = append(current.To, address(arg))
+			c.PrintfLine("250 OK")
+		case "DATA":
+			c.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(c.DotReader())
+			if err != nil {
+				return
+			}
+			current.Data = string(data)
+			s.mu.Lock()
+			s.received = append(s.received, current)
+			s.mu.Unlock()
+			current = &Received{}
+			c.PrintfLine("250 OK")
+		case "RSET":
+			current = &Received{}
+			c.PrintfLine("250 OK")
+		case "QUIT":
+			c.PrintfLine("221 bye")
+			return
+		default:
+			c.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+func (s *Server) nextFailure() (failure, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.failures) == 0 {
+		return failure{}, false
+	}
+	f := s.failures[0]
+	s.failures = s.failures[1:]
+	return f, true
+}
+
+// address は "FROM:<a@example.com> BODY=8BITMIME" から a@example.com を取り出す
+func address(arg string) string {
+	start := strings.Index(arg, "<")
+	end := strings.Index(arg, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return arg[start+1 : end]
+}
+
+// plainUser は "PLAIN <base64>" からユーザー名を取り出す
+func plainUser(arg string) (string, bool) {
+	mechanism, initial, _ := strings.Cut(arg, " ")
+	if !strings.EqualFold(mechanism, "PLAIN") {
+		return "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(initial)
+	if err != nil {
+		return "", false
+	}
+	parts := strings.Split(string(decoded), "\x00")
+	if len(parts) != 3 || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
@@ -0,0 +1,127 @@
+package mail
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQueueFull は送信待ちのメールが上限に達している場合のエラー
+	ErrQueueFull = errors.New("mail queue is full")
+	// ErrQueueClosed は停止したQueueに追加しようとした場合のエラー
+	ErrQueueClosed = errors.New("mail queue is closed")
+)
+
+// QueueConfig はQueueの設定（0なら既定値）
+type QueueConfig struct {
+	Workers     int           // 同時に送信する数（既定: 2）
+	Size        int           // 送信待ちのメールの上限（既定: 1000）
+	MaxAttempts int           // 1通あたりの送信の試行回数（既定: 5）
+	Backoff     time.Duration // 最初の再送までの待ち時間。以降は倍にしていく（既定: 5秒）
+}
+
+// Queue はメールを非同期に送信し、失敗したら間隔を空けて再送する
+// 送信待ちのメールはメモリにだけ持つので、プロセスが終了すると失われる
+type Queue struct {
+	sender      Sender
+	maxAttempts int
+	backoff     time.Duration
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan *Message
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewQueue はsenderで送信するQueueを生成し、送信を始める
+func NewQueue(sender Sender, cfg QueueConfig) *Queue {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 2
+	}
+	if cfg.Size <= 0 {
+		cfg.Size = 1000
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 5 * time.Second
+	}
+	q := &Queue{
+		sender:      sender,
+		maxAttempts: cfg.MaxAttempts,
+		backoff:     cfg.Backoff,
+		queue:       make(chan *Message, cfg.Size),
+		done:        make(chan struct{}),
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue はメールを送信待ちに追加する（送信を待たずに返る）
+func (q *Queue) Enqueue(msg *Message) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case q.queue <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close は追加を止め、送信待ちのメールを1回ずつ送信してから返る（再送待ちのメールは諦める）
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.queue)
+	close(q.done)
+	q.mu.Unlock()
+	q.wg.Wait()
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for msg := range q.queue {
+		q.deliver(msg)
+	}
+}
+
+// deliver は成功するか、再送しても成功しないエラーになるか、試行回数に達するまで送信する
+func (q *Queue) deliver(msg *Message) {
+	wait := q.backoff
+	for attempt := 1; ; attempt++ {
+		err := q.sender.Send(msg)
+		if err == nil {
+			return
+		}
+		if IsPermanent(err) || attempt >= q.maxAttempts {
+			log.Printf("WARNING: giving up mail to %s after %d attempt(s): %v", msg.To, attempt, err)
+			return
+		}
+		log.Printf("WARNING: failed to send mail to %s (attempt %d), retrying in %s: %v", msg.To, attempt, wait, err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-q.done:
+			timer.Stop()
+			log.Printf("WARNING: mail queue closed, dropping mail to %s", msg.To)
+			return
+		}
+		wait *= 2
+	}
+}
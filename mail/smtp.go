@@ -0,0 +1,79 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPConfig はSMTPサーバーの設定
+type SMTPConfig struct {
+	Addr     string // host:port
+	Username string // 空なら認証しない
+	Password string
+	From     string // 差出人（"ethershop <noreply@example.com>" の形式も可）
+}
+
+// SMTPSender はSMTPサーバーからメールを送信する
+// サーバーが対応していればSTARTTLSで暗号化する（net/smtpはlocalhost以外への平文での認証を拒否する）
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+	from *mail.Address
+	now  func() time.Time
+}
+
+func NewSMTPSender(cfg SMTPConfig) (*SMTPSender, error) {
+	host, _, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP_ADDR %q: %w", cfg.Addr, err)
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAIL_FROM %q: %w", cfg.From, err)
+	}
+	s := &SMTPSender{addr: cfg.Addr, from: from, now: time.Now}
+	if cfg.Username != "" {
+		s.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+	return s, nil
+}
+
+func (s *SMTPSender) Send(msg *Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return &PermanentError{Err: fmt.Errorf("invalid recipient %q: %w", msg.To, err)}
+	}
+	data := s.build(to, msg)
+	if err := smtp.SendMail(s.addr, s.auth, s.from.Address, []string{to.Address}, data); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+// build はヘッダーと本文を組み立てる（件名はMIMEエンコード、本文はUTF-8のbase64）
+func (s *SMTPSender) build(to *mail.Address, msg *Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.from.String())
+	fmt.Fprintf(&b, "To: %s\r\n", to.String())
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", s.now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n")
+	b.WriteString("\r\n")
+
+	// 1行76文字で折り返す（RFC 2045）
+	encoded := base64.StdEncoding.EncodeToString([]byte(msg.Body))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return b.Bytes()
+}
@@ -6,29 +6,69 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
+	"uttc-hackathon-backend/auth"
+	adminDao "uttc-hackathon-backend/dao/admin"
+	auditDao "uttc-hackathon-backend/dao/audit"
+	blocksDao "uttc-hackathon-backend/dao/blocks"
+	chainEventsDao "uttc-hackathon-backend/dao/chainEvents"
+	followsDao "uttc-hackathon-backend/dao/follows"
 	getItemDao "uttc-hackathon-backend/dao/getItems"
+	imagesDao "uttc-hackathon-backend/dao/images"
 	likesDao "uttc-hackathon-backend/dao/likes"
 	messagesDao "uttc-hackathon-backend/dao/messages"
+	notificationsDao "uttc-hackathon-backend/dao/notifications"
+	ordersDao "uttc-hackathon-backend/dao/orders"
 	postItemsDao "uttc-hackathon-backend/dao/postItems"
 	postUserDao "uttc-hackathon-backend/dao/postUser"
 	purchaseItemDao "uttc-hackathon-backend/dao/purchaseItem"
+	reportsDao "uttc-hackathon-backend/dao/reports"
+	reviewsDao "uttc-hackathon-backend/dao/reviews"
+	shippingDao "uttc-hackathon-backend/dao/shipping"
+	uploadsDao "uttc-hackathon-backend/dao/uploads"
+	usersDao "uttc-hackathon-backend/dao/users"
+	"uttc-hackathon-backend/events"
+	adminHdr "uttc-hackathon-backend/handlers/admin"
+	blocksHdr "uttc-hackathon-backend/handlers/blocks"
+	followsHdr "uttc-hackathon-backend/handlers/follows"
 	getItemHdr "uttc-hackathon-backend/handlers/getItems"
+	itemEventsHdr "uttc-hackathon-backend/handlers/itemEvents"
 	likesHdr "uttc-hackathon-backend/handlers/likes"
 	messagesHdr "uttc-hackathon-backend/handlers/messages"
+	notificationsHdr "uttc-hackathon-backend/handlers/notifications"
+	ordersHdr "uttc-hackathon-backend/handlers/orders"
 	postItemsHdr "uttc-hackathon-backend/handlers/postItems"
 	postUserHdr "uttc-hackathon-backend/handlers/postUser"
 	purchaseItemHdr "uttc-hackathon-backend/handlers/purchaseItem"
+	reportsHdr "uttc-hackathon-backend/handlers/reports"
+	reviewsHdr "uttc-hackathon-backend/handlers/reviews"
+	shippingHdr "uttc-hackathon-backend/handlers/shipping"
+	usersHdr "uttc-hackathon-backend/handlers/users"
 	blockchainHdr "uttc-hackathon-backend/handlers/blockchain"
 	blockchainUc "uttc-hackathon-backend/usecase/blockchain"
 	geminiHdr "uttc-hackathon-backend/handlers/gemini"
+	adminUc "uttc-hackathon-backend/usecase/admin"
+	blocksUc "uttc-hackathon-backend/usecase/blocks"
+	followsUc "uttc-hackathon-backend/usecase/follows"
 	geminiUc "uttc-hackathon-backend/usecase/gemini"
 	getItemUc "uttc-hackathon-backend/usecase/getItems"
 	likesUc "uttc-hackathon-backend/usecase/likes"
 	messagesUc "uttc-hackathon-backend/usecase/messages"
+	notificationsUc "uttc-hackathon-backend/usecase/notifications"
+	ordersUc "uttc-hackathon-backend/usecase/orders"
 	postItemsUc "uttc-hackathon-backend/usecase/postItems"
 	postUserUc "uttc-hackathon-backend/usecase/postUser"
 	purchaseItemUc "uttc-hackathon-backend/usecase/purchaseItem"
+	reportsUc "uttc-hackathon-backend/usecase/reports"
+	reviewsUc "uttc-hackathon-backend/usecase/reviews"
+	shippingUc "uttc-hackathon-backend/usecase/shipping"
+	usersUc "uttc-hackathon-backend/usecase/users"
+	"uttc-hackathon-backend/mail"
+	"uttc-hackathon-backend/realtime"
+	"uttc-hackathon-backend/secret"
+	"uttc-hackathon-backend/storage"
 
 	_ "github.com/go-sql-driver/mysql"
 )
@@ -55,9 +95,41 @@ func main() {
 
 	log.Println("Connected to Cloud SQL!")
 
+	// 本人確認に使うFirebase AuthのIDトークンの検証（FIREBASE_PROJECT_ID）
+	tokenVerifier, err := auth.NewVerifierFromEnv()
+	if err != nil {
+		log.Fatalf("auth init error: %v", err)
+	}
+
+	// 画像の保存先（STORAGE_BACKEND=local|s3）
+	store, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatalf("storage init error: %v", err)
+	}
+
+	// 配送先住所の暗号化キー（ADDRESS_ENCRYPTION_KEY）
+	addressBox, err := secret.NewBoxFromEnv()
+	if err != nil {
+		log.Fatalf("address encryption init error: %v", err)
+	}
+
+	// WebSocketで接続中のユーザーへのイベント配信（REALTIME_BACKEND=memory|mysql）
+	realtimeBackend, err := realtime.NewBackendFromEnv(db)
+	if err != nil {
+		log.Fatalf("realtime init error: %v", err)
+	}
+	hub := realtime.NewHub(realtimeBackend, isAllowedOrigin)
+	defer hub.Close()
+
+	// 商品の状態・いいね数の変化のイベントバス（DAOの書き込みから発行し、SSEで配信）
+	itemEventBus := events.NewBus(realtimeBackend)
+	itemEventHandler := itemEventsHdr.NewItemEventHandler(itemEventBus)
+
 	// 既存のハンドラー設定
 	itemDAO := postItemsDao.NewItemDAO(db)
-	itemUsecase := postItemsUc.NewItemUsecase(itemDAO)
+	imageDAO := imagesDao.NewImageDAO(db)
+	uploadSessionDAO := uploadsDao.NewUploadSessionDAO(db)
+	itemUsecase := postItemsUc.NewItemUsecase(itemDAO, imageDAO, uploadSessionDAO, store)
 	itemHandler := postItemsHdr.NewItemHandler(itemUsecase)
 
 	getItemDAO := getItemDao.NewItemDAO(db)
@@ -68,42 +140,185 @@ func main() {
 	userUsecase := postUserUc.NewUserUsecase(userDAO)
 	userHandler := postUserHdr.NewUserHandler(userUsecase)
 
-	purchaseDAO := purchaseItemDao.NewPurchaseDAO(db)
-	purchaseUsecase := purchaseItemUc.NewPurchaseUsecase(purchaseDAO)
-	purchaseHandler := purchaseItemHdr.NewPurchaseHandler(purchaseUsecase)
+	profileDAO := usersDao.NewUserDAO(db)
+	profileUsecase := usersUc.NewUserUsecase(profileDAO, itemUsecase)
+	profileHandler := usersHdr.NewUserHandler(profileUsecase)
 
+	// 通報・モデレーションキュー
+	reportDAO := reportsDao.NewReportDAO(db)
+	reportUsecase := reportsUc.NewReportUsecase(reportDAO)
+	reportHandler := reportsHdr.NewReportHandler(reportUsecase)
+
+	// Gemini handler
+	geminiUsecase := geminiUc.NewGeminiUsecase()
+	geminiHandler := geminiHdr.NewGeminiHandler(geminiUsecase)
+
+	// メールの送信（MAIL_BACKEND=smtp ならSMTP_ADDRのサーバーから送信。失敗したら再送する）
+	mailSender, err := mail.NewSenderFromEnv()
+	if err != nil {
+		log.Fatalf("failed to initialize mail sender: %v", err)
+	}
+	mailQueue := mail.NewQueue(mailSender, mail.QueueConfig{})
+	defer mailQueue.Close()
+
+	// アプリ内の通知（いいね・購入・受け取り確認・メッセージ）
+	// 商品が売れた・受け取りが確認された・オフライン中のメッセージはメールでも送る
+	notificationUsecase := notificationsUc.NewNotificationUsecase(notificationsDao.NewNotificationDAO(db), hub, &notificationsUc.EmailConfig{
+		Mailer:   mailQueue,
+		Presence: hub,
+		BaseURL:  os.Getenv("FRONTEND_BASE_URL"),
+	})
+	notificationHandler := notificationsHdr.NewNotificationHandler(notificationUsecase)
+
+	// メッセージのコンテンツフィルタ（フラグを立てた・保留したメッセージはモデレーションキューへ）
+	// MESSAGE_LLM_FILTER=true ならLLMによる分類も行う（OPENAI_API_KEYが必要）
 	messageDAO := messagesDao.NewMessageDAO(db)
-	messageUsecase := messagesUc.NewMessageUsecase(messageDAO)
-	messageHandler := messagesHdr.NewMessageHandler(messageUsecase)
+	messageFilters := messagesUc.NewDefaultFilterChain(messageDAO, listFromEnv("MESSAGE_BLOCKED_WORDS"))
+	if os.Getenv("MESSAGE_LLM_FILTER") == "true" {
+		messageFilters = append(messageFilters, messagesUc.NewClassifierFilter(geminiUsecase))
+	}
+
+	// 取引の節目（購入・発送・受け取り・キャンセル）はメッセージのスレッドにも書き込む
+	messageUsecase := messagesUc.NewMessageUsecase(messageDAO, hub, itemUsecase, messageFilters, reportUsecase, notificationUsecase)
+	reportUsecase.SetMessageReleaser(messageUsecase)
+	messageHandler := messagesHdr.NewMessageHandler(messageUsecase, hub)
+
+	orderDAO := ordersDao.NewOrderDAO(db, itemEventBus)
+	orderUsecase := ordersUc.NewOrderUsecase(orderDAO, messageUsecase, notificationUsecase)
+	orderHandler := ordersHdr.NewOrderHandler(orderUsecase)
 
-	likeDAO := likesDao.NewLikeDAO(db)
-	likeUsecase := likesUc.NewLikeUsecase(likeDAO)
+	purchaseDAO := purchaseItemDao.NewPurchaseDAO(db, itemEventBus)
+	purchaseUsecase := purchaseItemUc.NewPurchaseUsecase(purchaseDAO, orderUsecase, notificationUsecase)
+	purchaseHandler := purchaseItemHdr.NewPurchaseHandler(purchaseUsecase)
+
+	likeDAO := likesDao.NewLikeDAO(db, itemEventBus)
+	likeUsecase := likesUc.NewLikeUsecase(likeDAO, notificationUsecase)
 	likeHandler := likesHdr.NewLikeHandler(likeUsecase)
 
+	followDAO := followsDao.NewFollowDAO(db)
+	followUsecase := followsUc.NewFollowUsecase(followDAO)
+	followHandler := followsHdr.NewFollowHandler(followUsecase)
+
+	blockDAO := blocksDao.NewBlockDAO(db)
+	blockUsecase := blocksUc.NewBlockUsecase(blockDAO)
+	blockHandler := blocksHdr.NewBlockHandler(blockUsecase)
+
+	reviewDAO := reviewsDao.NewReviewDAO(db)
+	reviewUsecase := reviewsUc.NewReviewUsecase(reviewDAO)
+	reviewHandler := reviewsHdr.NewReviewHandler(reviewUsecase)
+
+	// 配送先住所と発送情報
+	shippingDAO := shippingDao.NewShippingDAO(db)
+	shippingUsecase := shippingUc.NewShippingUsecase(shippingDAO, addressBox, messageUsecase)
+	shippingHandler := shippingHdr.NewShippingHandler(shippingUsecase)
+
 	// Blockchain handler
-	blockchainUsecase := blockchainUc.NewBlockchainUsecase(itemDAO, purchaseDAO)
+	chainEventDAO := chainEventsDao.NewChainEventDAO(db)
+	blockchainUsecase := blockchainUc.NewBlockchainUsecase(itemDAO, purchaseDAO, chainEventDAO, orderUsecase, itemUsecase, messageUsecase, notificationUsecase)
 	blockchainHandler := blockchainHdr.NewBlockchainHandler(blockchainUsecase)
 
-	// Gemini handler
-	geminiUsecase := geminiUc.NewGeminiUsecase()
-	geminiHandler := geminiHdr.NewGeminiHandler(geminiUsecase)
+	// 出品されなかった画像のGC（IMAGE_GC_GRACE経過後に削除）
+	imageGC := postItemsUc.NewImageGC(imageDAO, uploadSessionDAO, messageDAO, store, durationFromEnv("IMAGE_GC_GRACE", 24*time.Hour))
+	stopImageGC := imageGC.Start(durationFromEnv("IMAGE_GC_INTERVAL", time.Hour))
+	defer stopImageGC()
+
+	// 管理者API（users.roleで認可し、操作は監査ログに残す）
+	adminDAO := adminDao.NewAdminDAO(db, itemEventBus)
+	adminUsecase := adminUc.NewAdminUsecase(adminDAO, auditDao.NewAuditDAO(db), chainEventDAO)
+	adminHandler := adminHdr.NewAdminHandler(adminUsecase)
+	requireModerator := func(next http.HandlerFunc) http.HandlerFunc {
+		return auth.RequireRole(tokenVerifier, adminDAO, auth.RoleModerator, next)
+	}
+	requireAdmin := func(next http.HandlerFunc) http.HandlerFunc {
+		return auth.RequireRole(tokenVerifier, adminDAO, auth.RoleAdmin, next)
+	}
+	// 本人確認が必要なAPI（IDトークンのユーザーとして操作する。存在しない・利用停止中・退会済みのユーザーは拒否）
+	requireUser := func(next http.HandlerFunc) http.HandlerFunc {
+		return auth.RequireRole(tokenVerifier, adminDAO, auth.RoleUser, next)
+	}
+	// 未ログインでも使えるが、IDトークンがあればそのユーザーとして扱うAPI（商品一覧の閲覧者など）
+	optionalUser := func(next http.HandlerFunc) http.HandlerFunc {
+		return auth.OptionalUser(tokenVerifier, adminDAO, next)
+	}
 
 	// HTTPルーティング
 	http.HandleFunc("/postItems", itemHandler.CreateItem)
 	http.HandleFunc("/uploadImage", itemHandler.UploadImage)
-	http.HandleFunc("/getItems", getItemHandler.GetItems)
-	http.HandleFunc("/getItems/latest", getItemHandler.GetLatestItems)
-	http.HandleFunc("/getItems/", getItemHandler.GetItemByID)
+	http.HandleFunc("/api/v1/uploads/presign", requireUser(itemHandler.PresignUpload))
+	http.HandleFunc("/api/v1/uploads/complete", requireUser(itemHandler.CompleteUpload))
+	http.HandleFunc("/getItems", optionalUser(getItemHandler.GetItems))
+	http.HandleFunc("/getItems/latest", optionalUser(getItemHandler.GetLatestItems))
+	http.HandleFunc("/getItems/", optionalUser(getItemHandler.GetItemByID))
+	http.HandleFunc("/api/v1/feed", requireUser(getItemHandler.GetFeed))
+	http.HandleFunc("/api/v1/items/", itemEventHandler.ItemEvents)
 	http.HandleFunc("/register", userHandler.RegisterUser)
+	http.HandleFunc("/api/v1/users/me", requireUser(profileHandler.Me))
+	http.HandleFunc("/api/v1/users/me/avatar", requireUser(profileHandler.Avatar))
+	http.HandleFunc("/api/v1/users/me/export", requireUser(profileHandler.Export))
+	http.HandleFunc("/api/v1/users/me/events", requireUser(itemEventHandler.MyEvents))
+	http.HandleFunc("/api/v1/users/", profileHandler.GetUser)
 	http.HandleFunc("/items/", purchaseHandler.PurchaseItem)
 	http.HandleFunc("/purchases", purchaseHandler.GetPurchasedItems)
 	http.HandleFunc("/messages", messageHandler.GetMessages)
-	http.HandleFunc("/messages/send", messageHandler.SendMessage)
+	http.HandleFunc("/messages/send", requireUser(messageHandler.SendMessage))
+	http.HandleFunc("/messages/contact", requireUser(messageHandler.ContactSeller))
+	http.HandleFunc("/messages/attachments", requireUser(messageHandler.UploadAttachment))
+	http.HandleFunc("/messages/attachments/", requireUser(messageHandler.GetAttachment))
 	http.HandleFunc("/messages/read", messageHandler.MarkAsRead)
 	http.HandleFunc("/messages/conversations", messageHandler.GetConversations)
+	http.HandleFunc("/api/v1/messages", requireUser(messageHandler.History))
+	http.HandleFunc("/api/v1/messages/unread-count", requireUser(messageHandler.UnreadCount))
+	http.HandleFunc("/api/v1/messages/", requireUser(messageHandler.MessageByID))
+	http.HandleFunc("/ws/messages", requireUser(messageHandler.Realtime))
+	http.HandleFunc("/api/v1/notifications", requireUser(notificationHandler.List))
+	http.HandleFunc("/api/v1/notifications/read", requireUser(notificationHandler.MarkRead))
+	http.HandleFunc("/api/v1/notifications/read-all", requireUser(notificationHandler.MarkAllRead))
+	http.HandleFunc("/api/v1/notifications/preferences", requireUser(notificationHandler.Preferences))
+	http.HandleFunc("/api/v1/notifications/email", requireUser(notificationHandler.Email))
 	http.HandleFunc("/likes", likeHandler.HandleLike)
 	http.HandleFunc("/likes/status", likeHandler.GetLikeStatus)
 	http.HandleFunc("/likes/user", likeHandler.GetUserLikes)
+	// レビューの一覧は誰でも見られるが、投稿はIDトークンのユーザーとして行う
+	createReview := requireUser(reviewHandler.HandleReviews)
+	http.HandleFunc("/api/v1/reviews", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			createReview(w, r)
+			return
+		}
+		reviewHandler.HandleReviews(w, r)
+	})
+	http.HandleFunc("/api/v1/addresses", requireUser(shippingHandler.HandleAddresses))
+	http.HandleFunc("/api/v1/addresses/", requireUser(shippingHandler.HandleAddress))
+	http.HandleFunc("/api/v1/shipments", requireUser(shippingHandler.GetShipment))
+	http.HandleFunc("/api/v1/shipments/address", requireUser(shippingHandler.AttachAddress))
+	http.HandleFunc("/api/v1/shipments/tracking", requireUser(shippingHandler.SetTracking))
+	http.HandleFunc("/api/v1/orders", requireUser(orderHandler.ListOrders))
+	http.HandleFunc("/api/v1/orders/", requireUser(orderHandler.HandleOrder))
+	// フォロー・フォロワーの一覧は誰でも見られるが、フォロー・解除はIDトークンのユーザーとして行う
+	updateFollow := requireUser(followHandler.HandleFollow)
+	http.HandleFunc("/api/v1/follows", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost || r.Method == http.MethodDelete {
+			updateFollow(w, r)
+			return
+		}
+		followHandler.HandleFollow(w, r)
+	})
+	http.HandleFunc("/api/v1/follows/status", requireUser(followHandler.GetFollowStatus))
+	http.HandleFunc("/api/v1/blocks", requireUser(blockHandler.HandleBlock))
+	http.HandleFunc("/api/v1/blocks/status", requireUser(blockHandler.GetBlockStatus))
+	http.HandleFunc("/api/v1/reports", requireUser(reportHandler.CreateReport))
+	// 管理者API
+	http.HandleFunc("/api/v1/admin/reports", requireModerator(reportHandler.ListQueue))
+	http.HandleFunc("/api/v1/admin/reports/resolve", requireModerator(reportHandler.ResolveReport))
+	http.HandleFunc("/api/v1/admin/items", requireModerator(adminHandler.ListItems))
+	http.HandleFunc("/api/v1/admin/items/status", requireAdmin(adminHandler.ForceItemStatus))
+	http.HandleFunc("/api/v1/admin/items/", requireModerator(adminHandler.ItemAuditEvents))
+	http.HandleFunc("/api/v1/admin/users/suspend", requireModerator(adminHandler.SuspendUser))
+	http.HandleFunc("/api/v1/admin/users/role", requireAdmin(adminHandler.SetRole))
+	http.HandleFunc("/api/v1/admin/chain-events", requireModerator(adminHandler.ListChainEvents))
+	http.HandleFunc("/api/v1/admin/messages/", requireModerator(messageHandler.ModerationMessage))
+	http.HandleFunc("/api/v1/admin/audit-events", requireAdmin(adminHandler.ListAuditEvents))
+	http.HandleFunc("/api/v1/admin/orders/refund", requireAdmin(orderHandler.Refund))
 	// Blockchain endpoints
 	http.HandleFunc("/api/v1/blockchain/item-listed", blockchainHandler.HandleItemListed)
 	http.HandleFunc("/api/v1/blockchain/item-purchased", blockchainHandler.HandleItemPurchased)
@@ -111,7 +326,10 @@ func main() {
 	http.HandleFunc("/api/v1/blockchain/item-cancelled", blockchainHandler.HandleItemCancelled)
 	// Gemini endpoint
 	http.HandleFunc("/api/v1/gemini/generate", geminiHandler.GenerateContent)
-	http.Handle("/uploads/", http.StripPrefix("/uploads/", http.FileServer(http.Dir("./uploads"))))
+	// ローカルストレージの場合のみ自前で配信する（S3等は公開URL/署名付きURLで直接配信）
+	if localStore, ok := store.(*storage.LocalStorage); ok {
+		http.Handle("/uploads/", http.StripPrefix("/uploads/", localStore))
+	}
 
 	standardRouter := http.DefaultServeMux
 	
@@ -137,3 +355,28 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// listFromEnv は環境変数をカンマ区切りのリストとして読み取る（未設定ならnil）
+func listFromEnv(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// durationFromEnv は環境変数を "1h30m" 形式で読み取る（未設定・不正ならデフォルト値）
+func durationFromEnv(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("WARNING: invalid %s=%q, using default %s", key, v, def)
+		return def
+	}
+	return d
+}
@@ -1,21 +1,30 @@
 package main
 
 import (
+	"bufio"
+	"errors"
+	"net"
 	"net/http"
 )
 
+var allowedOrigins = map[string]bool{
+	"http://localhost:3000": true,
+	"https://uttc-hackathon-frontend-ix9mgv4j6-kazukis-projects-f47db0d9.vercel.app": true,
+	"https://uttc-hackathon-frontend-pink.vercel.app":                                true,
+}
+
+// isAllowedOrigin はCORS・WebSocketで許可するOriginか（Originがないサーバー間通信も許可する）
+func isAllowedOrigin(origin string) bool {
+	return origin == "" || allowedOrigins[origin]
+}
+
 func handleCors(next http.Handler, w http.ResponseWriter, r *http.Request) {
 	// 1. CORSヘッダーの設定
 	origin := r.Header.Get("Origin")
-	allowedOrigins := map[string]bool{
-		"http://localhost:3000": true,
-		"https://uttc-hackathon-frontend-ix9mgv4j6-kazukis-projects-f47db0d9.vercel.app": true,
-		"https://uttc-hackathon-frontend-pink.vercel.app":                                true,
-	}
 
 	// back-onchainからのリクエストはOriginがないか、異なるOriginの可能性がある
 	// その場合はすべてのOriginを許可（サーバー間通信のため）
-	if origin == "" || allowedOrigins[origin] {
+	if isAllowedOrigin(origin) {
 		if origin != "" {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 		} else {
@@ -23,7 +32,7 @@ func handleCors(next http.Handler, w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 		}
 	}
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 	if r.Method == http.MethodOptions {
@@ -54,3 +63,19 @@ func (lrw *loggingResponseWriter) WriteHeader(code int) {
 func (lrw *loggingResponseWriter) Write(b []byte) (int, error) {
 	return lrw.ResponseWriter.Write(b)
 }
+
+// Hijack はWebSocketへのアップグレードのために元のResponseWriterのHijackを呼ぶ
+func (lrw *loggingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := lrw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
+
+// Flush はServer-Sent Eventsのために元のResponseWriterのFlushを呼ぶ
+func (lrw *loggingResponseWriter) Flush() {
+	if flusher, ok := lrw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
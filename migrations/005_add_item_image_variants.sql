@@ -0,0 +1,7 @@
+-- item_imagesテーブルにサイズ別の画像URLカラムを追加
+-- image_url は full サイズ（長辺1600px）、medium_url は長辺720px、thumbnail_url は長辺240px
+-- 既存のレコードや外部URLの画像は縮小版がないためNULLのまま
+
+ALTER TABLE item_images
+ADD COLUMN medium_url VARCHAR(500) NULL COMMENT '中サイズ画像URL' AFTER image_url,
+ADD COLUMN thumbnail_url VARCHAR(500) NULL COMMENT 'サムネイル画像URL' AFTER medium_url;
@@ -0,0 +1,21 @@
+-- アップロード画像をコンテンツハッシュ（元画像のSHA-256）で管理するテーブル
+-- ストレージ上のキーは "<hash>_<thumb|medium|full>.<ext>"
+-- item_images.image_hash から参照されず、last_uploaded_at から猶予期間が過ぎた画像はGCで削除される
+-- ※ 005_add_item_image_variants.sql の実行後に実行すること
+
+CREATE TABLE images (
+    hash CHAR(64) PRIMARY KEY COMMENT '元画像のSHA-256（16進数）',
+    ext VARCHAR(10) NOT NULL COMMENT '保存時の拡張子（jpg/png）',
+    image_url VARCHAR(500) NOT NULL COMMENT 'fullサイズのURL',
+    medium_url VARCHAR(500) NOT NULL COMMENT 'mediumサイズのURL',
+    thumbnail_url VARCHAR(500) NOT NULL COMMENT 'サムネイルのURL',
+    size_bytes INT NOT NULL COMMENT '元画像のバイト数',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_uploaded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '最後に同じ画像がアップロードされた日時（GCの猶予期間の起点）',
+    INDEX idx_last_uploaded_at (last_uploaded_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- item_imagesからの参照（既存の画像はハッシュを持たないためNULLのまま＝GC対象外）
+ALTER TABLE item_images
+ADD COLUMN image_hash CHAR(64) NULL COMMENT 'imagesテーブルのハッシュ' AFTER thumbnail_url,
+ADD INDEX idx_image_hash (image_hash);
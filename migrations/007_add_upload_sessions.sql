@@ -0,0 +1,18 @@
+-- クライアントが署名付きURLでストレージへ直接アップロードするためのセッション
+-- /api/v1/uploads/presign で発行し、/api/v1/uploads/complete でサイズ・形式・SHA-256を検証してimagesに登録する
+-- アップロード先は "staging/<token>"。検証後に加工済みの画像を "<hash>_<size>.<ext>" に保存し、stagingは削除する
+-- ※ 006_add_images_table.sql の実行後に実行すること
+
+CREATE TABLE upload_sessions (
+    token CHAR(64) PRIMARY KEY COMMENT 'アップロードトークン（ランダムな16進数）',
+    uid VARCHAR(255) NOT NULL COMMENT 'URLを発行したユーザー',
+    staging_key VARCHAR(255) NOT NULL COMMENT 'アップロード先のストレージキー',
+    content_type VARCHAR(50) NOT NULL COMMENT '申告されたContent-Type',
+    size_bytes INT NOT NULL COMMENT '申告されたバイト数',
+    sha256 CHAR(64) NOT NULL COMMENT '申告された元画像のSHA-256（完了後はimages.hashと一致）',
+    status ENUM('pending', 'completed', 'failed') NOT NULL DEFAULT 'pending',
+    expires_at TIMESTAMP NOT NULL COMMENT 'アップロードURLの有効期限',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_uid (uid),
+    INDEX idx_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
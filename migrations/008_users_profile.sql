@@ -0,0 +1,30 @@
+-- プロフィールAPI用にusersテーブルを変更する
+-- birthdateはVARCHAR(10)だったが、アプリからは数値（MMDD）で保存されていたためDATE型に変換する
+-- ※ 変換できない値はNULLになる。実行前にバックアップを取ること
+
+-- 1. DATE型の列を追加
+ALTER TABLE users
+ADD COLUMN birthdate_new DATE NULL COMMENT '生年月日' AFTER birthyear;
+
+-- 2. 旧形式（birthyear + MMDDの数値）から変換
+UPDATE users
+SET birthdate_new = STR_TO_DATE(CONCAT(birthyear, LPAD(birthdate, 4, '0')), '%Y%m%d')
+WHERE birthyear IS NOT NULL AND birthyear > 0
+  AND birthdate REGEXP '^[0-9]{3,4}$';
+
+-- 3. 既に "YYYY-MM-DD" で保存されていた行
+UPDATE users
+SET birthdate_new = STR_TO_DATE(birthdate, '%Y-%m-%d')
+WHERE birthdate_new IS NULL
+  AND birthdate REGEXP '^[0-9]{4}-[0-9]{2}-[0-9]{2}$';
+
+-- 4. 旧列を削除して置き換え
+ALTER TABLE users DROP COLUMN birthdate;
+ALTER TABLE users CHANGE COLUMN birthdate_new birthdate DATE NULL COMMENT '生年月日';
+
+-- 5. アバター画像のURL
+ALTER TABLE users
+ADD COLUMN avatar_url VARCHAR(500) NULL COMMENT 'アバター画像のURL' AFTER nickname;
+
+-- 確認
+-- SELECT uid, birthyear, birthdate FROM users LIMIT 10;
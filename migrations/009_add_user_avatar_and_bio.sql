@@ -0,0 +1,9 @@
+-- アバター画像と自己紹介
+-- アバターは商品画像と同じくimagesテーブルで管理し、avatar_hashで参照されている間はGCで削除しない
+-- ※ 008_users_profile.sql の実行後に実行すること
+
+ALTER TABLE users
+ADD COLUMN avatar_thumbnail_url VARCHAR(500) NULL COMMENT 'アバターのサムネイルURL' AFTER avatar_url,
+ADD COLUMN avatar_hash CHAR(64) NULL COMMENT 'imagesテーブルのハッシュ' AFTER avatar_thumbnail_url,
+ADD COLUMN bio VARCHAR(2000) NULL COMMENT '自己紹介（最大500文字）' AFTER avatar_hash,
+ADD INDEX idx_avatar_hash (avatar_hash);
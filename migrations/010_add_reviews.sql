@@ -0,0 +1,16 @@
+-- 取引完了後の評価（購入者・出品者がそれぞれ相手を1回だけ評価できる）
+
+CREATE TABLE reviews (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    item_id INT NOT NULL COMMENT '取引した商品',
+    reviewer_uid VARCHAR(255) NOT NULL COMMENT '評価した人',
+    reviewee_uid VARCHAR(255) NOT NULL COMMENT '評価された人',
+    role ENUM('buyer', 'seller') NOT NULL COMMENT '評価した人の立場',
+    rating TINYINT NOT NULL COMMENT '1〜5',
+    comment TEXT COMMENT 'コメント',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_review (item_id, reviewer_uid),
+    INDEX idx_reviewee_uid (reviewee_uid, created_at),
+    CONSTRAINT chk_rating CHECK (rating BETWEEN 1 AND 5),
+    FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
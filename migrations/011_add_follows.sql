@@ -0,0 +1,13 @@
+-- ユーザー間のフォロー（フィードでフォロー中の出品者の新着出品を表示する）
+
+CREATE TABLE follows (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    follower_uid VARCHAR(255) NOT NULL COMMENT 'フォローした人',
+    followee_uid VARCHAR(255) NOT NULL COMMENT 'フォローされた人',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_follow (follower_uid, followee_uid),
+    INDEX idx_followee_uid (followee_uid)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- フィード用（フォロー中の出品者の出品中商品を新しい順に取得）
+ALTER TABLE items ADD INDEX idx_uid_status_created_at (uid, status, created_at);
@@ -0,0 +1,35 @@
+-- ユーザーのブロックと通報（モデレーションキュー）
+
+-- ブロック（ブロックされた人はブロックした人にメッセージを送れず、出品も表示されない）
+CREATE TABLE blocks (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    blocker_uid VARCHAR(255) NOT NULL COMMENT 'ブロックした人',
+    blocked_uid VARCHAR(255) NOT NULL COMMENT 'ブロックされた人',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_block (blocker_uid, blocked_uid),
+    INDEX idx_blocked_uid (blocked_uid)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 通報（商品・メッセージ・ユーザー）
+CREATE TABLE reports (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    reporter_uid VARCHAR(255) NOT NULL COMMENT '通報した人',
+    target_type ENUM('item', 'message', 'user') NOT NULL COMMENT '通報対象の種類',
+    target_id VARCHAR(255) NOT NULL COMMENT '商品ID・メッセージID・UID',
+    reason VARCHAR(50) NOT NULL COMMENT '通報理由',
+    detail TEXT COMMENT '詳細',
+    status ENUM('open', 'resolved', 'dismissed') NOT NULL DEFAULT 'open' COMMENT '対応状況',
+    action VARCHAR(50) COMMENT '実施した対応（hide_item, suspend_user）',
+    resolved_by VARCHAR(255) COMMENT '対応した管理者',
+    resolved_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_status_created_at (status, created_at),
+    INDEX idx_target (target_type, target_id),
+    INDEX idx_reporter_uid (reporter_uid)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 管理者による商品の非表示
+ALTER TABLE items ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT FALSE COMMENT '管理者により非表示' AFTER status;
+
+-- 管理者によるユーザーの利用停止
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP NULL COMMENT '利用停止日時（NULLなら利用可能）';
@@ -0,0 +1,37 @@
+-- 管理者API用のロール・監査ログ・オンチェーンイベント履歴
+
+-- ロール（user: 一般, moderator: 通報対応・利用停止, admin: すべての管理操作）
+-- 最初の管理者は次のように手動で設定する:
+--   UPDATE users SET role = 'admin' WHERE uid = '<uid>';
+ALTER TABLE users ADD COLUMN role ENUM('user', 'moderator', 'admin') NOT NULL DEFAULT 'user' COMMENT 'ロール';
+
+-- 監査ログ（誰が・何を・どう変えたか）
+CREATE TABLE audit_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    actor_uid VARCHAR(255) COMMENT '操作した人（システムの場合はNULL）',
+    source VARCHAR(30) NOT NULL COMMENT '操作元（admin, moderation など）',
+    action VARCHAR(50) NOT NULL COMMENT '操作（item.force_status, user.suspend など）',
+    entity_type VARCHAR(20) NOT NULL COMMENT '対象の種類（item, user, report）',
+    entity_id VARCHAR(255) NOT NULL COMMENT '対象のID',
+    before_value JSON COMMENT '変更前の値',
+    after_value JSON COMMENT '変更後の値',
+    reason TEXT COMMENT '理由',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_entity (entity_type, entity_id, created_at),
+    INDEX idx_actor_uid (actor_uid),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- onchainサービスから受け取ったイベントの履歴
+CREATE TABLE chain_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    event_type VARCHAR(30) NOT NULL COMMENT 'ItemListed, ItemPurchased, ReceiptConfirmed, ItemCancelled',
+    chain_item_id BIGINT NOT NULL COMMENT 'スマートコントラクト上の商品ID',
+    tx_hash VARCHAR(66) COMMENT 'トランザクションハッシュ',
+    payload JSON COMMENT '受け取ったイベントの内容',
+    status ENUM('processed', 'failed') NOT NULL COMMENT '処理結果',
+    error TEXT COMMENT '処理に失敗した場合のエラー',
+    received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_chain_item_id (chain_item_id, received_at),
+    INDEX idx_received_at (received_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
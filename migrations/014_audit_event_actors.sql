@@ -0,0 +1,9 @@
+-- 監査ログに操作した主体の種類とウォレットアドレスを追加
+-- actor_type: user（本人）, wallet（onchainイベントのウォレット）, webhook（ウォレット不明のonchainイベント）, admin（管理者API・通報対応）, system
+ALTER TABLE audit_events
+    ADD COLUMN actor_type VARCHAR(20) NOT NULL DEFAULT 'system' COMMENT '操作した主体の種類' AFTER id,
+    ADD COLUMN actor_wallet VARCHAR(42) COMMENT '操作したウォレットアドレス' AFTER actor_uid,
+    ADD INDEX idx_actor_wallet (actor_wallet);
+
+-- 035で記録した管理操作は管理者によるもの
+UPDATE audit_events SET actor_type = 'admin' WHERE source IN ('admin', 'moderation');
@@ -0,0 +1,6 @@
+-- 退会（アカウント削除）
+-- 退会したユーザーの行は取引相手の履歴やonchainの参照を保つため削除せず、個人情報だけを消去する
+-- deleted_atが入っているユーザーは利用停止中のユーザーと同様に操作できない
+
+ALTER TABLE users
+ADD COLUMN deleted_at TIMESTAMP NULL COMMENT '退会日時（NULLは利用中）' AFTER suspended_at;
@@ -0,0 +1,21 @@
+-- 配送先住所と発送情報
+-- 住所はアプリ側でAES-256-GCMで暗号化して保存する（鍵は環境変数 ADDRESS_ENCRYPTION_KEY）
+
+CREATE TABLE shipping_addresses (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    uid VARCHAR(255) NOT NULL COMMENT '登録したユーザー',
+    label VARCHAR(50) NOT NULL DEFAULT '' COMMENT '表示名（自宅、職場など）',
+    encrypted_address VARBINARY(4096) NOT NULL COMMENT '住所（暗号化したJSON）',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE COMMENT '購入時にはじめに選択する住所',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_uid (uid)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 購入ごとの配送先と発送情報
+-- 配送先は購入時点の住所のコピー（購入者が後で住所を編集・削除しても変わらない）
+ALTER TABLE purchases
+ADD COLUMN shipping_address_encrypted VARBINARY(4096) NULL COMMENT '配送先（暗号化したJSON）',
+ADD COLUMN carrier VARCHAR(50) NULL COMMENT '配送業者',
+ADD COLUMN tracking_number VARCHAR(100) NULL COMMENT '追跡番号',
+ADD COLUMN shipped_at TIMESTAMP NULL COMMENT '発送日時';
@@ -0,0 +1,48 @@
+-- 注文（cash購入とonchain購入を同じモデルで扱う）
+-- status: created → paid → shipped → delivered → completed（途中で cancelled / refunded）
+-- purchasesテーブルは購入履歴・配送先のために引き続き書き込み、orders.purchase_idで紐づける
+
+CREATE TABLE orders (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    item_id INT NOT NULL COMMENT '商品',
+    purchase_id INT NULL COMMENT 'purchasesの行',
+    seller_uid VARCHAR(255) NOT NULL COMMENT '出品者',
+    buyer_uid VARCHAR(255) NULL COMMENT '購入者（onchain購入でウォレット未登録の場合はNULL）',
+    buyer_address VARCHAR(42) NULL COMMENT '購入者のウォレットアドレス（onchain購入）',
+    payment_method ENUM('cash', 'onchain') NOT NULL COMMENT '支払い方法',
+    status ENUM('created', 'paid', 'shipped', 'delivered', 'completed', 'cancelled', 'refunded') NOT NULL DEFAULT 'created' COMMENT '注文の状態',
+    amount INT NOT NULL COMMENT '金額（円）',
+    amount_wei VARCHAR(78) NULL COMMENT 'onchainで支払われた金額（Wei）',
+    tx_hash VARCHAR(66) NULL COMMENT '購入トランザクションのハッシュ（onchain購入）',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    paid_at TIMESTAMP NULL,
+    shipped_at TIMESTAMP NULL,
+    delivered_at TIMESTAMP NULL,
+    completed_at TIMESTAMP NULL,
+    cancelled_at TIMESTAMP NULL,
+    refunded_at TIMESTAMP NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_tx_hash (tx_hash),
+    INDEX idx_item_id (item_id),
+    INDEX idx_buyer_uid (buyer_uid, created_at),
+    INDEX idx_seller_uid (seller_uid, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 既存の購入記録から注文を作成する（商品ごとに最新の購入記録のみ）
+INSERT INTO orders (item_id, purchase_id, seller_uid, buyer_uid, buyer_address, payment_method, status, amount,
+                    created_at, paid_at, shipped_at, completed_at, cancelled_at)
+SELECT
+    i.id, p.id, i.uid, NULLIF(p.buyer_uid, ''), NULLIF(p.buyer_address, ''),
+    IF(COALESCE(p.buyer_address, '') = '', 'cash', 'onchain'),
+    CASE
+        WHEN i.status = 'completed' THEN 'completed'
+        WHEN i.status = 'cancelled' THEN 'cancelled'
+        WHEN p.shipped_at IS NOT NULL THEN 'shipped'
+        ELSE 'paid'
+    END,
+    i.price, p.purchased_at, p.purchased_at, p.shipped_at,
+    IF(i.status = 'completed', i.updated_at, NULL),
+    IF(i.status = 'cancelled', i.updated_at, NULL)
+FROM purchases p
+JOIN items i ON i.id = p.item_id
+WHERE p.id = (SELECT MAX(id) FROM purchases WHERE item_id = p.item_id);
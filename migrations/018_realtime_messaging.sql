@@ -0,0 +1,14 @@
+-- リアルタイムメッセージ（WebSocket）
+-- 受信者の端末に届いた日時（配信確認）
+ALTER TABLE messages
+ADD COLUMN delivered_at TIMESTAMP NULL COMMENT '受信者の端末に届いた日時' AFTER is_read;
+
+-- 複数インスタンスでイベントを共有するためのテーブル（REALTIME_BACKEND=mysql の場合のみ使用）
+-- 各インスタンスが新しい行をポーリングして自分に接続しているユーザーへ配信する。古い行は定期的に削除する
+CREATE TABLE realtime_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    origin VARCHAR(32) NOT NULL COMMENT '発行したインスタンス',
+    payload JSON NOT NULL COMMENT '配信先のuidとイベント',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,5 @@
+-- 商品ごとのメッセージスレッド
+-- item_idがNULLのメッセージは相手との通常のスレッド、値があればその商品についてのスレッドとして扱う
+ALTER TABLE messages
+ADD COLUMN item_id INT NULL COMMENT 'どの商品についてのメッセージか（NULLなら商品に紐づかない）' AFTER receiver_uid,
+ADD INDEX idx_item_id (item_id);
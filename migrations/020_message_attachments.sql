@@ -0,0 +1,16 @@
+-- メッセージの添付画像
+-- 画像は商品画像と同じく検証・加工し、ストレージの private/<hash>_<thumb|medium|full>.<ext> に保存する（署名付きURLでのみ配信）
+-- 送信前（message_idがNULL）のまま猶予期間が過ぎた行は画像GCで削除され、同じハッシュの行がなくなればファイルも削除される
+CREATE TABLE message_attachments (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    message_id INT NULL COMMENT '添付したメッセージ（送信前はNULL）',
+    uploader_uid VARCHAR(255) NOT NULL COMMENT 'アップロードしたユーザー',
+    image_hash CHAR(64) NOT NULL COMMENT '元画像のSHA-256（16進数）',
+    ext VARCHAR(10) NOT NULL COMMENT '保存時の拡張子（jpg/png）',
+    size_bytes INT NOT NULL COMMENT '元画像のバイト数',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_message_id (message_id),
+    INDEX idx_image_hash (image_hash),
+    INDEX idx_created_at (created_at),
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
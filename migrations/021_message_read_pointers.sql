@@ -0,0 +1,22 @@
+-- メッセージの既読をスレッドごとの「どこまで読んだか」で管理する
+-- これまではメッセージごとに is_read を更新していたが、既読にするたびに未読の行をすべて更新する必要があった
+-- message_id <= last_read_message_id の相手からのメッセージを既読として扱う（messages.is_read は今後更新しない）
+CREATE TABLE message_read_pointers (
+    reader_uid VARCHAR(255) NOT NULL COMMENT '読んだユーザー',
+    partner_uid VARCHAR(255) NOT NULL COMMENT 'スレッドの相手',
+    thread_item_id INT NOT NULL DEFAULT 0 COMMENT 'スレッドの商品ID（商品に紐づかないスレッドは0）',
+    last_read_message_id INT NOT NULL DEFAULT 0 COMMENT '既読にした最後のメッセージ',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (reader_uid, partner_uid, thread_item_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 既存の既読状態を移す（スレッドで最後に既読になったメッセージまでを既読とする）
+INSERT INTO message_read_pointers (reader_uid, partner_uid, thread_item_id, last_read_message_id)
+SELECT receiver_uid, sender_uid, COALESCE(item_id, 0), MAX(id)
+FROM messages
+WHERE is_read = true
+GROUP BY receiver_uid, sender_uid, COALESCE(item_id, 0);
+
+-- スレッドの履歴のページングと未読件数の集計用
+ALTER TABLE messages
+ADD INDEX idx_thread (receiver_uid, sender_uid, item_id, id);
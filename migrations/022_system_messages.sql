@@ -0,0 +1,6 @@
+-- 取引の節目（購入・発送・受け取り・キャンセルなど）を知らせるシステムメッセージ
+-- type が 'system' のメッセージはユーザーではなくサービスが書き込んだもので、system_event に節目の種類が入る
+-- sender_uid はきっかけになった側（購入なら購入者、発送なら出品者）、receiver_uid は知らせる相手
+ALTER TABLE messages
+ADD COLUMN type VARCHAR(16) NOT NULL DEFAULT 'user' COMMENT 'user: ユーザーのメッセージ、system: システムメッセージ' AFTER item_id,
+ADD COLUMN system_event VARCHAR(32) NULL COMMENT 'システムメッセージの種類（purchased / shipped など）' AFTER type;
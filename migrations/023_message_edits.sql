@@ -0,0 +1,16 @@
+-- メッセージの編集と削除
+-- 送信者は送信から一定時間（usecase/messages の messageEditWindow）だけ編集・削除できる
+-- 削除は論理削除で、当事者には本文と添付画像を返さないが、通報の確認のためモデレーターは元の本文を見られる
+ALTER TABLE messages
+ADD COLUMN edited_at TIMESTAMP NULL COMMENT '最後に編集した日時（編集していなければNULL）' AFTER delivered_at,
+ADD COLUMN deleted_at TIMESTAMP NULL COMMENT '送信者が削除した日時（論理削除）' AFTER edited_at;
+
+-- 編集前の本文の履歴
+CREATE TABLE message_edits (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    message_id INT NOT NULL,
+    previous_content TEXT NOT NULL COMMENT '編集前の本文',
+    edited_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_message_id (message_id),
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
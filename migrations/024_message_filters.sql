@@ -0,0 +1,9 @@
+-- メッセージのコンテンツフィルタ
+-- quarantined_at が入っているメッセージはフィルタで保留されたもので、モデレーターが通報を却下するまで受信者には見せない
+-- （送信者には通常どおり表示する）
+ALTER TABLE messages
+ADD COLUMN quarantined_at TIMESTAMP NULL COMMENT 'フィルタで保留した日時（NULLなら受信者に表示する）' AFTER deleted_at;
+
+-- 送信者ごとの送信数の制限（直近の送信数を数える）
+ALTER TABLE messages
+ADD INDEX idx_sender_created_at (sender_uid, created_at);
@@ -0,0 +1,25 @@
+-- アプリ内の通知（いいね・購入・受け取り確認・メッセージ）
+-- group_key は同じ出来事の通知を重複させないためのキー（Webhookの再送など）
+-- メッセージの通知は相手とスレッドごとに1件にまとめ、新しいメッセージが届くと未読に戻して更新する
+CREATE TABLE notifications (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    uid VARCHAR(255) NOT NULL COMMENT '通知する相手',
+    type VARCHAR(32) NOT NULL COMMENT 'like / purchase / receipt_confirmed / message',
+    actor_uid VARCHAR(255) NULL COMMENT 'きっかけになったユーザー（未登録のウォレットならNULL）',
+    item_id INT NULL COMMENT '関係する商品',
+    group_key VARCHAR(255) NOT NULL COMMENT '重複を防ぐキー',
+    read_at TIMESTAMP NULL COMMENT '既読にした日時',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_group (uid, group_key),
+    INDEX idx_uid_created_at (uid, created_at),
+    INDEX idx_uid_read_at (uid, read_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 通知の種類ごとの受け取り設定（行がなければ受け取る）
+CREATE TABLE notification_preferences (
+    uid VARCHAR(255) NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (uid, type)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,12 @@
+-- 重要な通知（商品が売れた・受け取りが確認された・オフライン中のメッセージ）のメール配信
+-- emailed_at はメールを送った日時（メッセージの通知は一定時間メールを送らないようにするのに使う）
+ALTER TABLE notifications ADD COLUMN emailed_at TIMESTAMP NULL COMMENT 'メールを送った日時' AFTER read_at;
+
+-- メールの送信先と言語（行がなければメールを送らない）
+CREATE TABLE email_settings (
+    uid VARCHAR(255) NOT NULL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    locale VARCHAR(8) NOT NULL DEFAULT 'ja' COMMENT 'ja / en',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
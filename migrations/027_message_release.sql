@@ -0,0 +1,8 @@
+-- フィルタで保留したメッセージの公開
+-- 公開したメッセージは既読ポインタ（message_read_pointers.last_read_message_id）より古いことがあるので、
+-- released_at が read_at（最後に既読にした日時）より後なら未読として数える
+ALTER TABLE messages
+ADD COLUMN released_at TIMESTAMP(6) NULL COMMENT 'モデレーターが保留を解除して受信者に見せた日時' AFTER quarantined_at;
+
+ALTER TABLE message_read_pointers
+ADD COLUMN read_at TIMESTAMP(6) NULL COMMENT '最後に既読にした日時' AFTER last_read_message_id;
@@ -0,0 +1,10 @@
+-- 複数インスタンスでユーザーの接続状態を共有するためのテーブル（REALTIME_BACKEND=mysql の場合のみ使用）
+-- 各インスタンスが自分に接続しているuidを記録し、定期的にseen_atを更新する。更新が止まった行は接続していないとみなして削除する
+CREATE TABLE realtime_presence (
+    origin VARCHAR(32) NOT NULL COMMENT '接続しているインスタンス',
+    uid VARCHAR(255) NOT NULL,
+    seen_at TIMESTAMP(6) NOT NULL COMMENT '最後に接続を確認した日時',
+    PRIMARY KEY (origin, uid),
+    INDEX idx_uid_seen_at (uid, seen_at),
+    INDEX idx_seen_at (seen_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
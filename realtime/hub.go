@@ -0,0 +1,275 @@
+package realtime
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait は1回の書き込みの期限
+	writeWait = 10 * time.Second
+	// pongWait はpongを待つ期限（これを過ぎたら切断）
+	pongWait = 60 * time.Second
+	// pingPeriod はpingを送る間隔（pongWaitより短くする）
+	pingPeriod = pongWait * 9 / 10
+	// maxClientMessageSize はクライアントから受け取るメッセージの最大サイズ
+	maxClientMessageSize = 4096
+	// sendBufferSize は送信待ちのイベント数の上限（溢れたクライアントは切断する）
+	sendBufferSize = 64
+)
+
+// ClientMessage はクライアントから送られてくるメッセージ {"type": "...", "data": {...}}
+type ClientMessage struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// outgoing はクライアントへ送るメッセージ（配信先のuidは含めない）
+type outgoing struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Hub はWebSocketで接続しているユーザーを管理し、Backendから受け取ったイベントを配信する
+type Hub struct {
+	backend  Backend
+	upgrader websocket.Upgrader
+
+	mu      sync.RWMutex
+	clients map[string]map[*client]struct{}
+
+	// presenceMu は接続状態をBackendに記録する順番を揃える（接続と切断が前後して古い状態が残らないように）
+	presenceMu sync.Mutex
+}
+
+type client struct {
+	uid  string
+	conn *websocket.Conn
+	send chan []byte
+	once sync.Once
+}
+
+func (c *client) close() {
+	c.once.Do(func() { close(c.send) })
+}
+
+// NewHub はbackendのイベントを配信するHubを生成する
+// checkOriginはブラウザからの接続を許可するOriginか（Originヘッダーがない場合は空文字列）
+func NewHub(backend Backend, checkOrigin func(origin string) bool) *Hub {
+	h := &Hub{
+		backend: backend,
+		clients: make(map[string]map[*client]struct{}),
+	}
+	h.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin: func(r *http.Request) bool {
+			return checkOrigin(r.Header.Get("Origin"))
+		},
+	}
+	backend.Subscribe(h.deliver)
+	return h
+}
+
+// Publish はuidsに接続しているクライアントへイベントを送る
+// リアルタイム配信は補助的なものなので、失敗してもログに残すだけにする
+func (h *Hub) Publish(uids []string, eventType string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("WARNING: failed to encode %s event: %v", eventType, err)
+		return
+	}
+	if err := h.backend.Publish(Event{UIDs: uids, Type: eventType, Data: payload}); err != nil {
+		log.Printf("WARNING: failed to publish %s event: %v", eventType, err)
+	}
+}
+
+// deliver はこのインスタンスに接続している配信先のクライアントへイベントを送る
+func (h *Hub) deliver(event Event) {
+	msg, err := json.Marshal(outgoing{Type: event.Type, Data: event.Data})
+	if err != nil {
+		log.Printf("WARNING: failed to encode %s event: %v", event.Type, err)
+		return
+	}
+
+	h.mu.RLock()
+	var slow []*client
+	seen := make(map[string]bool, len(event.UIDs))
+	for _, uid := range event.UIDs {
+		if seen[uid] {
+			continue
+		}
+		seen[uid] = true
+		for c := range h.clients[uid] {
+			select {
+			case c.send <- msg:
+			default:
+				slow = append(slow, c)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	// 送信が追いつかないクライアントは切断する（再接続時にAPIで取り直してもらう）
+	// sendへの書き込みは読み取りロック中に行うので、閉じるのは書き込みロックを取ってから
+	// 閉じたsendに次のイベントを書き込まないように、閉じる前にclientsから外す
+	if len(slow) > 0 {
+		h.mu.Lock()
+		for _, c := range slow {
+			h.removeLocked(c)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// Serve はリクエストをWebSocketにアップグレードし、切断されるまでuid宛てのイベントを送る
+// クライアントから受け取ったメッセージはonMessageに渡す（nilなら読み捨てる）
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request, uid string, onMessage func(ClientMessage)) error {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgradeがエラーレスポンスを返している
+		return err
+	}
+
+	c := &client{uid: uid, conn: conn, send: make(chan []byte, sendBufferSize)}
+	h.register(c)
+	defer h.unregister(c)
+
+	go c.writePump()
+	c.readPump(onMessage)
+	return nil
+}
+
+// Close はすべての接続を切断し、Backendを停止する
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	for _, clients := range h.clients {
+		for c := range clients {
+			h.removeLocked(c)
+		}
+	}
+	h.mu.Unlock()
+	return h.backend.Close()
+}
+
+func (h *Hub) register(c *client) {
+	h.mu.Lock()
+	if h.clients[c.uid] == nil {
+		h.clients[c.uid] = make(map[*client]struct{})
+	}
+	h.clients[c.uid][c] = struct{}{}
+	h.mu.Unlock()
+	h.syncPresence(c.uid)
+}
+
+// unregister は切断したクライアントを外す（送信が追いつかずに外したクライアントでも呼んでよい）
+func (h *Hub) unregister(c *client) {
+	h.mu.Lock()
+	h.removeLocked(c)
+	h.mu.Unlock()
+	h.syncPresence(c.uid)
+}
+
+// syncPresence はuidの今の接続状態をBackendに記録する（失敗してもログに残すだけにする）
+// 記録する状態はpresenceMuを取ってから読むので、最後に記録されるのは最新の状態になる
+func (h *Hub) syncPresence(uid string) {
+	h.presenceMu.Lock()
+	defer h.presenceMu.Unlock()
+	if err := h.backend.SetOnline(uid, h.connections(uid) > 0); err != nil {
+		log.Printf("WARNING: failed to record presence for uid=%s: %v", uid, err)
+	}
+}
+
+// removeLocked はクライアントをclientsから外してsendを閉じる（書き込みロックを取って呼ぶ。何度呼んでもよい）
+func (h *Hub) removeLocked(c *client) {
+	if clients, ok := h.clients[c.uid]; ok {
+		delete(clients, c)
+		if len(clients) == 0 {
+			delete(h.clients, c.uid)
+		}
+	}
+	c.close()
+}
+
+// IsOnline はuidがいずれかのインスタンスに接続しているか
+// このインスタンスへの接続がなければBackendに問い合わせる（memoryなら他のインスタンスはない）
+// 問い合わせに失敗した場合は接続していないとみなす（メールの通知を送る側に倒す）
+func (h *Hub) IsOnline(uid string) bool {
+	if h.connections(uid) > 0 {
+		return true
+	}
+	online, err := h.backend.IsOnline(uid)
+	if err != nil {
+		log.Printf("WARNING: failed to check presence for uid=%s: %v", uid, err)
+		return false
+	}
+	return online
+}
+
+// connections はuidの接続数
+func (h *Hub) connections(uid string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[uid])
+}
+
+// readPump はクライアントからのメッセージを読み続ける（切断・エラーで終了）
+func (c *client) readPump(onMessage func(ClientMessage)) {
+	defer c.conn.Close()
+	c.conn.SetReadLimit(maxClientMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("websocket read error: %v", err)
+			}
+			return
+		}
+		if onMessage == nil {
+			continue
+		}
+		var msg ClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type == "" {
+			continue
+		}
+		onMessage(msg)
+	}
+}
+
+// writePump はsendのイベントとpingを書き込む（sendが閉じられたら接続を閉じる）
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
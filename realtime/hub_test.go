@@ -0,0 +1,288 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func allowAll(string) bool { return true }
+
+// newTestServer はクエリのuidでhubに接続するテスト用サーバー
+func newTestServer(t *testing.T, hub *Hub, onMessage func(uid string, msg ClientMessage)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uid := r.URL.Query().Get("uid")
+		hub.Serve(w, r, uid, func(msg ClientMessage) {
+			if onMessage != nil {
+				onMessage(uid, msg)
+			}
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// dial はuidとして接続し、hubに登録されるまで待つ
+func dial(t *testing.T, srv *httptest.Server, hub *Hub, uid string) *websocket.Conn {
+	t.Helper()
+	before := hub.connections(uid)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/?uid=" + uid
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	waitFor(t, func() bool { return hub.connections(uid) > before })
+	return conn
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func readEvent(t *testing.T, conn *websocket.Conn) outgoing {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg outgoing
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("failed to read event: %v", err)
+	}
+	return msg
+}
+
+// expectNoEvent は短い時間内にイベントが届かないことを確認する
+func expectNoEvent(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	var msg outgoing
+	if err := conn.ReadJSON(&msg); err == nil {
+		t.Fatalf("expected no event, got %s %s", msg.Type, msg.Data)
+	}
+}
+
+// TestHub_PublishToRecipients 配信先のユーザーのすべての接続にだけ届く
+func TestHub_PublishToRecipients(t *testing.T) {
+	hub := NewHub(NewMemoryBackend(), allowAll)
+	srv := newTestServer(t, hub, nil)
+
+	alice1 := dial(t, srv, hub, "alice")
+	alice2 := dial(t, srv, hub, "alice")
+	bob := dial(t, srv, hub, "bob")
+	carol := dial(t, srv, hub, "carol")
+
+	hub.Publish([]string{"alice", "bob", "alice"}, "message.created", map[string]string{"content": "hello"})
+
+	for _, conn := range []*websocket.Conn{alice1, alice2, bob} {
+		msg := readEvent(t, conn)
+		if msg.Type != "message.created" || string(msg.Data) != `{"content":"hello"}` {
+			t.Errorf("unexpected event: %s %s", msg.Type, msg.Data)
+		}
+	}
+	// 重複したuidにも1回だけ届く
+	expectNoEvent(t, alice1)
+	expectNoEvent(t, carol)
+}
+
+// TestHub_ClientMessages クライアントから送ったメッセージが接続したuidと一緒に渡される
+func TestHub_ClientMessages(t *testing.T) {
+	hub := NewHub(NewMemoryBackend(), allowAll)
+	received := make(chan string, 1)
+	srv := newTestServer(t, hub, func(uid string, msg ClientMessage) {
+		var data struct {
+			MessageID int `json:"message_id"`
+		}
+		json.Unmarshal(msg.Data, &data)
+		received <- fmt.Sprintf("%s:%s:%d", uid, msg.Type, data.MessageID)
+	})
+
+	conn := dial(t, srv, hub, "alice")
+	conn.WriteMessage(websocket.TextMessage, []byte("not json"))
+	if err := conn.WriteJSON(map[string]interface{}{"type": "delivered", "data": map[string]int{"message_id": 7}}); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	select {
+	case got := <-received:
+		if got != "alice:delivered:7" {
+			t.Errorf("unexpected client message: %s", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("client message was not received")
+	}
+}
+
+// TestHub_Unregister 切断した接続は登録から外れる
+func TestHub_Unregister(t *testing.T) {
+	hub := NewHub(NewMemoryBackend(), allowAll)
+	srv := newTestServer(t, hub, nil)
+
+	conn := dial(t, srv, hub, "alice")
+	conn.Close()
+	waitFor(t, func() bool { return hub.connections("alice") == 0 })
+
+	// 接続がなくても配信でエラーにならない
+	hub.Publish([]string{"alice"}, "unread_count", map[string]int{"total_unread": 1})
+}
+
+// TestHub_SlowClient 送信が追いつかずに切断したクライアントには、次のイベントを送らない（閉じたsendに書き込まない）
+func TestHub_SlowClient(t *testing.T) {
+	hub := NewHub(NewMemoryBackend(), allowAll)
+	// writePumpを動かさず、sendが溢れる状態を作る
+	slow := &client{uid: "alice", send: make(chan []byte, 1)}
+	other := &client{uid: "alice", send: make(chan []byte, sendBufferSize)}
+	hub.register(slow)
+	hub.register(other)
+
+	hub.Publish([]string{"alice"}, "unread_count", map[string]int{"total_unread": 1})
+	hub.Publish([]string{"alice"}, "unread_count", map[string]int{"total_unread": 2})
+	if got := hub.connections("alice"); got != 1 {
+		t.Fatalf("expected slow client to be dropped, got %d connections", got)
+	}
+
+	// 切断済みのクライアントへの次のイベントでpanicしない
+	hub.Publish([]string{"alice"}, "unread_count", map[string]int{"total_unread": 3})
+	// Serveの終了時のunregisterは、既に外したクライアントでも他の接続を外さない
+	hub.unregister(slow)
+	if got := hub.connections("alice"); got != 1 {
+		t.Errorf("expected the other client to stay connected, got %d connections", got)
+	}
+	if got := len(other.send); got != 3 {
+		t.Errorf("expected the other client to receive 3 events, got %d", got)
+	}
+
+	hub.Close()
+	if got := hub.connections("alice"); got != 0 {
+		t.Errorf("expected no connections after Close, got %d", got)
+	}
+	hub.unregister(other)
+}
+
+// TestHub_SharedBackend 同じBackendを共有するHub（別インスタンス）に接続したユーザーにも届く
+func TestHub_SharedBackend(t *testing.T) {
+	backend := NewMemoryBackend()
+	hubA := NewHub(backend, allowAll)
+	hubB := NewHub(backend, allowAll)
+	srvA := newTestServer(t, hubA, nil)
+	srvB := newTestServer(t, hubB, nil)
+
+	alice := dial(t, srvA, hubA, "alice")
+	bob := dial(t, srvB, hubB, "bob")
+
+	hubA.Publish([]string{"alice", "bob"}, "messages.read", map[string]string{"reader_uid": "alice"})
+
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		if msg := readEvent(t, conn); msg.Type != "messages.read" {
+			t.Errorf("unexpected event: %s", msg.Type)
+		}
+	}
+}
+
+// sharedPresence はテスト用のインスタンス間で共有する接続状態（インスタンス -> 接続しているuid）
+type sharedPresence struct {
+	mu     sync.Mutex
+	online map[string]map[string]bool
+}
+
+// presenceBackend はsharedPresenceに接続状態を記録するインスタンスごとのBackend
+type presenceBackend struct {
+	*MemoryBackend
+	origin string
+	shared *sharedPresence
+}
+
+func (b *presenceBackend) SetOnline(uid string, online bool) error {
+	b.shared.mu.Lock()
+	defer b.shared.mu.Unlock()
+	if b.shared.online[b.origin] == nil {
+		b.shared.online[b.origin] = make(map[string]bool)
+	}
+	b.shared.online[b.origin][uid] = online
+	return nil
+}
+
+func (b *presenceBackend) IsOnline(uid string) (bool, error) {
+	b.shared.mu.Lock()
+	defer b.shared.mu.Unlock()
+	for _, uids := range b.shared.online {
+		if uids[uid] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// failingPresenceBackend は接続状態を問い合わせられないBackend
+type failingPresenceBackend struct {
+	*MemoryBackend
+}
+
+func (b *failingPresenceBackend) IsOnline(uid string) (bool, error) {
+	return false, errors.New("database is unavailable")
+}
+
+// TestHub_IsOnline 別のインスタンスへの接続もBackendで共有し、すべて切断したら接続していないとみなす
+func TestHub_IsOnline(t *testing.T) {
+	events := NewMemoryBackend()
+	shared := &sharedPresence{online: make(map[string]map[string]bool)}
+	hubA := NewHub(&presenceBackend{MemoryBackend: events, origin: "a", shared: shared}, allowAll)
+	hubB := NewHub(&presenceBackend{MemoryBackend: events, origin: "b", shared: shared}, allowAll)
+	srvA := newTestServer(t, hubA, nil)
+
+	first := dial(t, srvA, hubA, "alice")
+	second := dial(t, srvA, hubA, "alice")
+	if !hubA.IsOnline("alice") || !hubB.IsOnline("alice") {
+		t.Fatal("expected alice to be online on both instances")
+	}
+	if hubB.IsOnline("bob") {
+		t.Error("expected bob to be offline")
+	}
+
+	// 接続が残っている間は接続中のまま
+	first.Close()
+	waitFor(t, func() bool { return hubA.connections("alice") == 1 })
+	if !hubB.IsOnline("alice") {
+		t.Error("expected alice to stay online while another connection remains")
+	}
+	second.Close()
+	waitFor(t, func() bool { return !hubB.IsOnline("alice") })
+
+	// 問い合わせに失敗したら接続していないとみなす
+	failing := NewHub(&failingPresenceBackend{MemoryBackend: NewMemoryBackend()}, allowAll)
+	if failing.IsOnline("alice") {
+		t.Error("expected alice to be offline when presence is unavailable")
+	}
+}
+
+// TestHub_CheckOrigin 許可していないOriginからの接続は拒否する
+func TestHub_CheckOrigin(t *testing.T) {
+	hub := NewHub(NewMemoryBackend(), func(origin string) bool { return origin == "https://allowed.example" })
+	srv := newTestServer(t, hub, nil)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/?uid=alice"
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://evil.example"}})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %v %v", resp, err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://allowed.example"}})
+	if err != nil {
+		t.Fatalf("expected allowed origin to connect, got %v", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,43 @@
+package realtime
+
+import "sync"
+
+// MemoryBackend は同じプロセス内でのみイベントを配信するBackend（1インスタンス用・テスト用）
+type MemoryBackend struct {
+	mu       sync.RWMutex
+	handlers []func(Event)
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{}
+}
+
+func (b *MemoryBackend) Publish(event Event) error {
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(event)
+	}
+	return nil
+}
+
+func (b *MemoryBackend) Subscribe(handler func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// SetOnline は何もしない（同じプロセスのHubは自分の接続で判断する）
+func (b *MemoryBackend) SetOnline(uid string, online bool) error {
+	return nil
+}
+
+// IsOnline は常にfalse（他のインスタンスはない）
+func (b *MemoryBackend) IsOnline(uid string) (bool, error) {
+	return false, nil
+}
+
+func (b *MemoryBackend) Close() error {
+	return nil
+}
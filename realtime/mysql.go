@@ -0,0 +1,278 @@
+package realtime
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// pollBatchSize は1回のポーリングで読む最大件数
+	pollBatchSize = 500
+	// eventRetention はrealtime_eventsに残しておく期間（停止していたインスタンスはこれより古いイベントを受け取らない）
+	eventRetention = 10 * time.Minute
+	// pruneInterval は古いイベントを削除する間隔
+	pruneInterval = time.Minute
+	// gapWait は欠番を待つ時間。採番された後にコミットが遅れた行は、自分より大きいidの行より後に見えることがある
+	// この間は欠番も読み直し、過ぎたらロールバックなどで使われなかったidとみなす
+	gapWait = 10 * time.Second
+	// maxGaps は待っている欠番の上限（溢れた欠番は待たない）
+	maxGaps = 1000
+	// presenceHeartbeat はこのインスタンスの接続状態の記録を更新する間隔
+	presenceHeartbeat = 30 * time.Second
+	// presenceTTL は更新されていない接続状態を無効とみなすまでの時間（停止したインスタンスの記録を数えない）
+	presenceTTL = 3 * presenceHeartbeat
+)
+
+// MySQLBackend はrealtime_eventsテーブルを介して複数インスタンスでイベントを共有するBackend
+// 自分が発行したイベントはすぐにローカルのハンドラへ届け、他のインスタンスのイベントはポーリングで受け取る
+// 接続状態はrealtime_presenceテーブルにインスタンスごとに記録し、定期的に更新する
+type MySQLBackend struct {
+	db       *sql.DB
+	origin   string
+	interval time.Duration
+
+	mu       sync.RWMutex
+	handlers []func(Event)
+
+	lastID int64
+	gaps   *gapTracker
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewMySQLBackend は起動時点の最新のイベント以降をポーリングするBackendを生成する
+func NewMySQLBackend(db *sql.DB, interval time.Duration) (*MySQLBackend, error) {
+	origin, err := newOrigin()
+	if err != nil {
+		return nil, err
+	}
+	b := &MySQLBackend{
+		db:       db,
+		origin:   origin,
+		interval: interval,
+		gaps:     newGapTracker(gapWait, maxGaps),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if err := db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM realtime_events").Scan(&b.lastID); err != nil {
+		return nil, fmt.Errorf("failed to get latest realtime event: %w", err)
+	}
+	go b.run()
+	return b, nil
+}
+
+func (b *MySQLBackend) Publish(event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode realtime event: %w", err)
+	}
+	if _, err := b.db.Exec("INSERT INTO realtime_events (origin, payload) VALUES (?, ?)", b.origin, payload); err != nil {
+		return fmt.Errorf("failed to insert realtime event: %w", err)
+	}
+	b.dispatch(event)
+	return nil
+}
+
+func (b *MySQLBackend) Subscribe(handler func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *MySQLBackend) SetOnline(uid string, online bool) error {
+	if !online {
+		if _, err := b.db.Exec("DELETE FROM realtime_presence WHERE origin = ? AND uid = ?", b.origin, uid); err != nil {
+			return fmt.Errorf("failed to delete presence: %w", err)
+		}
+		return nil
+	}
+	_, err := b.db.Exec(`
+		INSERT INTO realtime_presence (origin, uid, seen_at) VALUES (?, ?, CURRENT_TIMESTAMP(6))
+		ON DUPLICATE KEY UPDATE seen_at = VALUES(seen_at)
+	`, b.origin, uid)
+	if err != nil {
+		return fmt.Errorf("failed to save presence: %w", err)
+	}
+	return nil
+}
+
+func (b *MySQLBackend) IsOnline(uid string) (bool, error) {
+	var online bool
+	err := b.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM realtime_presence
+			WHERE uid = ? AND seen_at > CURRENT_TIMESTAMP(6) - INTERVAL ? SECOND
+		)
+	`, uid, int64(presenceTTL.Seconds())).Scan(&online)
+	if err != nil {
+		return false, fmt.Errorf("failed to get presence: %w", err)
+	}
+	return online, nil
+}
+
+// Close はポーリングを止め、このインスタンスの接続状態の記録を消す
+func (b *MySQLBackend) Close() error {
+	close(b.stop)
+	<-b.done
+	if _, err := b.db.Exec("DELETE FROM realtime_presence WHERE origin = ?", b.origin); err != nil {
+		return fmt.Errorf("failed to delete presence: %w", err)
+	}
+	return nil
+}
+
+func (b *MySQLBackend) dispatch(event Event) {
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+func (b *MySQLBackend) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	lastPrune := time.Now()
+	lastHeartbeat := time.Now()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+		}
+		if err := b.poll(); err != nil {
+			log.Printf("WARNING: failed to poll realtime events: %v", err)
+		}
+		if time.Since(lastHeartbeat) >= presenceHeartbeat {
+			lastHeartbeat = time.Now()
+			if _, err := b.db.Exec("UPDATE realtime_presence SET seen_at = CURRENT_TIMESTAMP(6) WHERE origin = ?", b.origin); err != nil {
+				log.Printf("WARNING: failed to refresh presence: %v", err)
+			}
+		}
+		if time.Since(lastPrune) >= pruneInterval {
+			lastPrune = time.Now()
+			if _, err := b.db.Exec("DELETE FROM realtime_events WHERE created_at < ? LIMIT 1000", time.Now().Add(-eventRetention)); err != nil {
+				log.Printf("WARNING: failed to prune realtime events: %v", err)
+			}
+			// 停止したインスタンスの記録を消す
+			_, err := b.db.Exec(
+				"DELETE FROM realtime_presence WHERE seen_at < CURRENT_TIMESTAMP(6) - INTERVAL ? SECOND LIMIT 1000",
+				int64(presenceTTL.Seconds()),
+			)
+			if err != nil {
+				log.Printf("WARNING: failed to prune presence: %v", err)
+			}
+		}
+	}
+}
+
+// poll はlastIDより新しいイベントと、待っている欠番のイベントのうち、他のインスタンスが発行したものを配信する
+func (b *MySQLBackend) poll() error {
+	query := "SELECT id, origin, payload FROM realtime_events WHERE id > ?"
+	args := []interface{}{b.lastID}
+	if gaps := b.gaps.pending(time.Now()); len(gaps) > 0 {
+		query += " OR id IN (?" + strings.Repeat(", ?", len(gaps)-1) + ")"
+		for _, id := range gaps {
+			args = append(args, id)
+		}
+	}
+	query += " ORDER BY id LIMIT ?"
+	args = append(args, pollBatchSize)
+
+	rows, err := b.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var id int64
+		var origin string
+		var payload []byte
+		if err := rows.Scan(&id, &origin, &payload); err != nil {
+			return err
+		}
+		if id > b.lastID {
+			b.gaps.skip(b.lastID, id, time.Now())
+			b.lastID = id
+		} else if !b.gaps.fill(id) {
+			continue
+		}
+		if origin == b.origin {
+			continue
+		}
+		var event Event
+		if err := json.Unmarshal(payload, &event); err != nil {
+			log.Printf("WARNING: skipping malformed realtime event id=%d: %v", id, err)
+			continue
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, event := range events {
+		b.dispatch(event)
+	}
+	return nil
+}
+
+func newOrigin() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate instance id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// gapTracker は読み終えたidの間の欠番を、コミットが遅れた行として一定時間待つ
+type gapTracker struct {
+	wait  time.Duration
+	limit int
+	gaps  map[int64]time.Time // 欠番 -> 待つ期限
+}
+
+func newGapTracker(wait time.Duration, limit int) *gapTracker {
+	return &gapTracker{wait: wait, limit: limit, gaps: make(map[int64]time.Time)}
+}
+
+// skip はlastより大きいidの行を読んだときに、間の欠番を待ち始める
+func (g *gapTracker) skip(last, id int64, now time.Time) {
+	for missing := last + 1; missing < id && len(g.gaps) < g.limit; missing++ {
+		g.gaps[missing] = now.Add(g.wait)
+	}
+}
+
+// fill は欠番の行を読んだときに待つのをやめる（待っていない欠番ならfalse）
+func (g *gapTracker) fill(id int64) bool {
+	if _, ok := g.gaps[id]; !ok {
+		return false
+	}
+	delete(g.gaps, id)
+	return true
+}
+
+// pending は期限切れの欠番を捨て、まだ待っている欠番を小さい順に返す
+func (g *gapTracker) pending(now time.Time) []int64 {
+	ids := make([]int64, 0, len(g.gaps))
+	for id, deadline := range g.gaps {
+		if now.After(deadline) {
+			delete(g.gaps, id)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
@@ -0,0 +1,48 @@
+package realtime
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestGapTracker コミットが遅れた行の欠番を一定時間待ち、読めたら・期限が過ぎたら待つのをやめる
+func TestGapTracker(t *testing.T) {
+	now := time.Now()
+	g := newGapTracker(10*time.Second, 100)
+
+	// id=3を読んだ時点で、採番済みのid=2はまだコミットされていない
+	g.skip(1, 3, now)
+	g.skip(3, 6, now)
+	if got := g.pending(now); !reflect.DeepEqual(got, []int64{2, 4, 5}) {
+		t.Fatalf("expected gaps [2 4 5], got %v", got)
+	}
+
+	// 遅れてコミットされた行は1回だけ配信する
+	if !g.fill(2) {
+		t.Error("expected id=2 to be a pending gap")
+	}
+	if g.fill(2) {
+		t.Error("expected id=2 to be delivered only once")
+	}
+	if g.fill(3) {
+		t.Error("expected id=3 not to be a gap")
+	}
+
+	// 期限が過ぎた欠番は使われなかったidとみなす
+	if got := g.pending(now.Add(11 * time.Second)); len(got) != 0 {
+		t.Errorf("expected expired gaps to be dropped, got %v", got)
+	}
+	if g.fill(4) {
+		t.Error("expected expired gap not to be delivered")
+	}
+}
+
+// TestGapTracker_Limit 大きく飛んだidでも待つ欠番は上限まで
+func TestGapTracker_Limit(t *testing.T) {
+	g := newGapTracker(10*time.Second, 100)
+	g.skip(0, 1_000_000, time.Now())
+	if got := len(g.pending(time.Now())); got != 100 {
+		t.Errorf("expected 100 gaps, got %d", got)
+	}
+}
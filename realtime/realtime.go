@@ -0,0 +1,57 @@
+package realtime
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Event は接続中のユーザーへ届けるイベント
+// UIDsは配信先（クライアントには送らない）、Dataはイベントの内容（JSON）
+type Event struct {
+	UIDs []string        `json:"uids"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Backend はイベントをインスタンス間で共有するファンアウトの実装
+// Hubは自分に接続しているユーザーにだけ配信するため、複数インスタンスで動かす場合は
+// 別のインスタンスで発行されたイベントもBackend経由で受け取る必要がある
+type Backend interface {
+	// Publish はイベントをすべてのインスタンス（自分を含む）のハンドラへ届ける
+	Publish(event Event) error
+	// Subscribe はイベントを受け取るハンドラを登録する
+	Subscribe(handler func(Event))
+	// SetOnline はuidがこのインスタンスに接続しているか（onlineがfalseならすべて切断した）を記録する
+	SetOnline(uid string, online bool) error
+	// IsOnline はuidがいずれかのインスタンスに接続しているか
+	IsOnline(uid string) (bool, error)
+	// Close は配信を停止する
+	Close() error
+}
+
+// NewBackendFromEnv は環境変数 REALTIME_BACKEND に応じてBackendを生成する
+//
+//	memory (デフォルト): 同じインスタンス内でのみ配信（1インスタンスで動かす場合）
+//	mysql: realtime_events テーブルを介して全インスタンスへ配信（REALTIME_POLL_INTERVAL でポーリング間隔を指定）
+func NewBackendFromEnv(db *sql.DB) (Backend, error) {
+	backend := strings.ToLower(os.Getenv("REALTIME_BACKEND"))
+	switch backend {
+	case "", "memory":
+		return NewMemoryBackend(), nil
+	case "mysql":
+		interval := 500 * time.Millisecond
+		if v := os.Getenv("REALTIME_POLL_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid REALTIME_POLL_INTERVAL: %q", v)
+			}
+			interval = d
+		}
+		return NewMySQLBackend(db, interval)
+	}
+	return nil, fmt.Errorf("unknown REALTIME_BACKEND: %s", backend)
+}
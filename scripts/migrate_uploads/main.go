@@ -0,0 +1,207 @@
+// ローカルの uploads/ ディレクトリの画像をオブジェクトストレージへ移行し、
+// item_images の画像URLを新しいURLに書き換える
+//
+// 使用方法:
+//
+//	STORAGE_BACKEND=s3 S3_ENDPOINT=... S3_BUCKET=... \
+//	go run ./scripts/migrate_uploads -dir ./uploads [-dry-run] [-delete-local]
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"uttc-hackathon-backend/storage"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func main() {
+	dir := flag.String("dir", "./uploads", "移行元のディレクトリ")
+	dryRun := flag.Bool("dry-run", false, "アップロード・DB更新をせずに対象のみ表示する")
+	deleteLocal := flag.Bool("delete-local", false, "移行とDB更新が成功したらローカルファイルを削除する")
+	flag.Parse()
+
+	store, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatalf("storage init error: %v", err)
+	}
+	if _, ok := store.(*storage.LocalStorage); ok {
+		log.Fatal("STORAGE_BACKEND must be a remote storage (e.g. s3) for migration")
+	}
+
+	// 環境変数から接続情報を取得
+	mysqlUser := os.Getenv("MYSQL_USER")
+	mysqlUserPwd := os.Getenv("MYSQL_USER_PWD")
+	mysqlDatabase := os.Getenv("MYSQL_DATABASE")
+	instanceConnectionName := os.Getenv("INSTANCE_CONNECTION_NAME")
+
+	if mysqlUser == "" || mysqlUserPwd == "" || mysqlDatabase == "" || instanceConnectionName == "" {
+		log.Fatal("Required environment variables: MYSQL_USER, MYSQL_USER_PWD, MYSQL_DATABASE, INSTANCE_CONNECTION_NAME")
+	}
+
+	dsn := fmt.Sprintf(
+		"%s:%s@unix(/cloudsql/%s)/%s?parseTime=true",
+		mysqlUser,
+		mysqlUserPwd,
+		instanceConnectionName,
+		mysqlDatabase,
+	)
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Ping(); err != nil {
+		log.Fatalf("Failed to ping database: %v", err)
+	}
+
+	// 1. ファイルをストレージへアップロード
+	migrated, err := uploadFiles(store, *dir, *dryRun)
+	if err != nil {
+		log.Fatalf("Failed to upload files: %v", err)
+	}
+	log.Printf("Uploaded %d files", len(migrated))
+
+	// 2. item_images のURLを書き換え
+	updated, err := rewriteImageURLs(db, store, migrated, *dryRun)
+	if err != nil {
+		log.Fatalf("Failed to rewrite image URLs: %v", err)
+	}
+	log.Printf("Rewrote %d item_images rows", updated)
+
+	// 3. 必要ならローカルファイルを削除
+	if *deleteLocal && !*dryRun {
+		for key := range migrated {
+			if err := os.Remove(filepath.Join(*dir, filepath.FromSlash(key))); err != nil {
+				log.Printf("Failed to delete local file %s: %v", key, err)
+			}
+		}
+		log.Printf("Deleted %d local files", len(migrated))
+	}
+
+	log.Println("Migration completed successfully!")
+}
+
+// uploadFiles はディレクトリ内のファイルを同じキーでアップロードし、移行したキーの集合を返す
+func uploadFiles(store storage.Storage, dir string, dryRun bool) (map[string]bool, error) {
+	migrated := make(map[string]bool)
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+
+		if dryRun {
+			log.Printf("[dry-run] would upload %s -> %s", path, store.URL(key))
+			migrated[key] = true
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		contentType := mime.TypeByExtension(filepath.Ext(path))
+		if err := store.Put(key, data, contentType); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+		log.Printf("Uploaded %s -> %s", path, store.URL(key))
+		migrated[key] = true
+		return nil
+	})
+	return migrated, err
+}
+
+// rewriteImageURLs は "uploads/key" を参照しているURLを新しいストレージのURLに置き換える
+func rewriteImageURLs(db *sql.DB, store storage.Storage, migrated map[string]bool, dryRun bool) (int, error) {
+	rows, err := db.Query("SELECT id, image_url, medium_url, thumbnail_url FROM item_images")
+	if err != nil {
+		return 0, err
+	}
+	type imageRow struct {
+		id                      int
+		imageURL                string
+		mediumURL, thumbnailURL sql.NullString
+	}
+	var targets []imageRow
+	for rows.Next() {
+		var r imageRow
+		if err := rows.Scan(&r.id, &r.imageURL, &r.mediumURL, &r.thumbnailURL); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		targets = append(targets, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	updated := 0
+	for _, r := range targets {
+		newImage, changed1 := rewriteURL(r.imageURL, store, migrated)
+		newMedium, changed2 := rewriteURL(r.mediumURL.String, store, migrated)
+		newThumb, changed3 := rewriteURL(r.thumbnailURL.String, store, migrated)
+		if !changed1 && !changed2 && !changed3 {
+			continue
+		}
+		if dryRun {
+			log.Printf("[dry-run] item_images.id=%d: %s -> %s", r.id, r.imageURL, newImage)
+			updated++
+			continue
+		}
+		_, err := tx.Exec(
+			"UPDATE item_images SET image_url = ?, medium_url = ?, thumbnail_url = ? WHERE id = ?",
+			newImage, nullIfEmpty(newMedium), nullIfEmpty(newThumb), r.id,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update item_images.id=%d: %w", r.id, err)
+		}
+		updated++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
+
+// rewriteURL は "uploads/key" または "https://host/uploads/key" を新しいURLに変換する
+func rewriteURL(url string, store storage.Storage, migrated map[string]bool) (string, bool) {
+	idx := strings.LastIndex(url, "uploads/")
+	if idx < 0 {
+		return url, false
+	}
+	key := url[idx+len("uploads/"):]
+	if !migrated[key] {
+		return url, false
+	}
+	newURL := store.URL(key)
+	return newURL, newURL != url
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
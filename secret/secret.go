@@ -0,0 +1,81 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// version は暗号文の形式（鍵や方式を変える場合に増やす）
+const version byte = 1
+
+// ErrDecrypt は改ざん・鍵の不一致・形式の誤りで復号できない場合のエラー
+var ErrDecrypt = errors.New("failed to decrypt")
+
+// Box は個人情報をDBに保存する前に暗号化する（AES-256-GCM）
+// 暗号文は version(1byte) + nonce(12byte) + ciphertext の形式
+type Box struct {
+	aead cipher.AEAD
+}
+
+// NewBox は32バイトの鍵からBoxを生成する
+func NewBox(key []byte) (*Box, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return &Box{aead: aead}, nil
+}
+
+// NewBoxFromEnv は環境変数 ADDRESS_ENCRYPTION_KEY（32バイトの鍵をbase64にしたもの）からBoxを生成する
+//
+//	生成例: openssl rand -base64 32
+func NewBoxFromEnv() (*Box, error) {
+	encoded := os.Getenv("ADDRESS_ENCRYPTION_KEY")
+	if encoded == "" {
+		return nil, errors.New("ADDRESS_ENCRYPTION_KEY is not set")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("ADDRESS_ENCRYPTION_KEY must be base64: %w", err)
+	}
+	return NewBox(key)
+}
+
+// Seal はplaintextを暗号化する
+// aadには保存先（ユーザーや購入）を表す値を渡し、別の行に暗号文をコピーしても復号できないようにする
+func (b *Box) Seal(plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	out := make([]byte, 0, 1+len(nonce)+len(plaintext)+b.aead.Overhead())
+	out = append(out, version)
+	out = append(out, nonce...)
+	return b.aead.Seal(out, nonce, plaintext, aad), nil
+}
+
+// Open はSealで暗号化したデータを復号する（aadはSealと同じ値を渡す）
+func (b *Box) Open(data, aad []byte) ([]byte, error) {
+	nonceSize := b.aead.NonceSize()
+	if len(data) < 1+nonceSize || data[0] != version {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := b.aead.Open(nil, data[1:1+nonceSize], data[1+nonceSize:], aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,85 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func newTestBox(t *testing.T, fill byte) *Box {
+	t.Helper()
+	box, err := NewBox(bytes.Repeat([]byte{fill}, 32))
+	if err != nil {
+		t.Fatalf("failed to create box: %v", err)
+	}
+	return box
+}
+
+// TestSealOpen 暗号化したデータを同じaadで復号できる
+func TestSealOpen(t *testing.T) {
+	box := newTestBox(t, 1)
+	plaintext := []byte("東京都千代田区1-1")
+
+	sealed, err := box.Seal(plaintext, []byte("user:1"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if bytes.Contains(sealed, plaintext) {
+		t.Error("expected plaintext not to appear in sealed data")
+	}
+	opened, err := box.Open(sealed, []byte("user:1"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("expected %q, got %q", plaintext, opened)
+	}
+
+	// 同じ平文でもnonceが違うので暗号文は毎回変わる
+	again, _ := box.Seal(plaintext, []byte("user:1"))
+	if bytes.Equal(sealed, again) {
+		t.Error("expected different ciphertexts for the same plaintext")
+	}
+}
+
+// TestOpen_Rejected aad・鍵の違いや改ざんは復号できない
+func TestOpen_Rejected(t *testing.T) {
+	box := newTestBox(t, 1)
+	sealed, _ := box.Seal([]byte("secret"), []byte("user:1"))
+
+	tampered := append([]byte{}, sealed...)
+	tampered[len(tampered)-1] ^= 0xff
+
+	cases := map[string]struct {
+		box  *Box
+		data []byte
+		aad  string
+	}{
+		"other aad": {box, sealed, "user:2"},
+		"other key": {newTestBox(t, 2), sealed, "user:1"},
+		"tampered":  {box, tampered, "user:1"},
+		"truncated": {box, sealed[:5], "user:1"},
+	}
+	for name, tc := range cases {
+		if _, err := tc.box.Open(tc.data, []byte(tc.aad)); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("%s: expected ErrDecrypt, got %v", name, err)
+		}
+	}
+}
+
+// TestNewBoxFromEnv 鍵は32バイトのbase64でなければならない
+func TestNewBoxFromEnv(t *testing.T) {
+	t.Setenv("ADDRESS_ENCRYPTION_KEY", "")
+	if _, err := NewBoxFromEnv(); err == nil {
+		t.Error("expected error for missing key")
+	}
+	t.Setenv("ADDRESS_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString([]byte("short")))
+	if _, err := NewBoxFromEnv(); err == nil {
+		t.Error("expected error for short key")
+	}
+	t.Setenv("ADDRESS_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)))
+	if _, err := NewBoxFromEnv(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
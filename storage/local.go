@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxPutBytes は署名付きURLでPUTできる最大サイズ
+	maxPutBytes = 20 << 20
+)
+
+// protectedDirs 以下のキー（ディレクトリ自体を含む）は署名付きURLでのみ配信する
+// private/ は本人確認書類など、staging/ は検証前のアップロード
+var protectedDirs = []string{"private", "staging"}
+
+func isProtected(key string) bool {
+	for _, dir := range protectedDirs {
+		if key == dir || strings.HasPrefix(key, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalStorage はローカルディスクに保存するStorage実装
+// Cloud Runでは再デプロイで消えるため、開発環境向け
+type LocalStorage struct {
+	dir        string
+	signingKey []byte
+	now        func() time.Time
+}
+
+func NewLocalStorage(dir string, signingKey string) *LocalStorage {
+	return &LocalStorage{dir: dir, signingKey: []byte(signingKey), now: time.Now}
+}
+
+func (s *LocalStorage) path(key string) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+func (s *LocalStorage) Put(key string, data []byte, contentType string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+		return fmt.Errorf("could not create directory: %w", err)
+	}
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		return fmt.Errorf("could not save file content: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalStorage) Get(key string) ([]byte, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *LocalStorage) Delete(key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// URL は従来通り "uploads/key" の相対パスを返す（ハンドラー側でベースURLを付与）
+func (s *LocalStorage) URL(key string) string {
+	return "uploads/" + key
+}
+
+// SignedURL は有効期限とHMAC署名をクエリに付けたURLを返す
+func (s *LocalStorage) SignedURL(key string, expires time.Duration) (string, error) {
+	return s.signedURL(http.MethodGet, key, expires)
+}
+
+// SignedPutURL はServeHTTPにPUTでアップロードできる署名付きURLを返す
+func (s *LocalStorage) SignedPutURL(key string, expires time.Duration) (string, error) {
+	return s.signedURL(http.MethodPut, key, expires)
+}
+
+func (s *LocalStorage) signedURL(method, key string, expires time.Duration) (string, error) {
+	if len(s.signingKey) == 0 {
+		return "", fmt.Errorf("STORAGE_SIGNING_KEY is not set")
+	}
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	exp := s.now().Add(expires).Unix()
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(exp, 10))
+	q.Set("sig", s.sign(method, key, exp))
+	return s.URL(key) + "?" + q.Encode(), nil
+}
+
+func (s *LocalStorage) sign(method, key string, exp int64) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(mac, "%s\n%s\n%d", method, key, exp)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServeHTTP は /uploads/ 配下のファイルを配信する（http.StripPrefixと組み合わせて使う）
+// private/・staging/ 配下は有効な署名付きURLでのみアクセスでき、PUTは署名付きURLでのみ受け付ける
+// ディレクトリは一覧を返さないように404にする
+func (s *LocalStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if isProtected(key) || r.URL.Query().Has("sig") {
+			if !s.verify(http.MethodGet, key, r.URL.Query()) {
+				http.Error(w, "invalid or expired signature", http.StatusForbidden)
+				return
+			}
+		}
+		p, err := s.path(key)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if info, err := os.Stat(p); err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, p)
+	case http.MethodPut:
+		if !s.verify(http.MethodPut, key, r.URL.Query()) {
+			http.Error(w, "invalid or expired signature", http.StatusForbidden)
+			return
+		}
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPutBytes))
+		if err != nil {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err := s.Put(key, data, r.Header.Get("Content-Type")); err != nil {
+			http.Error(w, "failed to store object", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *LocalStorage) verify(method, key string, q url.Values) bool {
+	if len(s.signingKey) == 0 {
+		return false
+	}
+	exp, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	if err != nil || s.now().Unix() > exp {
+		return false
+	}
+	return hmac.Equal([]byte(q.Get("sig")), []byte(s.sign(method, key, exp)))
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryStorage はテスト用のインメモリStorage実装
+type MemoryStorage struct {
+	mu           sync.Mutex
+	Objects      map[string][]byte
+	ContentTypes map[string]string
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		Objects:      make(map[string][]byte),
+		ContentTypes: make(map[string]string),
+	}
+}
+
+func (s *MemoryStorage) Put(key string, data []byte, contentType string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Objects[key] = append([]byte(nil), data...)
+	s.ContentTypes[key] = contentType
+	return nil
+}
+
+func (s *MemoryStorage) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.Objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (s *MemoryStorage) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.Objects, key)
+	delete(s.ContentTypes, key)
+	return nil
+}
+
+func (s *MemoryStorage) URL(key string) string {
+	return "memory://" + key
+}
+
+func (s *MemoryStorage) SignedURL(key string, expires time.Duration) (string, error) {
+	return fmt.Sprintf("memory://%s?expires=%d", key, int64(expires.Seconds())), nil
+}
+
+func (s *MemoryStorage) SignedPutURL(key string, expires time.Duration) (string, error) {
+	return fmt.Sprintf("memory://%s?method=PUT&expires=%d", key, int64(expires.Seconds())), nil
+}
@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	amzDateFormat   = "20060102T150405Z"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// S3Config はS3互換ストレージの接続設定
+// GCSの場合はHMACキーを発行し、Endpointに https://storage.googleapis.com を指定する
+type S3Config struct {
+	Endpoint        string // 例: https://s3.ap-northeast-1.amazonaws.com, http://localhost:9000
+	Region          string // GCSの場合は "auto"
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PublicBaseURL   string // 公開URLのベース（CDN等）。空ならエンドポイントのURLを使う
+	PathStyle       bool   // trueなら endpoint/bucket/key、falseなら bucket.endpoint/key
+}
+
+// S3Storage はS3互換API（AWS Signature V4）で保存するStorage実装
+type S3Storage struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+func NewS3Storage(cfg S3Config) (*S3Storage, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3_ENDPOINT: %s", cfg.Endpoint)
+	}
+	return &S3Storage{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		now:      time.Now,
+	}, nil
+}
+
+// objectURL はオブジェクトのAPI URLを組み立てる
+func (s *S3Storage) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.cfg.PathStyle {
+		u.Path = "/" + s.cfg.Bucket + "/" + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	return &u
+}
+
+func (s *S3Storage) Put(key string, data []byte, contentType string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	headers := map[string]string{}
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+	resp, err := s.do(http.MethodPut, key, data, headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s.responseError("put", key, resp)
+	}
+	return nil
+}
+
+func (s *S3Storage) Get(key string) ([]byte, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	resp, err := s.do(http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, s.responseError("get", key, resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *S3Storage) Delete(key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// S3は存在しないキーでも204を返すが、互換実装によっては404を返す
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s.responseError("delete", key, resp)
+	}
+	return nil
+}
+
+func (s *S3Storage) URL(key string) string {
+	if s.cfg.PublicBaseURL != "" {
+		return strings.TrimSuffix(s.cfg.PublicBaseURL, "/") + "/" + key
+	}
+	return s.objectURL(key).String()
+}
+
+// SignedURL はクエリ文字列で署名したGET用のURL（presigned URL）を返す
+func (s *S3Storage) SignedURL(key string, expires time.Duration) (string, error) {
+	return s.presign(http.MethodGet, key, expires)
+}
+
+// SignedPutURL はクエリ文字列で署名したPUT用のURLを返す
+// Content-Typeは署名に含めないため、アップロード後に中身を検証すること
+func (s *S3Storage) SignedPutURL(key string, expires time.Duration) (string, error) {
+	return s.presign(http.MethodPut, key, expires)
+}
+
+// presign はSigV4のクエリ署名でURLを生成する（最長7日）
+func (s *S3Storage) presign(method, key string, expires time.Duration) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	if expires <= 0 || expires > 7*24*time.Hour {
+		return "", fmt.Errorf("expires must be between 1s and 7 days")
+	}
+	now := s.now().UTC()
+	u := s.objectURL(key)
+
+	q := url.Values{}
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", s.cfg.AccessKeyID+"/"+s.scope(now))
+	q.Set("X-Amz-Date", now.Format(amzDateFormat))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	q.Set("X-Amz-SignedHeaders", "host")
+
+	canonical := strings.Join([]string{
+		method,
+		canonicalURI(u.Path),
+		canonicalQuery(q),
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	q.Set("X-Amz-Signature", s.signature(now, canonical))
+	u.RawQuery = canonicalQuery(q)
+	return u.String(), nil
+}
+
+// do はヘッダーで署名したリクエストを送信する
+func (s *S3Storage) do(method, key string, body []byte, headers map[string]string) (*http.Response, error) {
+	now := s.now().UTC()
+	u := s.objectURL(key)
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", now.Format(amzDateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// 署名対象ヘッダー（host + x-amz-* + content-type）
+	signed := map[string]string{"host": u.Host}
+	for k := range req.Header {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "x-amz-") || lk == "content-type" {
+			signed[lk] = strings.TrimSpace(req.Header.Get(k))
+		}
+	}
+	names := make([]string, 0, len(signed))
+	for k := range signed {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + signed[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		method,
+		canonicalURI(u.Path),
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKeyID, s.scope(now), signedHeaders, s.signature(now, canonical),
+	))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 %s request failed: %w", strings.ToLower(method), err)
+	}
+	return resp, nil
+}
+
+func (s *S3Storage) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+func (s *S3Storage) signature(t time.Time, canonicalRequest string) string {
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		t.Format(amzDateFormat),
+		s.scope(t),
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func (s *S3Storage) responseError(op, key string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3 %s %s failed: status=%d, body=%s", op, key, resp.StatusCode, string(body))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalURI はパスの各セグメントをRFC3986でエンコードする（"/"はそのまま）
+func canonicalURI(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		segments[i] = uriEncode(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery はキー順にソートしてエンコードしたクエリ文字列を返す
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range q[k] {
+			parts = append(parts, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode はSigV4の仕様通り、非予約文字以外を%XXにエンコードする
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNotFound は指定したキーのオブジェクトが存在しない場合のエラー
+var ErrNotFound = errors.New("object not found")
+
+// Storage はアップロードされたファイルの保存先を抽象化するインターフェース
+// キーは "1700000000_abcd_full.jpg" のようなスラッシュ区切りの相対パス
+type Storage interface {
+	// Put はオブジェクトを保存する（同じキーがあれば上書き）
+	Put(key string, data []byte, contentType string) error
+	// Get はオブジェクトを取得する。存在しない場合はErrNotFoundを返す
+	Get(key string) ([]byte, error)
+	// Delete はオブジェクトを削除する。存在しない場合もエラーにしない
+	Delete(key string) error
+	// URL はDBに保存・クライアントに返すためのURLを返す
+	// ローカルの場合は "uploads/key" の相対パス、S3の場合は絶対URL
+	URL(key string) string
+	// SignedURL は期限付きでアクセスできる署名付きURLを返す
+	SignedURL(key string, expires time.Duration) (string, error)
+	// SignedPutURL はクライアントが直接PUTでアップロードできる期限付きURLを返す
+	SignedPutURL(key string, expires time.Duration) (string, error)
+}
+
+// NewFromEnv は環境変数 STORAGE_BACKEND に応じてStorageを生成する
+//
+//	local (デフォルト): ./uploads に保存
+//	s3: S3互換API（AWS S3 / GCS相互運用API / MinIO）に保存
+func NewFromEnv() (Storage, error) {
+	backend := strings.ToLower(os.Getenv("STORAGE_BACKEND"))
+	switch backend {
+	case "", "local":
+		return NewLocalStorage("./uploads", os.Getenv("STORAGE_SIGNING_KEY")), nil
+	case "s3", "gcs", "minio":
+		cfg := S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PublicBaseURL:   os.Getenv("S3_PUBLIC_BASE_URL"),
+			PathStyle:       os.Getenv("S3_PATH_STYLE") != "false",
+		}
+		return NewS3Storage(cfg)
+	}
+	return nil, fmt.Errorf("unknown STORAGE_BACKEND: %s", backend)
+}
+
+// validateKey はディレクトリトラバーサルになるキーを拒否する
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid storage key: %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid storage key: %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"uttc-hackathon-backend/auth"
+	adminDao "uttc-hackathon-backend/dao/admin"
+	auditDao "uttc-hackathon-backend/dao/audit"
+	chainEventsDao "uttc-hackathon-backend/dao/chainEvents"
+	"uttc-hackathon-backend/usecase/itemStatus"
+)
+
+// maxReasonLength は管理操作の理由の最大文字数
+const maxReasonLength = 500
+
+var (
+	// ErrInvalidInput は入力が不正な場合のエラー
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrItemNotFound は商品が存在しない場合のエラー
+	ErrItemNotFound = errors.New("item not found")
+	// ErrUserNotFound はユーザーが存在しない場合のエラー
+	ErrUserNotFound = errors.New("user not found")
+	// ErrCannotTargetSelf は自分自身を利用停止・ロール変更しようとした場合のエラー
+	ErrCannotTargetSelf = errors.New("cannot change your own account")
+	// ErrActiveOrder は取引中の注文がある商品の状態を変更しようとした場合のエラー
+	ErrActiveOrder = errors.New("item has an active order; cancel or refund the order first")
+	// ErrForbidden は自分と同じかそれより上のロールのユーザーを操作しようとした場合のエラー
+	ErrForbidden = errors.New("cannot change a user with the same or a higher role")
+)
+
+// StatusChange は商品の状態の変更結果
+type StatusChange struct {
+	ItemID int    `json:"item_id"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+type AdminUsecase struct {
+	adminDao      adminDao.AdminDAOInterface
+	auditDao      auditDao.AuditDAOInterface
+	chainEventDao chainEventsDao.ChainEventDAOInterface
+}
+
+func NewAdminUsecase(admin adminDao.AdminDAOInterface, audit auditDao.AuditDAOInterface, chainEvents chainEventsDao.ChainEventDAOInterface) *AdminUsecase {
+	return &AdminUsecase{adminDao: admin, auditDao: audit, chainEventDao: chainEvents}
+}
+
+// ListItems は状態・非表示で絞り込んだ商品を新しい順に返す
+func (u *AdminUsecase) ListItems(status string, hidden *bool, page, limit int) ([]*adminDao.Item, error) {
+	if status != "" && !itemStatus.Lifecycle.Valid(status) {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidInput, status)
+	}
+	items, err := u.adminDao.ListItems(adminDao.ItemFilter{Status: status, Hidden: hidden}, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+	return items, nil
+}
+
+// ForceItemStatus は管理者の操作で商品の状態を変更する（理由は必須）
+// 通常の遷移に加えて管理者だけの遷移（itemStatus.Lifecycle.CheckOverride）もでき、それ以外は*itemStatus.ConflictError
+// 取引中の注文がある商品はErrActiveOrder（注文はOrderUsecaseでキャンセル・返金する）
+func (u *AdminUsecase) ForceItemStatus(actorUID string, itemID int, status, reason string) (*StatusChange, error) {
+	if !itemStatus.Lifecycle.Valid(status) {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidInput, status)
+	}
+	reason, err := validateReason(reason)
+	if err != nil {
+		return nil, err
+	}
+	before, err := u.adminDao.GetItemStatus(itemID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item status: %w", err)
+	}
+	if err := itemStatus.Lifecycle.CheckOverride(itemID, before, status); err != nil {
+		return nil, err
+	}
+	err = u.adminDao.ForceItemStatus(itemID, before, status, actorUID, reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrItemNotFound
+	}
+	if errors.Is(err, adminDao.ErrStatusChanged) {
+		return nil, fmt.Errorf("item status changed during update: %w", &itemStatus.ConflictError{ItemID: itemID, From: before, To: status})
+	}
+	if errors.Is(err, adminDao.ErrActiveOrder) {
+		return nil, ErrActiveOrder
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to force item status: %w", err)
+	}
+	return &StatusChange{ItemID: itemID, From: before, To: status}, nil
+}
+
+// SetSuspended はユーザーを利用停止（suspended=falseなら解除）にする（理由は必須）
+// 操作者より下のロールのユーザーだけを対象にできる（モデレーターが管理者を締め出せないように）
+func (u *AdminUsecase) SetSuspended(actorUID, actorRole, uid string, suspended bool, reason string) error {
+	if uid == actorUID {
+		return ErrCannotTargetSelf
+	}
+	reason, err := validateReason(reason)
+	if err != nil {
+		return err
+	}
+	targetRole, _, err := u.adminDao.LookupRole(uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user role: %w", err)
+	}
+	if auth.HasRole(targetRole, actorRole) {
+		return ErrForbidden
+	}
+	err = u.adminDao.SetSuspended(uid, suspended, actorUID, reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update suspension: %w", err)
+	}
+	return nil
+}
+
+// SetRole はユーザーのロールを変更する（理由は必須）
+func (u *AdminUsecase) SetRole(actorUID, uid, role, reason string) error {
+	if uid == actorUID {
+		return ErrCannotTargetSelf
+	}
+	if !auth.ValidRole(role) {
+		return fmt.Errorf("%w: role must be user, moderator or admin", ErrInvalidInput)
+	}
+	reason, err := validateReason(reason)
+	if err != nil {
+		return err
+	}
+	_, err = u.adminDao.SetRole(uid, role, actorUID, reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+	return nil
+}
+
+// ListChainEvents はonchainサービスから受け取ったイベントの履歴を返す（chainItemIDが0なら全件）
+func (u *AdminUsecase) ListChainEvents(chainItemID int64, page, limit int) ([]*chainEventsDao.ChainEvent, error) {
+	events, err := u.chainEventDao.ListEvents(chainItemID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chain events: %w", err)
+	}
+	return events, nil
+}
+
+// ListAuditEvents は監査ログを新しい順に返す
+func (u *AdminUsecase) ListAuditEvents(filter auditDao.Filter, page, limit int) ([]*auditDao.Event, error) {
+	events, err := u.auditDao.ListEvents(filter, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	return events, nil
+}
+
+// GetItemHistory は1つの商品に対する変更履歴（出品・購入・onchain連携・管理操作）を新しい順に返す
+func (u *AdminUsecase) GetItemHistory(itemID int, page, limit int) ([]*auditDao.Event, error) {
+	if itemID <= 0 {
+		return nil, fmt.Errorf("%w: invalid item id", ErrInvalidInput)
+	}
+	filter := auditDao.Filter{EntityType: "item", EntityID: strconv.Itoa(itemID)}
+	return u.ListAuditEvents(filter, page, limit)
+}
+
+func validateReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "", fmt.Errorf("%w: reason is required", ErrInvalidInput)
+	}
+	if utf8.RuneCountInString(reason) > maxReasonLength {
+		return "", fmt.Errorf("%w: reason must be at most %d characters", ErrInvalidInput, maxReasonLength)
+	}
+	return reason, nil
+}
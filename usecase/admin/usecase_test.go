@@ -0,0 +1,360 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	adminDao "uttc-hackathon-backend/dao/admin"
+	auditDao "uttc-hackathon-backend/dao/audit"
+	chainEventsDao "uttc-hackathon-backend/dao/chainEvents"
+	"uttc-hackathon-backend/usecase/itemStatus"
+)
+
+// MockAdminDAO はテスト用のモックDAO（変更のたびに監査ログを積む）
+type MockAdminDAO struct {
+	items     map[int]*adminDao.Item
+	orders    map[int]bool // 取引中の注文がある商品
+	roles     map[string]string
+	suspended map[string]bool
+	audit     []*auditDao.Event
+	filter    adminDao.ItemFilter
+}
+
+func NewMockAdminDAO() *MockAdminDAO {
+	return &MockAdminDAO{
+		items: map[int]*adminDao.Item{
+			1: {ID: 1, Status: "purchased"},
+		},
+		orders:    make(map[int]bool),
+		roles:     map[string]string{"admin": "admin", "alice": "user", "mod": "moderator", "mod2": "moderator", "admin2": "admin"},
+		suspended: make(map[string]bool),
+	}
+}
+
+func (m *MockAdminDAO) LookupRole(uid string) (string, bool, error) {
+	role, ok := m.roles[uid]
+	if !ok {
+		return "", false, sql.ErrNoRows
+	}
+	return role, m.suspended[uid], nil
+}
+
+func (m *MockAdminDAO) ListItems(filter adminDao.ItemFilter, limit, offset int) ([]*adminDao.Item, error) {
+	m.filter = filter
+	var result []*adminDao.Item
+	for _, item := range m.items {
+		if filter.Status == "" || item.Status == filter.Status {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockAdminDAO) GetItemStatus(itemID int) (string, error) {
+	item, ok := m.items[itemID]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return item.Status, nil
+}
+
+func (m *MockAdminDAO) ForceItemStatus(itemID int, from, to, actorUID, reason string) error {
+	item, ok := m.items[itemID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if item.Status != from {
+		return adminDao.ErrStatusChanged
+	}
+	if m.orders[itemID] {
+		return adminDao.ErrActiveOrder
+	}
+	item.Status = to
+	m.audit = append(m.audit, &auditDao.Event{ActorUID: actorUID, Action: "item.force_status", Reason: reason})
+	return nil
+}
+
+func (m *MockAdminDAO) SetSuspended(uid string, suspended bool, actorUID, reason string) error {
+	if _, ok := m.roles[uid]; !ok {
+		return sql.ErrNoRows
+	}
+	m.suspended[uid] = suspended
+	m.audit = append(m.audit, &auditDao.Event{ActorUID: actorUID, Action: "user.suspend", Reason: reason})
+	return nil
+}
+
+func (m *MockAdminDAO) SetRole(uid, role, actorUID, reason string) (string, error) {
+	before, ok := m.roles[uid]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	m.roles[uid] = role
+	m.audit = append(m.audit, &auditDao.Event{ActorUID: actorUID, Action: "user.set_role", Reason: reason})
+	return before, nil
+}
+
+// MockAuditDAO はテスト用のモックDAO
+type MockAuditDAO struct {
+	filter auditDao.Filter
+}
+
+func (m *MockAuditDAO) ListEvents(filter auditDao.Filter, limit, offset int) ([]*auditDao.Event, error) {
+	m.filter = filter
+	return []*auditDao.Event{}, nil
+}
+
+// MockChainEventDAO はテスト用のモックDAO
+type MockChainEventDAO struct {
+	events []*chainEventsDao.ChainEvent
+}
+
+func (m *MockChainEventDAO) Record(e *chainEventsDao.ChainEvent) error {
+	m.events = append(m.events, e)
+	return nil
+}
+
+func (m *MockChainEventDAO) ListEvents(chainItemID int64, limit, offset int) ([]*chainEventsDao.ChainEvent, error) {
+	var result []*chainEventsDao.ChainEvent
+	for _, e := range m.events {
+		if chainItemID == 0 || e.ChainItemID == chainItemID {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func newTestUsecase() (*AdminUsecase, *MockAdminDAO, *MockChainEventDAO) {
+	mockDAO := NewMockAdminDAO()
+	events := &MockChainEventDAO{}
+	return NewAdminUsecase(mockDAO, &MockAuditDAO{}, events), mockDAO, events
+}
+
+// TestListItems_Filter 状態で絞り込み、不明な状態はエラー
+func TestListItems_Filter(t *testing.T) {
+	usecase, mockDAO, _ := newTestUsecase()
+	hidden := true
+
+	items, err := usecase.ListItems("purchased", &hidden, 1, 20)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(items) != 1 {
+		t.Errorf("expected 1 item, got %d", len(items))
+	}
+	if mockDAO.filter.Status != "purchased" || mockDAO.filter.Hidden == nil || !*mockDAO.filter.Hidden {
+		t.Errorf("unexpected filter: %+v", mockDAO.filter)
+	}
+	if _, err := usecase.ListItems("sold", nil, 1, 20); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput, got %v", err)
+	}
+}
+
+// TestForceItemStatus_Success 理由付きで状態を強制変更し、監査ログに残る
+func TestForceItemStatus_Success(t *testing.T) {
+	usecase, mockDAO, _ := newTestUsecase()
+
+	change, err := usecase.ForceItemStatus("admin", 1, "listed", "  購入がオンチェーンで取り消されたため  ")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if change.From != "purchased" || change.To != "listed" {
+		t.Errorf("unexpected change: %+v", change)
+	}
+	if mockDAO.items[1].Status != "listed" {
+		t.Errorf("expected status listed, got %s", mockDAO.items[1].Status)
+	}
+	if len(mockDAO.audit) != 1 || mockDAO.audit[0].ActorUID != "admin" || mockDAO.audit[0].Reason != "購入がオンチェーンで取り消されたため" {
+		t.Errorf("unexpected audit log: %+v", mockDAO.audit)
+	}
+}
+
+// TestForceItemStatus_Rejected 不正な入力では変更しない
+func TestForceItemStatus_Rejected(t *testing.T) {
+	usecase, mockDAO, _ := newTestUsecase()
+
+	cases := []struct {
+		name   string
+		itemID int
+		status string
+		reason string
+		want   error
+	}{
+		{"unknown status", 1, "sold", "fix", ErrInvalidInput},
+		{"missing reason", 1, "listed", "   ", ErrInvalidInput},
+		{"reason too long", 1, "listed", strings.Repeat("あ", maxReasonLength+1), ErrInvalidInput},
+		{"missing item", 999, "listed", "fix", ErrItemNotFound},
+	}
+	for _, c := range cases {
+		if _, err := usecase.ForceItemStatus("admin", c.itemID, c.status, c.reason); !errors.Is(err, c.want) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, err)
+		}
+	}
+	if mockDAO.items[1].Status != "purchased" || len(mockDAO.audit) != 0 {
+		t.Errorf("expected no changes, got status=%s audit=%d", mockDAO.items[1].Status, len(mockDAO.audit))
+	}
+}
+
+// TestForceItemStatus_Override 管理者だけの遷移はでき、それ以外の遷移は競合になる
+func TestForceItemStatus_Override(t *testing.T) {
+	usecase, mockDAO, _ := newTestUsecase()
+
+	// purchased → cancelled は管理者だけができる遷移
+	change, err := usecase.ForceItemStatus("admin", 1, "cancelled", "不正な取引のため")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if change.From != "purchased" || change.To != "cancelled" {
+		t.Errorf("unexpected change: %+v", change)
+	}
+
+	// cancelled → completed は管理者でもできない
+	if _, err := usecase.ForceItemStatus("admin", 1, "completed", "fix"); !itemStatus.IsConflict(err) {
+		t.Errorf("expected ConflictError, got %v", err)
+	}
+	// 同じ状態への変更もできない
+	if _, err := usecase.ForceItemStatus("admin", 1, "cancelled", "fix"); !itemStatus.IsConflict(err) {
+		t.Errorf("expected ConflictError, got %v", err)
+	}
+	if mockDAO.items[1].Status != "cancelled" || len(mockDAO.audit) != 1 {
+		t.Errorf("expected only the override to be applied, got status=%s audit=%d", mockDAO.items[1].Status, len(mockDAO.audit))
+	}
+}
+
+// TestForceItemStatus_ActiveOrder 取引中の注文がある商品は変更できず、注文が終わった後は変更できる
+func TestForceItemStatus_ActiveOrder(t *testing.T) {
+	usecase, mockDAO, _ := newTestUsecase()
+	mockDAO.orders[1] = true
+
+	if _, err := usecase.ForceItemStatus("admin", 1, "cancelled", "不正な取引のため"); !errors.Is(err, ErrActiveOrder) {
+		t.Fatalf("expected ErrActiveOrder, got %v", err)
+	}
+	if mockDAO.items[1].Status != "purchased" || len(mockDAO.audit) != 0 {
+		t.Errorf("expected no changes, got status=%s audit=%d", mockDAO.items[1].Status, len(mockDAO.audit))
+	}
+
+	// 注文を返金した後は変更できる
+	mockDAO.orders[1] = false
+	if _, err := usecase.ForceItemStatus("admin", 1, "cancelled", "不正な取引のため"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if mockDAO.items[1].Status != "cancelled" {
+		t.Errorf("expected cancelled, got %s", mockDAO.items[1].Status)
+	}
+}
+
+// TestSetSuspended 利用停止と解除（自分自身は不可）
+func TestSetSuspended(t *testing.T) {
+	usecase, mockDAO, _ := newTestUsecase()
+
+	if err := usecase.SetSuspended("admin", "admin", "alice", true, "spam"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !mockDAO.suspended["alice"] {
+		t.Error("expected alice to be suspended")
+	}
+	if err := usecase.SetSuspended("admin", "admin", "alice", false, "appeal accepted"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if mockDAO.suspended["alice"] {
+		t.Error("expected alice to be unsuspended")
+	}
+
+	if err := usecase.SetSuspended("admin", "admin", "admin", true, "oops"); !errors.Is(err, ErrCannotTargetSelf) {
+		t.Errorf("expected ErrCannotTargetSelf, got %v", err)
+	}
+	if err := usecase.SetSuspended("admin", "admin", "nobody", true, "spam"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+	if err := usecase.SetSuspended("admin", "admin", "alice", true, ""); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput, got %v", err)
+	}
+	if len(mockDAO.audit) != 2 {
+		t.Errorf("expected 2 audit events, got %d", len(mockDAO.audit))
+	}
+}
+
+// TestSetSuspended_RoleHierarchy モデレーターは一般ユーザーだけを利用停止でき、モデレーター・管理者は対象にできない
+func TestSetSuspended_RoleHierarchy(t *testing.T) {
+	usecase, mockDAO, _ := newTestUsecase()
+
+	if err := usecase.SetSuspended("mod", "moderator", "alice", true, "spam"); err != nil {
+		t.Fatalf("expected moderator to suspend a user, got %v", err)
+	}
+	for _, target := range []string{"mod2", "admin"} {
+		if err := usecase.SetSuspended("mod", "moderator", target, true, "spam"); !errors.Is(err, ErrForbidden) {
+			t.Errorf("expected ErrForbidden for %s, got %v", target, err)
+		}
+		if mockDAO.suspended[target] {
+			t.Errorf("expected %s not to be suspended", target)
+		}
+	}
+	// 管理者はモデレーターを利用停止できるが、他の管理者はできない
+	if err := usecase.SetSuspended("admin", "admin", "mod2", true, "abuse"); err != nil {
+		t.Errorf("expected admin to suspend a moderator, got %v", err)
+	}
+	if err := usecase.SetSuspended("admin", "admin", "admin2", true, "abuse"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden for another admin, got %v", err)
+	}
+	if len(mockDAO.audit) != 2 {
+		t.Errorf("expected 2 audit events, got %d", len(mockDAO.audit))
+	}
+}
+
+// TestSetRole ロールの変更（不明なロール・自分自身は不可）
+func TestSetRole(t *testing.T) {
+	usecase, mockDAO, _ := newTestUsecase()
+
+	if err := usecase.SetRole("admin", "alice", "moderator", "joined the support team"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if mockDAO.roles["alice"] != "moderator" {
+		t.Errorf("expected moderator, got %s", mockDAO.roles["alice"])
+	}
+	if err := usecase.SetRole("admin", "alice", "root", "why not"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput, got %v", err)
+	}
+	if err := usecase.SetRole("admin", "admin", "user", "stepping down"); !errors.Is(err, ErrCannotTargetSelf) {
+		t.Errorf("expected ErrCannotTargetSelf, got %v", err)
+	}
+	if err := usecase.SetRole("admin", "nobody", "user", "cleanup"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+// TestListChainEvents chain_item_idで絞り込む
+func TestListChainEvents(t *testing.T) {
+	usecase, _, events := newTestUsecase()
+	_ = events.Record(&chainEventsDao.ChainEvent{EventType: "ItemListed", ChainItemID: 1})
+	_ = events.Record(&chainEventsDao.ChainEvent{EventType: "ItemPurchased", ChainItemID: 1})
+	_ = events.Record(&chainEventsDao.ChainEvent{EventType: "ItemListed", ChainItemID: 2})
+
+	all, err := usecase.ListChainEvents(0, 1, 20)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("expected 3 events, got %d", len(all))
+	}
+	filtered, _ := usecase.ListChainEvents(1, 1, 20)
+	if len(filtered) != 2 {
+		t.Errorf("expected 2 events, got %d", len(filtered))
+	}
+}
+
+// TestGetItemHistory 商品IDで監査ログを絞り込み、不正なIDはエラー
+func TestGetItemHistory(t *testing.T) {
+	audit := &MockAuditDAO{}
+	usecase := NewAdminUsecase(NewMockAdminDAO(), audit, &MockChainEventDAO{})
+
+	if _, err := usecase.GetItemHistory(42, 1, 20); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if audit.filter.EntityType != "item" || audit.filter.EntityID != "42" {
+		t.Errorf("unexpected filter: %+v", audit.filter)
+	}
+	if _, err := usecase.GetItemHistory(0, 1, 20); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput, got %v", err)
+	}
+}
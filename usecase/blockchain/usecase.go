@@ -1,28 +1,95 @@
 package blockchain
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
+	auditDao "uttc-hackathon-backend/dao/audit"
+	chainEventsDao "uttc-hackathon-backend/dao/chainEvents"
+	messagesDao "uttc-hackathon-backend/dao/messages"
 	postItemsDao "uttc-hackathon-backend/dao/postItems"
+	ordersDao "uttc-hackathon-backend/dao/orders"
 	purchaseItemDao "uttc-hackathon-backend/dao/purchaseItem"
+	"uttc-hackathon-backend/usecase/itemStatus"
+	notificationsUc "uttc-hackathon-backend/usecase/notifications"
+	ordersUc "uttc-hackathon-backend/usecase/orders"
+	postItemsUc "uttc-hackathon-backend/usecase/postItems"
 )
 
+// Timeline は出品の取り消しを商品のスレッドにシステムメッセージとして書き込む（messages.MessageUsecase）
+// 購入・受け取り確認はorderUcが書き込む
+type Timeline interface {
+	PostItemSystemMessage(itemID int, event string) error
+}
+
+// Notifier は出品者に購入を通知する（notifications.NotificationUsecase）
+type Notifier interface {
+	Notify(in notificationsUc.NotifyInput) error
+}
+
 type BlockchainUsecase struct {
 	itemDAO     *postItemsDao.ItemDAO
 	purchaseDAO *purchaseItemDao.PurchaseDAO
+	eventDAO    *chainEventsDao.ChainEventDAO
+	orderUc     *ordersUc.OrderUsecase
+	itemUc      *postItemsUc.ItemUsecase
+	timeline    Timeline
+	notifier    Notifier
 }
 
-func NewBlockchainUsecase(itemDAO *postItemsDao.ItemDAO, purchaseDAO *purchaseItemDao.PurchaseDAO) *BlockchainUsecase {
+func NewBlockchainUsecase(itemDAO *postItemsDao.ItemDAO, purchaseDAO *purchaseItemDao.PurchaseDAO, eventDAO *chainEventsDao.ChainEventDAO, orderUc *ordersUc.OrderUsecase, itemUc *postItemsUc.ItemUsecase, timeline Timeline, notifier Notifier) *BlockchainUsecase {
 	return &BlockchainUsecase{
 		itemDAO:     itemDAO,
 		purchaseDAO: purchaseDAO,
+		eventDAO:    eventDAO,
+		orderUc:     orderUc,
+		itemUc:      itemUc,
+		timeline:    timeline,
+		notifier:    notifier,
+	}
+}
+
+// notify は出品者に通知する（失敗してもイベントの処理は成功とする）
+func (uc *BlockchainUsecase) notify(in notificationsUc.NotifyInput) {
+	if uc.notifier == nil {
+		return
+	}
+	if err := uc.notifier.Notify(in); err != nil {
+		log.Printf("WARNING: failed to notify %s for item_id=%d: %v", in.Type, *in.ItemID, err)
+	}
+}
+
+// recordEvent は受け取ったイベントと処理結果を履歴に残す（記録に失敗しても処理は止めない）
+func (uc *BlockchainUsecase) recordEvent(eventType string, chainItemID int64, txHash string, payload map[string]interface{}, handleErr error) {
+	event := &chainEventsDao.ChainEvent{
+		EventType:   eventType,
+		ChainItemID: chainItemID,
+		TxHash:      txHash,
+		Status:      "processed",
+	}
+	if data, err := json.Marshal(payload); err == nil {
+		event.Payload = data
+	}
+	if handleErr != nil {
+		event.Status = "failed"
+		event.Error = handleErr.Error()
+	}
+	if err := uc.eventDAO.Record(event); err != nil {
+		log.Printf("WARNING: failed to record %s event for chain_item_id=%d: %v", eventType, chainItemID, err)
 	}
 }
 
 // HandleItemListed はonchainで商品が登録された際に呼ばれる
 // onchainのイベントから商品情報を取得してDBに挿入する
-func (uc *BlockchainUsecase) HandleItemListed(chainItemID int64, tokenID int64, title string, priceWei string, explanation string, imageURL string, uid string, category string, seller string, createdAt int64, txHash string) error {
+func (uc *BlockchainUsecase) HandleItemListed(chainItemID int64, tokenID int64, title string, priceWei string, explanation string, imageURL string, uid string, category string, seller string, createdAt int64, txHash string) (err error) {
+	defer func() {
+		uc.recordEvent("ItemListed", chainItemID, txHash, map[string]interface{}{
+			"token_id": tokenID, "title": title, "price_wei": priceWei, "image_url": imageURL,
+			"uid": uid, "category": category, "seller": seller, "created_at": createdAt,
+		}, err)
+	}()
 	log.Printf("HandleItemListed called: chain_item_id=%d, title=%s, uid=%s, seller=%s, price_wei=%s", chainItemID, title, uid, seller, priceWei)
 	
 	// バリデーション
@@ -43,7 +110,7 @@ func (uc *BlockchainUsecase) HandleItemListed(chainItemID int64, tokenID int64,
 	if err == nil && existingItemID > 0 {
 		log.Printf("Item with chain_item_id=%d already exists (item_id=%d), updating...", chainItemID, existingItemID)
 		// 既に存在する場合は更新のみ
-		if err := uc.itemDAO.UpdateChainItemID(existingItemID, chainItemID, seller, tokenID); err != nil {
+		if err := uc.itemDAO.UpdateChainItemID(existingItemID, chainItemID, seller, tokenID, auditDao.WebhookActor(seller, uid)); err != nil {
 			return fmt.Errorf("failed to update chain_item_id: %w", err)
 		}
 		log.Printf("Successfully updated existing item (item_id=%d)", existingItemID)
@@ -66,9 +133,10 @@ func (uc *BlockchainUsecase) HandleItemListed(chainItemID int64, tokenID int64,
 	}
 
 	// 新規商品を作成（chain_item_idを含む）
-	imageURLs := []string{}
+	// /uploadImageで保存された画像ならmedium/thumbのURLも導出できる
+	images := []postItemsDao.ItemImage{}
 	if imageURL != "" {
-		imageURLs = append(imageURLs, imageURL)
+		images = append(images, uc.itemUc.ItemImageFromURL(imageURL))
 	}
 
 	// 既存の商品（uidとtitleでマッチング、chain_item_idがNULL）を検索
@@ -76,7 +144,7 @@ func (uc *BlockchainUsecase) HandleItemListed(chainItemID int64, tokenID int64,
 	if err == nil && existingItemID > 0 {
 		log.Printf("Found existing item by uid and title (item_id=%d), linking chain_item_id...", existingItemID)
 		// 既存の商品にchain_item_idを関連付ける
-		if err := uc.itemDAO.UpdateChainItemID(existingItemID, chainItemID, seller, tokenID); err != nil {
+		if err := uc.itemDAO.UpdateChainItemID(existingItemID, chainItemID, seller, tokenID, auditDao.WebhookActor(seller, uid)); err != nil {
 			return fmt.Errorf("failed to update chain_item_id: %w", err)
 		}
 		log.Printf("Successfully linked chain_item_id=%d to existing item (item_id=%d)", chainItemID, existingItemID)
@@ -86,7 +154,7 @@ func (uc *BlockchainUsecase) HandleItemListed(chainItemID int64, tokenID int64,
 
 	// InsertItemWithChainIDを使用してchain_item_idを含めて挿入
 	log.Printf("Inserting new item: title=%s, price=%d, chain_item_id=%d, uid=%s", title, priceInt, chainItemID, uid)
-	if err := uc.itemDAO.InsertItemWithChainID(title, priceInt, explanation, imageURLs, uid, "listed", category, chainItemID, seller, tokenID); err != nil {
+	if err := uc.itemDAO.InsertItemWithChainID(title, priceInt, explanation, images, uid, "listed", category, chainItemID, seller, tokenID, auditDao.WebhookActor(seller, uid)); err != nil {
 		log.Printf("Error inserting item: %v", err)
 		return fmt.Errorf("failed to create item: %w", err)
 	}
@@ -96,7 +164,12 @@ func (uc *BlockchainUsecase) HandleItemListed(chainItemID int64, tokenID int64,
 }
 
 // HandleItemPurchased はonchainで商品が購入された際に呼ばれる
-func (uc *BlockchainUsecase) HandleItemPurchased(chainItemID int64, buyer string, priceWei string, tokenID int64, txHash string) error {
+func (uc *BlockchainUsecase) HandleItemPurchased(chainItemID int64, buyer string, priceWei string, tokenID int64, txHash string) (err error) {
+	defer func() {
+		uc.recordEvent("ItemPurchased", chainItemID, txHash, map[string]interface{}{
+			"buyer": buyer, "price_wei": priceWei, "token_id": tokenID,
+		}, err)
+	}()
 	log.Printf("HandleItemPurchased called: chain_item_id=%d, buyer=%s, txHash=%s", chainItemID, buyer, txHash)
 
 	// chain_item_idで商品を検索
@@ -116,38 +189,88 @@ func (uc *BlockchainUsecase) HandleItemPurchased(chainItemID int64, buyer string
 		log.Printf("Buyer UID not found for address=%s (this is OK if user not registered)", buyer)
 	}
 
-	// 購入状態を更新（buyer_addressも保存）
-	if err := uc.purchaseDAO.UpdatePurchaseStatus(int(itemID), buyerUID, buyer); err != nil {
-		return fmt.Errorf("failed to update purchase status: %w", err)
+	// cash購入と同じ経路で注文を作成（同じイベントの再送では既存の注文が返る）
+	order, err := uc.orderUc.PlaceOrder(ordersUc.PlaceOrderInput{
+		ItemID:        int(itemID),
+		BuyerUID:      buyerUID,
+		BuyerAddress:  buyer,
+		PaymentMethod: ordersDao.PaymentOnchain,
+		AmountWei:     priceWei,
+		TxHash:        txHash,
+		Actor:         auditDao.WebhookActor(buyer, buyerUID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to place order: %w", err)
 	}
 
-	log.Printf("Successfully updated purchase status: item_id=%d, chain_item_id=%d, buyer=%s", itemID, chainItemID, buyer)
+	log.Printf("Successfully placed order: order_id=%d, item_id=%d, chain_item_id=%d, buyer=%s", order.ID, itemID, chainItemID, buyer)
+
+	// 同じイベントの再送では同じ注文が返るので、通知は注文ごとに1件になる
+	notifyItemID := int(itemID)
+	uc.notify(notificationsUc.NotifyInput{
+		UID:      order.SellerUID,
+		Type:     notificationsUc.TypePurchase,
+		ActorUID: buyerUID,
+		ItemID:   &notifyItemID,
+		RefID:    order.ID,
+	})
 	return nil
 }
 
 // HandleReceiptConfirmed はonchainで商品受け取り確認された際に呼ばれる
-func (uc *BlockchainUsecase) HandleReceiptConfirmed(chainItemID int64, buyer string, seller string, priceWei string, txHash string) error {
+func (uc *BlockchainUsecase) HandleReceiptConfirmed(chainItemID int64, buyer string, seller string, priceWei string, txHash string) (err error) {
+	defer func() {
+		uc.recordEvent("ReceiptConfirmed", chainItemID, txHash, map[string]interface{}{
+			"buyer": buyer, "seller": seller, "price_wei": priceWei,
+		}, err)
+	}()
 	log.Printf("HandleReceiptConfirmed called: chain_item_id=%d, buyer=%s, seller=%s, txHash=%s", chainItemID, buyer, seller, txHash)
 
-	// ステータスをcompletedに更新
-	if err := uc.purchaseDAO.UpdateToCompleted(chainItemID); err != nil {
+	// 注文をdelivered → completedに遷移（商品もcompletedになる）
+	if err := uc.orderUc.ConfirmReceiptOnchain(chainItemID, auditDao.WebhookActor(buyer, "")); err != nil {
 		return fmt.Errorf("failed to update status to completed: %w", err)
 	}
 
+	// 出品者への通知は注文の完了と一緒に行う（OrderUsecase）
 	log.Printf("Successfully updated status to completed: chain_item_id=%d", chainItemID)
 	return nil
 }
 
 // HandleItemCancelled はonchainで商品がキャンセルされた際に呼ばれる
-func (uc *BlockchainUsecase) HandleItemCancelled(chainItemID int64, seller string, txHash string) error {
+func (uc *BlockchainUsecase) HandleItemCancelled(chainItemID int64, seller string, txHash string) (err error) {
+	defer func() {
+		uc.recordEvent("ItemCancelled", chainItemID, txHash, map[string]interface{}{"seller": seller}, err)
+	}()
 	log.Printf("HandleItemCancelled called: chain_item_id=%d, seller=%s, txHash=%s", chainItemID, seller, txHash)
 
+	itemID, status, err := uc.purchaseDAO.GetItemStatusByChainID(chainItemID)
+	if err != nil {
+		return fmt.Errorf("item not found for chain_item_id %d: %w", chainItemID, err)
+	}
+	if status == itemStatus.Cancelled {
+		// 同じイベントの再送
+		log.Printf("Item already cancelled: chain_item_id=%d", chainItemID)
+		return nil
+	}
+	if err := itemStatus.Lifecycle.Check(itemID, status, itemStatus.Cancelled); err != nil {
+		return err
+	}
+
 	// ステータスをcancelledに更新
-	if err := uc.purchaseDAO.UpdateToCancelled(chainItemID); err != nil {
+	err = uc.purchaseDAO.UpdateStatusByChainID(chainItemID, status, itemStatus.Cancelled, "item.cancel", auditDao.WebhookActor(seller, ""))
+	if errors.Is(err, purchaseItemDao.ErrStatusChanged) {
+		return fmt.Errorf("item status changed during cancel: %w", &itemStatus.ConflictError{ItemID: itemID, From: status, To: itemStatus.Cancelled})
+	}
+	if err != nil {
 		return fmt.Errorf("failed to update status to cancelled: %w", err)
 	}
 
 	log.Printf("Successfully updated status to cancelled: chain_item_id=%d", chainItemID)
+
+	// 問い合わせていた相手に出品の取り消しを知らせる（失敗してもイベントの処理は成功とする）
+	if err := uc.timeline.PostItemSystemMessage(itemID, messagesDao.SystemEventItemCancelled); err != nil {
+		log.Printf("WARNING: failed to post item cancelled messages for item_id=%d: %v", itemID, err)
+	}
 	return nil
 }
 
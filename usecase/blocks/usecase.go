@@ -0,0 +1,64 @@
+package blocks
+
+import (
+	"errors"
+	"fmt"
+	blocksDao "uttc-hackathon-backend/dao/blocks"
+)
+
+var (
+	// ErrCannotBlockSelf は自分自身をブロックしようとした場合のエラー
+	ErrCannotBlockSelf = errors.New("cannot block yourself")
+	// ErrUserNotFound はブロック対象のユーザーが存在しない場合のエラー
+	ErrUserNotFound = errors.New("user not found")
+)
+
+type BlockUsecase struct {
+	blockDao blocksDao.BlockDAOInterface
+}
+
+func NewBlockUsecase(dao blocksDao.BlockDAOInterface) *BlockUsecase {
+	return &BlockUsecase{blockDao: dao}
+}
+
+// Block はユーザーをブロックする
+// ブロックされた人はブロックした人にメッセージを送れなくなり、ブロックした人の出品も表示されなくなる
+func (u *BlockUsecase) Block(blockerUID, blockedUID string) error {
+	if blockerUID == blockedUID {
+		return ErrCannotBlockSelf
+	}
+	exists, err := u.blockDao.UserExists(blockedUID)
+	if err != nil {
+		return fmt.Errorf("failed to check user: %w", err)
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+	if err := u.blockDao.Block(blockerUID, blockedUID); err != nil {
+		return fmt.Errorf("failed to block: %w", err)
+	}
+	return nil
+}
+
+func (u *BlockUsecase) Unblock(blockerUID, blockedUID string) error {
+	if err := u.blockDao.Unblock(blockerUID, blockedUID); err != nil {
+		return fmt.Errorf("failed to unblock: %w", err)
+	}
+	return nil
+}
+
+func (u *BlockUsecase) IsBlocking(blockerUID, blockedUID string) (bool, error) {
+	blocking, err := u.blockDao.IsBlocking(blockerUID, blockedUID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check block status: %w", err)
+	}
+	return blocking, nil
+}
+
+func (u *BlockUsecase) GetBlockedUsers(uid string, page, limit int) ([]*blocksDao.BlockedUser, error) {
+	users, err := u.blockDao.GetBlockedUsers(uid, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocked users: %w", err)
+	}
+	return users, nil
+}
@@ -0,0 +1,100 @@
+package blocks
+
+import (
+	"errors"
+	"testing"
+
+	blocksDao "uttc-hackathon-backend/dao/blocks"
+)
+
+// MockBlockDAO はテスト用のモックDAO
+type MockBlockDAO struct {
+	users  map[string]bool
+	blocks map[[2]string]bool // {blocker, blocked}
+}
+
+func NewMockBlockDAO() *MockBlockDAO {
+	return &MockBlockDAO{
+		users:  map[string]bool{"alice": true, "bob": true},
+		blocks: make(map[[2]string]bool),
+	}
+}
+
+func (m *MockBlockDAO) UserExists(uid string) (bool, error) {
+	return m.users[uid], nil
+}
+
+func (m *MockBlockDAO) Block(blockerUID, blockedUID string) error {
+	m.blocks[[2]string{blockerUID, blockedUID}] = true
+	return nil
+}
+
+func (m *MockBlockDAO) Unblock(blockerUID, blockedUID string) error {
+	delete(m.blocks, [2]string{blockerUID, blockedUID})
+	return nil
+}
+
+func (m *MockBlockDAO) IsBlocking(blockerUID, blockedUID string) (bool, error) {
+	return m.blocks[[2]string{blockerUID, blockedUID}], nil
+}
+
+func (m *MockBlockDAO) GetBlockedUsers(uid string, limit, offset int) ([]*blocksDao.BlockedUser, error) {
+	var result []*blocksDao.BlockedUser
+	for key := range m.blocks {
+		if key[0] == uid {
+			result = append(result, &blocksDao.BlockedUser{UID: key[1]})
+		}
+	}
+	return result, nil
+}
+
+// TestBlock_Success ブロック・ブロック解除
+func TestBlock_Success(t *testing.T) {
+	mockDAO := NewMockBlockDAO()
+	usecase := NewBlockUsecase(mockDAO)
+
+	if err := usecase.Block("alice", "bob"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// 2回ブロックしてもエラーにならない
+	if err := usecase.Block("alice", "bob"); err != nil {
+		t.Fatalf("expected no error on duplicate block, got %v", err)
+	}
+	blocking, _ := usecase.IsBlocking("alice", "bob")
+	if !blocking {
+		t.Error("expected alice to block bob")
+	}
+	// ブロックは一方向
+	blocking, _ = usecase.IsBlocking("bob", "alice")
+	if blocking {
+		t.Error("expected bob not to block alice")
+	}
+	users, _ := usecase.GetBlockedUsers("alice", 1, 20)
+	if len(users) != 1 || users[0].UID != "bob" {
+		t.Errorf("expected bob in blocked users, got %+v", users)
+	}
+
+	if err := usecase.Unblock("alice", "bob"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	blocking, _ = usecase.IsBlocking("alice", "bob")
+	if blocking {
+		t.Error("expected alice not to block bob")
+	}
+}
+
+// TestBlock_Rejected 自分自身・存在しないユーザーはブロックできない
+func TestBlock_Rejected(t *testing.T) {
+	mockDAO := NewMockBlockDAO()
+	usecase := NewBlockUsecase(mockDAO)
+
+	if err := usecase.Block("alice", "alice"); !errors.Is(err, ErrCannotBlockSelf) {
+		t.Errorf("expected ErrCannotBlockSelf, got %v", err)
+	}
+	if err := usecase.Block("alice", "unknown"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+	if len(mockDAO.blocks) != 0 {
+		t.Errorf("expected no blocks, got %d", len(mockDAO.blocks))
+	}
+}
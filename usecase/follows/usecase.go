@@ -0,0 +1,70 @@
+package follows
+
+import (
+	"errors"
+	"fmt"
+	followsDao "uttc-hackathon-backend/dao/follows"
+)
+
+var (
+	// ErrCannotFollowSelf は自分自身をフォローしようとした場合のエラー
+	ErrCannotFollowSelf = errors.New("cannot follow yourself")
+	// ErrUserNotFound はフォロー先のユーザーが存在しない場合のエラー
+	ErrUserNotFound = errors.New("user not found")
+)
+
+type FollowUsecase struct {
+	followDao followsDao.FollowDAOInterface
+}
+
+func NewFollowUsecase(dao followsDao.FollowDAOInterface) *FollowUsecase {
+	return &FollowUsecase{followDao: dao}
+}
+
+func (u *FollowUsecase) Follow(followerUID, followeeUID string) error {
+	if followerUID == followeeUID {
+		return ErrCannotFollowSelf
+	}
+	exists, err := u.followDao.UserExists(followeeUID)
+	if err != nil {
+		return fmt.Errorf("failed to check user: %w", err)
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+	if err := u.followDao.Follow(followerUID, followeeUID); err != nil {
+		return fmt.Errorf("failed to follow: %w", err)
+	}
+	return nil
+}
+
+func (u *FollowUsecase) Unfollow(followerUID, followeeUID string) error {
+	if err := u.followDao.Unfollow(followerUID, followeeUID); err != nil {
+		return fmt.Errorf("failed to unfollow: %w", err)
+	}
+	return nil
+}
+
+func (u *FollowUsecase) IsFollowing(followerUID, followeeUID string) (bool, error) {
+	following, err := u.followDao.IsFollowing(followerUID, followeeUID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check follow status: %w", err)
+	}
+	return following, nil
+}
+
+func (u *FollowUsecase) GetFollowers(uid string, page, limit int) ([]*followsDao.FollowUser, error) {
+	users, err := u.followDao.GetFollowers(uid, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get followers: %w", err)
+	}
+	return users, nil
+}
+
+func (u *FollowUsecase) GetFollowing(uid string, page, limit int) ([]*followsDao.FollowUser, error) {
+	users, err := u.followDao.GetFollowing(uid, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get following: %w", err)
+	}
+	return users, nil
+}
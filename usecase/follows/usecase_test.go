@@ -0,0 +1,105 @@
+package follows
+
+import (
+	"errors"
+	"testing"
+
+	followsDao "uttc-hackathon-backend/dao/follows"
+)
+
+// MockFollowDAO はテスト用のモックDAO
+type MockFollowDAO struct {
+	users   map[string]bool
+	follows map[[2]string]bool // {follower, followee} -> following
+}
+
+func NewMockFollowDAO() *MockFollowDAO {
+	return &MockFollowDAO{
+		users:   map[string]bool{"alice": true, "bob": true},
+		follows: make(map[[2]string]bool),
+	}
+}
+
+func (m *MockFollowDAO) UserExists(uid string) (bool, error) {
+	return m.users[uid], nil
+}
+
+func (m *MockFollowDAO) Follow(followerUID, followeeUID string) error {
+	m.follows[[2]string{followerUID, followeeUID}] = true
+	return nil
+}
+
+func (m *MockFollowDAO) Unfollow(followerUID, followeeUID string) error {
+	delete(m.follows, [2]string{followerUID, followeeUID})
+	return nil
+}
+
+func (m *MockFollowDAO) IsFollowing(followerUID, followeeUID string) (bool, error) {
+	return m.follows[[2]string{followerUID, followeeUID}], nil
+}
+
+func (m *MockFollowDAO) GetFollowers(uid string, limit, offset int) ([]*followsDao.FollowUser, error) {
+	var result []*followsDao.FollowUser
+	for key := range m.follows {
+		if key[1] == uid {
+			result = append(result, &followsDao.FollowUser{UID: key[0]})
+		}
+	}
+	return result, nil
+}
+
+func (m *MockFollowDAO) GetFollowing(uid string, limit, offset int) ([]*followsDao.FollowUser, error) {
+	var result []*followsDao.FollowUser
+	for key := range m.follows {
+		if key[0] == uid {
+			result = append(result, &followsDao.FollowUser{UID: key[1]})
+		}
+	}
+	return result, nil
+}
+
+// TestFollow_Success フォロー・フォロー解除
+func TestFollow_Success(t *testing.T) {
+	mockDAO := NewMockFollowDAO()
+	usecase := NewFollowUsecase(mockDAO)
+
+	if err := usecase.Follow("alice", "bob"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// 2回フォローしてもエラーにならない
+	if err := usecase.Follow("alice", "bob"); err != nil {
+		t.Fatalf("expected no error on duplicate follow, got %v", err)
+	}
+	following, _ := usecase.IsFollowing("alice", "bob")
+	if !following {
+		t.Error("expected alice to follow bob")
+	}
+	followers, _ := usecase.GetFollowers("bob", 1, 20)
+	if len(followers) != 1 || followers[0].UID != "alice" {
+		t.Errorf("expected alice in followers, got %+v", followers)
+	}
+
+	if err := usecase.Unfollow("alice", "bob"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	following, _ = usecase.IsFollowing("alice", "bob")
+	if following {
+		t.Error("expected alice not to follow bob")
+	}
+}
+
+// TestFollow_Rejected 自分自身・存在しないユーザーはフォローできない
+func TestFollow_Rejected(t *testing.T) {
+	mockDAO := NewMockFollowDAO()
+	usecase := NewFollowUsecase(mockDAO)
+
+	if err := usecase.Follow("alice", "alice"); !errors.Is(err, ErrCannotFollowSelf) {
+		t.Errorf("expected ErrCannotFollowSelf, got %v", err)
+	}
+	if err := usecase.Follow("alice", "unknown"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+	if len(mockDAO.follows) != 0 {
+		t.Errorf("expected no follows, got %d", len(mockDAO.follows))
+	}
+}
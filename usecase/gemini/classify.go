@@ -0,0 +1,82 @@
+package gemini
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// classifyTimeout はメッセージの分類を待つ時間（送信を待たせすぎないように短くする）
+const classifyTimeout = 5 * time.Second
+
+const classifyPrompt = `あなたはフリマアプリethershopのダイレクトメッセージを確認するモデレーターです。
+ユーザーが送ったメッセージを次のいずれか1つに分類し、その単語だけを返してください。
+
+ok: 問題のない取引の連絡や雑談
+spam: 宣伝、無関係なリンク、同じ内容の大量送信
+scam: アプリの外での支払い（銀行振込・現金・直接の送金）やアプリの外での取引への誘導、詐欺
+harassment: 脅迫、暴言、嫌がらせ`
+
+// MessageLabels は ClassifyMessage が返す分類
+var MessageLabels = map[string]bool{"ok": true, "spam": true, "scam": true, "harassment": true}
+
+// ClassifyMessage はダイレクトメッセージをLLMで ok, spam, scam, harassment のいずれかに分類する
+func (uc *ChatUsecase) ClassifyMessage(content string) (string, error) {
+	if uc.apiKey == "" {
+		return "", fmt.Errorf("OPENAI_API_KEY environment variable is not set")
+	}
+
+	jsonData, err := json.Marshal(OpenAIRequest{
+		Model: "gpt-4o-mini",
+		Messages: []OpenAIMessage{
+			{Role: "system", Content: classifyPrompt},
+			{Role: "user", Content: content},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+uc.apiKey)
+
+	client := &http.Client{Timeout: classifyTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var openaiResp OpenAIResponse
+	if err := json.Unmarshal(body, &openaiResp); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if openaiResp.Error != nil {
+		return "", fmt.Errorf("OpenAI API error: %s", openaiResp.Error.Message)
+	}
+	if len(openaiResp.Choices) == 0 {
+		return "", fmt.Errorf("no choices in response")
+	}
+
+	label := strings.ToLower(strings.Trim(strings.TrimSpace(openaiResp.Choices[0].Message.Content), ".。"))
+	if !MessageLabels[label] {
+		return "", fmt.Errorf("unexpected label %q", label)
+	}
+	return label, nil
+}
@@ -13,34 +13,50 @@ func NewItemUsecase(dao *getItemDao.ItemDAO) *ItemUsecase {
 	return &ItemUsecase{getItemDao: dao}
 }
 
-func (u *ItemUsecase) GetItemsByCategory(category string, page, limit int) ([]*getItemDao.Item, error) {
-	items, err := u.getItemDao.GetItemsByCategory(category, page, limit)
+// viewerUIDは閲覧者のuid（未ログインなら空）。ブロック・非表示の商品を除くために使う
+func (u *ItemUsecase) GetItemsByCategory(category, viewerUID string, page, limit int) ([]*getItemDao.Item, error) {
+	items, err := u.getItemDao.GetItemsByCategory(category, viewerUID, page, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get items: %w", err)
 	}
 	return items, nil
 }
 
-func (u *ItemUsecase) GetItemByID(id int) (*getItemDao.Item, error) {
-	item, err := u.getItemDao.GetItemByID(id)
+func (u *ItemUsecase) GetItemByID(id int, viewerUID string) (*getItemDao.Item, error) {
+	item, err := u.getItemDao.GetItemByID(id, viewerUID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get item: %w", err)
 	}
 	return item, nil
 }
 
-func (u *ItemUsecase) GetItemsByUid(uid string) ([]*getItemDao.Item, error) {
-	items, err := u.getItemDao.GetItemsByUid(uid)
+func (u *ItemUsecase) GetItemsByUid(uid, viewerUID string) ([]*getItemDao.Item, error) {
+	items, err := u.getItemDao.GetItemsByUid(uid, viewerUID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get items by uid: %w", err)
 	}
 	return items, nil
 }
 
-func (u *ItemUsecase) GetLatestItems(limit int) ([]*getItemDao.Item, error) {
-	items, err := u.getItemDao.GetLatestItems(limit)
+func (u *ItemUsecase) GetLatestItems(limit int, viewerUID string) ([]*getItemDao.Item, error) {
+	items, err := u.getItemDao.GetLatestItems(limit, viewerUID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get latest items: %w", err)
 	}
 	return items, nil
 }
+
+// GetFeed はフォロー中の出品者の新着出品を取得する
+func (u *ItemUsecase) GetFeed(uid string, page, limit int) ([]*getItemDao.Item, error) {
+	if uid == "" {
+		return nil, fmt.Errorf("uid is required")
+	}
+	items, err := u.getItemDao.GetFeedItems(uid, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed: %w", err)
+	}
+	if items == nil {
+		items = []*getItemDao.Item{}
+	}
+	return items, nil
+}
@@ -0,0 +1,118 @@
+package itemStatus
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 商品の状態（items.statusのENUM）
+const (
+	Listed    = "listed"
+	Purchased = "purchased"
+	Completed = "completed"
+	Cancelled = "cancelled"
+)
+
+// ErrUnknownStatus は商品の状態として存在しない値が渡された場合のエラー
+var ErrUnknownStatus = errors.New("unknown item status")
+
+// ConflictError は現在の状態から遷移できない場合のエラー（HTTPでは409として扱う）
+type ConflictError struct {
+	ItemID int
+	From   string
+	To     string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("item %d cannot change status from %s to %s", e.ItemID, e.From, e.To)
+}
+
+// IsConflict はerrが（ラップされた）ConflictErrorか
+func IsConflict(err error) bool {
+	var conflict *ConflictError
+	return errors.As(err, &conflict)
+}
+
+// Machine は商品の状態遷移表（キーの状態から値の状態へ遷移できる）
+type Machine struct {
+	transitions map[string][]string
+	overrides   map[string][]string // 管理者の強制変更だけで行える遷移
+}
+
+// Lifecycle は商品の状態遷移
+//
+//	listed    → purchased（購入） / cancelled（onchainでの出品取り消し）
+//	purchased → completed（取引完了） / listed（cash購入のキャンセル・返金による再出品）
+//	completed / cancelled は終端
+//
+// 管理者による強制変更（admin.ForceItemStatus）では、上の遷移に加えて次の遷移もできる（CheckOverride）
+//
+//	purchased → cancelled（不正な取引の取り消し）
+//	completed → purchased（誤って完了した取引の差し戻し）
+//	cancelled → listed（誤って取り消した出品の再開）
+var Lifecycle = &Machine{
+	transitions: map[string][]string{
+		Listed:    {Purchased, Cancelled},
+		Purchased: {Completed, Listed},
+		Completed: {},
+		Cancelled: {},
+	},
+	overrides: map[string][]string{
+		Purchased: {Cancelled},
+		Completed: {Purchased},
+		Cancelled: {Listed},
+	},
+}
+
+// Valid はstatusが商品の状態として存在するか
+func (m *Machine) Valid(status string) bool {
+	_, ok := m.transitions[status]
+	return ok
+}
+
+// CanTransition は商品をfromからtoへ遷移できるか（同じ状態への遷移は含まない）
+func (m *Machine) CanTransition(from, to string) bool {
+	for _, next := range m.transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Check は商品itemIDをfromからtoへ遷移できるか検証する
+// 存在しない状態はErrUnknownStatus、遷移できない場合は*ConflictErrorを返す
+func (m *Machine) Check(itemID int, from, to string) error {
+	return m.check(itemID, from, to, m.CanTransition)
+}
+
+// CanOverride は管理者の強制変更で商品をfromからtoへ遷移できるか（通常の遷移を含む）
+func (m *Machine) CanOverride(from, to string) bool {
+	if m.CanTransition(from, to) {
+		return true
+	}
+	for _, next := range m.overrides[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckOverride は管理者の強制変更として商品itemIDをfromからtoへ遷移できるか検証する（エラーはCheckと同じ）
+func (m *Machine) CheckOverride(itemID int, from, to string) error {
+	return m.check(itemID, from, to, m.CanOverride)
+}
+
+func (m *Machine) check(itemID int, from, to string, can func(from, to string) bool) error {
+	if !m.Valid(from) {
+		return fmt.Errorf("%w: %q", ErrUnknownStatus, from)
+	}
+	if !m.Valid(to) {
+		return fmt.Errorf("%w: %q", ErrUnknownStatus, to)
+	}
+	if !can(from, to) {
+		return &ConflictError{ItemID: itemID, From: from, To: to}
+	}
+	return nil
+}
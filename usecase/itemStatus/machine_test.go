@@ -0,0 +1,116 @@
+package itemStatus
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+var allStatuses = []string{Listed, Purchased, Completed, Cancelled}
+
+// TestLifecycle_Check すべての状態の組み合わせで遷移の可否を検証
+func TestLifecycle_Check(t *testing.T) {
+	allowed := map[[2]string]bool{
+		{Listed, Purchased}:    true,
+		{Listed, Cancelled}:    true,
+		{Purchased, Completed}: true,
+		{Purchased, Listed}:    true,
+	}
+
+	for _, from := range allStatuses {
+		for _, to := range allStatuses {
+			t.Run(from+"->"+to, func(t *testing.T) {
+				err := Lifecycle.Check(1, from, to)
+				if allowed[[2]string{from, to}] {
+					if err != nil {
+						t.Errorf("expected transition to be allowed, got %v", err)
+					}
+					return
+				}
+
+				var conflict *ConflictError
+				if !errors.As(err, &conflict) {
+					t.Fatalf("expected ConflictError, got %v", err)
+				}
+				if conflict.ItemID != 1 || conflict.From != from || conflict.To != to {
+					t.Errorf("unexpected conflict: %+v", conflict)
+				}
+			})
+		}
+	}
+}
+
+// TestLifecycle_CheckOverride 管理者の強制変更は通常の遷移と管理者だけの遷移だけを許す
+func TestLifecycle_CheckOverride(t *testing.T) {
+	allowed := map[[2]string]bool{
+		{Listed, Purchased}:    true,
+		{Listed, Cancelled}:    true,
+		{Purchased, Completed}: true,
+		{Purchased, Listed}:    true,
+		{Purchased, Cancelled}: true,
+		{Completed, Purchased}: true,
+		{Cancelled, Listed}:    true,
+	}
+
+	for _, from := range allStatuses {
+		for _, to := range allStatuses {
+			err := Lifecycle.CheckOverride(1, from, to)
+			if allowed[[2]string{from, to}] {
+				if err != nil {
+					t.Errorf("%s->%s: expected override to be allowed, got %v", from, to, err)
+				}
+				continue
+			}
+			if !IsConflict(err) {
+				t.Errorf("%s->%s: expected ConflictError, got %v", from, to, err)
+			}
+		}
+	}
+	if err := Lifecycle.CheckOverride(1, Listed, "sold"); !errors.Is(err, ErrUnknownStatus) {
+		t.Errorf("expected ErrUnknownStatus, got %v", err)
+	}
+}
+
+// TestLifecycle_UnknownStatus 存在しない状態は遷移の競合ではなく入力エラー
+func TestLifecycle_UnknownStatus(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+	}{
+		{"sold", Listed},
+		{Listed, "sold"},
+		{"", Purchased},
+		{Purchased, ""},
+	}
+	for _, tt := range tests {
+		err := Lifecycle.Check(1, tt.from, tt.to)
+		if !errors.Is(err, ErrUnknownStatus) {
+			t.Errorf("Check(%q, %q): expected ErrUnknownStatus, got %v", tt.from, tt.to, err)
+		}
+		if IsConflict(err) {
+			t.Errorf("Check(%q, %q): unknown status should not be a conflict", tt.from, tt.to)
+		}
+	}
+}
+
+// TestLifecycle_Terminal completed / cancelled からはどこへも遷移できない
+func TestLifecycle_Terminal(t *testing.T) {
+	for _, from := range []string{Completed, Cancelled} {
+		for _, to := range allStatuses {
+			if Lifecycle.CanTransition(from, to) {
+				t.Errorf("expected %s to be terminal, but can transition to %s", from, to)
+			}
+		}
+	}
+}
+
+// TestIsConflict ラップされたConflictErrorも判定できるか
+func TestIsConflict(t *testing.T) {
+	err := fmt.Errorf("failed: %w", &ConflictError{ItemID: 1, From: Completed, To: Listed})
+	if !IsConflict(err) {
+		t.Error("expected wrapped ConflictError to be detected")
+	}
+	if IsConflict(errors.New("other")) || IsConflict(nil) {
+		t.Error("expected other errors not to be conflicts")
+	}
+}
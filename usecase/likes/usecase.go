@@ -2,15 +2,23 @@ package likes
 
 import (
 	"fmt"
+	"log"
 	likesDao "uttc-hackathon-backend/dao/likes"
+	notificationsUc "uttc-hackathon-backend/usecase/notifications"
 )
 
+// Notifier は出品者にいいねを通知する（notifications.NotificationUsecase）
+type Notifier interface {
+	Notify(in notificationsUc.NotifyInput) error
+}
+
 type LikeUsecase struct {
-	likeDao likesDao.LikeDAOInterface
+	likeDao  likesDao.LikeDAOInterface
+	notifier Notifier
 }
 
-func NewLikeUsecase(dao likesDao.LikeDAOInterface) *LikeUsecase {
-	return &LikeUsecase{likeDao: dao}
+func NewLikeUsecase(dao likesDao.LikeDAOInterface, notifier Notifier) *LikeUsecase {
+	return &LikeUsecase{likeDao: dao, notifier: notifier}
 }
 
 func (u *LikeUsecase) AddLike(itemID int, uid string) error {
@@ -18,6 +26,14 @@ func (u *LikeUsecase) AddLike(itemID int, uid string) error {
 	if err != nil {
 		return fmt.Errorf("failed to add like: %w", err)
 	}
+
+	// 出品者に通知する（失敗してもいいねは成功とする）
+	if u.notifier != nil {
+		err := u.notifier.Notify(notificationsUc.NotifyInput{Type: notificationsUc.TypeLike, ActorUID: uid, ItemID: &itemID})
+		if err != nil {
+			log.Printf("WARNING: failed to notify like on item_id=%d by uid=%s: %v", itemID, uid, err)
+		}
+	}
 	return nil
 }
 
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	notificationsUc "uttc-hackathon-backend/usecase/notifications"
 )
 
 // MockLikeDAO はテスト用のモックDAO
@@ -85,10 +86,21 @@ func (m *MockLikeDAO) GetLikedItemsByUser(uid string) ([]int, error) {
 	return items, nil
 }
 
+// MockNotifier はテスト用の通知（受け取った通知を記録する）
+type MockNotifier struct {
+	notified []notificationsUc.NotifyInput
+	err      error
+}
+
+func (m *MockNotifier) Notify(in notificationsUc.NotifyInput) error {
+	m.notified = append(m.notified, in)
+	return m.err
+}
+
 // TestAddLike_Success いいね追加成功
 func TestAddLike_Success(t *testing.T) {
 	mockDAO := NewMockLikeDAO()
-	usecase := NewLikeUsecase(mockDAO)
+	usecase := NewLikeUsecase(mockDAO, nil)
 
 	err := usecase.AddLike(1, "user123")
 	if err != nil {
@@ -111,7 +123,7 @@ func TestAddLike_Success(t *testing.T) {
 // TestAddLike_Duplicate 重複いいねエラー
 func TestAddLike_Duplicate(t *testing.T) {
 	mockDAO := NewMockLikeDAO()
-	usecase := NewLikeUsecase(mockDAO)
+	usecase := NewLikeUsecase(mockDAO, nil)
 
 	// 1回目は成功
 	_ = usecase.AddLike(1, "user123")
@@ -127,7 +139,7 @@ func TestAddLike_Duplicate(t *testing.T) {
 func TestAddLike_DAOError(t *testing.T) {
 	mockDAO := NewMockLikeDAO()
 	mockDAO.addLikeErr = errors.New("database error")
-	usecase := NewLikeUsecase(mockDAO)
+	usecase := NewLikeUsecase(mockDAO, nil)
 
 	err := usecase.AddLike(1, "user123")
 	if err == nil {
@@ -138,7 +150,7 @@ func TestAddLike_DAOError(t *testing.T) {
 // TestRemoveLike_Success いいね削除成功
 func TestRemoveLike_Success(t *testing.T) {
 	mockDAO := NewMockLikeDAO()
-	usecase := NewLikeUsecase(mockDAO)
+	usecase := NewLikeUsecase(mockDAO, nil)
 
 	// まずいいねを追加
 	_ = usecase.AddLike(1, "user123")
@@ -165,7 +177,7 @@ func TestRemoveLike_Success(t *testing.T) {
 // TestRemoveLike_NotLiked いいねしていない商品の削除
 func TestRemoveLike_NotLiked(t *testing.T) {
 	mockDAO := NewMockLikeDAO()
-	usecase := NewLikeUsecase(mockDAO)
+	usecase := NewLikeUsecase(mockDAO, nil)
 
 	// いいねしていない商品を削除してもエラーにならない
 	err := usecase.RemoveLike(1, "user123")
@@ -177,7 +189,7 @@ func TestRemoveLike_NotLiked(t *testing.T) {
 // TestIsLiked_True いいね済み
 func TestIsLiked_True(t *testing.T) {
 	mockDAO := NewMockLikeDAO()
-	usecase := NewLikeUsecase(mockDAO)
+	usecase := NewLikeUsecase(mockDAO, nil)
 
 	_ = usecase.AddLike(1, "user123")
 
@@ -193,7 +205,7 @@ func TestIsLiked_True(t *testing.T) {
 // TestIsLiked_False いいねしていない
 func TestIsLiked_False(t *testing.T) {
 	mockDAO := NewMockLikeDAO()
-	usecase := NewLikeUsecase(mockDAO)
+	usecase := NewLikeUsecase(mockDAO, nil)
 
 	liked, err := usecase.IsLiked(1, "user123")
 	if err != nil {
@@ -207,7 +219,7 @@ func TestIsLiked_False(t *testing.T) {
 // TestGetLikeCount カウント取得
 func TestGetLikeCount(t *testing.T) {
 	mockDAO := NewMockLikeDAO()
-	usecase := NewLikeUsecase(mockDAO)
+	usecase := NewLikeUsecase(mockDAO, nil)
 
 	// 複数ユーザーがいいね
 	_ = usecase.AddLike(1, "user1")
@@ -226,7 +238,7 @@ func TestGetLikeCount(t *testing.T) {
 // TestGetLikedItemsByUser ユーザーのいいね一覧
 func TestGetLikedItemsByUser(t *testing.T) {
 	mockDAO := NewMockLikeDAO()
-	usecase := NewLikeUsecase(mockDAO)
+	usecase := NewLikeUsecase(mockDAO, nil)
 
 	// ユーザーが複数商品にいいね
 	_ = usecase.AddLike(1, "user123")
@@ -243,3 +255,27 @@ func TestGetLikedItemsByUser(t *testing.T) {
 		t.Errorf("expected 3 items, got %d", len(items))
 	}
 }
+
+// TestAddLike_Notifies いいねすると出品者に通知し、通知に失敗してもいいねは成功する
+func TestAddLike_Notifies(t *testing.T) {
+	mockDAO := NewMockLikeDAO()
+	notifier := &MockNotifier{err: errors.New("db error")}
+	usecase := NewLikeUsecase(mockDAO, notifier)
+
+	if err := usecase.AddLike(1, "user123"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(notifier.notified) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(notifier.notified))
+	}
+	in := notifier.notified[0]
+	if in.Type != notificationsUc.TypeLike || in.ActorUID != "user123" || in.ItemID == nil || *in.ItemID != 1 || in.UID != "" {
+		t.Errorf("unexpected notification: %+v", in)
+	}
+
+	// 重複したいいねは通知しない
+	_ = usecase.AddLike(1, "user123")
+	if len(notifier.notified) != 1 {
+		t.Errorf("expected no notification for duplicate like, got %d", len(notifier.notified))
+	}
+}
@@ -0,0 +1,296 @@
+package messages
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// フィルタの既定値
+const (
+	maxMessageLength    = 2000
+	burstMessageLimit   = 10 // 1分あたりの送信数の上限
+	burstMessageWindow  = time.Minute
+	hourlyMessageLimit  = 100 // 1時間あたりの送信数の上限
+	hourlyMessageWindow = time.Hour
+)
+
+var (
+	// ErrMessageTooLong は本文が長すぎる場合のエラー
+	ErrMessageTooLong = fmt.Errorf("message must be at most %d characters", maxMessageLength)
+	// ErrRateLimited は短時間に送りすぎた場合のエラー
+	ErrRateLimited = errors.New("too many messages, please wait before sending more")
+	// ErrMessageRejected はフィルタにより送信できない場合のエラー
+	ErrMessageRejected = errors.New("message was rejected by the content filter")
+)
+
+// FilterAction はコンテンツフィルタの判定
+type FilterAction string
+
+const (
+	FilterAllow      FilterAction = "allow"
+	FilterFlag       FilterAction = "flag"       // 届けるが、モデレーションキューに入れる
+	FilterQuarantine FilterAction = "quarantine" // モデレーションキューに入れ、モデレーターが却下するまで受信者には見せない
+	FilterReject     FilterAction = "reject"     // 送信させない
+)
+
+// filterSeverity は判定の強さ（チェーンでは最も強い判定を採用する）
+var filterSeverity = map[FilterAction]int{
+	FilterAllow:      0,
+	FilterFlag:       1,
+	FilterQuarantine: 2,
+	FilterReject:     3,
+}
+
+// FilterInput はフィルタに渡すメッセージ
+type FilterInput struct {
+	SenderUID   string
+	ReceiverUID string
+	ItemID      *int
+	Content     string
+	IsEdit      bool // 送信済みのメッセージの編集（送信数には数えない）
+}
+
+// FilterVerdict はフィルタの判定
+type FilterVerdict struct {
+	Action FilterAction
+	Filter string // 判定したフィルタの名前（Runが設定する）
+	Reason string // モデレーションキューに入れるときの通報理由（spam, fraud, inappropriate, harassment）
+	Detail string // モデレーター向けの説明
+	Err    error  // FilterRejectのときに送信者に返すエラー（nilならErrMessageRejected）
+}
+
+// rejectError は送信を拒否したときに送信者に返すエラー
+func (v *FilterVerdict) rejectError() error {
+	if v.Err != nil {
+		return v.Err
+	}
+	return ErrMessageRejected
+}
+
+// ContentFilter はメッセージの内容を確認するフィルタ（問題がなければnilを返す）
+type ContentFilter interface {
+	Name() string
+	Check(in *FilterInput) (*FilterVerdict, error)
+}
+
+// FilterChain はフィルタを順に適用する。重いフィルタ（LLMの分類など）は後ろに置く
+type FilterChain []ContentFilter
+
+// Run はフィルタを順に適用して最も強い判定を返す（送信拒否になった時点で残りのフィルタは実行しない）
+func (c FilterChain) Run(in *FilterInput) (*FilterVerdict, error) {
+	result := &FilterVerdict{Action: FilterAllow}
+	for _, f := range c {
+		verdict, err := f.Check(in)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run %s filter: %w", f.Name(), err)
+		}
+		if verdict == nil || filterSeverity[verdict.Action] <= filterSeverity[result.Action] {
+			continue
+		}
+		verdict.Filter = f.Name()
+		result = verdict
+		if verdict.Action == FilterReject {
+			break
+		}
+	}
+	return result, nil
+}
+
+// NewDefaultFilterChain は既定のフィルタ（文字数・送信数・禁止語・外部での支払いへの誘導）を返す
+// blockedWordsが空なら既定の禁止語を使う
+func NewDefaultFilterChain(counter MessageCounter, blockedWords []string) FilterChain {
+	if len(blockedWords) == 0 {
+		blockedWords = defaultBlockedWords
+	}
+	return FilterChain{
+		&LengthFilter{Max: maxMessageLength},
+		NewRateLimitFilter(counter, burstMessageLimit, burstMessageWindow),
+		NewRateLimitFilter(counter, hourlyMessageLimit, hourlyMessageWindow),
+		NewBlockedWordFilter(blockedWords, FilterQuarantine, "harassment"),
+		&PaymentFilter{},
+	}
+}
+
+// LengthFilter は本文の文字数を制限する
+type LengthFilter struct {
+	Max int
+}
+
+func (f *LengthFilter) Name() string { return "length" }
+
+func (f *LengthFilter) Check(in *FilterInput) (*FilterVerdict, error) {
+	if utf8.RuneCountInString(in.Content) > f.Max {
+		return &FilterVerdict{Action: FilterReject, Err: ErrMessageTooLong}, nil
+	}
+	return nil, nil
+}
+
+// MessageCounter は送信者の直近の送信数を数える（MessageDAOが実装する）
+type MessageCounter interface {
+	CountSentSince(senderUID string, since time.Time) (int, error)
+}
+
+// RateLimitFilter は送信者ごとにwindowあたりの送信数を制限する
+// DBの送信履歴で数えるので、複数インスタンスで動かしても同じ上限になる
+type RateLimitFilter struct {
+	counter MessageCounter
+	limit   int
+	window  time.Duration
+	now     func() time.Time
+}
+
+func NewRateLimitFilter(counter MessageCounter, limit int, window time.Duration) *RateLimitFilter {
+	return &RateLimitFilter{counter: counter, limit: limit, window: window, now: time.Now}
+}
+
+func (f *RateLimitFilter) Name() string { return fmt.Sprintf("rate_limit_%s", f.window) }
+
+func (f *RateLimitFilter) Check(in *FilterInput) (*FilterVerdict, error) {
+	if in.IsEdit {
+		return nil, nil
+	}
+	sent, err := f.counter.CountSentSince(in.SenderUID, f.now().Add(-f.window))
+	if err != nil {
+		return nil, err
+	}
+	if sent >= f.limit {
+		return &FilterVerdict{Action: FilterReject, Err: ErrRateLimited}, nil
+	}
+	return nil, nil
+}
+
+// defaultBlockedWords は既定の禁止語（MESSAGE_BLOCKED_WORDSで置き換えられる）
+var defaultBlockedWords = []string{"死ね", "殺すぞ", "kill yourself"}
+
+// BlockedWordFilter は禁止語を含むメッセージにactionを適用する
+// 大文字・小文字、全角・半角の英数字、空白の有無の違いは同じとみなす
+type BlockedWordFilter struct {
+	words  []string
+	action FilterAction
+	reason string
+}
+
+func NewBlockedWordFilter(words []string, action FilterAction, reason string) *BlockedWordFilter {
+	f := &BlockedWordFilter{action: action, reason: reason}
+	for _, w := range words {
+		if w = normalizeForMatch(w); w != "" {
+			f.words = append(f.words, w)
+		}
+	}
+	return f
+}
+
+func (f *BlockedWordFilter) Name() string { return "blocked_word" }
+
+func (f *BlockedWordFilter) Check(in *FilterInput) (*FilterVerdict, error) {
+	content := normalizeForMatch(in.Content)
+	for _, w := range f.words {
+		if strings.Contains(content, w) {
+			return &FilterVerdict{Action: f.action, Reason: f.reason, Detail: fmt.Sprintf("blocked word %q", w)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// normalizeForMatch は全角英数字を半角にし、小文字にして空白を除く
+func normalizeForMatch(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '！' && r <= '～' {
+			r -= '！' - '!'
+		}
+		if unicode.IsSpace(r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// 外部での支払いへの誘導の検出に使うパターン
+var (
+	paymentWordPattern = regexp.MustCompile(`(?i)振込|振り込|銀行|口座|現金|手渡し|直接取引|直接やり取り|paypay|ペイペイ|paypal|venmo|banktransfer|wiretransfer|westernunion|cashapp`)
+	walletPattern      = regexp.MustCompile(`0x[0-9a-fA-F]{40}`)
+	contactPatterns    = []*regexp.Regexp{
+		regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), // メールアドレス
+		regexp.MustCompile(`0\d{1,4}-?\d{1,4}-?\d{4}`),                         // 電話番号
+		regexp.MustCompile(`(?i)line\s*id|ライン\s*id|LINE交換|ライン交換`),
+		regexp.MustCompile(`(?i)https?://`),
+	}
+)
+
+// PaymentFilter はアプリの外での支払い（振込・現金・直接の送金）への誘導を検出する
+// 支払いの話題か送金先のウォレットアドレスだけならフラグ、連絡先・ウォレットアドレスと一緒なら保留にする
+type PaymentFilter struct{}
+
+func (f *PaymentFilter) Name() string { return "off_platform_payment" }
+
+func (f *PaymentFilter) Check(in *FilterInput) (*FilterVerdict, error) {
+	// 支払いの語は空白を除いて探す（"bank transfer" は "banktransfer" として探す）
+	payment := paymentWordPattern.FindString(normalizeForMatch(in.Content))
+	wallet := walletPattern.MatchString(in.Content)
+	contact := wallet
+	for _, p := range contactPatterns {
+		if p.MatchString(in.Content) {
+			contact = true
+		}
+	}
+
+	switch {
+	case payment != "" && contact:
+		return &FilterVerdict{Action: FilterQuarantine, Reason: "fraud", Detail: fmt.Sprintf("payment term %q with contact details", payment)}, nil
+	case payment != "":
+		return &FilterVerdict{Action: FilterFlag, Reason: "fraud", Detail: fmt.Sprintf("payment term %q", payment)}, nil
+	case wallet:
+		return &FilterVerdict{Action: FilterFlag, Reason: "fraud", Detail: "wallet address"}, nil
+	}
+	return nil, nil
+}
+
+// MessageClassifier はLLMでメッセージを分類する（geminiのChatUsecaseが実装する）
+// ok, spam, scam, harassment のいずれかを返す
+type MessageClassifier interface {
+	ClassifyMessage(content string) (string, error)
+}
+
+// classifierVerdicts は分類ごとの判定
+var classifierVerdicts = map[string]FilterVerdict{
+	"spam":       {Action: FilterFlag, Reason: "spam"},
+	"scam":       {Action: FilterQuarantine, Reason: "fraud"},
+	"harassment": {Action: FilterQuarantine, Reason: "harassment"},
+}
+
+// ClassifierFilter はLLMの分類でメッセージを判定する
+// 分類に失敗した場合は送信を止めない（ログに残して通す）
+type ClassifierFilter struct {
+	classifier MessageClassifier
+}
+
+func NewClassifierFilter(classifier MessageClassifier) *ClassifierFilter {
+	return &ClassifierFilter{classifier: classifier}
+}
+
+func (f *ClassifierFilter) Name() string { return "classifier" }
+
+func (f *ClassifierFilter) Check(in *FilterInput) (*FilterVerdict, error) {
+	if strings.TrimSpace(in.Content) == "" {
+		return nil, nil
+	}
+	label, err := f.classifier.ClassifyMessage(in.Content)
+	if err != nil {
+		log.Printf("WARNING: failed to classify message from uid=%s: %v", in.SenderUID, err)
+		return nil, nil
+	}
+	verdict, ok := classifierVerdicts[label]
+	if !ok {
+		return nil, nil
+	}
+	verdict.Detail = "classified as " + label
+	return &verdict, nil
+}
@@ -0,0 +1,223 @@
+package messages
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	dao "uttc-hackathon-backend/dao/messages"
+)
+
+// stubFilter はテスト用のフィルタ（決まった判定を返し、呼ばれた回数を数える）
+type stubFilter struct {
+	name    string
+	verdict *FilterVerdict
+	calls   int
+}
+
+func (f *stubFilter) Name() string { return f.name }
+
+func (f *stubFilter) Check(in *FilterInput) (*FilterVerdict, error) {
+	f.calls++
+	if f.verdict == nil {
+		return nil, nil
+	}
+	v := *f.verdict
+	return &v, nil
+}
+
+// stubClassifier はテスト用のLLMの分類
+type stubClassifier struct {
+	label string
+	err   error
+}
+
+func (c *stubClassifier) ClassifyMessage(content string) (string, error) {
+	return c.label, c.err
+}
+
+// TestFilterChain_Run 最も強い判定を採用し、拒否した時点で残りのフィルタは実行しない
+func TestFilterChain_Run(t *testing.T) {
+	flag := &stubFilter{name: "flag", verdict: &FilterVerdict{Action: FilterFlag, Reason: "spam"}}
+	quarantine := &stubFilter{name: "quarantine", verdict: &FilterVerdict{Action: FilterQuarantine, Reason: "fraud"}}
+	allow := &stubFilter{name: "allow"}
+
+	verdict, err := FilterChain{flag, quarantine, allow}.Run(&FilterInput{Content: "hi"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if verdict.Action != FilterQuarantine || verdict.Filter != "quarantine" || verdict.Reason != "fraud" {
+		t.Errorf("expected quarantine verdict, got %+v", verdict)
+	}
+	if allow.calls != 1 {
+		t.Errorf("expected all filters to run, got %d calls", allow.calls)
+	}
+
+	reject := &stubFilter{name: "reject", verdict: &FilterVerdict{Action: FilterReject}}
+	after := &stubFilter{name: "after"}
+	verdict, _ = FilterChain{reject, after}.Run(&FilterInput{Content: "hi"})
+	if verdict.Action != FilterReject || !errors.Is(verdict.rejectError(), ErrMessageRejected) {
+		t.Errorf("expected reject with ErrMessageRejected, got %+v", verdict)
+	}
+	if after.calls != 0 {
+		t.Errorf("expected filters after reject to be skipped, got %d calls", after.calls)
+	}
+
+	var none FilterChain
+	if verdict, _ := none.Run(&FilterInput{Content: "hi"}); verdict.Action != FilterAllow {
+		t.Errorf("expected empty chain to allow, got %+v", verdict)
+	}
+}
+
+// TestLengthFilter 上限を超える文字数（バイト数ではない）は拒否する
+func TestLengthFilter(t *testing.T) {
+	f := &LengthFilter{Max: 5}
+	if v, _ := f.Check(&FilterInput{Content: "あいうえお"}); v != nil {
+		t.Errorf("expected 5 characters to pass, got %+v", v)
+	}
+	v, _ := f.Check(&FilterInput{Content: "あいうえおか"})
+	if v == nil || v.Action != FilterReject || !errors.Is(v.rejectError(), ErrMessageTooLong) {
+		t.Errorf("expected ErrMessageTooLong, got %+v", v)
+	}
+}
+
+// TestRateLimitFilter 期間内の送信数が上限に達したら拒否し、編集は数えない
+func TestRateLimitFilter(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	f := NewRateLimitFilter(mockDAO, 2, time.Minute)
+	in := &FilterInput{SenderUID: "sender", ReceiverUID: "receiver", Content: "hi"}
+
+	old, _ := mockDAO.CreateMessage("sender", "receiver", "old", nil, nil, false)
+	old.CreatedAt = time.Now().Add(-2 * time.Minute)
+	_, _ = mockDAO.CreateMessage("sender", "receiver", "1", nil, nil, false)
+	_, _ = mockDAO.CreateMessage("other", "receiver", "x", nil, nil, false)
+	if v, _ := f.Check(in); v != nil {
+		t.Errorf("expected 1 recent message to pass, got %+v", v)
+	}
+
+	_, _ = mockDAO.CreateMessage("sender", "receiver", "2", nil, nil, false)
+	v, _ := f.Check(in)
+	if v == nil || !errors.Is(v.rejectError(), ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got %+v", v)
+	}
+	if v, _ := f.Check(&FilterInput{SenderUID: "sender", Content: "fixed", IsEdit: true}); v != nil {
+		t.Errorf("expected edits to be exempt, got %+v", v)
+	}
+}
+
+// TestBlockedWordFilter 全角・大文字・空白の違いがあっても禁止語を検出する
+func TestBlockedWordFilter(t *testing.T) {
+	f := NewBlockedWordFilter([]string{"kill yourself", "死ね", " "}, FilterQuarantine, "harassment")
+
+	for _, content := range []string{"KILL YOURSELF", "ｋｉｌｌ　ｙｏｕｒｓｅｌｆ", "killyourself!", "もう死ね"} {
+		v, _ := f.Check(&FilterInput{Content: content})
+		if v == nil || v.Action != FilterQuarantine || v.Reason != "harassment" {
+			t.Errorf("%q: expected quarantine, got %+v", content, v)
+		}
+	}
+	if v, _ := f.Check(&FilterInput{Content: "よろしくお願いします"}); v != nil {
+		t.Errorf("expected no match, got %+v", v)
+	}
+}
+
+// TestPaymentFilter 外部での支払いの話題はフラグ、連絡先・ウォレットアドレスと一緒なら保留にする
+func TestPaymentFilter(t *testing.T) {
+	wallet := "0x" + strings.Repeat("ab", 20)
+	cases := []struct {
+		content string
+		action  FilterAction // 空なら判定なし
+	}{
+		{"送料込みで3000円です。明日発送します", ""},
+		{"追跡番号は https://tracking.example.com で確認できます", ""},
+		{"銀行振込でも大丈夫ですか？", FilterFlag},
+		{"PayPayで払います", FilterFlag},
+		{"Can I pay by Bank Transfer?", FilterFlag},
+		{"代金はこちらへ " + wallet, FilterFlag},
+		{"アプリを通さず直接取引しませんか？ foo@example.com まで", FilterQuarantine},
+		{"現金手渡しでお願いします。090-1234-5678", FilterQuarantine},
+		{"振込先: " + wallet, FilterQuarantine},
+	}
+	f := &PaymentFilter{}
+	for _, c := range cases {
+		v, err := f.Check(&FilterInput{Content: c.content})
+		if err != nil {
+			t.Fatalf("%q: expected no error, got %v", c.content, err)
+		}
+		if c.action == "" {
+			if v != nil {
+				t.Errorf("%q: expected no verdict, got %+v", c.content, v)
+			}
+			continue
+		}
+		if v == nil || v.Action != c.action || v.Reason != "fraud" {
+			t.Errorf("%q: expected %s, got %+v", c.content, c.action, v)
+		}
+	}
+}
+
+// TestClassifierFilter 分類に応じて判定し、分類に失敗した場合は通す
+func TestClassifierFilter(t *testing.T) {
+	cases := []struct {
+		label  string
+		err    error
+		action FilterAction // 空なら判定なし
+	}{
+		{"ok", nil, ""},
+		{"spam", nil, FilterFlag},
+		{"scam", nil, FilterQuarantine},
+		{"harassment", nil, FilterQuarantine},
+		{"", errors.New("timeout"), ""},
+	}
+	for _, c := range cases {
+		f := NewClassifierFilter(&stubClassifier{label: c.label, err: c.err})
+		v, err := f.Check(&FilterInput{SenderUID: "sender", Content: "hello"})
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", c.label, err)
+		}
+		if c.action == "" {
+			if v != nil {
+				t.Errorf("%s: expected no verdict, got %+v", c.label, v)
+			}
+			continue
+		}
+		if v == nil || v.Action != c.action {
+			t.Errorf("%s: expected %s, got %+v", c.label, c.action, v)
+		}
+	}
+
+	// 本文のない（画像だけの）メッセージは分類しない
+	classifier := &stubClassifier{label: "spam"}
+	if v, _ := NewClassifierFilter(classifier).Check(&FilterInput{Content: "  "}); v != nil {
+		t.Errorf("expected empty content to be skipped, got %+v", v)
+	}
+}
+
+// TestNewDefaultFilterChain 既定のチェーンは文字数・送信数・禁止語・外部での支払いを確認する
+func TestNewDefaultFilterChain(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	chain := NewDefaultFilterChain(mockDAO, nil)
+
+	long := strings.Repeat("あ", maxMessageLength+1)
+	if v, _ := chain.Run(&FilterInput{SenderUID: "sender", Content: long}); !errors.Is(v.rejectError(), ErrMessageTooLong) {
+		t.Errorf("expected ErrMessageTooLong, got %+v", v)
+	}
+	if v, _ := chain.Run(&FilterInput{SenderUID: "sender", Content: "死ね"}); v.Action != FilterQuarantine || v.Filter != "blocked_word" {
+		t.Errorf("expected default blocked word to quarantine, got %+v", v)
+	}
+	if v, _ := chain.Run(&FilterInput{SenderUID: "sender", Content: "よろしくお願いします"}); v.Action != FilterAllow {
+		t.Errorf("expected allow, got %+v", v)
+	}
+
+	for i := 0; i < burstMessageLimit; i++ {
+		mockDAO.messages = append(mockDAO.messages, &dao.Message{SenderUID: "sender", Type: dao.TypeUser, CreatedAt: time.Now()})
+	}
+	if v, _ := chain.Run(&FilterInput{SenderUID: "sender", Content: "hi"}); !errors.Is(v.rejectError(), ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got %+v", v)
+	}
+
+	custom := NewDefaultFilterChain(mockDAO, []string{"badword"})
+	if v, _ := custom.Run(&FilterInput{SenderUID: "other", Content: "死ね badword"}); v.Detail != `blocked word "badword"` {
+		t.Errorf("expected custom blocked words to replace defaults, got %+v", v)
+	}
+}
@@ -1,29 +1,685 @@
 package messages
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"time"
+	imagesDao "uttc-hackathon-backend/dao/images"
 	dao "uttc-hackathon-backend/dao/messages"
+	notificationsUc "uttc-hackathon-backend/usecase/notifications"
+	postItemsUc "uttc-hackathon-backend/usecase/postItems"
 )
 
+const (
+	// maxAttachmentsPerMessage は1通に添付できる画像の最大数
+	maxAttachmentsPerMessage = 4
+	// attachmentURLExpiry は添付画像の署名付きURLの有効期限（切れたら /messages/attachments/{id} で取り直す）
+	attachmentURLExpiry = time.Hour
+	// defaultMessagePageSize・maxMessagePageSize はメッセージ履歴の1ページの件数（未指定時）と上限
+	defaultMessagePageSize = 50
+	maxMessagePageSize     = 100
+	// messageEditWindow は送信者がメッセージを編集・削除できる期間（送信から）
+	messageEditWindow = 15 * time.Minute
+)
+
+var (
+	// ErrBlocked はどちらかがもう一方をブロックしていて送信できない場合のエラー
+	ErrBlocked = errors.New("cannot send message to this user")
+	// ErrSuspended は送信者が利用停止中の場合のエラー
+	ErrSuspended = errors.New("your account is suspended")
+	// ErrMessageNotFound はメッセージが存在しない（受信者でない場合を含む）場合のエラー
+	ErrMessageNotFound = errors.New("message not found")
+	// ErrItemNotFound はスレッドの対象の商品が存在しない（非表示を含む）場合のエラー
+	ErrItemNotFound = errors.New("item not found")
+	// ErrItemNotInThread は送信者・受信者のどちらも商品の出品者でない場合のエラー
+	ErrItemNotInThread = errors.New("item is not related to this conversation")
+	// ErrOwnItem は自分が出品した商品について出品者に問い合わせようとした場合のエラー
+	ErrOwnItem = errors.New("cannot contact yourself about your own item")
+	// ErrEmptyMessage は本文も添付画像もないメッセージを送ろうとした場合のエラー
+	ErrEmptyMessage = errors.New("content or attachments are required")
+	// ErrTooManyAttachments は1通に添付できる数を超えた場合のエラー
+	ErrTooManyAttachments = fmt.Errorf("too many attachments (max %d)", maxAttachmentsPerMessage)
+	// ErrAttachmentNotFound は添付画像が存在しない・見る権限がない・添付済みの場合のエラー
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	// ErrInvalidAttachment は添付画像が受け付けられない形式・サイズの場合のエラー
+	ErrInvalidAttachment = errors.New("invalid attachment")
+	// ErrMessageNotEditable は送信者でない・期間を過ぎた・削除済み・システムメッセージのため編集や削除ができない場合のエラー
+	ErrMessageNotEditable = fmt.Errorf("only the sender can edit or delete a message within %s of sending it", messageEditWindow)
+)
+
+// リアルタイムで配信するイベントの種類
+const (
+	// EventMessageCreated は新しいメッセージ（送信者・受信者へ。データは*dao.Message）
+	EventMessageCreated = "message.created"
+	// EventMessageDelivered は受信者の端末に届いた（送信者・受信者へ。データはDeliveredEvent）
+	EventMessageDelivered = "message.delivered"
+	// EventMessagesRead は相手からのメッセージを既読にした（両者へ。データはReadEvent）
+	EventMessagesRead = "messages.read"
+	// EventUnreadCount は未読件数が変わった（受信者へ。データはUnreadCountEvent）
+	EventUnreadCount = "unread_count"
+	// EventMessageUpdated はメッセージが編集された（送信者・受信者へ。データは*dao.Message）
+	EventMessageUpdated = "message.updated"
+	// EventMessageDeleted はメッセージが削除された（送信者・受信者へ。データはDeletedEvent）
+	EventMessageDeleted = "message.deleted"
+)
+
+type DeliveredEvent struct {
+	MessageID   int       `json:"message_id"`
+	SenderUID   string    `json:"sender_uid"`
+	ReceiverUID string    `json:"receiver_uid"`
+	DeliveredAt time.Time `json:"delivered_at"`
+}
+
+type DeletedEvent struct {
+	MessageID   int       `json:"message_id"`
+	SenderUID   string    `json:"sender_uid"`
+	ReceiverUID string    `json:"receiver_uid"`
+	ItemID      *int      `json:"item_id"`
+	DeletedAt   time.Time `json:"deleted_at"`
+}
+
+type ReadEvent struct {
+	ReaderUID  string    `json:"reader_uid"`
+	PartnerUID string    `json:"partner_uid"`
+	ItemID     *int      `json:"item_id"`
+	ReadAt     time.Time `json:"read_at"`
+}
+
+type UnreadCountEvent struct {
+	PartnerUID  string `json:"partner_uid"`
+	UnreadCount int    `json:"unread_count"`
+	TotalUnread int    `json:"total_unread"`
+}
+
+// Publisher は接続中のユーザーへイベントを届ける（realtime.Hub）
+type Publisher interface {
+	Publish(uids []string, eventType string, data interface{})
+}
+
+// AttachmentStore は添付画像を商品画像と同じく検証・加工して非公開で保存し、署名付きURLを発行する（postItems.ItemUsecase）
+type AttachmentStore interface {
+	UploadPrivateImage(file multipart.File, fileHeader *multipart.FileHeader, register func(*imagesDao.PrivateImage) error) (*imagesDao.PrivateImage, error)
+	PrivateImageURL(hash, ext, variant string, expires time.Duration) (string, error)
+}
+
+// ModerationQueue はフィルタに引っかかったメッセージをモデレーションキューに入れる（reports.ReportUsecase）
+type ModerationQueue interface {
+	FlagMessage(messageID int, reason, detail string) error
+}
+
+// Notifier は受信者に新しいメッセージを通知する（notifications.NotificationUsecase）
+type Notifier interface {
+	Notify(in notificationsUc.NotifyInput) error
+}
+
+// systemMessageTexts はシステムメッセージの本文（クライアントはsystem_eventで表示を切り替えてもよい）
+var systemMessageTexts = map[string]string{
+	dao.SystemEventPurchased:     "商品が購入されました。発送の準備をお願いします。",
+	dao.SystemEventShipped:       "出品者が商品を発送しました。",
+	dao.SystemEventDelivered:     "購入者が商品の受け取りを報告しました。",
+	dao.SystemEventCompleted:     "受け取りが確認され、取引が完了しました。",
+	dao.SystemEventCancelled:     "注文がキャンセルされました。",
+	dao.SystemEventRefunded:      "注文が返金されました。",
+	dao.SystemEventItemCancelled: "この商品の出品は取り消されました。",
+}
+
+// MessagePage はメッセージ履歴の1ページ（古い順）
+// NextCursorはさらに前のメッセージを取得するときのbefore（これより前がなければnil）
+type MessagePage struct {
+	Messages   []*dao.Message `json:"messages"`
+	NextCursor *int           `json:"next_cursor"`
+}
+
+// ModerationMessage はモデレーターが通報を確認するためのメッセージ（削除された本文・添付画像と編集履歴を含む）
+type ModerationMessage struct {
+	*dao.Message
+	QuarantinedAt *time.Time         `json:"quarantined_at"` // フィルタで保留されて受信者に見せていなければ日時
+	Edits         []*dao.MessageEdit `json:"edits"`
+}
+
 type MessageUsecase struct {
-	messageDAO dao.MessageDAOInterface
+	messageDAO  dao.MessageDAOInterface
+	publisher   Publisher
+	attachments AttachmentStore
+	filters     FilterChain
+	moderation  ModerationQueue
+	notifier    Notifier
+	now         func() time.Time
+}
+
+// NewMessageUsecase はMessageUsecaseを返す
+// filtersは送信・編集するメッセージに適用するフィルタ（nilならフィルタしない）、moderationはフラグを立てたメッセージの通報先
+// notifierは新しいメッセージの通知先（nilなら通知しない）
+func NewMessageUsecase(messageDAO dao.MessageDAOInterface, publisher Publisher, attachments AttachmentStore, filters FilterChain, moderation ModerationQueue, notifier Notifier) *MessageUsecase {
+	return &MessageUsecase{
+		messageDAO:  messageDAO,
+		publisher:   publisher,
+		attachments: attachments,
+		filters:     filters,
+		moderation:  moderation,
+		notifier:    notifier,
+		now:         time.Now,
+	}
+}
+
+// GetMessages は相手とのスレッドのメッセージを新しい方からlimit件取得する（itemIDがnilなら商品に紐づかないスレッド）
+// beforeにはNextCursorを渡して前のページを取得する（0なら最新のページ）
+func (u *MessageUsecase) GetMessages(myUID, partnerUID string, itemID *int, before, limit int) (*MessagePage, error) {
+	if limit <= 0 {
+		limit = defaultMessagePageSize
+	}
+	if limit > maxMessagePageSize {
+		limit = maxMessagePageSize
+	}
+
+	// 1件多く取得して、さらに前のメッセージがあるかを判定する
+	messages, err := u.messageDAO.GetMessagesByPartner(myUID, partnerUID, itemID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &MessagePage{Messages: messages}
+	if len(messages) > limit {
+		page.Messages = messages[1:]
+		page.NextCursor = &page.Messages[0].ID
+	}
+	if page.Messages == nil {
+		page.Messages = []*dao.Message{}
+	}
+	for _, msg := range page.Messages {
+		if err := u.signAttachments(msg.Attachments); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// GetThread は相手とのスレッドのメッセージをすべて古い順に取得する（旧API GET /messages 用）
+// GetMessagesのページを最初のメッセージまでさかのぼってつなげる
+func (u *MessageUsecase) GetThread(myUID, partnerUID string, itemID *int) ([]*dao.Message, error) {
+	var pages [][]*dao.Message
+	total, before := 0, 0
+	for {
+		page, err := u.GetMessages(myUID, partnerUID, itemID, before, maxMessagePageSize)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, page.Messages)
+		total += len(page.Messages)
+		if page.NextCursor == nil {
+			break
+		}
+		before = *page.NextCursor
+	}
+
+	messages := make([]*dao.Message, 0, total)
+	for i := len(pages) - 1; i >= 0; i-- {
+		messages = append(messages, pages[i]...)
+	}
+	return messages, nil
+}
+
+// SendMessage はメッセージを送信する（ブロック関係にある相手・利用停止中のユーザーは送信できない）
+// itemIDを指定した場合はその商品のスレッドに送る。送信者か受信者のどちらかが出品者でなければならない
+// attachmentIDsはUploadAttachmentで送信者がアップロードした、まだ添付していない画像
+func (u *MessageUsecase) SendMessage(senderUID, receiverUID, content string, itemID *int, attachmentIDs []int64) (*dao.Message, error) {
+	if content == "" && len(attachmentIDs) == 0 {
+		return nil, ErrEmptyMessage
+	}
+	if len(attachmentIDs) > maxAttachmentsPerMessage {
+		return nil, ErrTooManyAttachments
+	}
+	suspended, err := u.messageDAO.IsSuspended(senderUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check suspension: %w", err)
+	}
+	if suspended {
+		return nil, ErrSuspended
+	}
+	blocked, err := u.messageDAO.IsBlockedBetween(senderUID, receiverUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check block: %w", err)
+	}
+	if blocked {
+		return nil, ErrBlocked
+	}
+	if itemID != nil {
+		item, err := u.getItem(*itemID)
+		if err != nil {
+			return nil, err
+		}
+		if item.SellerUID != senderUID && item.SellerUID != receiverUID {
+			return nil, ErrItemNotInThread
+		}
+	}
+	verdict, err := u.filters.Run(&FilterInput{SenderUID: senderUID, ReceiverUID: receiverUID, ItemID: itemID, Content: content})
+	if err != nil {
+		return nil, err
+	}
+	if verdict.Action == FilterReject {
+		return nil, verdict.rejectError()
+	}
+
+	quarantined := verdict.Action == FilterQuarantine
+	message, err := u.messageDAO.CreateMessage(senderUID, receiverUID, content, itemID, attachmentIDs, quarantined)
+	if errors.Is(err, dao.ErrAttachmentUnavailable) {
+		return nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := u.signAttachments(message.Attachments); err != nil {
+		return nil, err
+	}
+
+	u.flag(message.ID, verdict)
+	// 保留したメッセージは送信者にだけ通常どおり見せる（保留されたことは送信者に知らせない）
+	u.publisher.Publish(recipients(message), EventMessageCreated, message)
+	if !quarantined {
+		u.publishUnreadCount(receiverUID, senderUID)
+		u.notify(message)
+	}
+	return message, nil
 }
 
-func NewMessageUsecase(messageDAO dao.MessageDAOInterface) *MessageUsecase {
-	return &MessageUsecase{messageDAO: messageDAO}
+// ContactSeller は商品ページから出品者へ、その商品のスレッドでメッセージを送る
+func (u *MessageUsecase) ContactSeller(buyerUID string, itemID int, content string) (*dao.Message, error) {
+	item, err := u.getItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.SellerUID == buyerUID {
+		return nil, ErrOwnItem
+	}
+	return u.SendMessage(buyerUID, item.SellerUID, content, &item.ID, nil)
 }
 
-func (u *MessageUsecase) GetMessages(myUID, partnerUID string) ([]*dao.Message, error) {
-	return u.messageDAO.GetMessagesByPartner(myUID, partnerUID)
+// EditMessage は送信者が送信からmessageEditWindow以内のメッセージの本文を編集する（編集前の本文は履歴に残る）
+func (u *MessageUsecase) EditMessage(uid string, messageID int, content string) (*dao.Message, error) {
+	if content == "" {
+		return nil, ErrEmptyMessage
+	}
+	message, err := u.getOwnMessage(uid, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if content == message.Content {
+		return message, u.signAttachments(message.Attachments)
+	}
+
+	suspended, err := u.messageDAO.IsSuspended(uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check suspension: %w", err)
+	}
+	if suspended {
+		return nil, ErrSuspended
+	}
+	blocked, err := u.messageDAO.IsBlockedBetween(uid, message.ReceiverUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check block: %w", err)
+	}
+	if blocked {
+		return nil, ErrBlocked
+	}
+	// 届いた後のメッセージは保留にできないので、編集では保留の判定も拒否にする
+	verdict, err := u.filters.Run(&FilterInput{
+		SenderUID: uid, ReceiverUID: message.ReceiverUID, ItemID: message.ItemID, Content: content, IsEdit: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if verdict.Action == FilterReject {
+		return nil, verdict.rejectError()
+	}
+	if verdict.Action == FilterQuarantine && message.QuarantinedAt == nil {
+		return nil, ErrMessageRejected
+	}
+
+	// 確認してから更新するまでに期間を過ぎた・削除された場合も更新しない
+	ok, err := u.messageDAO.EditMessage(messageID, uid, content, u.now().Add(-messageEditWindow))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrMessageNotEditable
+	}
+
+	updated, err := u.messageDAO.GetMessage(messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	if err := u.signAttachments(updated.Attachments); err != nil {
+		return nil, err
+	}
+	u.flag(messageID, verdict)
+	u.publisher.Publish(recipients(updated), EventMessageUpdated, updated)
+	return updated, nil
+}
+
+// DeleteMessage は送信者が送信からmessageEditWindow以内のメッセージを削除する
+// 論理削除なので、当事者には本文と添付画像を返さなくなるが、モデレーターは通報の確認のために見られる
+func (u *MessageUsecase) DeleteMessage(uid string, messageID int) error {
+	message, err := u.getOwnMessage(uid, messageID)
+	if err != nil {
+		return err
+	}
+	ok, err := u.messageDAO.DeleteMessage(messageID, uid, u.now().Add(-messageEditWindow))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrMessageNotEditable
+	}
+
+	u.publisher.Publish(recipients(message), EventMessageDeleted, DeletedEvent{
+		MessageID:   message.ID,
+		SenderUID:   message.SenderUID,
+		ReceiverUID: message.ReceiverUID,
+		ItemID:      message.ItemID,
+		DeletedAt:   u.now(),
+	})
+	// 未読のまま削除された場合は受信者の未読件数が減る
+	if !message.IsRead && message.QuarantinedAt == nil {
+		u.publishUnreadCount(message.ReceiverUID, message.SenderUID)
+	}
+	return nil
+}
+
+// getOwnMessage はuidが編集・削除できるメッセージを取得する
+// 当事者でなければErrMessageNotFound、受信者・期間切れ・削除済み・システムメッセージならErrMessageNotEditable
+func (u *MessageUsecase) getOwnMessage(uid string, messageID int) (*dao.Message, error) {
+	message, err := u.messageDAO.GetMessage(messageID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	if message.SenderUID != uid && message.ReceiverUID != uid {
+		return nil, ErrMessageNotFound
+	}
+	if message.SenderUID != uid || message.Type != dao.TypeUser || message.DeletedAt != nil ||
+		u.now().Sub(message.CreatedAt) > messageEditWindow {
+		return nil, ErrMessageNotEditable
+	}
+	return message, nil
+}
+
+// GetMessageForModeration はモデレーターが通報されたメッセージを確認するために、削除された本文や編集履歴も含めて取得する
+func (u *MessageUsecase) GetMessageForModeration(messageID int) (*ModerationMessage, error) {
+	message, err := u.messageDAO.GetMessage(messageID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	if err := u.signAttachments(message.Attachments); err != nil {
+		return nil, err
+	}
+	edits, err := u.messageDAO.ListMessageEdits(messageID)
+	if err != nil {
+		return nil, err
+	}
+	return &ModerationMessage{Message: message, QuarantinedAt: message.QuarantinedAt, Edits: edits}, nil
+}
+
+// flag はフィルタがフラグを立てた・保留したメッセージをモデレーションキューに入れる
+// 送信は済んでいるので、失敗してもログに残すだけにする
+func (u *MessageUsecase) flag(messageID int, verdict *FilterVerdict) {
+	if verdict.Action != FilterFlag && verdict.Action != FilterQuarantine {
+		return
+	}
+	if u.moderation == nil {
+		log.Printf("WARNING: message id=%d was flagged by %s filter but no moderation queue is configured", messageID, verdict.Filter)
+		return
+	}
+	detail := fmt.Sprintf("%s filter (%s): %s", verdict.Filter, verdict.Action, verdict.Detail)
+	if err := u.moderation.FlagMessage(messageID, verdict.Reason, detail); err != nil {
+		log.Printf("WARNING: failed to flag message id=%d: %v", messageID, err)
+	}
+}
+
+// notify は受信者に新しいメッセージを通知する（相手・商品ごとに1件にまとめる）
+// 送信は済んでいるので、失敗してもログに残すだけにする
+func (u *MessageUsecase) notify(message *dao.Message) {
+	if u.notifier == nil {
+		return
+	}
+	err := u.notifier.Notify(notificationsUc.NotifyInput{
+		UID:      message.ReceiverUID,
+		Type:     notificationsUc.TypeMessage,
+		ActorUID: message.SenderUID,
+		ItemID:   message.ItemID,
+	})
+	if err != nil {
+		log.Printf("WARNING: failed to notify message id=%d: %v", message.ID, err)
+	}
+}
+
+// MessageReleased はモデレーターが保留を解除したメッセージを、新しいメッセージとして受信者に届けて通知する
+// 送信者には送信時に届けているので送らない。解除の前に削除されていたメッセージは届けない
+func (u *MessageUsecase) MessageReleased(messageID int) error {
+	message, err := u.messageDAO.GetMessage(messageID)
+	if err != nil {
+		return fmt.Errorf("failed to get message: %w", err)
+	}
+	if message.DeletedAt != nil || message.QuarantinedAt != nil {
+		return nil
+	}
+	if err := u.signAttachments(message.Attachments); err != nil {
+		return err
+	}
+	u.publisher.Publish([]string{message.ReceiverUID}, EventMessageCreated, message)
+	u.publishUnreadCount(message.ReceiverUID, message.SenderUID)
+	u.notify(message)
+	return nil
+}
+
+// recipients はメッセージの変化を配信する相手（保留中のメッセージは送信者だけ）
+func recipients(message *dao.Message) []string {
+	if message.QuarantinedAt != nil {
+		return []string{message.SenderUID}
+	}
+	return []string{message.SenderUID, message.ReceiverUID}
+}
+
+// PostSystemMessage は取引の節目を購入者と出品者の商品スレッドにシステムメッセージとして書き込み、両者に届ける
+// fromUIDはきっかけになった側、toUIDは知らせる相手。どちらかが不明（未登録のウォレットなど）なら何もしない
+// 取引の記録なので、ブロック関係にあっても書き込む
+func (u *MessageUsecase) PostSystemMessage(itemID int, fromUID, toUID, event string) error {
+	content, ok := systemMessageTexts[event]
+	if !ok {
+		return fmt.Errorf("unknown system message event %q", event)
+	}
+	if fromUID == "" || toUID == "" || fromUID == toUID {
+		return nil
+	}
+
+	message, err := u.messageDAO.CreateSystemMessage(fromUID, toUID, itemID, event, content)
+	if err != nil {
+		return err
+	}
+	u.publisher.Publish([]string{toUID, fromUID}, EventMessageCreated, message)
+	u.publishUnreadCount(toUID, fromUID)
+	return nil
+}
+
+// PostItemSystemMessage は商品のスレッドで出品者とやり取りしている全員にシステムメッセージを書き込む（出品の取り消しなど）
+func (u *MessageUsecase) PostItemSystemMessage(itemID int, event string) error {
+	item, err := u.messageDAO.GetMessageItem(itemID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get item: %w", err)
+	}
+	partners, err := u.messageDAO.GetItemThreadPartners(itemID, item.SellerUID)
+	if err != nil {
+		return err
+	}
+	for _, partnerUID := range partners {
+		if err := u.PostSystemMessage(itemID, item.SellerUID, partnerUID, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UploadAttachment は添付画像をアップロードする（メッセージに添付するまでは本人だけが見られる）
+// 形式・サイズ・画素数の制限は商品画像と同じ
+func (u *MessageUsecase) UploadAttachment(uid string, file multipart.File, fileHeader *multipart.FileHeader) (*dao.Attachment, error) {
+	if file == nil {
+		return nil, fmt.Errorf("%w: image file is required", ErrInvalidAttachment)
+	}
+	suspended, err := u.messageDAO.IsSuspended(uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check suspension: %w", err)
+	}
+	if suspended {
+		return nil, ErrSuspended
+	}
+
+	// 添付画像の行はファイルを保存する前に登録する（GCとの競合を避けるため。UploadPrivateImageを参照）
+	var attachment *dao.Attachment
+	_, err = u.attachments.UploadPrivateImage(file, fileHeader, func(image *imagesDao.PrivateImage) error {
+		var err error
+		attachment, err = u.messageDAO.CreateAttachment(uid, image.Hash, image.Ext, image.SizeBytes)
+		return err
+	})
+	if errors.Is(err, postItemsUc.ErrInvalidImage) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAttachment, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload attachment: %w", err)
+	}
+	if err := u.signAttachments([]*dao.Attachment{attachment}); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// AttachmentURL は添付画像の署名付きURLを返す
+// 見られるのは添付したメッセージの送信者・受信者（送信前はアップロードした本人）だけで、メッセージが削除されたら誰も見られない
+func (u *MessageUsecase) AttachmentURL(uid string, attachmentID int64, variant string) (string, error) {
+	attachment, err := u.messageDAO.GetAttachment(attachmentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrAttachmentNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get attachment: %w", err)
+	}
+	if !canViewAttachment(attachment, uid) {
+		// 権限がないことも存在しないものとして扱う
+		return "", ErrAttachmentNotFound
+	}
+	if variant != "thumb" && variant != "medium" {
+		variant = "full"
+	}
+	return u.attachments.PrivateImageURL(attachment.Hash, attachment.Ext, variant, attachmentURLExpiry)
+}
+
+func canViewAttachment(attachment *dao.Attachment, uid string) bool {
+	if attachment.MessageID == nil {
+		return attachment.UploaderUID == uid
+	}
+	// 削除されたメッセージの添付画像は当事者にも見せない。保留中のメッセージの添付画像は送信者だけが見られる
+	if attachment.MessageDeleted {
+		return false
+	}
+	if attachment.MessageQuarantined {
+		return attachment.SenderUID == uid
+	}
+	return attachment.SenderUID == uid || attachment.ReceiverUID == uid
+}
+
+// signAttachments は添付画像に署名付きURLを設定する
+func (u *MessageUsecase) signAttachments(attachments []*dao.Attachment) error {
+	for _, a := range attachments {
+		url, err := u.attachments.PrivateImageURL(a.Hash, a.Ext, "full", attachmentURLExpiry)
+		if err != nil {
+			return err
+		}
+		thumbnailURL, err := u.attachments.PrivateImageURL(a.Hash, a.Ext, "thumb", attachmentURLExpiry)
+		if err != nil {
+			return err
+		}
+		a.URL, a.ThumbnailURL = url, thumbnailURL
+	}
+	return nil
+}
+
+func (u *MessageUsecase) getItem(itemID int) (*dao.MessageItem, error) {
+	item, err := u.messageDAO.GetMessageItem(itemID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+	return item, nil
+}
+
+// MarkAsRead はスレッドの相手からのメッセージを既読にし、既読になったことを両者に知らせる
+func (u *MessageUsecase) MarkAsRead(myUID, partnerUID string, itemID *int) error {
+	updated, err := u.messageDAO.MarkAsRead(myUID, partnerUID, itemID)
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return nil
+	}
+
+	u.publisher.Publish([]string{partnerUID, myUID}, EventMessagesRead, ReadEvent{
+		ReaderUID:  myUID,
+		PartnerUID: partnerUID,
+		ItemID:     itemID,
+		ReadAt:     time.Now(),
+	})
+	u.publishUnreadCount(myUID, partnerUID)
+	return nil
+}
+
+// MarkAsDelivered は受信者の端末に届いたメッセージを配信済みにし、送信者に知らせる
+func (u *MessageUsecase) MarkAsDelivered(myUID string, messageID int) error {
+	message, err := u.messageDAO.MarkAsDelivered(myUID, messageID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrMessageNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to mark message as delivered: %w", err)
+	}
+
+	event := DeliveredEvent{MessageID: message.ID, SenderUID: message.SenderUID, ReceiverUID: message.ReceiverUID}
+	if message.DeliveredAt != nil {
+		event.DeliveredAt = *message.DeliveredAt
+	}
+	u.publisher.Publish([]string{message.SenderUID, myUID}, EventMessageDelivered, event)
+	return nil
 }
 
-func (u *MessageUsecase) SendMessage(senderUID, receiverUID, content string) (*dao.Message, error) {
-	return u.messageDAO.CreateMessage(senderUID, receiverUID, content)
+// publishUnreadCount はmyUIDの未読件数（partnerUIDからの件数と全体）を知らせる
+func (u *MessageUsecase) publishUnreadCount(myUID, partnerUID string) {
+	fromPartner, total, err := u.messageDAO.CountUnread(myUID, partnerUID)
+	if err != nil {
+		log.Printf("WARNING: failed to count unread messages for uid=%s: %v", myUID, err)
+		return
+	}
+	u.publisher.Publish([]string{myUID}, EventUnreadCount, UnreadCountEvent{
+		PartnerUID:  partnerUID,
+		UnreadCount: fromPartner,
+		TotalUnread: total,
+	})
 }
 
-func (u *MessageUsecase) MarkAsRead(myUID, partnerUID string) error {
-	return u.messageDAO.MarkAsRead(myUID, partnerUID)
+// GetUnreadSummary は未読の件数と未読があるスレッドの数を取得する
+func (u *MessageUsecase) GetUnreadSummary(myUID string) (*dao.UnreadSummary, error) {
+	return u.messageDAO.GetUnreadSummary(myUID)
 }
 
+// GetConversations はやり取りしているスレッド（相手と商品の組み合わせごと）の一覧を取得する
 func (u *MessageUsecase) GetConversations(myUID string) ([]*dao.Conversation, error) {
 	return u.messageDAO.GetConversations(myUID)
 }
@@ -1,13 +1,47 @@
 package messages
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
+	"mime/multipart"
+	"strings"
 	"testing"
 	"time"
 
+	imagesDao "uttc-hackathon-backend/dao/images"
 	dao "uttc-hackathon-backend/dao/messages"
+	notificationsUc "uttc-hackathon-backend/usecase/notifications"
+	postItemsUc "uttc-hackathon-backend/usecase/postItems"
 )
 
+// publishedEvent はMockPublisherが受け取ったイベント
+type publishedEvent struct {
+	uids      []string
+	eventType string
+	data      interface{}
+}
+
+// MockPublisher はテスト用のリアルタイム配信（イベントを記録するだけ）
+type MockPublisher struct {
+	events []publishedEvent
+}
+
+func (p *MockPublisher) Publish(uids []string, eventType string, data interface{}) {
+	p.events = append(p.events, publishedEvent{uids: uids, eventType: eventType, data: data})
+}
+
+// ofType はeventTypeのイベントだけを返す
+func (p *MockPublisher) ofType(eventType string) []publishedEvent {
+	var result []publishedEvent
+	for _, e := range p.events {
+		if e.eventType == eventType {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
 // MockMessageDAO はテスト用のモックDAO
 type MockMessageDAO struct {
 	messages       []*dao.Message
@@ -16,59 +50,363 @@ type MockMessageDAO struct {
 	getMessagesErr error
 	markAsReadErr  error
 	getConvErr     error
+	blocks         map[[2]string]bool // {blocker, blocked}
+	suspended      map[string]bool
+	items          map[int]*dao.MessageItem
+	attachments    map[int64]*dao.Attachment
+	nextAttachID   int64
+	edits          map[int][]*dao.MessageEdit
 }
 
 func NewMockMessageDAO() *MockMessageDAO {
 	return &MockMessageDAO{
-		messages: make([]*dao.Message, 0),
-		nextID:   1,
+		messages:    make([]*dao.Message, 0),
+		nextID:      1,
+		blocks:      make(map[[2]string]bool),
+		suspended:   make(map[string]bool),
+		items:       make(map[int]*dao.MessageItem),
+		attachments: make(map[int64]*dao.Attachment),
+		edits:       make(map[int][]*dao.MessageEdit),
+	}
+}
+
+// MockAttachmentStore はテスト用の添付画像の保存先（中身は読まずに内容のないファイルだけ弾く）
+type MockAttachmentStore struct {
+	uploads int
+}
+
+func NewMockAttachmentStore() *MockAttachmentStore {
+	return &MockAttachmentStore{}
+}
+
+func (s *MockAttachmentStore) UploadPrivateImage(file multipart.File, fileHeader *multipart.FileHeader, register func(*imagesDao.PrivateImage) error) (*imagesDao.PrivateImage, error) {
+	if fileHeader.Size == 0 {
+		return nil, fmt.Errorf("%w: empty file", postItemsUc.ErrInvalidImage)
 	}
+	s.uploads++
+	image := &imagesDao.PrivateImage{Hash: fmt.Sprintf("hash%d", s.uploads), Ext: "jpg", SizeBytes: int(fileHeader.Size)}
+	if err := register(image); err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+func (s *MockAttachmentStore) PrivateImageURL(hash, ext, variant string, expires time.Duration) (string, error) {
+	return fmt.Sprintf("/files/private/%s_%s.%s?sig=test", hash, variant, ext), nil
+}
+
+// flaggedMessage はMockModerationQueueに入ったメッセージ
+type flaggedMessage struct {
+	messageID int
+	reason    string
+	detail    string
 }
 
-func (m *MockMessageDAO) GetMessagesByPartner(myUID, partnerUID string) ([]*dao.Message, error) {
+// MockModerationQueue はテスト用のモデレーションキュー
+type MockModerationQueue struct {
+	flagged []flaggedMessage
+}
+
+func (q *MockModerationQueue) FlagMessage(messageID int, reason, detail string) error {
+	q.flagged = append(q.flagged, flaggedMessage{messageID: messageID, reason: reason, detail: detail})
+	return nil
+}
+
+// MockNotifier はテスト用の通知（受け取った通知を記録する）
+type MockNotifier struct {
+	notified []notificationsUc.NotifyInput
+}
+
+func (n *MockNotifier) Notify(in notificationsUc.NotifyInput) error {
+	n.notified = append(n.notified, in)
+	return nil
+}
+
+// nopFile はmultipart.Fileの代わり（MockAttachmentStoreは中身を読まない）
+type nopFile struct {
+	*strings.Reader
+}
+
+func (nopFile) Close() error { return nil }
+
+// upload はsizeバイトの画像としてuidの添付画像をアップロードする
+func upload(usecase *MessageUsecase, uid string, size int64) (*dao.Attachment, error) {
+	return usecase.UploadAttachment(uid, nopFile{strings.NewReader("")}, &multipart.FileHeader{Filename: "photo.jpg", Size: size})
+}
+
+// sameThread はメッセージの商品IDがスレッドの商品IDと同じか（どちらもnilなら同じ）
+func sameThread(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func (m *MockMessageDAO) GetMessagesByPartner(myUID, partnerUID string, itemID *int, beforeID, limit int) ([]*dao.Message, error) {
 	if m.getMessagesErr != nil {
 		return nil, m.getMessagesErr
 	}
 
 	var result []*dao.Message
 	for _, msg := range m.messages {
-		if (msg.SenderUID == myUID && msg.ReceiverUID == partnerUID) ||
-			(msg.SenderUID == partnerUID && msg.ReceiverUID == myUID) {
+		if ((msg.SenderUID == myUID && msg.ReceiverUID == partnerUID) ||
+			(msg.SenderUID == partnerUID && msg.ReceiverUID == myUID)) && sameThread(msg.ItemID, itemID) &&
+			(beforeID == 0 || msg.ID < beforeID) && (msg.QuarantinedAt == nil || msg.SenderUID == myUID) {
+			// 削除されたメッセージは本文と添付画像を除いて返す
+			if msg.DeletedAt != nil {
+				deleted := *msg
+				deleted.Content = ""
+				deleted.Attachments = []*dao.Attachment{}
+				msg = &deleted
+			}
 			result = append(result, msg)
 		}
 	}
+	// 新しい方からlimit件（古い順）
+	if len(result) > limit {
+		result = result[len(result)-limit:]
+	}
 	return result, nil
 }
 
-func (m *MockMessageDAO) CreateMessage(senderUID, receiverUID, content string) (*dao.Message, error) {
+func (m *MockMessageDAO) GetMessage(id int) (*dao.Message, error) {
+	for _, msg := range m.messages {
+		if msg.ID == id {
+			return msg, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// editable はDAOの条件（送信者・未削除・ユーザーのメッセージ・since以降の送信）と同じ判定
+func (m *MockMessageDAO) editable(id int, senderUID string, since time.Time) *dao.Message {
+	for _, msg := range m.messages {
+		if msg.ID == id && msg.SenderUID == senderUID && msg.DeletedAt == nil &&
+			msg.Type == dao.TypeUser && !msg.CreatedAt.Before(since) {
+			return msg
+		}
+	}
+	return nil
+}
+
+func (m *MockMessageDAO) EditMessage(id int, senderUID, content string, since time.Time) (bool, error) {
+	msg := m.editable(id, senderUID, since)
+	if msg == nil {
+		return false, nil
+	}
+	now := time.Now()
+	m.edits[id] = append(m.edits[id], &dao.MessageEdit{PreviousContent: msg.Content, EditedAt: now})
+	msg.Content = content
+	msg.EditedAt = &now
+	return true, nil
+}
+
+func (m *MockMessageDAO) DeleteMessage(id int, senderUID string, since time.Time) (bool, error) {
+	msg := m.editable(id, senderUID, since)
+	if msg == nil {
+		return false, nil
+	}
+	now := time.Now()
+	msg.DeletedAt = &now
+	for _, a := range msg.Attachments {
+		a.MessageDeleted = true
+	}
+	return true, nil
+}
+
+func (m *MockMessageDAO) ListMessageEdits(id int) ([]*dao.MessageEdit, error) {
+	return m.edits[id], nil
+}
+
+func (m *MockMessageDAO) CreateMessage(senderUID, receiverUID, content string, itemID *int, attachmentIDs []int64, quarantined bool) (*dao.Message, error) {
 	if m.createErr != nil {
 		return nil, m.createErr
 	}
 
+	// 送信者がアップロードした未添付の画像だけ添付できる
+	attachments := []*dao.Attachment{}
+	for _, id := range attachmentIDs {
+		a, ok := m.attachments[id]
+		if !ok || a.UploaderUID != senderUID || a.MessageID != nil {
+			return nil, dao.ErrAttachmentUnavailable
+		}
+		attachments = append(attachments, a)
+	}
+
 	msg := &dao.Message{
 		ID:          m.nextID,
 		SenderUID:   senderUID,
 		ReceiverUID: receiverUID,
+		ItemID:      itemID,
+		Type:        dao.TypeUser,
 		Content:     content,
 		IsRead:      false,
 		CreatedAt:   time.Now(),
+		Attachments: attachments,
+	}
+	if quarantined {
+		now := time.Now()
+		msg.QuarantinedAt = &now
+	}
+	for _, a := range attachments {
+		a.MessageID = &msg.ID
+		a.SenderUID, a.ReceiverUID = senderUID, receiverUID
+		a.MessageQuarantined = quarantined
+	}
+	m.nextID++
+	m.messages = append(m.messages, msg)
+	return msg, nil
+}
+
+func (m *MockMessageDAO) CreateSystemMessage(senderUID, receiverUID string, itemID int, event, content string) (*dao.Message, error) {
+	if m.createErr != nil {
+		return nil, m.createErr
+	}
+	msg := &dao.Message{
+		ID:          m.nextID,
+		SenderUID:   senderUID,
+		ReceiverUID: receiverUID,
+		ItemID:      &itemID,
+		Type:        dao.TypeSystem,
+		SystemEvent: event,
+		Content:     content,
+		CreatedAt:   time.Now(),
+		Attachments: []*dao.Attachment{},
 	}
 	m.nextID++
 	m.messages = append(m.messages, msg)
 	return msg, nil
 }
 
-func (m *MockMessageDAO) MarkAsRead(myUID, partnerUID string) error {
+func (m *MockMessageDAO) GetItemThreadPartners(itemID int, sellerUID string) ([]string, error) {
+	var partners []string
+	seen := make(map[string]bool)
+	for _, msg := range m.messages {
+		if msg.ItemID == nil || *msg.ItemID != itemID {
+			continue
+		}
+		var partnerUID string
+		switch sellerUID {
+		case msg.SenderUID:
+			partnerUID = msg.ReceiverUID
+		case msg.ReceiverUID:
+			partnerUID = msg.SenderUID
+		default:
+			continue
+		}
+		if !seen[partnerUID] {
+			seen[partnerUID] = true
+			partners = append(partners, partnerUID)
+		}
+	}
+	return partners, nil
+}
+
+func (m *MockMessageDAO) CreateAttachment(uploaderUID, hash, ext string, sizeBytes int) (*dao.Attachment, error) {
+	m.nextAttachID++
+	a := &dao.Attachment{ID: m.nextAttachID, UploaderUID: uploaderUID, Hash: hash, Ext: ext, SizeBytes: sizeBytes, CreatedAt: time.Now()}
+	m.attachments[a.ID] = a
+	return a, nil
+}
+
+func (m *MockMessageDAO) GetAttachment(id int64) (*dao.Attachment, error) {
+	a, ok := m.attachments[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return a, nil
+}
+
+func (m *MockMessageDAO) IsBlockedBetween(uidA, uidB string) (bool, error) {
+	return m.blocks[[2]string{uidA, uidB}] || m.blocks[[2]string{uidB, uidA}], nil
+}
+
+func (m *MockMessageDAO) IsSuspended(uid string) (bool, error) {
+	return m.suspended[uid], nil
+}
+
+func (m *MockMessageDAO) MarkAsRead(myUID, partnerUID string, itemID *int) (int64, error) {
 	if m.markAsReadErr != nil {
-		return m.markAsReadErr
+		return 0, m.markAsReadErr
 	}
 
+	var updated int64
 	for _, msg := range m.messages {
-		if msg.SenderUID == partnerUID && msg.ReceiverUID == myUID && !msg.IsRead {
+		if msg.SenderUID == partnerUID && msg.ReceiverUID == myUID && sameThread(msg.ItemID, itemID) && !msg.IsRead {
 			msg.IsRead = true
+			updated++
 		}
 	}
-	return nil
+	return updated, nil
+}
+
+func (m *MockMessageDAO) MarkAsDelivered(receiverUID string, messageID int) (*dao.Message, error) {
+	for _, msg := range m.messages {
+		if msg.ID == messageID && msg.ReceiverUID == receiverUID {
+			if msg.DeliveredAt == nil {
+				now := time.Now()
+				msg.DeliveredAt = &now
+			}
+			return msg, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *MockMessageDAO) CountSentSince(senderUID string, since time.Time) (int, error) {
+	var count int
+	for _, msg := range m.messages {
+		if msg.SenderUID == senderUID && msg.Type == dao.TypeUser && !msg.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MockMessageDAO) GetMessageItem(itemID int) (*dao.MessageItem, error) {
+	item, ok := m.items[itemID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return item, nil
+}
+
+func (m *MockMessageDAO) CountUnread(myUID, partnerUID string) (int, int, error) {
+	var fromPartner, total int
+	for _, msg := range m.messages {
+		if msg.ReceiverUID == myUID && !msg.IsRead && msg.DeletedAt == nil && msg.QuarantinedAt == nil {
+			total++
+			if msg.SenderUID == partnerUID {
+				fromPartner++
+			}
+		}
+	}
+	return fromPartner, total, nil
+}
+
+func (m *MockMessageDAO) GetUnreadSummary(myUID string) (*dao.UnreadSummary, error) {
+	type threadKey struct {
+		partnerUID string
+		itemID     int // 商品に紐づかないスレッドは0
+	}
+	summary := &dao.UnreadSummary{}
+	threads := make(map[threadKey]bool)
+	for _, msg := range m.messages {
+		if msg.ReceiverUID == myUID && !msg.IsRead && msg.DeletedAt == nil && msg.QuarantinedAt == nil {
+			summary.Total++
+			key := threadKey{partnerUID: msg.SenderUID}
+			if msg.ItemID != nil {
+				key.itemID = *msg.ItemID
+			}
+			threads[key] = true
+		}
+	}
+	summary.Conversations = len(threads)
+	return summary, nil
 }
 
 func (m *MockMessageDAO) GetConversations(myUID string) ([]*dao.Conversation, error) {
@@ -76,27 +414,36 @@ func (m *MockMessageDAO) GetConversations(myUID string) ([]*dao.Conversation, er
 		return nil, m.getConvErr
 	}
 
-	// 簡易的に相手ごとの最新メッセージを返す
-	partners := make(map[string]*dao.Conversation)
+	// 簡易的に相手と商品の組み合わせごとの最新メッセージを返す
+	type threadKey struct {
+		partnerUID string
+		itemID     int // 商品に紐づかないスレッドは0
+	}
+	threads := make(map[threadKey]*dao.Conversation)
 	for _, msg := range m.messages {
 		var partnerUID string
 		if msg.SenderUID == myUID {
 			partnerUID = msg.ReceiverUID
-		} else if msg.ReceiverUID == myUID {
+		} else if msg.ReceiverUID == myUID && msg.QuarantinedAt == nil {
 			partnerUID = msg.SenderUID
 		} else {
 			continue
 		}
 
-		if _, ok := partners[partnerUID]; !ok {
-			partners[partnerUID] = &dao.Conversation{
+		key := threadKey{partnerUID: partnerUID}
+		if msg.ItemID != nil {
+			key.itemID = *msg.ItemID
+		}
+		if _, ok := threads[key]; !ok {
+			threads[key] = &dao.Conversation{
 				PartnerUID:    partnerUID,
+				ItemID:        msg.ItemID,
 				LastMessage:   msg.Content,
 				LastMessageAt: msg.CreatedAt,
 				UnreadCount:   0,
 			}
 		} else {
-			conv := partners[partnerUID]
+			conv := threads[key]
 			if msg.CreatedAt.After(conv.LastMessageAt) {
 				conv.LastMessage = msg.Content
 				conv.LastMessageAt = msg.CreatedAt
@@ -105,12 +452,12 @@ func (m *MockMessageDAO) GetConversations(myUID string) ([]*dao.Conversation, er
 
 		// 未読カウント
 		if msg.SenderUID == partnerUID && msg.ReceiverUID == myUID && !msg.IsRead {
-			partners[partnerUID].UnreadCount++
+			threads[key].UnreadCount++
 		}
 	}
 
 	var result []*dao.Conversation
-	for _, conv := range partners {
+	for _, conv := range threads {
 		result = append(result, conv)
 	}
 	return result, nil
@@ -119,9 +466,9 @@ func (m *MockMessageDAO) GetConversations(myUID string) ([]*dao.Conversation, er
 // TestSendMessage_Success メッセージ送信成功
 func TestSendMessage_Success(t *testing.T) {
 	mockDAO := NewMockMessageDAO()
-	usecase := NewMessageUsecase(mockDAO)
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
 
-	msg, err := usecase.SendMessage("sender123", "receiver456", "Hello!")
+	msg, err := usecase.SendMessage("sender123", "receiver456", "Hello!", nil, nil)
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
@@ -143,29 +490,60 @@ func TestSendMessage_Success(t *testing.T) {
 func TestSendMessage_DAOError(t *testing.T) {
 	mockDAO := NewMockMessageDAO()
 	mockDAO.createErr = errors.New("database error")
-	usecase := NewMessageUsecase(mockDAO)
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
 
-	_, err := usecase.SendMessage("sender123", "receiver456", "Hello!")
+	_, err := usecase.SendMessage("sender123", "receiver456", "Hello!", nil, nil)
 	if err == nil {
 		t.Error("expected error")
 	}
 }
 
+// TestSendMessage_Blocked ブロック関係にある相手には送信できない
+func TestSendMessage_Blocked(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	mockDAO.blocks[[2]string{"receiver456", "sender123"}] = true
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
+
+	// ブロックされた側から送信
+	if _, err := usecase.SendMessage("sender123", "receiver456", "Hello!", nil, nil); !errors.Is(err, ErrBlocked) {
+		t.Errorf("expected ErrBlocked, got %v", err)
+	}
+	// ブロックした側から送信
+	if _, err := usecase.SendMessage("receiver456", "sender123", "Hello!", nil, nil); !errors.Is(err, ErrBlocked) {
+		t.Errorf("expected ErrBlocked, got %v", err)
+	}
+	if len(mockDAO.messages) != 0 {
+		t.Errorf("expected no messages, got %d", len(mockDAO.messages))
+	}
+}
+
+// TestSendMessage_Suspended 利用停止中のユーザーは送信できない
+func TestSendMessage_Suspended(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	mockDAO.suspended["sender123"] = true
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
+
+	if _, err := usecase.SendMessage("sender123", "receiver456", "Hello!", nil, nil); !errors.Is(err, ErrSuspended) {
+		t.Errorf("expected ErrSuspended, got %v", err)
+	}
+}
+
 // TestGetMessages_Success メッセージ取得成功
 func TestGetMessages_Success(t *testing.T) {
 	mockDAO := NewMockMessageDAO()
-	usecase := NewMessageUsecase(mockDAO)
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
 
 	// メッセージを送信
-	_, _ = usecase.SendMessage("user1", "user2", "Hello")
-	_, _ = usecase.SendMessage("user2", "user1", "Hi there")
-	_, _ = usecase.SendMessage("user1", "user2", "How are you?")
+	_, _ = usecase.SendMessage("user1", "user2", "Hello", nil, nil)
+	_, _ = usecase.SendMessage("user2", "user1", "Hi there", nil, nil)
+	_, _ = usecase.SendMessage("user1", "user2", "How are you?", nil, nil)
 
 	// メッセージを取得
-	messages, err := usecase.GetMessages("user1", "user2")
+	page, err := usecase.GetMessages("user1", "user2", nil, 0, 0)
 	if err != nil {
-		t.Errorf("expected no error, got %v", err)
+		t.Fatalf("expected no error, got %v", err)
 	}
+	messages := page.Messages
 	if len(messages) != 3 {
 		t.Errorf("expected 3 messages, got %d", len(messages))
 	}
@@ -174,12 +552,13 @@ func TestGetMessages_Success(t *testing.T) {
 // TestGetMessages_Empty メッセージなし
 func TestGetMessages_Empty(t *testing.T) {
 	mockDAO := NewMockMessageDAO()
-	usecase := NewMessageUsecase(mockDAO)
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
 
-	messages, err := usecase.GetMessages("user1", "user2")
+	page, err := usecase.GetMessages("user1", "user2", nil, 0, 0)
 	if err != nil {
-		t.Errorf("expected no error, got %v", err)
+		t.Fatalf("expected no error, got %v", err)
 	}
+	messages := page.Messages
 	if len(messages) != 0 {
 		t.Errorf("expected 0 messages, got %d", len(messages))
 	}
@@ -188,15 +567,16 @@ func TestGetMessages_Empty(t *testing.T) {
 // TestGetMessages_OnlyBetweenPartners 指定した相手とのメッセージのみ取得
 func TestGetMessages_OnlyBetweenPartners(t *testing.T) {
 	mockDAO := NewMockMessageDAO()
-	usecase := NewMessageUsecase(mockDAO)
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
 
 	// 異なる相手とのメッセージ
-	_, _ = usecase.SendMessage("user1", "user2", "To user2")
-	_, _ = usecase.SendMessage("user1", "user3", "To user3")
-	_, _ = usecase.SendMessage("user2", "user1", "From user2")
+	_, _ = usecase.SendMessage("user1", "user2", "To user2", nil, nil)
+	_, _ = usecase.SendMessage("user1", "user3", "To user3", nil, nil)
+	_, _ = usecase.SendMessage("user2", "user1", "From user2", nil, nil)
 
 	// user1とuser2の会話のみ取得
-	messages, _ := usecase.GetMessages("user1", "user2")
+	page, _ := usecase.GetMessages("user1", "user2", nil, 0, 0)
+	messages := page.Messages
 	if len(messages) != 2 {
 		t.Errorf("expected 2 messages, got %d", len(messages))
 	}
@@ -205,20 +585,21 @@ func TestGetMessages_OnlyBetweenPartners(t *testing.T) {
 // TestMarkAsRead_Success 既読更新成功
 func TestMarkAsRead_Success(t *testing.T) {
 	mockDAO := NewMockMessageDAO()
-	usecase := NewMessageUsecase(mockDAO)
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
 
 	// 相手からのメッセージを作成
-	_, _ = usecase.SendMessage("user2", "user1", "Hello")
-	_, _ = usecase.SendMessage("user2", "user1", "Are you there?")
+	_, _ = usecase.SendMessage("user2", "user1", "Hello", nil, nil)
+	_, _ = usecase.SendMessage("user2", "user1", "Are you there?", nil, nil)
 
 	// 既読にする
-	err := usecase.MarkAsRead("user1", "user2")
+	err := usecase.MarkAsRead("user1", "user2", nil)
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
 
 	// メッセージが既読になっているか確認
-	messages, _ := usecase.GetMessages("user1", "user2")
+	page, _ := usecase.GetMessages("user1", "user2", nil, 0, 0)
+	messages := page.Messages
 	for _, msg := range messages {
 		if !msg.IsRead {
 			t.Error("expected all messages to be read")
@@ -229,12 +610,12 @@ func TestMarkAsRead_Success(t *testing.T) {
 // TestGetConversations_Success 会話一覧取得成功
 func TestGetConversations_Success(t *testing.T) {
 	mockDAO := NewMockMessageDAO()
-	usecase := NewMessageUsecase(mockDAO)
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
 
 	// 複数の相手とやり取り
-	_, _ = usecase.SendMessage("user1", "user2", "Hello user2")
-	_, _ = usecase.SendMessage("user1", "user3", "Hello user3")
-	_, _ = usecase.SendMessage("user4", "user1", "Hello from user4")
+	_, _ = usecase.SendMessage("user1", "user2", "Hello user2", nil, nil)
+	_, _ = usecase.SendMessage("user1", "user3", "Hello user3", nil, nil)
+	_, _ = usecase.SendMessage("user4", "user1", "Hello from user4", nil, nil)
 
 	conversations, err := usecase.GetConversations("user1")
 	if err != nil {
@@ -248,12 +629,12 @@ func TestGetConversations_Success(t *testing.T) {
 // TestGetConversations_UnreadCount 未読カウント
 func TestGetConversations_UnreadCount(t *testing.T) {
 	mockDAO := NewMockMessageDAO()
-	usecase := NewMessageUsecase(mockDAO)
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
 
 	// 相手からの未読メッセージ
-	_, _ = usecase.SendMessage("user2", "user1", "Message 1")
-	_, _ = usecase.SendMessage("user2", "user1", "Message 2")
-	_, _ = usecase.SendMessage("user2", "user1", "Message 3")
+	_, _ = usecase.SendMessage("user2", "user1", "Message 1", nil, nil)
+	_, _ = usecase.SendMessage("user2", "user1", "Message 2", nil, nil)
+	_, _ = usecase.SendMessage("user2", "user1", "Message 3", nil, nil)
 
 	conversations, _ := usecase.GetConversations("user1")
 	if len(conversations) != 1 {
@@ -263,3 +644,836 @@ func TestGetConversations_UnreadCount(t *testing.T) {
 		t.Errorf("expected 3 unread, got %d", conversations[0].UnreadCount)
 	}
 }
+
+// TestSendMessage_PublishesEvents 送信すると両者に新着メッセージ、受信者に未読件数が届く
+func TestSendMessage_PublishesEvents(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	publisher := &MockPublisher{}
+	usecase := NewMessageUsecase(mockDAO, publisher, NewMockAttachmentStore(), nil, nil, nil)
+
+	_, _ = usecase.SendMessage("user3", "user1", "Hi from user3", nil, nil)
+	msg, err := usecase.SendMessage("user2", "user1", "Hello", nil, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	created := publisher.ofType(EventMessageCreated)
+	if len(created) != 2 || created[1].data.(*dao.Message).ID != msg.ID {
+		t.Fatalf("expected message.created for the new message, got %+v", created)
+	}
+	if uids := created[1].uids; len(uids) != 2 || uids[0] != "user2" || uids[1] != "user1" {
+		t.Errorf("expected sender and receiver, got %v", uids)
+	}
+
+	unread := publisher.ofType(EventUnreadCount)
+	last := unread[len(unread)-1]
+	if len(last.uids) != 1 || last.uids[0] != "user1" {
+		t.Errorf("expected unread count only for receiver, got %v", last.uids)
+	}
+	if data := last.data.(UnreadCountEvent); data.PartnerUID != "user2" || data.UnreadCount != 1 || data.TotalUnread != 2 {
+		t.Errorf("unexpected unread count: %+v", data)
+	}
+}
+
+// TestSendMessage_BlockedPublishesNothing 送信できなかった場合はイベントを送らない
+func TestSendMessage_BlockedPublishesNothing(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	mockDAO.blocks[[2]string{"user2", "user1"}] = true
+	publisher := &MockPublisher{}
+	usecase := NewMessageUsecase(mockDAO, publisher, NewMockAttachmentStore(), nil, nil, nil)
+
+	if _, err := usecase.SendMessage("user1", "user2", "Hello", nil, nil); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("expected ErrBlocked, got %v", err)
+	}
+	if len(publisher.events) != 0 {
+		t.Errorf("expected no events, got %+v", publisher.events)
+	}
+}
+
+// TestMarkAsRead_PublishesReadReceipt 既読にすると相手に既読、自分に未読件数が届く（未読がなければ送らない）
+func TestMarkAsRead_PublishesReadReceipt(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	publisher := &MockPublisher{}
+	usecase := NewMessageUsecase(mockDAO, publisher, NewMockAttachmentStore(), nil, nil, nil)
+
+	_, _ = usecase.SendMessage("user2", "user1", "Hello", nil, nil)
+	publisher.events = nil
+
+	if err := usecase.MarkAsRead("user1", "user2", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	read := publisher.ofType(EventMessagesRead)
+	if len(read) != 1 || read[0].uids[0] != "user2" {
+		t.Fatalf("expected read receipt for partner, got %+v", read)
+	}
+	if data := read[0].data.(ReadEvent); data.ReaderUID != "user1" || data.PartnerUID != "user2" {
+		t.Errorf("unexpected read event: %+v", data)
+	}
+	unread := publisher.ofType(EventUnreadCount)
+	if len(unread) != 1 || unread[0].data.(UnreadCountEvent).TotalUnread != 0 {
+		t.Errorf("expected unread count 0, got %+v", unread)
+	}
+
+	// 既読にするものがなければ何も送らない
+	publisher.events = nil
+	if err := usecase.MarkAsRead("user1", "user2", nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(publisher.events) != 0 {
+		t.Errorf("expected no events, got %+v", publisher.events)
+	}
+}
+
+// TestMarkAsDelivered 受信者だけが配信済みにでき、送信者に配信確認が届く
+func TestMarkAsDelivered(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	publisher := &MockPublisher{}
+	usecase := NewMessageUsecase(mockDAO, publisher, NewMockAttachmentStore(), nil, nil, nil)
+
+	msg, _ := usecase.SendMessage("user2", "user1", "Hello", nil, nil)
+	publisher.events = nil
+
+	if err := usecase.MarkAsDelivered("user2", msg.ID); !errors.Is(err, ErrMessageNotFound) {
+		t.Errorf("expected ErrMessageNotFound for sender, got %v", err)
+	}
+	if err := usecase.MarkAsDelivered("user1", msg.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	delivered := publisher.ofType(EventMessageDelivered)
+	if len(delivered) != 1 || delivered[0].uids[0] != "user2" {
+		t.Fatalf("expected delivery receipt for sender, got %+v", delivered)
+	}
+	if data := delivered[0].data.(DeliveredEvent); data.MessageID != msg.ID || data.DeliveredAt.IsZero() {
+		t.Errorf("unexpected delivered event: %+v", data)
+	}
+}
+
+// TestSendMessage_ItemThreads 同じ相手でも商品ごとに別のスレッドになる
+func TestSendMessage_ItemThreads(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	mockDAO.items[10] = &dao.MessageItem{ID: 10, SellerUID: "seller"}
+	mockDAO.items[20] = &dao.MessageItem{ID: 20, SellerUID: "seller"}
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
+
+	_, _ = usecase.SendMessage("buyer", "seller", "Hello", nil, nil)
+	_, _ = usecase.SendMessage("buyer", "seller", "About item 10", intPtr(10), nil)
+	_, _ = usecase.SendMessage("seller", "buyer", "Reply about item 10", intPtr(10), nil)
+	msg, err := usecase.SendMessage("buyer", "seller", "About item 20", intPtr(20), nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if msg.ItemID == nil || *msg.ItemID != 20 {
+		t.Errorf("expected item_id 20, got %v", msg.ItemID)
+	}
+
+	tests := []struct {
+		itemID *int
+		want   int
+	}{
+		{nil, 1},
+		{intPtr(10), 2},
+		{intPtr(20), 1},
+	}
+	for _, tt := range tests {
+		page, err := usecase.GetMessages("buyer", "seller", tt.itemID, 0, 0)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		messages := page.Messages
+		if len(messages) != tt.want {
+			t.Errorf("item %v: expected %d messages, got %d", tt.itemID, tt.want, len(messages))
+		}
+	}
+
+	conversations, _ := usecase.GetConversations("buyer")
+	if len(conversations) != 3 {
+		t.Errorf("expected 3 threads, got %d", len(conversations))
+	}
+}
+
+// TestSendMessage_ItemValidation 存在しない商品や、どちらも出品者でない商品のスレッドには送れない
+func TestSendMessage_ItemValidation(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	mockDAO.items[10] = &dao.MessageItem{ID: 10, SellerUID: "seller"}
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
+
+	if _, err := usecase.SendMessage("buyer", "seller", "Hello", intPtr(99), nil); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("expected ErrItemNotFound, got %v", err)
+	}
+	if _, err := usecase.SendMessage("buyer", "other", "Hello", intPtr(10), nil); !errors.Is(err, ErrItemNotInThread) {
+		t.Errorf("expected ErrItemNotInThread, got %v", err)
+	}
+	if len(mockDAO.messages) != 0 {
+		t.Errorf("expected no messages, got %d", len(mockDAO.messages))
+	}
+}
+
+// TestContactSeller 商品ページから出品者へ、その商品のスレッドで送る
+func TestContactSeller(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	mockDAO.items[10] = &dao.MessageItem{ID: 10, SellerUID: "seller"}
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
+
+	msg, err := usecase.ContactSeller("buyer", 10, "Is this still available?")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if msg.ReceiverUID != "seller" || msg.ItemID == nil || *msg.ItemID != 10 {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+
+	if _, err := usecase.ContactSeller("seller", 10, "Hello"); !errors.Is(err, ErrOwnItem) {
+		t.Errorf("expected ErrOwnItem, got %v", err)
+	}
+	if _, err := usecase.ContactSeller("buyer", 99, "Hello"); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("expected ErrItemNotFound, got %v", err)
+	}
+
+	mockDAO.blocks[[2]string{"seller", "buyer"}] = true
+	if _, err := usecase.ContactSeller("buyer", 10, "Hello"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("expected ErrBlocked, got %v", err)
+	}
+}
+
+// TestMarkAsRead_ItemThread 既読にするのは指定したスレッドのメッセージだけ
+func TestMarkAsRead_ItemThread(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	mockDAO.items[10] = &dao.MessageItem{ID: 10, SellerUID: "seller"}
+	publisher := &MockPublisher{}
+	usecase := NewMessageUsecase(mockDAO, publisher, NewMockAttachmentStore(), nil, nil, nil)
+
+	_, _ = usecase.SendMessage("buyer", "seller", "Hello", nil, nil)
+	_, _ = usecase.SendMessage("buyer", "seller", "About item 10", intPtr(10), nil)
+
+	if err := usecase.MarkAsRead("seller", "buyer", intPtr(10)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, msg := range mockDAO.messages {
+		if read := msg.ItemID != nil; msg.IsRead != read {
+			t.Errorf("message %d: expected is_read=%v, got %v", msg.ID, read, msg.IsRead)
+		}
+	}
+	read := publisher.ofType(EventMessagesRead)
+	if len(read) != 1 {
+		t.Fatalf("expected 1 read receipt, got %d", len(read))
+	}
+	if data := read[0].data.(ReadEvent); data.ItemID == nil || *data.ItemID != 10 {
+		t.Errorf("expected read receipt for item 10, got %+v", data)
+	}
+}
+
+// TestUploadAttachment アップロードした画像は未添付のまま作成され、内容のないファイルや利用停止中のユーザーは拒否する
+func TestUploadAttachment(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
+
+	attachment, err := upload(usecase, "sender", 1024)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if attachment.MessageID != nil || attachment.UploaderUID != "sender" || attachment.SizeBytes != 1024 {
+		t.Errorf("unexpected attachment: %+v", attachment)
+	}
+	if attachment.URL == "" || attachment.ThumbnailURL == "" {
+		t.Error("expected signed urls to be set")
+	}
+
+	if _, err := upload(usecase, "sender", 0); !errors.Is(err, ErrInvalidAttachment) {
+		t.Errorf("expected ErrInvalidAttachment, got %v", err)
+	}
+	if _, err := usecase.UploadAttachment("sender", nil, nil); !errors.Is(err, ErrInvalidAttachment) {
+		t.Errorf("expected ErrInvalidAttachment for missing file, got %v", err)
+	}
+	mockDAO.suspended["sender"] = true
+	if _, err := upload(usecase, "sender", 1024); !errors.Is(err, ErrSuspended) {
+		t.Errorf("expected ErrSuspended, got %v", err)
+	}
+}
+
+// TestSendMessage_Attachments 添付画像付きのメッセージは本文を省略でき、配信するメッセージにも署名付きURLが含まれる
+func TestSendMessage_Attachments(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	publisher := &MockPublisher{}
+	usecase := NewMessageUsecase(mockDAO, publisher, NewMockAttachmentStore(), nil, nil, nil)
+
+	attachment, _ := upload(usecase, "sender", 1024)
+	msg, err := usecase.SendMessage("sender", "receiver", "", nil, []int64{attachment.ID})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].URL == "" {
+		t.Fatalf("expected 1 signed attachment, got %+v", msg.Attachments)
+	}
+	if created := publisher.ofType(EventMessageCreated); len(created) != 1 || len(created[0].data.(*dao.Message).Attachments) != 1 {
+		t.Error("expected published message to include the attachment")
+	}
+
+	// 添付済みの画像は別のメッセージに使えない
+	if _, err := usecase.SendMessage("sender", "receiver", "again", nil, []int64{attachment.ID}); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Errorf("expected ErrAttachmentNotFound, got %v", err)
+	}
+}
+
+// TestSendMessage_AttachmentValidation 本文も添付もない・添付が多すぎる・他人の画像を添付する場合は送信できない
+func TestSendMessage_AttachmentValidation(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
+
+	if _, err := usecase.SendMessage("sender", "receiver", "", nil, nil); !errors.Is(err, ErrEmptyMessage) {
+		t.Errorf("expected ErrEmptyMessage, got %v", err)
+	}
+	ids := make([]int64, maxAttachmentsPerMessage+1)
+	for i := range ids {
+		a, _ := upload(usecase, "sender", 1024)
+		ids[i] = a.ID
+	}
+	if _, err := usecase.SendMessage("sender", "receiver", "", nil, ids); !errors.Is(err, ErrTooManyAttachments) {
+		t.Errorf("expected ErrTooManyAttachments, got %v", err)
+	}
+
+	others, _ := upload(usecase, "other", 1024)
+	if _, err := usecase.SendMessage("sender", "receiver", "", nil, []int64{others.ID}); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Errorf("expected ErrAttachmentNotFound, got %v", err)
+	}
+	if len(mockDAO.messages) != 0 {
+		t.Errorf("expected no messages to be created, got %d", len(mockDAO.messages))
+	}
+}
+
+// TestAttachmentURL 添付画像を見られるのは会話の二人だけ（送信前はアップロードした本人だけ）
+func TestAttachmentURL(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
+
+	attachment, _ := upload(usecase, "sender", 1024)
+	if _, err := usecase.AttachmentURL("sender", attachment.ID, "full"); err != nil {
+		t.Errorf("expected uploader to view unsent attachment, got %v", err)
+	}
+	if _, err := usecase.AttachmentURL("receiver", attachment.ID, "full"); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Errorf("expected ErrAttachmentNotFound before sending, got %v", err)
+	}
+
+	_, _ = usecase.SendMessage("sender", "receiver", "photo", nil, []int64{attachment.ID})
+	url, err := usecase.AttachmentURL("receiver", attachment.ID, "thumb")
+	if err != nil {
+		t.Fatalf("expected receiver to view attachment, got %v", err)
+	}
+	if !strings.Contains(url, "_thumb.") {
+		t.Errorf("expected thumbnail url, got %s", url)
+	}
+	if _, err := usecase.AttachmentURL("stranger", attachment.ID, "full"); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Errorf("expected ErrAttachmentNotFound for stranger, got %v", err)
+	}
+	if _, err := usecase.AttachmentURL("sender", 999, "full"); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Errorf("expected ErrAttachmentNotFound for missing attachment, got %v", err)
+	}
+}
+
+// TestGetMessages_Pagination 新しい方からlimit件ずつ古い順で返し、next_cursorで前のページをたどれる
+func TestGetMessages_Pagination(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
+	for i := 1; i <= 5; i++ {
+		_, _ = usecase.SendMessage("user1", "user2", fmt.Sprintf("message %d", i), nil, nil)
+	}
+
+	page, err := usecase.GetMessages("user1", "user2", nil, 0, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(page.Messages) != 2 || page.Messages[0].ID != 4 || page.Messages[1].ID != 5 {
+		t.Fatalf("expected messages 4 and 5, got %+v", page.Messages)
+	}
+	if page.NextCursor == nil || *page.NextCursor != 4 {
+		t.Fatalf("expected next cursor 4, got %v", page.NextCursor)
+	}
+
+	var ids []int
+	for cursor := page.NextCursor; cursor != nil; cursor = page.NextCursor {
+		page, _ = usecase.GetMessages("user1", "user2", nil, *cursor, 2)
+		for _, msg := range page.Messages {
+			ids = append(ids, msg.ID)
+		}
+	}
+	if fmt.Sprint(ids) != "[2 3 1]" {
+		t.Errorf("expected pages [2 3] then [1], got %v", ids)
+	}
+
+	// 上限を超えるlimitは切り詰める
+	if page, _ := usecase.GetMessages("user1", "user2", nil, 0, maxMessagePageSize+1); len(page.Messages) != 5 || page.NextCursor != nil {
+		t.Errorf("expected all 5 messages without cursor, got %d", len(page.Messages))
+	}
+}
+
+// TestGetThread 50件（1ページの上限）を超えるスレッドでもすべてのメッセージを古い順に返す
+func TestGetThread(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
+	const count = 2*maxMessagePageSize + 10
+	for i := 1; i <= count; i++ {
+		sender, receiver := "user1", "user2"
+		if i%2 == 0 {
+			sender, receiver = receiver, sender
+		}
+		if _, err := usecase.SendMessage(sender, receiver, fmt.Sprintf("message %d", i), nil, nil); err != nil {
+			t.Fatalf("failed to send message %d: %v", i, err)
+		}
+	}
+	// 別のスレッドのメッセージは含めない
+	_, _ = usecase.SendMessage("user1", "user3", "other thread", nil, nil)
+
+	messages, err := usecase.GetThread("user1", "user2", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(messages) != count {
+		t.Fatalf("expected %d messages, got %d", count, len(messages))
+	}
+	for i, msg := range messages {
+		if msg.ID != i+1 {
+			t.Fatalf("expected message %d at %d, got %d", i+1, i, msg.ID)
+		}
+	}
+
+	empty, err := usecase.GetThread("user2", "user3", nil)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("expected empty thread, got %d (%v)", len(empty), err)
+	}
+}
+
+// TestGetUnreadSummary 未読の件数と未読があるスレッドの数を返し、既読にすると減る
+func TestGetUnreadSummary(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	mockDAO.items[10] = &dao.MessageItem{ID: 10, SellerUID: "user1"}
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
+
+	_, _ = usecase.SendMessage("user2", "user1", "Hello", nil, nil)
+	_, _ = usecase.SendMessage("user2", "user1", "About item 10", intPtr(10), nil)
+	_, _ = usecase.SendMessage("user2", "user1", "Still there?", intPtr(10), nil)
+	_, _ = usecase.SendMessage("user3", "user1", "Hi", nil, nil)
+	_, _ = usecase.SendMessage("user1", "user2", "Reply", nil, nil)
+
+	summary, err := usecase.GetUnreadSummary("user1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if summary.Total != 4 || summary.Conversations != 3 {
+		t.Errorf("expected 4 unread in 3 threads, got %+v", summary)
+	}
+
+	_ = usecase.MarkAsRead("user1", "user2", intPtr(10))
+	summary, _ = usecase.GetUnreadSummary("user1")
+	if summary.Total != 2 || summary.Conversations != 2 {
+		t.Errorf("expected 2 unread in 2 threads after reading item thread, got %+v", summary)
+	}
+}
+
+// TestPostSystemMessage 取引の節目を商品のスレッドに書き込み、相手の未読になる（ブロック関係でも書き込む）
+func TestPostSystemMessage(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	publisher := &MockPublisher{}
+	usecase := NewMessageUsecase(mockDAO, publisher, NewMockAttachmentStore(), nil, nil, nil)
+	mockDAO.blocks[[2]string{"seller", "buyer"}] = true
+
+	if err := usecase.PostSystemMessage(10, "buyer", "seller", dao.SystemEventPurchased); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(mockDAO.messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(mockDAO.messages))
+	}
+	msg := mockDAO.messages[0]
+	if msg.Type != dao.TypeSystem || msg.SystemEvent != dao.SystemEventPurchased || msg.Content == "" || *msg.ItemID != 10 {
+		t.Errorf("unexpected system message: %+v", msg)
+	}
+	if len(publisher.ofType(EventMessageCreated)) != 1 {
+		t.Error("expected message.created to be published")
+	}
+	if unread := publisher.ofType(EventUnreadCount); len(unread) != 1 || unread[0].uids[0] != "seller" {
+		t.Errorf("expected unread count for seller, got %+v", unread)
+	}
+
+	// 未登録のウォレットからの購入など、相手が不明な場合は書き込まない
+	if err := usecase.PostSystemMessage(10, "", "seller", dao.SystemEventPurchased); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := usecase.PostSystemMessage(10, "buyer", "seller", "unknown"); err == nil {
+		t.Error("expected error for unknown event")
+	}
+	if len(mockDAO.messages) != 1 {
+		t.Errorf("expected no more messages, got %d", len(mockDAO.messages))
+	}
+}
+
+// TestPostItemSystemMessage 出品の取り消しは商品のスレッドで出品者とやり取りしていた全員に届く
+func TestPostItemSystemMessage(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	mockDAO.items[10] = &dao.MessageItem{ID: 10, SellerUID: "seller"}
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
+
+	_, _ = usecase.ContactSeller("buyer1", 10, "Is this available?")
+	_, _ = usecase.ContactSeller("buyer2", 10, "Can you discount?")
+	_, _ = usecase.SendMessage("seller", "buyer1", "Yes", intPtr(10), nil)
+	_, _ = usecase.SendMessage("buyer3", "seller", "Unrelated", nil, nil)
+
+	if err := usecase.PostItemSystemMessage(10, dao.SystemEventItemCancelled); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var receivers []string
+	for _, msg := range mockDAO.messages {
+		if msg.Type == dao.TypeSystem {
+			receivers = append(receivers, msg.SenderUID+"->"+msg.ReceiverUID)
+		}
+	}
+	if fmt.Sprint(receivers) != "[seller->buyer1 seller->buyer2]" {
+		t.Errorf("unexpected system messages: %v", receivers)
+	}
+
+	if err := usecase.PostItemSystemMessage(99, dao.SystemEventItemCancelled); err != nil {
+		t.Errorf("expected missing item to be ignored, got %v", err)
+	}
+}
+
+// TestEditMessage 送信者は期間内なら編集でき、編集前の本文が履歴に残り、両者に配信される
+func TestEditMessage(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	publisher := &MockPublisher{}
+	usecase := NewMessageUsecase(mockDAO, publisher, NewMockAttachmentStore(), nil, nil, nil)
+	sent, _ := usecase.SendMessage("sender", "receiver", "helo", nil, nil)
+
+	edited, err := usecase.EditMessage("sender", sent.ID, "hello")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if edited.Content != "hello" || edited.EditedAt == nil {
+		t.Errorf("expected edited message, got %+v", edited)
+	}
+	events := publisher.ofType(EventMessageUpdated)
+	if len(events) != 1 || len(events[0].uids) != 2 {
+		t.Fatalf("expected 1 update event to both parties, got %+v", events)
+	}
+
+	page, _ := usecase.GetMessages("receiver", "sender", nil, 0, 0)
+	if page.Messages[0].Content != "hello" || page.Messages[0].EditedAt == nil {
+		t.Errorf("expected receiver to see edited marker, got %+v", page.Messages[0])
+	}
+	if edits := mockDAO.edits[sent.ID]; len(edits) != 1 || edits[0].PreviousContent != "helo" {
+		t.Errorf("expected previous content in history, got %+v", edits)
+	}
+
+	// 本文が変わらない編集は履歴に残さない
+	if _, err := usecase.EditMessage("sender", sent.ID, "hello"); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if len(mockDAO.edits[sent.ID]) != 1 {
+		t.Errorf("expected unchanged edit to be skipped, got %d edits", len(mockDAO.edits[sent.ID]))
+	}
+	if _, err := usecase.EditMessage("sender", sent.ID, ""); !errors.Is(err, ErrEmptyMessage) {
+		t.Errorf("expected ErrEmptyMessage, got %v", err)
+	}
+}
+
+// TestEditMessage_NotEditable 受信者・期間切れ・システムメッセージ・当事者以外は編集できない
+func TestEditMessage_NotEditable(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
+	sent, _ := usecase.SendMessage("sender", "receiver", "hi", nil, nil)
+
+	if _, err := usecase.EditMessage("receiver", sent.ID, "changed"); !errors.Is(err, ErrMessageNotEditable) {
+		t.Errorf("expected ErrMessageNotEditable for receiver, got %v", err)
+	}
+	if _, err := usecase.EditMessage("stranger", sent.ID, "changed"); !errors.Is(err, ErrMessageNotFound) {
+		t.Errorf("expected ErrMessageNotFound for stranger, got %v", err)
+	}
+	if _, err := usecase.EditMessage("sender", 999, "changed"); !errors.Is(err, ErrMessageNotFound) {
+		t.Errorf("expected ErrMessageNotFound, got %v", err)
+	}
+
+	mockDAO.items[1] = &dao.MessageItem{ID: 1, SellerUID: "seller"}
+	system, _ := mockDAO.CreateSystemMessage("sender", "receiver", 1, dao.SystemEventPurchased, "購入されました")
+	if _, err := usecase.EditMessage("sender", system.ID, "changed"); !errors.Is(err, ErrMessageNotEditable) {
+		t.Errorf("expected ErrMessageNotEditable for system message, got %v", err)
+	}
+
+	sent.CreatedAt = time.Now().Add(-messageEditWindow - time.Minute)
+	if _, err := usecase.EditMessage("sender", sent.ID, "changed"); !errors.Is(err, ErrMessageNotEditable) {
+		t.Errorf("expected ErrMessageNotEditable after window, got %v", err)
+	}
+	if err := usecase.DeleteMessage("sender", sent.ID); !errors.Is(err, ErrMessageNotEditable) {
+		t.Errorf("expected ErrMessageNotEditable after window, got %v", err)
+	}
+	if len(mockDAO.edits) != 0 || sent.DeletedAt != nil {
+		t.Errorf("expected message to be unchanged, got %+v", sent)
+	}
+}
+
+// TestEditMessage_Blocked ブロックされた相手へのメッセージは編集できない
+func TestEditMessage_Blocked(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
+	sent, _ := usecase.SendMessage("sender", "receiver", "hi", nil, nil)
+	mockDAO.blocks[[2]string{"receiver", "sender"}] = true
+
+	if _, err := usecase.EditMessage("sender", sent.ID, "changed"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("expected ErrBlocked, got %v", err)
+	}
+}
+
+// TestDeleteMessage 削除したメッセージは当事者には本文・添付画像のない状態で返り、未読件数からも除かれる
+func TestDeleteMessage(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	publisher := &MockPublisher{}
+	usecase := NewMessageUsecase(mockDAO, publisher, NewMockAttachmentStore(), nil, nil, nil)
+	attachment, _ := upload(usecase, "sender", 1024)
+	sent, _ := usecase.SendMessage("sender", "receiver", "secret", nil, []int64{attachment.ID})
+
+	if err := usecase.DeleteMessage("receiver", sent.ID); !errors.Is(err, ErrMessageNotEditable) {
+		t.Errorf("expected ErrMessageNotEditable for receiver, got %v", err)
+	}
+	if err := usecase.DeleteMessage("sender", sent.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := usecase.DeleteMessage("sender", sent.ID); !errors.Is(err, ErrMessageNotEditable) {
+		t.Errorf("expected ErrMessageNotEditable for deleted message, got %v", err)
+	}
+	if _, err := usecase.EditMessage("sender", sent.ID, "changed"); !errors.Is(err, ErrMessageNotEditable) {
+		t.Errorf("expected deleted message to be uneditable, got %v", err)
+	}
+
+	page, _ := usecase.GetMessages("receiver", "sender", nil, 0, 0)
+	if len(page.Messages) != 1 {
+		t.Fatalf("expected tombstone to remain in history, got %d messages", len(page.Messages))
+	}
+	tombstone := page.Messages[0]
+	if tombstone.DeletedAt == nil || tombstone.Content != "" || len(tombstone.Attachments) != 0 {
+		t.Errorf("expected tombstone without content, got %+v", tombstone)
+	}
+	if _, err := usecase.AttachmentURL("receiver", attachment.ID, "full"); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Errorf("expected deleted attachment to be hidden, got %v", err)
+	}
+
+	events := publisher.ofType(EventMessageDeleted)
+	if len(events) != 1 || events[0].data.(DeletedEvent).MessageID != sent.ID {
+		t.Fatalf("expected 1 delete event, got %+v", events)
+	}
+	unread := publisher.ofType(EventUnreadCount)
+	if last := unread[len(unread)-1].data.(UnreadCountEvent); last.TotalUnread != 0 {
+		t.Errorf("expected unread count to drop to 0, got %+v", last)
+	}
+}
+
+// TestGetMessageForModeration モデレーターは削除・編集されたメッセージの本文と編集履歴を確認できる
+func TestGetMessageForModeration(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), nil, nil, nil)
+	attachment, _ := upload(usecase, "sender", 1024)
+	sent, _ := usecase.SendMessage("sender", "receiver", "pay me outside", nil, []int64{attachment.ID})
+	_, _ = usecase.EditMessage("sender", sent.ID, "never mind")
+	_ = usecase.DeleteMessage("sender", sent.ID)
+
+	message, err := usecase.GetMessageForModeration(sent.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if message.Content != "never mind" || message.DeletedAt == nil {
+		t.Errorf("expected deleted message with content, got %+v", message.Message)
+	}
+	if len(message.Edits) != 1 || message.Edits[0].PreviousContent != "pay me outside" {
+		t.Errorf("expected original content in edits, got %+v", message.Edits)
+	}
+	if len(message.Attachments) != 1 || message.Attachments[0].URL == "" {
+		t.Errorf("expected signed attachment, got %+v", message.Attachments)
+	}
+	if _, err := usecase.GetMessageForModeration(999); !errors.Is(err, ErrMessageNotFound) {
+		t.Errorf("expected ErrMessageNotFound, got %v", err)
+	}
+}
+
+// TestSendMessage_FilterReject フィルタが拒否したメッセージは保存も配信もしない
+func TestSendMessage_FilterReject(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	publisher := &MockPublisher{}
+	queue := &MockModerationQueue{}
+	filters := FilterChain{&LengthFilter{Max: 10}, NewRateLimitFilter(mockDAO, 1, time.Minute)}
+	usecase := NewMessageUsecase(mockDAO, publisher, NewMockAttachmentStore(), filters, queue, nil)
+
+	if _, err := usecase.SendMessage("sender", "receiver", strings.Repeat("a", 11), nil, nil); !errors.Is(err, ErrMessageTooLong) {
+		t.Errorf("expected ErrMessageTooLong, got %v", err)
+	}
+	if _, err := usecase.SendMessage("sender", "receiver", "hi", nil, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := usecase.SendMessage("sender", "receiver", "hi again", nil, nil); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got %v", err)
+	}
+	if len(mockDAO.messages) != 1 || len(publisher.ofType(EventMessageCreated)) != 1 || len(queue.flagged) != 0 {
+		t.Errorf("expected only the first message to be sent, got %d messages", len(mockDAO.messages))
+	}
+}
+
+// TestSendMessage_Flagged フラグを立てたメッセージは通常どおり届き、モデレーションキューに入る
+func TestSendMessage_Flagged(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	publisher := &MockPublisher{}
+	queue := &MockModerationQueue{}
+	usecase := NewMessageUsecase(mockDAO, publisher, NewMockAttachmentStore(), FilterChain{&PaymentFilter{}}, queue, nil)
+
+	sent, err := usecase.SendMessage("sender", "receiver", "銀行振込でもいいですか？", nil, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	page, _ := usecase.GetMessages("receiver", "sender", nil, 0, 0)
+	if len(page.Messages) != 1 {
+		t.Errorf("expected receiver to see flagged message, got %d", len(page.Messages))
+	}
+	if events := publisher.ofType(EventMessageCreated); len(events) != 1 || len(events[0].uids) != 2 {
+		t.Errorf("expected message to be delivered to both, got %+v", events)
+	}
+	if len(queue.flagged) != 1 || queue.flagged[0].messageID != sent.ID || queue.flagged[0].reason != "fraud" {
+		t.Fatalf("expected message to be flagged, got %+v", queue.flagged)
+	}
+	if !strings.Contains(queue.flagged[0].detail, "off_platform_payment") {
+		t.Errorf("expected filter name in detail, got %s", queue.flagged[0].detail)
+	}
+}
+
+// TestSendMessage_Quarantined 保留したメッセージは送信者にだけ見え、受信者には届かず未読にも数えない
+func TestSendMessage_Quarantined(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	publisher := &MockPublisher{}
+	queue := &MockModerationQueue{}
+	filters := FilterChain{NewBlockedWordFilter([]string{"badword"}, FilterQuarantine, "harassment")}
+	usecase := NewMessageUsecase(mockDAO, publisher, NewMockAttachmentStore(), filters, queue, nil)
+	attachment, _ := upload(usecase, "sender", 1024)
+
+	sent, err := usecase.SendMessage("sender", "receiver", "BADWORD", nil, []int64{attachment.ID})
+	if err != nil {
+		t.Fatalf("expected sender not to notice quarantine, got %v", err)
+	}
+	if mine, _ := usecase.GetMessages("sender", "receiver", nil, 0, 0); len(mine.Messages) != 1 {
+		t.Errorf("expected sender to see own message, got %d", len(mine.Messages))
+	}
+	if theirs, _ := usecase.GetMessages("receiver", "sender", nil, 0, 0); len(theirs.Messages) != 0 {
+		t.Errorf("expected receiver not to see quarantined message, got %d", len(theirs.Messages))
+	}
+	if conversations, _ := usecase.GetConversations("receiver"); len(conversations) != 0 {
+		t.Errorf("expected no conversation for receiver, got %d", len(conversations))
+	}
+	if summary, _ := usecase.GetUnreadSummary("receiver"); summary.Total != 0 {
+		t.Errorf("expected no unread messages, got %d", summary.Total)
+	}
+
+	events := publisher.ofType(EventMessageCreated)
+	if len(events) != 1 || len(events[0].uids) != 1 || events[0].uids[0] != "sender" {
+		t.Errorf("expected event only to sender, got %+v", events)
+	}
+	if len(publisher.ofType(EventUnreadCount)) != 0 {
+		t.Error("expected no unread count event")
+	}
+	if _, err := usecase.AttachmentURL("receiver", attachment.ID, "full"); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Errorf("expected receiver not to view attachment, got %v", err)
+	}
+	if _, err := usecase.AttachmentURL("sender", attachment.ID, "full"); err != nil {
+		t.Errorf("expected sender to view attachment, got %v", err)
+	}
+	if len(queue.flagged) != 1 || queue.flagged[0].messageID != sent.ID || queue.flagged[0].reason != "harassment" {
+		t.Errorf("expected message to be flagged, got %+v", queue.flagged)
+	}
+
+	moderation, _ := usecase.GetMessageForModeration(sent.ID)
+	if moderation.QuarantinedAt == nil {
+		t.Error("expected moderators to see quarantine")
+	}
+}
+
+// TestEditMessage_Filtered 編集にもフィルタを適用し、届いた後に保留になる編集は拒否する
+func TestEditMessage_Filtered(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	publisher := &MockPublisher{}
+	queue := &MockModerationQueue{}
+	usecase := NewMessageUsecase(mockDAO, publisher, NewMockAttachmentStore(), FilterChain{&PaymentFilter{}}, queue, nil)
+	sent, _ := usecase.SendMessage("sender", "receiver", "明日発送します", nil, nil)
+
+	if _, err := usecase.EditMessage("sender", sent.ID, "振込先は foo@example.com で聞いてください"); !errors.Is(err, ErrMessageRejected) {
+		t.Errorf("expected ErrMessageRejected, got %v", err)
+	}
+	if sent.Content != "明日発送します" || len(queue.flagged) != 0 {
+		t.Errorf("expected rejected edit not to be applied, got %q", sent.Content)
+	}
+
+	if _, err := usecase.EditMessage("sender", sent.ID, "現金でも払えますか"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(queue.flagged) != 1 || queue.flagged[0].messageID != sent.ID {
+		t.Errorf("expected edited message to be flagged, got %+v", queue.flagged)
+	}
+}
+
+// TestSendMessage_Notifies 受信者に新しいメッセージを通知し、保留したメッセージは通知しない
+func TestSendMessage_Notifies(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	notifier := &MockNotifier{}
+	filters := FilterChain{NewBlockedWordFilter([]string{"badword"}, FilterQuarantine, "harassment")}
+	usecase := NewMessageUsecase(mockDAO, &MockPublisher{}, NewMockAttachmentStore(), filters, &MockModerationQueue{}, notifier)
+
+	if _, err := usecase.SendMessage("sender", "receiver", "hello", nil, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(notifier.notified) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(notifier.notified))
+	}
+	in := notifier.notified[0]
+	if in.UID != "receiver" || in.Type != notificationsUc.TypeMessage || in.ActorUID != "sender" || in.ItemID != nil {
+		t.Errorf("unexpected notification: %+v", in)
+	}
+
+	if _, err := usecase.SendMessage("sender", "receiver", "badword", nil, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(notifier.notified) != 1 {
+		t.Errorf("expected no notification for quarantined message, got %d", len(notifier.notified))
+	}
+}
+
+// TestMessageReleased 保留を解除したメッセージを受信者に届け、未読に数えて通知する
+func TestMessageReleased(t *testing.T) {
+	mockDAO := NewMockMessageDAO()
+	publisher := &MockPublisher{}
+	notifier := &MockNotifier{}
+	filters := FilterChain{NewBlockedWordFilter([]string{"badword"}, FilterQuarantine, "harassment")}
+	usecase := NewMessageUsecase(mockDAO, publisher, NewMockAttachmentStore(), filters, &MockModerationQueue{}, notifier)
+
+	sent, err := usecase.SendMessage("sender", "receiver", "BADWORD", nil, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(notifier.notified) != 0 {
+		t.Fatalf("expected no notification while quarantined, got %d", len(notifier.notified))
+	}
+
+	// モデレーターが通報を却下した（reports.ReportDAO.Resolveが保留を解除する）
+	sent.QuarantinedAt = nil
+	if err := usecase.MessageReleased(sent.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	events := publisher.ofType(EventMessageCreated)
+	if len(events) != 2 || len(events[1].uids) != 1 || events[1].uids[0] != "receiver" {
+		t.Errorf("expected released message to be sent to receiver, got %+v", events)
+	}
+	unread := publisher.ofType(EventUnreadCount)
+	if len(unread) != 1 || unread[0].data.(UnreadCountEvent).TotalUnread != 1 {
+		t.Errorf("expected unread count 1, got %+v", unread)
+	}
+	if len(notifier.notified) != 1 || notifier.notified[0].UID != "receiver" {
+		t.Errorf("expected receiver to be notified, got %+v", notifier.notified)
+	}
+
+	// 削除されたメッセージは届けない
+	now := time.Now()
+	sent.DeletedAt = &now
+	if err := usecase.MessageReleased(sent.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(publisher.ofType(EventMessageCreated)) != 2 || len(notifier.notified) != 1 {
+		t.Error("expected deleted message not to be delivered")
+	}
+}
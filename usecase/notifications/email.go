@@ -0,0 +1,206 @@
+package notifications
+
+import (
+	"bytes"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"log"
+	netmail "net/mail"
+	"strings"
+	"text/template"
+	"time"
+	dao "uttc-hackathon-backend/dao/notifications"
+	"uttc-hackathon-backend/mail"
+)
+
+// DefaultLocale はメールの既定の言語
+const DefaultLocale = "ja"
+
+// Locales はメールの言語の一覧
+var Locales = []string{"ja", "en"}
+
+// messageEmailInterval はメッセージの通知のメールを送る間隔（相手・商品ごと）
+// 続けてメッセージが届いても、この間は1通だけ送る
+const messageEmailInterval = 30 * time.Minute
+
+// emailIntervals はメールを送る通知の種類と、同じ通知のメールを再度送るまでの間隔（0なら1回だけ）
+// いいねはメールを送らない
+var emailIntervals = map[string]time.Duration{
+	TypePurchase:         0,
+	TypeReceiptConfirmed: 0,
+	TypeMessage:          messageEmailInterval,
+}
+
+var (
+	// ErrInvalidEmail はメールアドレスが不正な場合のエラー
+	ErrInvalidEmail = errors.New("invalid email address")
+	// ErrInvalidLocale はメールの言語が不正な場合のエラー
+	ErrInvalidLocale = fmt.Errorf("locale must be one of %s", strings.Join(Locales, ", "))
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// emailTemplates は言語ごとのメールのテンプレート（"<種類>.subject" と "<種類>.body" を定義する）
+var emailTemplates = map[string]*template.Template{
+	"ja": template.Must(template.ParseFS(templateFS, "templates/ja.tmpl")),
+	"en": template.Must(template.ParseFS(templateFS, "templates/en.tmpl")),
+}
+
+// recipientFallbacks はニックネームのない相手の宛名
+var recipientFallbacks = map[string]string{"ja": "ユーザー", "en": "there"}
+
+// Mailer はメールを送信待ちに追加する（mail.Queue）
+type Mailer interface {
+	Enqueue(msg *mail.Message) error
+}
+
+// Presence はユーザーがアプリに接続しているか（realtime.Hub）
+type Presence interface {
+	IsOnline(uid string) bool
+}
+
+// EmailConfig はメールの配信の設定
+type EmailConfig struct {
+	Mailer   Mailer
+	Presence Presence // nilなら接続中でもメッセージのメールを送る
+	BaseURL  string   // メールに載せるリンクのベースURL（空ならリンクを載せない）
+}
+
+// emailData はテンプレートに渡す値
+type emailData struct {
+	RecipientName string
+	ActorName     string // 未登録のウォレット・ニックネームのないユーザーなら空
+	ItemTitle     string
+	URL           string
+	SettingsURL   string
+}
+
+// GetEmailSettings はメールの設定を返す（設定していなければ送らない設定として返す）
+func (u *NotificationUsecase) GetEmailSettings(uid string) (*dao.EmailSettings, error) {
+	settings, err := u.notificationDAO.GetEmailSettings(uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &dao.EmailSettings{Locale: DefaultLocale}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email settings: %w", err)
+	}
+	return settings, nil
+}
+
+// SetEmailSettings はメールの送信先・言語・送るかどうかを保存する
+func (u *NotificationUsecase) SetEmailSettings(uid string, settings *dao.EmailSettings) (*dao.EmailSettings, error) {
+	settings.Email = strings.TrimSpace(settings.Email)
+	if settings.Email != "" {
+		// 表示名付きの形式（"name <a@example.com>"）は受け付けない
+		addr, err := netmail.ParseAddress(settings.Email)
+		if err != nil || addr.Address != settings.Email {
+			return nil, ErrInvalidEmail
+		}
+	} else if settings.Enabled {
+		return nil, fmt.Errorf("%w: email is required to enable email notifications", ErrInvalidEmail)
+	}
+	if settings.Locale == "" {
+		settings.Locale = DefaultLocale
+	}
+	if _, ok := emailTemplates[settings.Locale]; !ok {
+		return nil, ErrInvalidLocale
+	}
+
+	if err := u.notificationDAO.SetEmailSettings(uid, settings); err != nil {
+		return nil, fmt.Errorf("failed to save email settings: %w", err)
+	}
+	return u.GetEmailSettings(uid)
+}
+
+// sendEmail は重要な通知をメールでも送る（送信はQueueに任せ、失敗してもログに残すだけにする）
+// メッセージは相手がアプリに接続していないときだけ送る
+func (u *NotificationUsecase) sendEmail(n *dao.Notification) {
+	if u.email == nil || u.email.Mailer == nil {
+		return
+	}
+	interval, ok := emailIntervals[n.Type]
+	if !ok {
+		return
+	}
+	if n.Type == TypeMessage && u.email.Presence != nil && u.email.Presence.IsOnline(n.UID) {
+		return
+	}
+
+	settings, err := u.notificationDAO.GetEmailSettings(n.UID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		log.Printf("WARNING: failed to get email settings for uid=%s: %v", n.UID, err)
+		return
+	}
+	if !settings.Enabled || settings.Email == "" {
+		return
+	}
+
+	// 送る権利を取る前に作っておき、作れなかったメールで権利を使わないようにする
+	msg, err := u.renderEmail(settings, n)
+	if err != nil {
+		log.Printf("WARNING: failed to render email for notification id=%d: %v", n.ID, err)
+		return
+	}
+
+	claimed, err := u.notificationDAO.ClaimEmail(n.ID, interval)
+	if err != nil {
+		log.Printf("WARNING: failed to claim email for notification id=%d: %v", n.ID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+	// 送信待ちに追加できなければ権利を戻す（送ったことにすると、次の通知でもメールが届かない）
+	if err := u.email.Mailer.Enqueue(msg); err != nil {
+		log.Printf("WARNING: failed to queue email for notification id=%d: %v", n.ID, err)
+		if err := u.notificationDAO.ReleaseEmail(n.ID); err != nil {
+			log.Printf("WARNING: failed to release email claim for notification id=%d: %v", n.ID, err)
+		}
+	}
+}
+
+// renderEmail は設定の言語のテンプレートでメールを作る（未対応の言語なら既定の言語）
+func (u *NotificationUsecase) renderEmail(settings *dao.EmailSettings, n *dao.Notification) (*mail.Message, error) {
+	locale := settings.Locale
+	tmpl, ok := emailTemplates[locale]
+	if !ok {
+		locale = DefaultLocale
+		tmpl = emailTemplates[locale]
+	}
+
+	data := emailData{
+		RecipientName: settings.Nickname,
+		ActorName:     n.ActorNickname,
+		ItemTitle:     n.ItemTitle,
+	}
+	if data.RecipientName == "" {
+		data.RecipientName = recipientFallbacks[locale]
+	}
+	if base := strings.TrimRight(u.email.BaseURL, "/"); base != "" {
+		data.SettingsURL = base + "/settings/notifications"
+		if n.Type == TypeMessage {
+			data.URL = base + "/messages"
+		} else if n.ItemID != nil {
+			data.URL = fmt.Sprintf("%s/items/%d", base, *n.ItemID)
+		}
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, n.Type+".subject", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.ExecuteTemplate(&body, n.Type+".body", data); err != nil {
+		return nil, err
+	}
+	return &mail.Message{
+		To:      settings.Email,
+		Subject: strings.TrimSpace(subject.String()),
+		Body:    body.String(),
+	}, nil
+}
@@ -0,0 +1,291 @@
+package notifications
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	dao "uttc-hackathon-backend/dao/notifications"
+	"uttc-hackathon-backend/mail"
+	"uttc-hackathon-backend/mail/mailtest"
+)
+
+// MockPresence はテスト用の接続状態
+type MockPresence struct {
+	online map[string]bool
+}
+
+func (p *MockPresence) IsOnline(uid string) bool {
+	return p.online[uid]
+}
+
+// emailTestEnv はテスト用のSMTPサーバーに送信するNotificationUsecase
+type emailTestEnv struct {
+	usecase  *NotificationUsecase
+	dao      *MockNotificationDAO
+	server   *mailtest.Server
+	queue    *mail.Queue
+	presence *MockPresence
+}
+
+func newEmailTestEnv(t *testing.T) *emailTestEnv {
+	t.Helper()
+	server, err := mailtest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start SMTP server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	sender, err := mail.NewSMTPSender(mail.SMTPConfig{Addr: server.Addr(), From: "ethershop <noreply@example.com>"})
+	if err != nil {
+		t.Fatalf("failed to create sender: %v", err)
+	}
+	queue := mail.NewQueue(sender, mail.QueueConfig{Workers: 1, Backoff: 10 * time.Millisecond})
+	t.Cleanup(queue.Close)
+
+	env := &emailTestEnv{
+		dao:      NewMockNotificationDAO(),
+		server:   server,
+		queue:    queue,
+		presence: &MockPresence{online: make(map[string]bool)},
+	}
+	env.usecase = NewNotificationUsecase(env.dao, nil, &EmailConfig{
+		Mailer:   queue,
+		Presence: env.presence,
+		BaseURL:  "https://example.com/",
+	})
+	return env
+}
+
+// sent は送信待ちのメールを送り切ってから、SMTPサーバーが受け取ったメールを返す
+func (e *emailTestEnv) sent() []*mailtest.Received {
+	e.queue.Close()
+	return e.server.Messages()
+}
+
+func decode(t *testing.T, r *mailtest.Received) (string, string) {
+	t.Helper()
+	subject, err := r.Subject()
+	if err != nil {
+		t.Fatalf("failed to decode subject: %v", err)
+	}
+	body, err := r.Body()
+	if err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	return subject, body
+}
+
+// TestNotify_PurchaseEmail 商品が購入されたら出品者に日本語のメールを1通だけ送る
+func TestNotify_PurchaseEmail(t *testing.T) {
+	env := newEmailTestEnv(t)
+	env.dao.nicknames["seller"] = "花子"
+	env.dao.emailSettings["seller"] = &dao.EmailSettings{Email: "seller@example.com", Locale: "ja", Enabled: true}
+
+	in := NotifyInput{UID: "seller", Type: TypePurchase, ActorUID: "buyer", ItemID: intPtr(1), RefID: 10}
+	if err := env.usecase.Notify(in); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// 同じ注文の購入をもう一度通知してもメールは増えない
+	if err := env.usecase.Notify(in); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	messages := env.sent()
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 email, got %d", len(messages))
+	}
+	if messages[0].To[0] != "seller@example.com" {
+		t.Errorf("unexpected recipient: %v", messages[0].To)
+	}
+	subject, body := decode(t, messages[0])
+	if subject != "「カメラ」が購入されました" {
+		t.Errorf("unexpected subject: %q", subject)
+	}
+	for _, want := range []string{"花子さん", "太郎さんに購入されました", "https://example.com/items/1", "https://example.com/settings/notifications"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected body to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+// TestNotify_EmailLocale 英語を選んだユーザーには英語のテンプレートで送る
+func TestNotify_EmailLocale(t *testing.T) {
+	env := newEmailTestEnv(t)
+	env.dao.emailSettings["seller"] = &dao.EmailSettings{Email: "seller@example.com", Locale: "en", Enabled: true}
+
+	// 未登録のウォレットからの受け取り確認（ActorUIDなし）
+	err := env.usecase.Notify(NotifyInput{UID: "seller", Type: TypeReceiptConfirmed, ItemID: intPtr(1), RefID: 10})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	messages := env.sent()
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 email, got %d", len(messages))
+	}
+	subject, body := decode(t, messages[0])
+	if subject != `Receipt confirmed for "カメラ"` {
+		t.Errorf("unexpected subject: %q", subject)
+	}
+	if !strings.HasPrefix(body, "Hi there,") || !strings.Contains(body, `The buyer confirmed receipt of "カメラ"`) {
+		t.Errorf("unexpected body:\n%s", body)
+	}
+}
+
+// TestNotify_EmailRetried SMTPサーバーが一時的に失敗しても再送して届ける
+func TestNotify_EmailRetried(t *testing.T) {
+	env := newEmailTestEnv(t)
+	env.dao.emailSettings["seller"] = &dao.EmailSettings{Email: "seller@example.com", Locale: "ja", Enabled: true}
+	env.server.Fail(451, 2)
+
+	err := env.usecase.Notify(NotifyInput{UID: "seller", Type: TypePurchase, ActorUID: "buyer", ItemID: intPtr(1), RefID: 10})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if messages := env.server.WaitForMessages(1, 5*time.Second); len(messages) != 1 {
+		t.Fatalf("expected 1 email after retries, got %d", len(messages))
+	}
+}
+
+// TestNotify_EmailSkipped いいね・メールを送らない設定・設定なしの場合はメールを送らない
+func TestNotify_EmailSkipped(t *testing.T) {
+	env := newEmailTestEnv(t)
+	env.dao.owners[2] = "disabled"
+	env.dao.owners[3] = "nosettings"
+	env.dao.emailSettings["seller"] = &dao.EmailSettings{Email: "seller@example.com", Locale: "ja", Enabled: true}
+	env.dao.emailSettings["disabled"] = &dao.EmailSettings{Email: "disabled@example.com", Locale: "ja", Enabled: false}
+
+	inputs := []NotifyInput{
+		{Type: TypeLike, ActorUID: "buyer", ItemID: intPtr(1)},
+		{Type: TypePurchase, ActorUID: "buyer", ItemID: intPtr(2), RefID: 20},
+		{Type: TypePurchase, ActorUID: "buyer", ItemID: intPtr(3), RefID: 30},
+	}
+	for _, in := range inputs {
+		if err := env.usecase.Notify(in); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	if len(env.dao.notifications) != 3 {
+		t.Errorf("expected in-app notifications to be saved, got %d", len(env.dao.notifications))
+	}
+	if messages := env.sent(); len(messages) != 0 {
+		t.Errorf("expected no emails, got %d", len(messages))
+	}
+}
+
+// TestNotify_MessageEmail メッセージのメールは相手が接続していないときだけ、一定の間隔を空けて送る
+func TestNotify_MessageEmail(t *testing.T) {
+	env := newEmailTestEnv(t)
+	env.dao.emailSettings["seller"] = &dao.EmailSettings{Email: "seller@example.com", Locale: "ja", Enabled: true}
+	in := NotifyInput{UID: "seller", Type: TypeMessage, ActorUID: "buyer", ItemID: intPtr(1)}
+
+	// 接続中ならアプリの通知だけ
+	env.presence.online["seller"] = true
+	if err := env.usecase.Notify(in); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// 接続していなければメールを送り、続けて届いたメッセージでは送らない
+	env.presence.online["seller"] = false
+	for i := 0; i < 3; i++ {
+		if err := env.usecase.Notify(in); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	// 間隔を過ぎたら再度送る
+	for id := range env.dao.emailedAt {
+		env.dao.emailedAt[id] = time.Now().Add(-messageEmailInterval - time.Minute)
+	}
+	if err := env.usecase.Notify(in); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	messages := env.sent()
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 emails, got %d", len(messages))
+	}
+	subject, body := decode(t, messages[0])
+	if subject != "太郎さんから新しいメッセージが届きました" {
+		t.Errorf("unexpected subject: %q", subject)
+	}
+	if !strings.Contains(body, "「カメラ」について") || !strings.Contains(body, "https://example.com/messages") {
+		t.Errorf("unexpected body:\n%s", body)
+	}
+}
+
+// failingMailer は送信待ちに追加できないMailer（キューが満杯の場合など）
+type failingMailer struct {
+	next Mailer
+	fail bool
+}
+
+func (m *failingMailer) Enqueue(msg *mail.Message) error {
+	if m.fail {
+		return mail.ErrQueueFull
+	}
+	return m.next.Enqueue(msg)
+}
+
+// TestNotify_EmailReleasedOnQueueFailure 送信待ちに追加できなかったメールは送ったことにせず、次の通知で送る
+func TestNotify_EmailReleasedOnQueueFailure(t *testing.T) {
+	env := newEmailTestEnv(t)
+	env.dao.emailSettings["seller"] = &dao.EmailSettings{Email: "seller@example.com", Locale: "ja", Enabled: true}
+	mailer := &failingMailer{next: env.queue, fail: true}
+	env.usecase.email.Mailer = mailer
+	in := NotifyInput{UID: "seller", Type: TypeMessage, ActorUID: "buyer", ItemID: intPtr(1)}
+
+	if err := env.usecase.Notify(in); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(env.dao.emailedAt) != 0 {
+		t.Errorf("expected email claim to be released, got %v", env.dao.emailedAt)
+	}
+
+	mailer.fail = false
+	if err := env.usecase.Notify(in); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if messages := env.sent(); len(messages) != 1 {
+		t.Errorf("expected 1 email after the queue recovered, got %d", len(messages))
+	}
+}
+
+// TestSetEmailSettings メールアドレス・言語を検証して保存する
+func TestSetEmailSettings(t *testing.T) {
+	env := newEmailTestEnv(t)
+
+	settings, err := env.usecase.GetEmailSettings("seller")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if settings.Enabled || settings.Locale != DefaultLocale {
+		t.Errorf("expected default settings, got %+v", settings)
+	}
+
+	invalid := []struct {
+		settings dao.EmailSettings
+		want     error
+	}{
+		{dao.EmailSettings{Email: "not-an-email", Enabled: true}, ErrInvalidEmail},
+		{dao.EmailSettings{Email: "Seller <seller@example.com>", Enabled: true}, ErrInvalidEmail},
+		{dao.EmailSettings{Email: "", Enabled: true}, ErrInvalidEmail},
+		{dao.EmailSettings{Email: "seller@example.com", Locale: "fr", Enabled: true}, ErrInvalidLocale},
+	}
+	for _, tc := range invalid {
+		s := tc.settings
+		if _, err := env.usecase.SetEmailSettings("seller", &s); !errors.Is(err, tc.want) {
+			t.Errorf("%+v: expected %v, got %v", tc.settings, tc.want, err)
+		}
+	}
+
+	settings, err = env.usecase.SetEmailSettings("seller", &dao.EmailSettings{Email: " seller@example.com ", Enabled: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if settings.Email != "seller@example.com" || settings.Locale != DefaultLocale || !settings.Enabled {
+		t.Errorf("unexpected settings: %+v", settings)
+	}
+}
@@ -0,0 +1,282 @@
+package notifications
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	dao "uttc-hackathon-backend/dao/notifications"
+)
+
+// 通知の種類
+const (
+	// TypeLike は出品した商品にいいねされた（出品者へ）
+	TypeLike = "like"
+	// TypePurchase は出品した商品が購入された（出品者へ）
+	TypePurchase = "purchase"
+	// TypeReceiptConfirmed は購入者が受け取りを確認した（出品者へ）
+	TypeReceiptConfirmed = "receipt_confirmed"
+	// TypeMessage はメッセージが届いた（受信者へ。相手・商品ごとに1件にまとめる）
+	TypeMessage = "message"
+)
+
+// Types は通知の種類の一覧（受け取り設定の順番）
+var Types = []string{TypeLike, TypePurchase, TypeReceiptConfirmed, TypeMessage}
+
+// リアルタイムで配信するイベントの種類
+const (
+	// EventNotificationCreated は新しい通知（通知の相手へ。データは*dao.Notification）
+	EventNotificationCreated = "notification.created"
+	// EventNotificationUnreadCount は未読の通知の数が変わった（通知の相手へ。データはUnreadCountEvent）
+	EventNotificationUnreadCount = "notification.unread_count"
+)
+
+type UnreadCountEvent struct {
+	UnreadCount int `json:"unread_count"`
+}
+
+var (
+	// ErrInvalidType は通知の種類が不正な場合のエラー
+	ErrInvalidType = errors.New("invalid notification type")
+	// ErrRecipientNotFound は通知の相手（商品の出品者）が見つからない場合のエラー
+	ErrRecipientNotFound = errors.New("notification recipient not found")
+)
+
+// unknownActorName はニックネームのないユーザー・未登録のウォレットの表示名
+const unknownActorName = "ユーザー"
+
+// Publisher は接続中のユーザーへイベントを届ける（realtime.Hub）
+type Publisher interface {
+	Publish(uids []string, eventType string, data interface{})
+}
+
+// NotifyInput は通知する出来事
+type NotifyInput struct {
+	UID      string // 通知する相手（空なら商品の出品者）
+	Type     string
+	ActorUID string // きっかけになったユーザー（未登録のウォレットなら空）
+	ItemID   *int
+	RefID    int64 // 出来事を区別するID（注文IDなど）。同じ出来事の通知は1件だけ作る
+}
+
+// ListResult は通知の一覧
+type ListResult struct {
+	Notifications []*dao.Notification `json:"notifications"`
+	UnreadCount   int                 `json:"unread_count"`
+	Page          int                 `json:"page"`
+	Limit         int                 `json:"limit"`
+}
+
+type NotificationUsecase struct {
+	notificationDAO dao.NotificationDAOInterface
+	publisher       Publisher
+	email           *EmailConfig
+}
+
+// NewNotificationUsecase はNotificationUsecaseを返す（emailがnilならメールを送らない）
+func NewNotificationUsecase(notificationDAO dao.NotificationDAOInterface, publisher Publisher, email *EmailConfig) *NotificationUsecase {
+	return &NotificationUsecase{notificationDAO: notificationDAO, publisher: publisher, email: email}
+}
+
+// Notify は通知を作成し、接続中なら相手に配信する。重要な通知はメールでも送る
+// 自分の操作・受け取らない設定にしている種類・既に通知した出来事は通知しない
+func (u *NotificationUsecase) Notify(in NotifyInput) error {
+	if !isValidType(in.Type) {
+		return fmt.Errorf("%w: %s", ErrInvalidType, in.Type)
+	}
+	uid := in.UID
+	if uid == "" {
+		if in.ItemID == nil {
+			return fmt.Errorf("%w: uid or item_id is required", ErrRecipientNotFound)
+		}
+		owner, err := u.notificationDAO.GetItemOwner(*in.ItemID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: item_id=%d", ErrRecipientNotFound, *in.ItemID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get item owner: %w", err)
+		}
+		uid = owner
+	}
+	if uid == in.ActorUID {
+		return nil
+	}
+
+	prefs, err := u.notificationDAO.GetPreferences(uid)
+	if err != nil {
+		return fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	if enabled, ok := prefs[in.Type]; ok && !enabled {
+		return nil
+	}
+
+	// メッセージは相手・商品ごとに1件にまとめ、新しいメッセージで未読に戻す
+	refresh := in.Type == TypeMessage
+	id, changed, err := u.notificationDAO.Upsert(&dao.Notification{
+		UID:      uid,
+		Type:     in.Type,
+		ActorUID: in.ActorUID,
+		ItemID:   in.ItemID,
+		GroupKey: groupKey(in),
+	}, refresh)
+	if err != nil {
+		return fmt.Errorf("failed to save notification: %w", err)
+	}
+	if !changed {
+		return nil
+	}
+
+	notification, err := u.notificationDAO.GetNotification(id)
+	if err != nil {
+		return fmt.Errorf("failed to get notification: %w", err)
+	}
+	setText(notification)
+	u.publish(uid, EventNotificationCreated, notification)
+	u.publishUnreadCount(uid)
+	u.sendEmail(notification)
+	return nil
+}
+
+// List は通知を新しい順に返す（unreadOnlyなら未読のみ）
+func (u *NotificationUsecase) List(uid string, unreadOnly bool, page, limit int) (*ListResult, error) {
+	notifications, err := u.notificationDAO.List(uid, unreadOnly, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	for _, n := range notifications {
+		setText(n)
+	}
+	unread, err := u.notificationDAO.CountUnread(uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return &ListResult{Notifications: notifications, UnreadCount: unread, Page: page, Limit: limit}, nil
+}
+
+// MarkRead は指定した通知を既読にし、未読の通知の数を返す（他人の通知のidは無視する）
+func (u *NotificationUsecase) MarkRead(uid string, ids []int64) (int, error) {
+	marked, err := u.notificationDAO.MarkAsRead(uid, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications as read: %w", err)
+	}
+	return u.unreadCountAfterMark(uid, marked)
+}
+
+// MarkAllRead はすべての通知を既読にし、未読の通知の数を返す
+func (u *NotificationUsecase) MarkAllRead(uid string) (int, error) {
+	marked, err := u.notificationDAO.MarkAllAsRead(uid)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark all notifications as read: %w", err)
+	}
+	return u.unreadCountAfterMark(uid, marked)
+}
+
+// GetPreferences は種類ごとの受け取り設定を返す（設定していない種類は受け取る）
+func (u *NotificationUsecase) GetPreferences(uid string) (map[string]bool, error) {
+	saved, err := u.notificationDAO.GetPreferences(uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	prefs := make(map[string]bool, len(Types))
+	for _, t := range Types {
+		prefs[t] = true
+		if enabled, ok := saved[t]; ok {
+			prefs[t] = enabled
+		}
+	}
+	return prefs, nil
+}
+
+// SetPreferences は指定した種類の受け取り設定を変更し、変更後の設定をすべて返す
+func (u *NotificationUsecase) SetPreferences(uid string, prefs map[string]bool) (map[string]bool, error) {
+	for t := range prefs {
+		if !isValidType(t) {
+			return nil, fmt.Errorf("%w: %s (must be one of %s)", ErrInvalidType, t, strings.Join(Types, ", "))
+		}
+	}
+	if len(prefs) > 0 {
+		if err := u.notificationDAO.SetPreferences(uid, prefs); err != nil {
+			return nil, fmt.Errorf("failed to save notification preferences: %w", err)
+		}
+	}
+	return u.GetPreferences(uid)
+}
+
+// unreadCountAfterMark は既読にした後の未読の数を返し、変わっていれば他の端末に配信する
+func (u *NotificationUsecase) unreadCountAfterMark(uid string, marked int64) (int, error) {
+	unread, err := u.notificationDAO.CountUnread(uid)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	if marked > 0 {
+		u.publish(uid, EventNotificationUnreadCount, UnreadCountEvent{UnreadCount: unread})
+	}
+	return unread, nil
+}
+
+func (u *NotificationUsecase) publishUnreadCount(uid string) {
+	unread, err := u.notificationDAO.CountUnread(uid)
+	if err != nil {
+		log.Printf("WARNING: failed to count unread notifications for uid=%s: %v", uid, err)
+		return
+	}
+	u.publish(uid, EventNotificationUnreadCount, UnreadCountEvent{UnreadCount: unread})
+}
+
+func (u *NotificationUsecase) publish(uid string, eventType string, data interface{}) {
+	if u.publisher == nil {
+		return
+	}
+	u.publisher.Publish([]string{uid}, eventType, data)
+}
+
+func isValidType(t string) bool {
+	for _, v := range Types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// groupKey は同じ出来事の通知を1件にまとめるキー
+// いいねは商品・ユーザーごと（取り消して再度いいねしても通知しない）、メッセージは相手・商品ごと、購入・受け取り確認は注文ごと
+func groupKey(in NotifyInput) string {
+	itemID := 0
+	if in.ItemID != nil {
+		itemID = *in.ItemID
+	}
+	return fmt.Sprintf("%s:%d:%s:%d", in.Type, itemID, in.ActorUID, in.RefID)
+}
+
+// setText は通知の文面を設定する（表示名・商品名は取得時点のものを使う）
+func setText(n *dao.Notification) {
+	actor := n.ActorNickname
+	if actor == "" {
+		actor = unknownActorName
+	}
+	item := n.ItemTitle
+	switch n.Type {
+	case TypeLike:
+		n.Text = fmt.Sprintf("%sさんが「%s」にいいねしました", actor, item)
+	case TypePurchase:
+		if n.ActorUID == "" {
+			n.Text = fmt.Sprintf("「%s」が購入されました", item)
+		} else {
+			n.Text = fmt.Sprintf("%sさんが「%s」を購入しました", actor, item)
+		}
+	case TypeReceiptConfirmed:
+		if n.ActorUID == "" {
+			n.Text = fmt.Sprintf("「%s」の受け取りが確認されました", item)
+		} else {
+			n.Text = fmt.Sprintf("%sさんが「%s」の受け取りを確認しました", actor, item)
+		}
+	case TypeMessage:
+		if item == "" {
+			n.Text = fmt.Sprintf("%sさんからメッセージが届きました", actor)
+		} else {
+			n.Text = fmt.Sprintf("%sさんから「%s」についてメッセージが届きました", actor, item)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package notifications
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	dao "uttc-hackathon-backend/dao/notifications"
+)
+
+// MockNotificationDAO はテスト用のモックDAO
+type MockNotificationDAO struct {
+	notifications []*dao.Notification
+	owners        map[int]string             // itemID -> 出品者のuid
+	prefs         map[string]map[string]bool // uid -> type -> enabled
+	nicknames     map[string]string
+	titles        map[int]string
+	emailSettings map[string]*dao.EmailSettings
+	emailedAt     map[int64]time.Time // 通知ID -> メールを送った時刻
+	nextID        int64
+	upsertErr     error
+}
+
+func NewMockNotificationDAO() *MockNotificationDAO {
+	return &MockNotificationDAO{
+		owners:    map[int]string{1: "seller"},
+		prefs:     make(map[string]map[string]bool),
+		nicknames: map[string]string{"buyer": "太郎"},
+		titles:    map[int]string{1: "カメラ"},
+
+		emailSettings: make(map[string]*dao.EmailSettings),
+		emailedAt:     make(map[int64]time.Time),
+	}
+}
+
+func (m *MockNotificationDAO) GetItemOwner(itemID int) (string, error) {
+	owner, ok := m.owners[itemID]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return owner, nil
+}
+
+func (m *MockNotificationDAO) Upsert(n *dao.Notification, refresh bool) (int64, bool, error) {
+	if m.upsertErr != nil {
+		return 0, false, m.upsertErr
+	}
+	for _, existing := range m.notifications {
+		if existing.UID == n.UID && existing.GroupKey == n.GroupKey {
+			if !refresh {
+				return existing.ID, false, nil
+			}
+			existing.ActorUID = n.ActorUID
+			existing.IsRead = false
+			existing.CreatedAt = time.Now()
+			return existing.ID, true, nil
+		}
+	}
+	m.nextID++
+	saved := *n
+	saved.ID = m.nextID
+	saved.CreatedAt = time.Now()
+	m.notifications = append(m.notifications, &saved)
+	return saved.ID, true, nil
+}
+
+func (m *MockNotificationDAO) GetNotification(id int64) (*dao.Notification, error) {
+	for _, n := range m.notifications {
+		if n.ID == id {
+			return m.joined(n), nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// joined はニックネーム・商品名を結合したコピーを返す
+func (m *MockNotificationDAO) joined(n *dao.Notification) *dao.Notification {
+	c := *n
+	c.ActorNickname = m.nicknames[n.ActorUID]
+	if n.ItemID != nil {
+		c.ItemTitle = m.titles[*n.ItemID]
+	}
+	return &c
+}
+
+func (m *MockNotificationDAO) List(uid string, unreadOnly bool, limit, offset int) ([]*dao.Notification, error) {
+	result := []*dao.Notification{}
+	// 新しい順（後に追加・更新したものが先）
+	for i := len(m.notifications) - 1; i >= 0; i-- {
+		n := m.notifications[i]
+		if n.UID != uid || (unreadOnly && n.IsRead) {
+			continue
+		}
+		result = append(result, m.joined(n))
+	}
+	if offset >= len(result) {
+		return []*dao.Notification{}, nil
+	}
+	result = result[offset:]
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (m *MockNotificationDAO) CountUnread(uid string) (int, error) {
+	count := 0
+	for _, n := range m.notifications {
+		if n.UID == uid && !n.IsRead {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MockNotificationDAO) MarkAsRead(uid string, ids []int64) (int64, error) {
+	var marked int64
+	for _, n := range m.notifications {
+		for _, id := range ids {
+			if n.ID == id && n.UID == uid && !n.IsRead {
+				n.IsRead = true
+				marked++
+			}
+		}
+	}
+	return marked, nil
+}
+
+func (m *MockNotificationDAO) MarkAllAsRead(uid string) (int64, error) {
+	var marked int64
+	for _, n := range m.notifications {
+		if n.UID == uid && !n.IsRead {
+			n.IsRead = true
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+func (m *MockNotificationDAO) GetPreferences(uid string) (map[string]bool, error) {
+	prefs := make(map[string]bool)
+	for t, enabled := range m.prefs[uid] {
+		prefs[t] = enabled
+	}
+	return prefs, nil
+}
+
+func (m *MockNotificationDAO) SetPreferences(uid string, prefs map[string]bool) error {
+	if m.prefs[uid] == nil {
+		m.prefs[uid] = make(map[string]bool)
+	}
+	for t, enabled := range prefs {
+		m.prefs[uid][t] = enabled
+	}
+	return nil
+}
+
+func (m *MockNotificationDAO) GetEmailSettings(uid string) (*dao.EmailSettings, error) {
+	s, ok := m.emailSettings[uid]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	c := *s
+	c.Nickname = m.nicknames[uid]
+	return &c, nil
+}
+
+func (m *MockNotificationDAO) SetEmailSettings(uid string, s *dao.EmailSettings) error {
+	c := *s
+	m.emailSettings[uid] = &c
+	return nil
+}
+
+func (m *MockNotificationDAO) ClaimEmail(id int64, interval time.Duration) (bool, error) {
+	if at, ok := m.emailedAt[id]; ok && (interval == 0 || time.Since(at) < interval) {
+		return false, nil
+	}
+	m.emailedAt[id] = time.Now()
+	return true, nil
+}
+
+func (m *MockNotificationDAO) ReleaseEmail(id int64) error {
+	delete(m.emailedAt, id)
+	return nil
+}
+
+// publishedEvent はMockPublisherが受け取ったイベント
+type publishedEvent struct {
+	uids      []string
+	eventType string
+	data      interface{}
+}
+
+// MockPublisher はテスト用のリアルタイム配信（イベントを記録するだけ）
+type MockPublisher struct {
+	events []publishedEvent
+}
+
+func (p *MockPublisher) Publish(uids []string, eventType string, data interface{}) {
+	p.events = append(p.events, publishedEvent{uids: uids, eventType: eventType, data: data})
+}
+
+func (p *MockPublisher) ofType(eventType string) []publishedEvent {
+	var events []publishedEvent
+	for _, e := range p.events {
+		if e.eventType == eventType {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+func intPtr(v int) *int { return &v }
+
+// TestNotify_ItemOwner 相手を指定しなければ商品の出品者に通知し、文面を付けて配信する
+func TestNotify_ItemOwner(t *testing.T) {
+	mockDAO := NewMockNotificationDAO()
+	publisher := &MockPublisher{}
+	usecase := NewNotificationUsecase(mockDAO, publisher, nil)
+
+	err := usecase.Notify(NotifyInput{Type: TypeLike, ActorUID: "buyer", ItemID: intPtr(1)})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(mockDAO.notifications) != 1 || mockDAO.notifications[0].UID != "seller" {
+		t.Fatalf("expected notification to seller, got %+v", mockDAO.notifications)
+	}
+
+	created := publisher.ofType(EventNotificationCreated)
+	if len(created) != 1 || created[0].uids[0] != "seller" {
+		t.Fatalf("expected created event to seller, got %+v", created)
+	}
+	n := created[0].data.(*dao.Notification)
+	if n.Text != "太郎さんが「カメラ」にいいねしました" {
+		t.Errorf("unexpected text: %q", n.Text)
+	}
+	counts := publisher.ofType(EventNotificationUnreadCount)
+	if len(counts) != 1 || counts[0].data.(UnreadCountEvent).UnreadCount != 1 {
+		t.Errorf("expected unread count 1, got %+v", counts)
+	}
+}
+
+// TestNotify_Skipped 自分の操作・受け取らない設定の種類・存在しない商品は通知しない
+func TestNotify_Skipped(t *testing.T) {
+	mockDAO := NewMockNotificationDAO()
+	publisher := &MockPublisher{}
+	usecase := NewNotificationUsecase(mockDAO, publisher, nil)
+
+	if err := usecase.Notify(NotifyInput{Type: TypeLike, ActorUID: "seller", ItemID: intPtr(1)}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_, _ = usecase.SetPreferences("seller", map[string]bool{TypeLike: false})
+	if err := usecase.Notify(NotifyInput{Type: TypeLike, ActorUID: "buyer", ItemID: intPtr(1)}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(mockDAO.notifications) != 0 || len(publisher.events) != 0 {
+		t.Errorf("expected no notifications, got %+v", mockDAO.notifications)
+	}
+
+	err := usecase.Notify(NotifyInput{Type: TypeLike, ActorUID: "buyer", ItemID: intPtr(99)})
+	if !errors.Is(err, ErrRecipientNotFound) {
+		t.Errorf("expected ErrRecipientNotFound, got %v", err)
+	}
+	if err := usecase.Notify(NotifyInput{UID: "seller", Type: "unknown"}); !errors.Is(err, ErrInvalidType) {
+		t.Errorf("expected ErrInvalidType, got %v", err)
+	}
+}
+
+// TestNotify_Deduplicated 同じ出来事（Webhookの再送や、いいねの取り消し後の再いいね）は1件だけ通知する
+func TestNotify_Deduplicated(t *testing.T) {
+	mockDAO := NewMockNotificationDAO()
+	publisher := &MockPublisher{}
+	usecase := NewNotificationUsecase(mockDAO, publisher, nil)
+
+	purchase := NotifyInput{UID: "seller", Type: TypePurchase, ActorUID: "buyer", ItemID: intPtr(1), RefID: 10}
+	_ = usecase.Notify(purchase)
+	_ = usecase.Notify(purchase)
+	_ = usecase.Notify(NotifyInput{Type: TypeLike, ActorUID: "buyer", ItemID: intPtr(1)})
+	_ = usecase.Notify(NotifyInput{Type: TypeLike, ActorUID: "buyer", ItemID: intPtr(1)})
+
+	if len(mockDAO.notifications) != 2 {
+		t.Errorf("expected 2 notifications, got %d", len(mockDAO.notifications))
+	}
+	if created := publisher.ofType(EventNotificationCreated); len(created) != 2 {
+		t.Errorf("expected 2 created events, got %d", len(created))
+	}
+}
+
+// TestNotify_MessageRefreshed メッセージの通知は相手・商品ごとに1件にまとめ、既読でも新しいメッセージで未読に戻す
+func TestNotify_MessageRefreshed(t *testing.T) {
+	mockDAO := NewMockNotificationDAO()
+	usecase := NewNotificationUsecase(mockDAO, &MockPublisher{}, nil)
+
+	message := NotifyInput{UID: "seller", Type: TypeMessage, ActorUID: "buyer", ItemID: intPtr(1)}
+	_ = usecase.Notify(message)
+	_, _ = usecase.MarkAllRead("seller")
+	_ = usecase.Notify(message)
+	_ = usecase.Notify(NotifyInput{UID: "seller", Type: TypeMessage, ActorUID: "buyer"})
+
+	result, err := usecase.List("seller", false, 1, 20)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(result.Notifications) != 2 || result.UnreadCount != 2 {
+		t.Fatalf("expected 2 unread notifications, got %d (unread %d)", len(result.Notifications), result.UnreadCount)
+	}
+	if result.Notifications[0].Text != "太郎さんからメッセージが届きました" {
+		t.Errorf("unexpected text: %q", result.Notifications[0].Text)
+	}
+	if result.Notifications[1].Text != "太郎さんから「カメラ」についてメッセージが届きました" {
+		t.Errorf("unexpected text: %q", result.Notifications[1].Text)
+	}
+}
+
+// TestList 新しい順にページ単位で返し、unreadOnlyなら未読だけ返す
+func TestList(t *testing.T) {
+	mockDAO := NewMockNotificationDAO()
+	usecase := NewNotificationUsecase(mockDAO, &MockPublisher{}, nil)
+
+	for i := int64(1); i <= 3; i++ {
+		_ = usecase.Notify(NotifyInput{UID: "seller", Type: TypePurchase, ItemID: intPtr(1), RefID: i})
+	}
+	_, _ = usecase.MarkRead("seller", []int64{1})
+
+	page, _ := usecase.List("seller", false, 2, 2)
+	if len(page.Notifications) != 1 || page.Notifications[0].ID != 1 || page.UnreadCount != 2 {
+		t.Errorf("expected oldest notification on page 2, got %+v", page)
+	}
+	if page.Notifications[0].Text != "「カメラ」が購入されました" {
+		t.Errorf("expected text without actor, got %q", page.Notifications[0].Text)
+	}
+
+	unread, _ := usecase.List("seller", true, 1, 20)
+	if len(unread.Notifications) != 2 {
+		t.Errorf("expected 2 unread notifications, got %d", len(unread.Notifications))
+	}
+	if others, _ := usecase.List("buyer", false, 1, 20); len(others.Notifications) != 0 {
+		t.Errorf("expected no notifications for other user, got %d", len(others.Notifications))
+	}
+}
+
+// TestMarkRead 自分の通知だけ既読にし、既読にしたら未読件数を配信する
+func TestMarkRead(t *testing.T) {
+	mockDAO := NewMockNotificationDAO()
+	publisher := &MockPublisher{}
+	usecase := NewNotificationUsecase(mockDAO, publisher, nil)
+
+	_ = usecase.Notify(NotifyInput{UID: "seller", Type: TypeLike, ActorUID: "buyer", ItemID: intPtr(1)})
+	_ = usecase.Notify(NotifyInput{UID: "buyer", Type: TypeMessage, ActorUID: "seller"})
+	publisher.events = nil
+
+	unread, err := usecase.MarkRead("seller", []int64{1, 2})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if unread != 0 {
+		t.Errorf("expected 0 unread, got %d", unread)
+	}
+	if count, _ := mockDAO.CountUnread("buyer"); count != 1 {
+		t.Errorf("expected other user's notification to stay unread, got %d", count)
+	}
+	counts := publisher.ofType(EventNotificationUnreadCount)
+	if len(counts) != 1 || counts[0].uids[0] != "seller" {
+		t.Errorf("expected unread count event to seller, got %+v", counts)
+	}
+
+	// 既読にするものがなければ配信しない
+	_, _ = usecase.MarkRead("seller", []int64{1})
+	if len(publisher.ofType(EventNotificationUnreadCount)) != 1 {
+		t.Error("expected no event when nothing changed")
+	}
+}
+
+// TestMarkAllRead すべての通知を既読にする
+func TestMarkAllRead(t *testing.T) {
+	mockDAO := NewMockNotificationDAO()
+	usecase := NewNotificationUsecase(mockDAO, &MockPublisher{}, nil)
+
+	_ = usecase.Notify(NotifyInput{UID: "seller", Type: TypeLike, ActorUID: "buyer", ItemID: intPtr(1)})
+	_ = usecase.Notify(NotifyInput{UID: "seller", Type: TypeReceiptConfirmed, ActorUID: "buyer", ItemID: intPtr(1)})
+
+	unread, err := usecase.MarkAllRead("seller")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if unread != 0 {
+		t.Errorf("expected 0 unread, got %d", unread)
+	}
+}
+
+// TestPreferences 設定していない種類は受け取る設定として返し、不正な種類は拒否する
+func TestPreferences(t *testing.T) {
+	mockDAO := NewMockNotificationDAO()
+	usecase := NewNotificationUsecase(mockDAO, &MockPublisher{}, nil)
+
+	prefs, err := usecase.GetPreferences("seller")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, typ := range Types {
+		if !prefs[typ] {
+			t.Errorf("expected %s to be enabled by default", typ)
+		}
+	}
+
+	prefs, err = usecase.SetPreferences("seller", map[string]bool{TypeMessage: false})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if prefs[TypeMessage] || !prefs[TypeLike] || len(prefs) != len(Types) {
+		t.Errorf("unexpected preferences: %+v", prefs)
+	}
+
+	if _, err := usecase.SetPreferences("seller", map[string]bool{"newsletter": true}); !errors.Is(err, ErrInvalidType) {
+		t.Errorf("expected ErrInvalidType, got %v", err)
+	}
+}
@@ -0,0 +1,377 @@
+package orders
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	auditDao "uttc-hackathon-backend/dao/audit"
+	messagesDao "uttc-hackathon-backend/dao/messages"
+	ordersDao "uttc-hackathon-backend/dao/orders"
+	"uttc-hackathon-backend/usecase/itemStatus"
+	notificationsUc "uttc-hackathon-backend/usecase/notifications"
+)
+
+var (
+	// ErrInvalidInput は入力が不正な場合のエラー
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrItemNotFound は商品が存在しない場合のエラー
+	ErrItemNotFound = errors.New("item not found")
+	// ErrOrderNotFound は注文が存在しない（当事者でない場合を含む）場合のエラー
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrCannotBuyOwnItem は出品者が自分の商品を購入しようとした場合のエラー
+	ErrCannotBuyOwnItem = errors.New("seller cannot purchase their own item")
+	// ErrItemNotAvailable は商品が出品中でない（購入済み・キャンセル済み）場合のエラー
+	// 商品の状態から購入できない場合は*itemStatus.ConflictErrorもラップする
+	ErrItemNotAvailable = errors.New("item is not available for purchase")
+	// ErrInvalidTransition は注文の状態を遷移できない場合のエラー
+	ErrInvalidTransition = errors.New("invalid order status transition")
+	// ErrForbidden は注文の状態を変更する権限がない場合のエラー
+	ErrForbidden = errors.New("not allowed to change this order")
+)
+
+// transitions は注文の状態遷移（キーの状態から値の状態へ遷移できる）
+// completed / cancelled / refunded は終端
+var transitions = map[string][]string{
+	ordersDao.StatusCreated:   {ordersDao.StatusPaid, ordersDao.StatusCancelled},
+	ordersDao.StatusPaid:      {ordersDao.StatusShipped, ordersDao.StatusDelivered, ordersDao.StatusCancelled, ordersDao.StatusRefunded},
+	ordersDao.StatusShipped:   {ordersDao.StatusDelivered, ordersDao.StatusRefunded},
+	ordersDao.StatusDelivered: {ordersDao.StatusCompleted, ordersDao.StatusRefunded},
+}
+
+// CanTransition は注文をfromからtoへ遷移できるか
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// PlaceOrderInput は注文の作成に必要な値
+type PlaceOrderInput struct {
+	ItemID        int
+	BuyerUID      string // onchain購入でウォレットが未登録の場合は空
+	BuyerAddress  string // onchain購入のみ
+	PaymentMethod string
+	AmountWei     string // onchain購入のみ
+	TxHash        string // onchain購入のみ
+	Actor         auditDao.Actor
+}
+
+// Timeline は取引の節目を購入者と出品者のメッセージスレッドにシステムメッセージとして書き込む（messages.MessageUsecase）
+type Timeline interface {
+	PostSystemMessage(itemID int, fromUID, toUID, event string) error
+}
+
+// Notifier は出品者に受け取り確認を通知する（notifications.NotificationUsecase）
+type Notifier interface {
+	Notify(in notificationsUc.NotifyInput) error
+}
+
+type OrderUsecase struct {
+	orderDao ordersDao.OrderDAOInterface
+	timeline Timeline
+	notifier Notifier
+	now      func() time.Time
+}
+
+func NewOrderUsecase(dao ordersDao.OrderDAOInterface, timeline Timeline, notifier Notifier) *OrderUsecase {
+	return &OrderUsecase{orderDao: dao, timeline: timeline, notifier: notifier, now: time.Now}
+}
+
+// PlaceOrder は購入を注文として記録する（cash購入とonchain購入の共通の入口）
+// どちらも支払いが済んだ時点で呼ばれるため、注文はcreatedからpaidへ遷移した状態で作成する
+// onchainのイベントが再送された場合は同じ注文を返す
+func (u *OrderUsecase) PlaceOrder(input PlaceOrderInput) (*ordersDao.Order, error) {
+	switch input.PaymentMethod {
+	case ordersDao.PaymentCash:
+		if input.BuyerUID == "" {
+			return nil, fmt.Errorf("%w: buyer_uid is required", ErrInvalidInput)
+		}
+	case ordersDao.PaymentOnchain:
+		if input.BuyerAddress == "" {
+			return nil, fmt.Errorf("%w: buyer address is required", ErrInvalidInput)
+		}
+		if existing, err := u.findExistingOnchainOrder(input); err != nil || existing != nil {
+			return existing, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown payment method %q", ErrInvalidInput, input.PaymentMethod)
+	}
+
+	item, err := u.orderDao.GetItem(input.ItemID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+	if input.BuyerUID != "" && input.BuyerUID == item.SellerUID {
+		return nil, ErrCannotBuyOwnItem
+	}
+	if err := itemStatus.Lifecycle.Check(item.ID, item.Status, itemStatus.Purchased); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrItemNotAvailable, err)
+	}
+
+	paidAt := u.now()
+	order := &ordersDao.Order{
+		ItemID:        item.ID,
+		SellerUID:     item.SellerUID,
+		BuyerUID:      input.BuyerUID,
+		BuyerAddress:  input.BuyerAddress,
+		PaymentMethod: input.PaymentMethod,
+		Status:        ordersDao.StatusPaid,
+		Amount:        item.Price,
+		AmountWei:     input.AmountWei,
+		TxHash:        input.TxHash,
+		PaidAt:        &paidAt,
+	}
+	id, err := u.orderDao.CreateOrder(order, input.Actor)
+	if errors.Is(err, ordersDao.ErrItemNotListed) {
+		// 確認した後に他の購入・キャンセルで商品の状態が変わった（状態の競合として409にする）
+		return nil, fmt.Errorf("%w: %w", ErrItemNotAvailable, u.itemConflict(item, itemStatus.Purchased))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create order: %w", err)
+	}
+	order.ID = id
+	u.postTimeline(order, order.BuyerUID, messagesDao.SystemEventPurchased)
+	return u.getOrder(id)
+}
+
+// findExistingOnchainOrder は同じonchain購入の注文が既にあれば返す（イベントの再送対策）
+func (u *OrderUsecase) findExistingOnchainOrder(input PlaceOrderInput) (*ordersDao.Order, error) {
+	if input.TxHash != "" {
+		order, err := u.orderDao.GetOrderByTxHash(input.TxHash)
+		if err == nil {
+			return order, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to get order by tx hash: %w", err)
+		}
+	}
+	order, err := u.orderDao.GetActiveOrderByItem(input.ItemID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	if order.PaymentMethod == ordersDao.PaymentOnchain && order.BuyerAddress == input.BuyerAddress {
+		return order, nil
+	}
+	return nil, nil
+}
+
+// GetOrder は当事者（出品者・購入者）に注文を返す
+func (u *OrderUsecase) GetOrder(id int64, uid string) (*ordersDao.Order, error) {
+	order, err := u.getOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	if uid == "" || (uid != order.BuyerUID && uid != order.SellerUID) {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+// ListOrders はユーザーが購入者（buyer）または出品者（seller）の注文を返す
+func (u *OrderUsecase) ListOrders(uid, role string, page, limit int) ([]*ordersDao.Order, error) {
+	if uid == "" {
+		return nil, fmt.Errorf("%w: uid is required", ErrInvalidInput)
+	}
+	if role != "buyer" && role != "seller" {
+		return nil, fmt.Errorf("%w: role must be buyer or seller", ErrInvalidInput)
+	}
+	orders, err := u.orderDao.ListOrders(uid, role, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list orders: %w", err)
+	}
+	return orders, nil
+}
+
+// UpdateStatusByUser は当事者による状態の変更（cash購入のみ。onchain購入はコントラクトのイベントで遷移する）
+//
+//	cancelled: 発送前に購入者・出品者がキャンセル
+//	delivered: 購入者が受け取りを報告
+//	completed: 購入者が取引を完了
+func (u *OrderUsecase) UpdateStatusByUser(id int64, uid, to string) (*ordersDao.Order, error) {
+	order, err := u.GetOrder(id, uid)
+	if err != nil {
+		return nil, err
+	}
+	if order.PaymentMethod != ordersDao.PaymentCash {
+		return nil, ErrForbidden
+	}
+	switch to {
+	case ordersDao.StatusCancelled:
+	case ordersDao.StatusDelivered, ordersDao.StatusCompleted:
+		if uid != order.BuyerUID {
+			return nil, ErrForbidden
+		}
+	default:
+		return nil, ErrForbidden
+	}
+	if err := u.transition(order, to, auditDao.UserActor(uid)); err != nil {
+		return nil, err
+	}
+	u.postTimeline(order, uid, to)
+	if to == ordersDao.StatusCompleted {
+		u.notifyReceiptConfirmed(order)
+	}
+	return u.getOrder(id)
+}
+
+// Refund は管理者が注文を返金済みにする
+func (u *OrderUsecase) Refund(id int64, adminUID string) (*ordersDao.Order, error) {
+	order, err := u.getOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.transition(order, ordersDao.StatusRefunded, auditDao.AdminActor(adminUID, auditDao.SourceAdmin)); err != nil {
+		return nil, err
+	}
+	u.postTimeline(order, order.SellerUID, messagesDao.SystemEventRefunded)
+	return u.getOrder(id)
+}
+
+// ConfirmReceiptOnchain はonchainで受け取り確認された注文を完了にする（delivered → completed の順に遷移）
+// 商品・注文がない場合は何もしない（エラーにするとチェーンのイベントが再送され続ける）
+func (u *OrderUsecase) ConfirmReceiptOnchain(chainItemID int64, actor auditDao.Actor) error {
+	itemID, err := u.orderDao.FindItemIDByChainItemID(chainItemID)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("No item for receipt confirmation: chain_item_id=%d", chainItemID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find item for chain_item_id %d: %w", chainItemID, err)
+	}
+	order, err := u.orderDao.GetActiveOrderByItem(itemID)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("No active order for receipt confirmation: chain_item_id=%d, item_id=%d", chainItemID, itemID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get order for item %d: %w", itemID, err)
+	}
+	if order.Status == ordersDao.StatusCompleted {
+		return nil
+	}
+	for _, to := range []string{ordersDao.StatusDelivered, ordersDao.StatusCompleted} {
+		if order.Status == to {
+			continue
+		}
+		if err := u.transition(order, to, actor); err != nil {
+			return err
+		}
+		order.Status = to
+	}
+	// 受け取りの報告と完了は同時なので、スレッドには完了だけを書き込む
+	u.postTimeline(order, order.BuyerUID, messagesDao.SystemEventCompleted)
+	u.notifyReceiptConfirmed(order)
+	return nil
+}
+
+// transition は状態遷移を検証してから更新する
+// 商品の状態も変わる場合（itemTarget）は商品の遷移もitemStatus.Lifecycleで検証する
+func (u *OrderUsecase) transition(order *ordersDao.Order, to string, actor auditDao.Actor) error {
+	if !CanTransition(order.Status, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, order.Status, to)
+	}
+
+	var change *ordersDao.ItemChange
+	if target := itemTarget(order, to); target != "" {
+		item, err := u.orderDao.GetItem(order.ItemID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrItemNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get item: %w", err)
+		}
+		if err := itemStatus.Lifecycle.Check(item.ID, item.Status, target); err != nil {
+			return err
+		}
+		change = &ordersDao.ItemChange{From: item.Status, To: target}
+	}
+
+	err := u.orderDao.UpdateStatus(order, to, change, actor)
+	if errors.Is(err, ordersDao.ErrStatusChanged) {
+		return fmt.Errorf("%w: order was updated concurrently", ErrInvalidTransition)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+	return nil
+}
+
+// postTimeline は注文の節目をスレッドに書き込む。fromUIDはきっかけになった側（管理者の操作などでは出品者）
+// 注文の状態名（delivered / completed / cancelled / refunded）はそのままシステムメッセージの種類になる
+// スレッドへの書き込みは補助的なものなので、失敗してもログに残すだけにする
+func (u *OrderUsecase) postTimeline(order *ordersDao.Order, fromUID, event string) {
+	if u.timeline == nil {
+		return
+	}
+	toUID := order.SellerUID
+	if fromUID == order.SellerUID {
+		toUID = order.BuyerUID
+	}
+	if err := u.timeline.PostSystemMessage(order.ItemID, fromUID, toUID, event); err != nil {
+		log.Printf("WARNING: failed to post %s message for order_id=%d: %v", event, order.ID, err)
+	}
+}
+
+// notifyReceiptConfirmed は取引の完了（購入者の受け取り確認）を出品者に通知する
+// 購入者が未登録のウォレットなら、きっかけになったユーザーなしで通知する。失敗してもログに残すだけにする
+func (u *OrderUsecase) notifyReceiptConfirmed(order *ordersDao.Order) {
+	if u.notifier == nil {
+		return
+	}
+	itemID := order.ItemID
+	err := u.notifier.Notify(notificationsUc.NotifyInput{
+		UID:      order.SellerUID,
+		Type:     notificationsUc.TypeReceiptConfirmed,
+		ActorUID: order.BuyerUID,
+		ItemID:   &itemID,
+		RefID:    order.ID,
+	})
+	if err != nil {
+		log.Printf("WARNING: failed to notify %s for order_id=%d: %v", notificationsUc.TypeReceiptConfirmed, order.ID, err)
+	}
+}
+
+// itemTarget は注文をtoへ遷移させる際の商品の遷移先（商品の状態を変えない場合は空文字列）
+//
+//	completed: 商品もcompleted
+//	cancelled / refunded: cash購入なら再出品（onchain購入の商品の状態はコントラクト側が正なので変更しない）
+func itemTarget(order *ordersDao.Order, to string) string {
+	switch {
+	case to == ordersDao.StatusCompleted:
+		return itemStatus.Completed
+	case (to == ordersDao.StatusCancelled || to == ordersDao.StatusRefunded) && order.PaymentMethod == ordersDao.PaymentCash:
+		return itemStatus.Listed
+	}
+	return ""
+}
+
+// itemConflict は商品をtoへ遷移できなかった場合のエラー（現在の状態を取り直せなければ確認時の状態を使う）
+func (u *OrderUsecase) itemConflict(item *ordersDao.OrderItem, to string) error {
+	from := item.Status
+	if current, err := u.orderDao.GetItem(item.ID); err == nil {
+		from = current.Status
+	}
+	return &itemStatus.ConflictError{ItemID: item.ID, From: from, To: to}
+}
+
+func (u *OrderUsecase) getOrder(id int64) (*ordersDao.Order, error) {
+	order, err := u.orderDao.GetOrder(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	return order, nil
+}
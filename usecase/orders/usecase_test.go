@@ -0,0 +1,516 @@
+package orders
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	auditDao "uttc-hackathon-backend/dao/audit"
+	ordersDao "uttc-hackathon-backend/dao/orders"
+	"uttc-hackathon-backend/usecase/itemStatus"
+	notificationsUc "uttc-hackathon-backend/usecase/notifications"
+)
+
+// MockOrderDAO はテスト用のモックDAO
+type MockOrderDAO struct {
+	items       map[int]*ordersDao.OrderItem
+	chainItems  map[int64]int // chainItemID -> itemID
+	orders      map[int64]*ordersDao.Order
+	nextID      int64
+	createCalls int
+	createErr   error
+	actors      []auditDao.Actor
+}
+
+func NewMockOrderDAO() *MockOrderDAO {
+	return &MockOrderDAO{
+		items:      make(map[int]*ordersDao.OrderItem),
+		chainItems: make(map[int64]int),
+		orders:     make(map[int64]*ordersDao.Order),
+	}
+}
+
+func (m *MockOrderDAO) addItem(id int, seller string, price int) {
+	m.items[id] = &ordersDao.OrderItem{ID: id, SellerUID: seller, Status: "listed", Price: price}
+}
+
+func (m *MockOrderDAO) GetItem(itemID int) (*ordersDao.OrderItem, error) {
+	item, ok := m.items[itemID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *item
+	return &copied, nil
+}
+
+func (m *MockOrderDAO) FindItemIDByChainItemID(chainItemID int64) (int, error) {
+	itemID, ok := m.chainItems[chainItemID]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return itemID, nil
+}
+
+func (m *MockOrderDAO) GetOrder(id int64) (*ordersDao.Order, error) {
+	order, ok := m.orders[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *order
+	return &copied, nil
+}
+
+func (m *MockOrderDAO) GetOrderByTxHash(txHash string) (*ordersDao.Order, error) {
+	for _, order := range m.orders {
+		if order.TxHash == txHash {
+			copied := *order
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *MockOrderDAO) GetActiveOrderByItem(itemID int) (*ordersDao.Order, error) {
+	for _, order := range m.orders {
+		if order.ItemID == itemID && order.Status != ordersDao.StatusCancelled && order.Status != ordersDao.StatusRefunded {
+			copied := *order
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *MockOrderDAO) ListOrders(uid, role string, limit, offset int) ([]*ordersDao.Order, error) {
+	result := []*ordersDao.Order{}
+	for _, order := range m.orders {
+		if (role == "buyer" && order.BuyerUID == uid) || (role == "seller" && order.SellerUID == uid) {
+			result = append(result, order)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockOrderDAO) CreateOrder(order *ordersDao.Order, actor auditDao.Actor) (int64, error) {
+	m.createCalls++
+	m.actors = append(m.actors, actor)
+	if m.createErr != nil {
+		return 0, m.createErr
+	}
+	item := m.items[order.ItemID]
+	if item == nil || item.Status != "listed" {
+		return 0, ordersDao.ErrItemNotListed
+	}
+	item.Status = "purchased"
+	m.nextID++
+	created := *order
+	created.ID = m.nextID
+	created.CreatedAt = time.Now()
+	m.orders[created.ID] = &created
+	return created.ID, nil
+}
+
+func (m *MockOrderDAO) UpdateStatus(order *ordersDao.Order, to string, item *ordersDao.ItemChange, actor auditDao.Actor) error {
+	m.actors = append(m.actors, actor)
+	current, ok := m.orders[order.ID]
+	if !ok || current.Status != order.Status {
+		return ordersDao.ErrStatusChanged
+	}
+	if item != nil {
+		stored := m.items[order.ItemID]
+		if stored == nil || stored.Status != item.From {
+			return ordersDao.ErrStatusChanged
+		}
+		stored.Status = item.To
+	}
+	current.Status = to
+	return nil
+}
+
+// MockTimeline はテスト用のスレッドへの書き込み（"item:from->to:event" の形で記録する）
+type MockTimeline struct {
+	posts []string
+}
+
+func (m *MockTimeline) PostSystemMessage(itemID int, fromUID, toUID, event string) error {
+	m.posts = append(m.posts, fmt.Sprintf("%d:%s->%s:%s", itemID, fromUID, toUID, event))
+	return nil
+}
+
+// MockNotifier はテスト用の通知（"種類:uid<-actor:item:ref" の形で記録する）
+type MockNotifier struct {
+	notified []string
+}
+
+func (m *MockNotifier) Notify(in notificationsUc.NotifyInput) error {
+	m.notified = append(m.notified, fmt.Sprintf("%s:%s<-%s:%d:%d", in.Type, in.UID, in.ActorUID, *in.ItemID, in.RefID))
+	return nil
+}
+
+// TestCanTransition 注文の状態遷移表（すべての組み合わせ）
+func TestCanTransition(t *testing.T) {
+	statuses := []string{
+		ordersDao.StatusCreated, ordersDao.StatusPaid, ordersDao.StatusShipped, ordersDao.StatusDelivered,
+		ordersDao.StatusCompleted, ordersDao.StatusCancelled, ordersDao.StatusRefunded,
+	}
+	allowed := map[[2]string]bool{
+		{ordersDao.StatusCreated, ordersDao.StatusPaid}:        true,
+		{ordersDao.StatusCreated, ordersDao.StatusCancelled}:   true,
+		{ordersDao.StatusPaid, ordersDao.StatusShipped}:        true,
+		{ordersDao.StatusPaid, ordersDao.StatusDelivered}:      true,
+		{ordersDao.StatusPaid, ordersDao.StatusCancelled}:      true,
+		{ordersDao.StatusPaid, ordersDao.StatusRefunded}:       true,
+		{ordersDao.StatusShipped, ordersDao.StatusDelivered}:   true,
+		{ordersDao.StatusShipped, ordersDao.StatusRefunded}:    true,
+		{ordersDao.StatusDelivered, ordersDao.StatusCompleted}: true,
+		{ordersDao.StatusDelivered, ordersDao.StatusRefunded}:  true,
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := allowed[[2]string{from, to}]
+			if got := CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+// TestPlaceOrder_Cash cash購入で支払い済みの注文が作成されるか
+func TestPlaceOrder_Cash(t *testing.T) {
+	mockDAO := NewMockOrderDAO()
+	mockDAO.addItem(1, "seller", 1500)
+	usecase := NewOrderUsecase(mockDAO, nil, nil)
+
+	order, err := usecase.PlaceOrder(PlaceOrderInput{
+		ItemID: 1, BuyerUID: "buyer", PaymentMethod: ordersDao.PaymentCash, Actor: auditDao.UserActor("buyer"),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if order.Status != ordersDao.StatusPaid || order.PaidAt == nil {
+		t.Errorf("expected paid order, got %+v", order)
+	}
+	if order.SellerUID != "seller" || order.Amount != 1500 {
+		t.Errorf("unexpected order: %+v", order)
+	}
+
+	// 同じ商品は再度購入できない
+	_, err = usecase.PlaceOrder(PlaceOrderInput{
+		ItemID: 1, BuyerUID: "other", PaymentMethod: ordersDao.PaymentCash, Actor: auditDao.UserActor("other"),
+	})
+	if !errors.Is(err, ErrItemNotAvailable) {
+		t.Errorf("expected ErrItemNotAvailable, got %v", err)
+	}
+}
+
+// TestPlaceOrder_Invalid 入力不備・自分の商品・存在しない商品
+func TestPlaceOrder_Invalid(t *testing.T) {
+	mockDAO := NewMockOrderDAO()
+	mockDAO.addItem(1, "seller", 1500)
+	usecase := NewOrderUsecase(mockDAO, nil, nil)
+
+	tests := []struct {
+		name  string
+		input PlaceOrderInput
+		want  error
+	}{
+		{"uidなし", PlaceOrderInput{ItemID: 1, PaymentMethod: ordersDao.PaymentCash}, ErrInvalidInput},
+		{"アドレスなし", PlaceOrderInput{ItemID: 1, PaymentMethod: ordersDao.PaymentOnchain}, ErrInvalidInput},
+		{"不明な支払い方法", PlaceOrderInput{ItemID: 1, BuyerUID: "buyer", PaymentMethod: "card"}, ErrInvalidInput},
+		{"自分の商品", PlaceOrderInput{ItemID: 1, BuyerUID: "seller", PaymentMethod: ordersDao.PaymentCash}, ErrCannotBuyOwnItem},
+		{"存在しない商品", PlaceOrderInput{ItemID: 99, BuyerUID: "buyer", PaymentMethod: ordersDao.PaymentCash}, ErrItemNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := usecase.PlaceOrder(tt.input); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+	if mockDAO.createCalls != 0 {
+		t.Errorf("expected no order to be created, got %d", mockDAO.createCalls)
+	}
+}
+
+// TestPlaceOrder_OnchainIdempotent onchainのイベントが再送されても注文は1件
+func TestPlaceOrder_OnchainIdempotent(t *testing.T) {
+	mockDAO := NewMockOrderDAO()
+	mockDAO.addItem(1, "seller", 1500)
+	usecase := NewOrderUsecase(mockDAO, nil, nil)
+
+	input := PlaceOrderInput{
+		ItemID: 1, BuyerAddress: "0xbuyer", PaymentMethod: ordersDao.PaymentOnchain,
+		AmountWei: "1000000000000000", TxHash: "0xtx", Actor: auditDao.WebhookActor("0xbuyer", ""),
+	}
+	first, err := usecase.PlaceOrder(input)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	second, err := usecase.PlaceOrder(input)
+	if err != nil {
+		t.Fatalf("expected no error on retry, got %v", err)
+	}
+	if first.ID != second.ID || mockDAO.createCalls != 1 {
+		t.Errorf("expected the same order, got %d and %d (%d creates)", first.ID, second.ID, mockDAO.createCalls)
+	}
+
+	// tx hashがなくても同じ購入者アドレスなら同じ注文
+	input.TxHash = ""
+	third, err := usecase.PlaceOrder(input)
+	if err != nil || third.ID != first.ID {
+		t.Errorf("expected the same order without tx hash, got %+v, %v", third, err)
+	}
+
+	// 別のアドレスからの購入は購入済みとして扱う
+	input.BuyerAddress = "0xother"
+	if _, err := usecase.PlaceOrder(input); !errors.Is(err, ErrItemNotAvailable) {
+		t.Errorf("expected ErrItemNotAvailable, got %v", err)
+	}
+}
+
+// TestUpdateStatusByUser 当事者による状態変更の権限と遷移
+func TestUpdateStatusByUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		status string
+		uid    string
+		to     string
+		want   error
+		item   string // 成功時の商品の状態
+	}{
+		{"購入者がキャンセル", ordersDao.PaymentCash, ordersDao.StatusPaid, "buyer", ordersDao.StatusCancelled, nil, "listed"},
+		{"出品者がキャンセル", ordersDao.PaymentCash, ordersDao.StatusPaid, "seller", ordersDao.StatusCancelled, nil, "listed"},
+		{"購入者が受け取り", ordersDao.PaymentCash, ordersDao.StatusShipped, "buyer", ordersDao.StatusDelivered, nil, "purchased"},
+		{"購入者が完了", ordersDao.PaymentCash, ordersDao.StatusDelivered, "buyer", ordersDao.StatusCompleted, nil, "completed"},
+		{"出品者は受け取りを報告できない", ordersDao.PaymentCash, ordersDao.StatusShipped, "seller", ordersDao.StatusDelivered, ErrForbidden, ""},
+		{"発送後はキャンセルできない", ordersDao.PaymentCash, ordersDao.StatusShipped, "buyer", ordersDao.StatusCancelled, ErrInvalidTransition, ""},
+		{"受け取り前に完了できない", ordersDao.PaymentCash, ordersDao.StatusPaid, "buyer", ordersDao.StatusCompleted, ErrInvalidTransition, ""},
+		{"返金は管理者のみ", ordersDao.PaymentCash, ordersDao.StatusPaid, "buyer", ordersDao.StatusRefunded, ErrForbidden, ""},
+		{"onchain購入は変更できない", ordersDao.PaymentOnchain, ordersDao.StatusPaid, "buyer", ordersDao.StatusCancelled, ErrForbidden, ""},
+		{"当事者以外", ordersDao.PaymentCash, ordersDao.StatusPaid, "stranger", ordersDao.StatusCancelled, ErrOrderNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDAO := NewMockOrderDAO()
+			mockDAO.addItem(1, "seller", 1500)
+			mockDAO.items[1].Status = "purchased"
+			mockDAO.orders[1] = &ordersDao.Order{
+				ID: 1, ItemID: 1, SellerUID: "seller", BuyerUID: "buyer", PaymentMethod: tt.method, Status: tt.status,
+			}
+			usecase := NewOrderUsecase(mockDAO, nil, nil)
+
+			order, err := usecase.UpdateStatusByUser(1, tt.uid, tt.to)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+			if tt.want == nil && order.Status != tt.to {
+				t.Errorf("expected status %s, got %s", tt.to, order.Status)
+			}
+			if tt.want == nil && mockDAO.items[1].Status != tt.item {
+				t.Errorf("expected item status %s, got %s", tt.item, mockDAO.items[1].Status)
+			}
+			if tt.want != nil && mockDAO.orders[1].Status != tt.status {
+				t.Errorf("expected status to stay %s, got %s", tt.status, mockDAO.orders[1].Status)
+			}
+		})
+	}
+}
+
+// TestRefund 管理者による返金（終端の注文は返金できない）
+func TestRefund(t *testing.T) {
+	mockDAO := NewMockOrderDAO()
+	mockDAO.orders[1] = &ordersDao.Order{ID: 1, ItemID: 1, SellerUID: "seller", BuyerUID: "buyer", Status: ordersDao.StatusShipped}
+	mockDAO.orders[2] = &ordersDao.Order{ID: 2, ItemID: 2, SellerUID: "seller", BuyerUID: "buyer", Status: ordersDao.StatusCompleted}
+	usecase := NewOrderUsecase(mockDAO, nil, nil)
+
+	order, err := usecase.Refund(1, "admin")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if order.Status != ordersDao.StatusRefunded {
+		t.Errorf("expected refunded, got %s", order.Status)
+	}
+	actor := mockDAO.actors[len(mockDAO.actors)-1]
+	if actor.Type != auditDao.ActorAdmin || actor.UID != "admin" {
+		t.Errorf("unexpected actor: %+v", actor)
+	}
+
+	if _, err := usecase.Refund(2, "admin"); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("expected ErrInvalidTransition, got %v", err)
+	}
+	if _, err := usecase.Refund(3, "admin"); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("expected ErrOrderNotFound, got %v", err)
+	}
+}
+
+// TestConfirmReceiptOnchain 受け取り確認でdelivered → completedと遷移し、再送しても成功する
+func TestConfirmReceiptOnchain(t *testing.T) {
+	mockDAO := NewMockOrderDAO()
+	mockDAO.addItem(1, "seller", 1500)
+	mockDAO.items[1].Status = "purchased"
+	mockDAO.chainItems[10] = 1
+	mockDAO.orders[1] = &ordersDao.Order{
+		ID: 1, ItemID: 1, SellerUID: "seller", BuyerAddress: "0xbuyer",
+		PaymentMethod: ordersDao.PaymentOnchain, Status: ordersDao.StatusPaid,
+	}
+	usecase := NewOrderUsecase(mockDAO, nil, nil)
+	actor := auditDao.WebhookActor("0xbuyer", "")
+
+	if err := usecase.ConfirmReceiptOnchain(10, actor); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if mockDAO.orders[1].Status != ordersDao.StatusCompleted || mockDAO.items[1].Status != "completed" {
+		t.Errorf("expected order and item to be completed, got %s / %s", mockDAO.orders[1].Status, mockDAO.items[1].Status)
+	}
+	if len(mockDAO.actors) != 2 {
+		t.Errorf("expected 2 transitions, got %d", len(mockDAO.actors))
+	}
+
+	if err := usecase.ConfirmReceiptOnchain(10, actor); err != nil {
+		t.Errorf("expected retry to succeed, got %v", err)
+	}
+	// 商品・注文がない場合は何もしない（チェーンのイベントを再送させない）
+	if err := usecase.ConfirmReceiptOnchain(99, actor); err != nil {
+		t.Errorf("expected unknown chain item to be ignored, got %v", err)
+	}
+	mockDAO.addItem(2, "seller", 1500)
+	mockDAO.chainItems[20] = 2
+	if err := usecase.ConfirmReceiptOnchain(20, actor); err != nil {
+		t.Errorf("expected item without an order to be ignored, got %v", err)
+	}
+	if len(mockDAO.actors) != 2 {
+		t.Errorf("expected no more transitions, got %d", len(mockDAO.actors))
+	}
+}
+
+// TestTransition_ItemConflict 商品の状態から遷移できない場合は注文も更新しない
+func TestTransition_ItemConflict(t *testing.T) {
+	mockDAO := NewMockOrderDAO()
+	mockDAO.addItem(1, "seller", 1500)
+	mockDAO.items[1].Status = "cancelled" // 管理者が強制的に変更した場合など
+	mockDAO.orders[1] = &ordersDao.Order{
+		ID: 1, ItemID: 1, SellerUID: "seller", BuyerUID: "buyer", PaymentMethod: ordersDao.PaymentCash, Status: ordersDao.StatusDelivered,
+	}
+	usecase := NewOrderUsecase(mockDAO, nil, nil)
+
+	_, err := usecase.UpdateStatusByUser(1, "buyer", ordersDao.StatusCompleted)
+	var conflict *itemStatus.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected ConflictError, got %v", err)
+	}
+	if conflict.From != "cancelled" || conflict.To != "completed" {
+		t.Errorf("unexpected conflict: %+v", conflict)
+	}
+	if mockDAO.orders[1].Status != ordersDao.StatusDelivered {
+		t.Errorf("expected order to stay delivered, got %s", mockDAO.orders[1].Status)
+	}
+}
+
+// TestPlaceOrder_ItemConflict 出品中でない商品は状態の競合として購入できない
+func TestPlaceOrder_ItemConflict(t *testing.T) {
+	mockDAO := NewMockOrderDAO()
+	mockDAO.addItem(1, "seller", 1500)
+	mockDAO.items[1].Status = "cancelled"
+	usecase := NewOrderUsecase(mockDAO, nil, nil)
+
+	_, err := usecase.PlaceOrder(PlaceOrderInput{ItemID: 1, BuyerUID: "buyer", PaymentMethod: ordersDao.PaymentCash})
+	if !errors.Is(err, ErrItemNotAvailable) || !itemStatus.IsConflict(err) {
+		t.Errorf("expected ErrItemNotAvailable wrapping ConflictError, got %v", err)
+	}
+	if mockDAO.createCalls != 0 {
+		t.Errorf("expected no order to be created, got %d", mockDAO.createCalls)
+	}
+}
+
+// TestPlaceOrder_ConcurrentPurchase 確認した後に他の購入で商品の状態が変わった場合も状態の競合にする
+func TestPlaceOrder_ConcurrentPurchase(t *testing.T) {
+	mockDAO := NewMockOrderDAO()
+	mockDAO.addItem(1, "seller", 1500)
+	mockDAO.createErr = ordersDao.ErrItemNotListed
+	usecase := NewOrderUsecase(mockDAO, nil, nil)
+
+	_, err := usecase.PlaceOrder(PlaceOrderInput{ItemID: 1, BuyerUID: "buyer", PaymentMethod: ordersDao.PaymentCash})
+	var conflict *itemStatus.ConflictError
+	if !errors.Is(err, ErrItemNotAvailable) || !errors.As(err, &conflict) {
+		t.Fatalf("expected ErrItemNotAvailable wrapping ConflictError, got %v", err)
+	}
+	if conflict.ItemID != 1 || conflict.To != "purchased" {
+		t.Errorf("unexpected conflict: %+v", conflict)
+	}
+}
+
+// TestTimeline 購入・キャンセル・受け取り・返金をきっかけになった側から相手へスレッドに書き込む
+func TestTimeline(t *testing.T) {
+	mockDAO := NewMockOrderDAO()
+	mockDAO.addItem(1, "seller", 1500)
+	mockDAO.addItem(2, "seller", 800)
+	mockDAO.addItem(3, "seller", 800)
+	mockDAO.chainItems[30] = 3
+	timeline := &MockTimeline{}
+	usecase := NewOrderUsecase(mockDAO, timeline, nil)
+
+	first, _ := usecase.PlaceOrder(PlaceOrderInput{ItemID: 1, BuyerUID: "buyer", PaymentMethod: ordersDao.PaymentCash})
+	_, _ = usecase.UpdateStatusByUser(first.ID, "seller", ordersDao.StatusCancelled)
+	second, _ := usecase.PlaceOrder(PlaceOrderInput{ItemID: 2, BuyerUID: "buyer", PaymentMethod: ordersDao.PaymentCash})
+	_, _ = usecase.Refund(second.ID, "admin")
+	// onchain購入は受け取りの報告と完了を1件にまとめ、再送では書き込まない
+	_, _ = usecase.PlaceOrder(PlaceOrderInput{ItemID: 3, BuyerUID: "buyer", BuyerAddress: "0xbuyer", PaymentMethod: ordersDao.PaymentOnchain, TxHash: "0x1"})
+	_ = usecase.ConfirmReceiptOnchain(30, auditDao.WebhookActor("0xbuyer", "buyer"))
+	_ = usecase.ConfirmReceiptOnchain(30, auditDao.WebhookActor("0xbuyer", "buyer"))
+
+	want := []string{
+		"1:buyer->seller:purchased",
+		"1:seller->buyer:cancelled",
+		"2:buyer->seller:purchased",
+		"2:seller->buyer:refunded",
+		"3:buyer->seller:purchased",
+		"3:buyer->seller:completed",
+	}
+	if fmt.Sprint(timeline.posts) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, timeline.posts)
+	}
+}
+
+// TestReceiptConfirmedNotification cash購入の完了とonchainの受け取り確認のどちらでも出品者に通知する（再送では通知しない）
+func TestReceiptConfirmedNotification(t *testing.T) {
+	mockDAO := NewMockOrderDAO()
+	mockDAO.addItem(1, "seller", 1500)
+	mockDAO.addItem(2, "seller", 800)
+	mockDAO.items[1].Status = "purchased"
+	mockDAO.items[2].Status = "purchased"
+	mockDAO.chainItems[20] = 2
+	mockDAO.orders[1] = &ordersDao.Order{
+		ID: 1, ItemID: 1, SellerUID: "seller", BuyerUID: "buyer", PaymentMethod: ordersDao.PaymentCash, Status: ordersDao.StatusShipped,
+	}
+	mockDAO.orders[2] = &ordersDao.Order{
+		ID: 2, ItemID: 2, SellerUID: "seller", BuyerAddress: "0xbuyer", PaymentMethod: ordersDao.PaymentOnchain, Status: ordersDao.StatusPaid,
+	}
+	notifier := &MockNotifier{}
+	usecase := NewOrderUsecase(mockDAO, nil, notifier)
+
+	// 受け取りの報告だけでは通知しない
+	if _, err := usecase.UpdateStatusByUser(1, "buyer", ordersDao.StatusDelivered); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := usecase.UpdateStatusByUser(1, "buyer", ordersDao.StatusCompleted); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	actor := auditDao.WebhookActor("0xbuyer", "")
+	if err := usecase.ConfirmReceiptOnchain(20, actor); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := usecase.ConfirmReceiptOnchain(20, actor); err != nil {
+		t.Fatalf("expected retry to succeed, got %v", err)
+	}
+
+	want := []string{
+		"receipt_confirmed:seller<-buyer:1:1",
+		"receipt_confirmed:seller<-:2:2",
+	}
+	if fmt.Sprint(notifier.notified) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, notifier.notified)
+	}
+}
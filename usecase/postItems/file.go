@@ -1,49 +1,111 @@
 package postItems
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"mime/multipart"
-	"os"
-	"path/filepath"
-	"time"
+	imagesDao "uttc-hackathon-backend/dao/images"
+	postItemsDao "uttc-hackathon-backend/dao/postItems"
 )
 
-// saveUploadedFile: アップロードされたファイルを保存し、保存パスを返す
-func saveUploadedFile(file io.Reader, fileHeader *multipart.FileHeader) (string, error) {
+// saveUploadedFile: アップロードされた画像を検証・加工して各サイズをストレージに保存し、URLを返す
+// 同じ内容の画像が既に保存されていれば、加工・保存せずに既存のURLを返す
+func (h *ItemUsecase) saveUploadedFile(file io.Reader, fileHeader *multipart.FileHeader) (*postItemsDao.ItemImage, error) {
 
 	// ファイルがアップロードされていない場合はスキップ
 	if file == nil {
-		return "", nil
+		return nil, nil
 	}
 
-	// 💡 注意：セキュリティリスク軽減のため、ファイル名のサニタイズ（安全な処理）が必要です。
-	// 今回は例として前回のロジックをほぼ踏襲しますが、本番環境では必ず対策をしてください。
-
 	defer file.(multipart.File).Close() // multipart.File は io.Closerでもあるため、型アサーションが必要な場合がある
 
-	uploadDir := "./uploads"
-	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
-		return "", fmt.Errorf("could not create directory: %w", err)
+	// 上限+1バイトまで読み込み、超過していればprocessImageで弾く
+	data, err := io.ReadAll(io.LimitReader(file, maxImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("could not read uploaded file: %w", err)
 	}
 
-	// 簡易的なファイル名生成 (セキュリティ上の注意点あり)
-	// 【セキュリティ修正の提案】ファイル名からディレクトリ情報を除去
-	safeFilename := filepath.Base(fileHeader.Filename)
-	filename := fmt.Sprintf("%d_%s", time.Now().Unix(), safeFilename)
+	return h.storeImage(data)
+}
 
-	filePath := filepath.Join(uploadDir, filename)
+// storeImage は画像データを検証・加工して各サイズをストレージに保存する
+// multipartのアップロードと署名付きURLでの直接アップロードの両方で使う
+func (h *ItemUsecase) storeImage(data []byte) (*postItemsDao.ItemImage, error) {
+	// 元画像のSHA-256をキーにする（ファイル名の衝突が起きない）
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
 
-	dst, err := os.Create(filePath)
+	// 同じ画像が既にあれば猶予期間を延長して再利用する
+	touched, err := h.imageDao.Touch(hash)
 	if err != nil {
-		return "", fmt.Errorf("could not create file on disk: %w", err)
+		return nil, err
+	}
+	if touched {
+		existing, err := h.imageDao.FindByHash(hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find image: %w", err)
+		}
+		return &postItemsDao.ItemImage{
+			URL:          existing.URL,
+			MediumURL:    existing.MediumURL,
+			ThumbnailURL: existing.ThumbnailURL,
+			Hash:         existing.Hash,
+		}, nil
+	}
+
+	// マジックバイトで形式を確認し、EXIFを除去して各サイズに再エンコード
+	processed, err := processImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := "image/jpeg"
+	if processed.Ext == "png" {
+		contentType = "image/png"
 	}
-	defer dst.Close()
 
-	if _, err := io.Copy(dst, file); err != nil {
-		return "", fmt.Errorf("could not save file content: %w", err)
+	paths := make(map[string]string, len(processed.Variants))
+	for name, content := range processed.Variants {
+		key := imageKey(hash, name, processed.Ext)
+		if err := h.storage.Put(key, content, contentType); err != nil {
+			return nil, fmt.Errorf("could not save file content: %w", err)
+		}
+		// ローカルなら "uploads/key"、S3なら公開URL
+		paths[name] = h.storage.URL(key)
 	}
 
-	// "./uploads/filename" -> "uploads/filename" に変換
-	return "uploads/" + filename, nil
+	image := &imagesDao.Image{
+		Hash:         hash,
+		Ext:          processed.Ext,
+		URL:          paths["full"],
+		MediumURL:    paths["medium"],
+		ThumbnailURL: paths["thumb"],
+		SizeBytes:    len(data),
+	}
+	if err := h.imageDao.Insert(image); err != nil {
+		return nil, err
+	}
+
+	return &postItemsDao.ItemImage{
+		URL:          image.URL,
+		MediumURL:    image.MediumURL,
+		ThumbnailURL: image.ThumbnailURL,
+		Hash:         hash,
+	}, nil
+}
+
+// imageKey はストレージ上のキー "ハッシュ_サイズ.拡張子" を返す
+func imageKey(hash, variant, ext string) string {
+	return fmt.Sprintf("%s_%s.%s", hash, variant, ext)
+}
+
+// imageKeys はハッシュと拡張子から全サイズのストレージキーを返す（GCでの削除用）
+func imageKeys(hash, ext string) []string {
+	keys := make([]string, 0, len(imageVariants))
+	for _, v := range imageVariants {
+		keys = append(keys, imageKey(hash, v.Name, ext))
+	}
+	return keys
 }
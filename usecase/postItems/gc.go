@@ -0,0 +1,132 @@
+package postItems
+
+import (
+	"log"
+	"time"
+	imagesDao "uttc-hackathon-backend/dao/images"
+	messagesDao "uttc-hackathon-backend/dao/messages"
+	uploadsDao "uttc-hackathon-backend/dao/uploads"
+	"uttc-hackathon-backend/storage"
+)
+
+// gcBatchSize は1回のGCで削除を試みる最大件数
+const gcBatchSize = 100
+
+// ImageGC はどの商品からも参照されていない画像を定期的に削除する
+// /uploadImage だけ呼ばれて出品されなかった画像と、期限切れの直接アップロード、
+// 送信されなかったメッセージの添付画像が対象
+type ImageGC struct {
+	imageDao      imagesDao.ImageDAOInterface
+	uploadDao     uploadsDao.UploadSessionDAOInterface
+	attachmentDao messagesDao.AttachmentDAOInterface
+	storage       storage.Storage
+	grace         time.Duration
+	now           func() time.Time
+}
+
+// NewImageGC はアップロードからgrace以上経過した未参照画像を削除するGCを生成する
+func NewImageGC(imageDao imagesDao.ImageDAOInterface, uploadDao uploadsDao.UploadSessionDAOInterface, attachmentDao messagesDao.AttachmentDAOInterface, store storage.Storage, grace time.Duration) *ImageGC {
+	return &ImageGC{imageDao: imageDao, uploadDao: uploadDao, attachmentDao: attachmentDao, storage: store, grace: grace, now: time.Now}
+}
+
+// RunOnce は未参照画像を1バッチ分削除し、削除した件数を返す
+func (gc *ImageGC) RunOnce() (int, error) {
+	cutoff := gc.now().Add(-gc.grace)
+	if err := gc.cleanupUploadSessions(cutoff); err != nil {
+		return 0, err
+	}
+	if err := gc.cleanupAttachments(cutoff); err != nil {
+		return 0, err
+	}
+	candidates, err := gc.imageDao.ListUnreferenced(cutoff, gcBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, img := range candidates {
+		// 一覧取得後に出品・再アップロードされていないかを削除時に再確認する
+		// ファイルは行をロックしている間に消す（同じ画像の再アップロードは削除が終わってから保存し直す）
+		ok, err := gc.imageDao.DeleteIfUnreferenced(img.Hash, cutoff, func() {
+			for _, key := range imageKeys(img.Hash, img.Ext) {
+				if err := gc.storage.Delete(key); err != nil {
+					log.Printf("[ImageGC] failed to delete %s: %v", key, err)
+				}
+			}
+		})
+		if err != nil {
+			return deleted, err
+		}
+		if !ok {
+			continue
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// cleanupUploadSessions は期限切れからgrace以上経過したセッションとstagingのオブジェクトを削除する
+// 完了済みのセッションも、出品時にトークンで参照できる期間が過ぎたら削除する
+func (gc *ImageGC) cleanupUploadSessions(cutoff time.Time) error {
+	sessions, err := gc.uploadDao.ListExpired(cutoff, gcBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, s := range sessions {
+		if err := gc.storage.Delete(s.StagingKey); err != nil {
+			log.Printf("[ImageGC] failed to delete %s: %v", s.StagingKey, err)
+			continue
+		}
+		if err := gc.uploadDao.Delete(s.Token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanupAttachments はメッセージに添付されないままgrace以上経過した添付画像を削除する
+// 同じ画像を添付した他の行が残っている場合はファイルを消さない
+func (gc *ImageGC) cleanupAttachments(cutoff time.Time) error {
+	attachments, err := gc.attachmentDao.ListOrphanAttachments(cutoff, gcBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, a := range attachments {
+		// 一覧取得後にメッセージに添付されていないかを削除時に再確認する
+		// ファイルは同じ画像の行をロックしている間に消す（同じ画像のアップロードは削除が終わってから保存し直す）
+		_, err := gc.attachmentDao.DeleteOrphanAttachment(a.ID, a.Hash, cutoff, func() {
+			for _, key := range privateImageKeys(a.Hash, a.Ext) {
+				if err := gc.storage.Delete(key); err != nil {
+					log.Printf("[ImageGC] failed to delete %s: %v", key, err)
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Start はintervalごとにGCを実行するgoroutineを起動する。返り値の関数で停止する
+func (gc *ImageGC) Start(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				n, err := gc.RunOnce()
+				if err != nil {
+					log.Printf("[ImageGC] error: %v", err)
+				} else if n > 0 {
+					log.Printf("[ImageGC] deleted %d unreferenced images", n)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,357 @@
+package postItems
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"strings"
+	postItemsDao "uttc-hackathon-backend/dao/postItems"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+const (
+	// maxImageBytes はアップロードを受け付ける画像の最大サイズ
+	maxImageBytes = 10 << 20
+	// maxImagePixels はデコードを許可する最大画素数（解凍爆弾対策）
+	maxImagePixels = 40_000_000
+	// jpegQuality は再エンコード時のJPEG品質
+	jpegQuality = 85
+)
+
+// ErrInvalidImage はクライアントから送られた画像が受け付けられない場合のエラー
+var ErrInvalidImage = errors.New("invalid image")
+
+// ErrUnsupportedImage はJPEG/PNG/WebP以外のファイルが送られた場合のエラー
+var ErrUnsupportedImage = fmt.Errorf("%w: only JPEG, PNG and WebP are allowed", ErrInvalidImage)
+
+// imageVariant は生成する画像サイズの定義（長辺の最大ピクセル数）
+type imageVariant struct {
+	Name    string
+	MaxEdge int
+}
+
+// imageVariants は保存するサイズ一覧（thumb → medium → full の順）
+var imageVariants = []imageVariant{
+	{Name: "thumb", MaxEdge: 240},
+	{Name: "medium", MaxEdge: 720},
+	{Name: "full", MaxEdge: 1600},
+}
+
+// processedImage は再エンコード済みの各サイズの画像データ
+type processedImage struct {
+	Ext      string
+	Variants map[string][]byte
+}
+
+// detectImageFormat はマジックバイトから画像形式を判定する
+// クライアントのContent-Typeやファイル名の拡張子は信用しない
+func detectImageFormat(data []byte) (string, error) {
+	switch {
+	case len(data) >= 3 && data[0] == 0xFF && data[1] == 0xD8 && data[2] == 0xFF:
+		return "jpeg", nil
+	case len(data) >= 8 && bytes.Equal(data[:8], []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}):
+		return "png", nil
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return "webp", nil
+	}
+	return "", ErrUnsupportedImage
+}
+
+// processImage は画像を検証・デコードし、EXIF等のメタデータを除去した上で
+// 各サイズに縮小して再エンコードする
+func processImage(data []byte) (*processedImage, error) {
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("%w: too large (max %d bytes)", ErrInvalidImage, maxImageBytes)
+	}
+
+	format, err := detectImageFormat(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// 画素数を先に確認してからデコードする
+	cfg, err := decodeImageConfig(format, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: broken %s data: %v", ErrInvalidImage, format, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, fmt.Errorf("%w: dimensions %dx%d are not allowed", ErrInvalidImage, cfg.Width, cfg.Height)
+	}
+
+	src, err := decodeImage(format, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: broken %s data: %v", ErrInvalidImage, format, err)
+	}
+
+	// EXIFは再エンコードで消えるため、向き情報だけは先に画素へ反映しておく
+	if format == "jpeg" {
+		src = applyOrientation(src, jpegOrientation(data))
+	}
+
+	// PNGは透過を保つためPNGのまま、それ以外はJPEGで保存する
+	ext := "jpg"
+	if format == "png" {
+		ext = "png"
+	}
+
+	result := &processedImage{Ext: ext, Variants: make(map[string][]byte, len(imageVariants))}
+	for _, v := range imageVariants {
+		resized := resizeImage(src, v.MaxEdge, ext == "jpg")
+		var buf bytes.Buffer
+		if ext == "png" {
+			err = png.Encode(&buf, resized)
+		} else {
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: jpegQuality})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s variant: %w", v.Name, err)
+		}
+		result.Variants[v.Name] = buf.Bytes()
+	}
+	return result, nil
+}
+
+func decodeImageConfig(format string, data []byte) (image.Config, error) {
+	r := bytes.NewReader(data)
+	switch format {
+	case "jpeg":
+		return jpeg.DecodeConfig(r)
+	case "png":
+		return png.DecodeConfig(r)
+	case "webp":
+		return webp.DecodeConfig(r)
+	}
+	return image.Config{}, ErrUnsupportedImage
+}
+
+func decodeImage(format string, data []byte) (image.Image, error) {
+	r := bytes.NewReader(data)
+	switch format {
+	case "jpeg":
+		return jpeg.Decode(r)
+	case "png":
+		return png.Decode(r)
+	case "webp":
+		return webp.Decode(r)
+	}
+	return nil, ErrUnsupportedImage
+}
+
+// resizeImage は長辺がmaxEdge以下になるよう縮小する（拡大はしない）
+// opaqueがtrueの場合はJPEG用に白背景で透過部分を塗りつぶす
+func resizeImage(src image.Image, maxEdge int, opaque bool) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > maxEdge || h > maxEdge {
+		if w >= h {
+			h = max(1, h*maxEdge/w)
+			w = maxEdge
+		} else {
+			w = max(1, w*maxEdge/h)
+			h = maxEdge
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if opaque {
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	}
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+	return dst
+}
+
+// jpegOrientation はJPEGのEXIFからOrientationタグ(0x0112)を読み取る
+// 見つからない場合や壊れている場合は1（回転なし）を返す
+func jpegOrientation(data []byte) int {
+	pos := 2 // SOIの直後
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		if marker == 0xD8 || (marker >= 0xD0 && marker <= 0xD7) {
+			pos += 2
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 { // SOS/EOI以降にEXIFはない
+			return 1
+		}
+		segLen := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		if segLen < 2 || pos+2+segLen > len(data) {
+			return 1
+		}
+		seg := data[pos+4 : pos+2+segLen]
+		if marker == 0xE1 && len(seg) >= 6 && string(seg[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(seg[6:])
+		}
+		pos += 2 + segLen
+	}
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			o := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation はEXIF Orientationに従って画像を回転・反転する
+// 画素ごとのAt/Setは遅いので、RGBAに揃えてからPixを直接コピーする
+func applyOrientation(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	rgba := toRGBA(src)
+	b := rgba.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 { // 5〜8は縦横が入れ替わる
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	if w == 0 || h == 0 {
+		return dst
+	}
+
+	// 元画像の(x, y)の書き込み先は origin + x*colStep + y*rowStep（Pixのオフセット）
+	s := dst.Stride
+	var origin, colStep, rowStep int
+	switch orientation {
+	case 2: // 左右反転
+		origin, colStep, rowStep = (w-1)*4, -4, s
+	case 3: // 180度回転
+		origin, colStep, rowStep = (h-1)*s+(w-1)*4, -4, -s
+	case 4: // 上下反転
+		origin, colStep, rowStep = (h-1)*s, 4, -s
+	case 5: // 転置
+		origin, colStep, rowStep = 0, s, 4
+	case 6: // 時計回りに90度回転
+		origin, colStep, rowStep = (h-1)*4, s, -4
+	case 7: // 反転置
+		origin, colStep, rowStep = (w-1)*s+(h-1)*4, -s, -4
+	case 8: // 反時計回りに90度回転
+		origin, colStep, rowStep = (w-1)*s, -s, 4
+	}
+	for y := 0; y < h; y++ {
+		row := rgba.Pix[rgba.PixOffset(b.Min.X, b.Min.Y+y):]
+		o := origin + y*rowStep
+		for x := 0; x < w*4; x += 4 {
+			copy(dst.Pix[o:o+4], row[x:x+4])
+			o += colStep
+		}
+	}
+	return dst
+}
+
+// toRGBA は画像を*image.RGBAに変換する（YCbCr・NRGBAなどはimage/drawの高速な変換を使う）
+func toRGBA(src image.Image) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok {
+		return rgba
+	}
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	return dst
+}
+
+// ItemImageFromURL はfull画像のURLから同じ命名規則のmedium/thumbのURLとハッシュを導出する
+// オンチェーン経由で画像URLだけが届いた場合に使用する
+// 導出するのはこのストレージに保存した画像（storage.URLの形のURL）だけで、外部のURLはそのまま使う
+func (h *ItemUsecase) ItemImageFromURL(imageURL string) postItemsDao.ItemImage {
+	img := postItemsDao.ItemImage{URL: imageURL}
+	key, ok := h.storageKey(imageURL)
+	if !ok {
+		return img
+	}
+	dot := strings.LastIndex(key, ".")
+	if dot < 0 || !strings.HasSuffix(key[:dot], "_full") {
+		return img
+	}
+	stem := strings.TrimSuffix(key[:dot], "_full")
+	ext := key[dot:]
+	base := imageURL[:len(imageURL)-len(key)]
+	img.MediumURL = base + stem + "_medium" + ext
+	img.ThumbnailURL = base + stem + "_thumb" + ext
+
+	// ファイル名がSHA-256(64桁の16進数)ならコンテンツハッシュとして参照を記録する
+	if isSHA256Hex(stem) {
+		img.Hash = stem
+	}
+	return img
+}
+
+// storageKey は公開画像のURLからストレージのキーを取り出す（このストレージのURLでなければfalse）
+// ローカルストレージの相対パス（"uploads/key"）はハンドラーがベースURLを付けて返すので、URLのパスで判定する
+// 公開画像のキーは "ハッシュ_サイズ.拡張子" なので、"/" を含むキー（private/ 配下など）は対象外
+func (h *ItemUsecase) storageKey(rawURL string) (string, bool) {
+	prefix := h.storage.URL("")
+	var key string
+	switch {
+	case strings.HasPrefix(rawURL, prefix):
+		key = rawURL[len(prefix):]
+	case !strings.Contains(prefix, "://"):
+		u, err := url.Parse(rawURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.RawQuery != "" || u.Fragment != "" {
+			return "", false
+		}
+		path := strings.TrimPrefix(u.Path, "/")
+		if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(rawURL, path) {
+			return "", false
+		}
+		key = path[len(prefix):]
+	default:
+		return "", false
+	}
+	if key == "" || strings.ContainsAny(key, "/?#") {
+		return "", false
+	}
+	return key, true
+}
+
+func isSHA256Hex(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
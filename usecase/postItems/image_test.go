@@ -0,0 +1,281 @@
+package postItems
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+	"uttc-hackathon-backend/storage"
+)
+
+// newTestJPEG はテスト用の単色JPEGを生成する
+func newTestJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("failed to encode jpeg: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// withExif はJPEGのSOI直後にOrientationとGPSポインタを含むEXIFセグメントを差し込む
+func withExif(data []byte, orientation uint16) []byte {
+	var tiff bytes.Buffer
+	tiff.WriteString("II")
+	binary.Write(&tiff, binary.LittleEndian, uint16(42))
+	binary.Write(&tiff, binary.LittleEndian, uint32(8))
+	binary.Write(&tiff, binary.LittleEndian, uint16(2)) // エントリ数
+	// Orientation (SHORT)
+	binary.Write(&tiff, binary.LittleEndian, uint16(0x0112))
+	binary.Write(&tiff, binary.LittleEndian, uint16(3))
+	binary.Write(&tiff, binary.LittleEndian, uint32(1))
+	binary.Write(&tiff, binary.LittleEndian, orientation)
+	binary.Write(&tiff, binary.LittleEndian, uint16(0))
+	// GPSInfo IFD pointer (LONG)
+	binary.Write(&tiff, binary.LittleEndian, uint16(0x8825))
+	binary.Write(&tiff, binary.LittleEndian, uint16(4))
+	binary.Write(&tiff, binary.LittleEndian, uint32(1))
+	binary.Write(&tiff, binary.LittleEndian, uint32(0))
+	binary.Write(&tiff, binary.LittleEndian, uint32(0)) // 次のIFDなし
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	seg := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	seg = append(seg, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, seg...)
+	return append(out, data[2:]...)
+}
+
+// TestDetectImageFormat マジックバイトによる形式判定
+func TestDetectImageFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr bool
+	}{
+		{"jpeg", []byte{0xFF, 0xD8, 0xFF, 0xE0}, "jpeg", false},
+		{"png", []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}, "png", false},
+		{"webp", []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), "webp", false},
+		{"gif", []byte("GIF89a"), "", true},
+		{"html", []byte("<html><script>"), "", true},
+		{"empty", []byte{}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detectImageFormat(tt.data)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnsupportedImage) {
+					t.Errorf("expected ErrUnsupportedImage, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestProcessImage_RejectsNonImage 拡張子を偽装したファイルは拒否
+func TestProcessImage_RejectsNonImage(t *testing.T) {
+	_, err := processImage([]byte("#!/bin/sh\nrm -rf /\n"))
+	if !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("expected ErrInvalidImage, got %v", err)
+	}
+}
+
+// TestProcessImage_RejectsBrokenImage マジックバイトだけ正しい壊れた画像は拒否
+func TestProcessImage_RejectsBrokenImage(t *testing.T) {
+	_, err := processImage([]byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F'})
+	if !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("expected ErrInvalidImage, got %v", err)
+	}
+}
+
+// TestProcessImage_StripsExifAndAppliesOrientation EXIF除去と向きの反映
+func TestProcessImage_StripsExifAndAppliesOrientation(t *testing.T) {
+	src := withExif(newTestJPEG(t, 40, 20), 6)
+	if jpegOrientation(src) != 6 {
+		t.Fatalf("test fixture should have orientation 6")
+	}
+
+	processed, err := processImage(src)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if processed.Ext != "jpg" {
+		t.Errorf("expected jpg, got %s", processed.Ext)
+	}
+
+	for name, data := range processed.Variants {
+		if bytes.Contains(data, []byte("Exif")) {
+			t.Errorf("variant %s still contains EXIF data", name)
+		}
+	}
+
+	// 90度回転しているので縦長になる
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(processed.Variants["full"]))
+	if err != nil {
+		t.Fatalf("failed to decode output: %v", err)
+	}
+	if cfg.Width != 20 || cfg.Height != 40 {
+		t.Errorf("expected 20x40, got %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
+// TestProcessImage_Variants 各サイズの長辺が上限以下になる
+func TestProcessImage_Variants(t *testing.T) {
+	processed, err := processImage(newTestJPEG(t, 2000, 1000))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := map[string][2]int{
+		"thumb":  {240, 120},
+		"medium": {720, 360},
+		"full":   {1600, 800},
+	}
+	for name, size := range want {
+		data, ok := processed.Variants[name]
+		if !ok {
+			t.Fatalf("variant %s is missing", name)
+		}
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("failed to decode %s: %v", name, err)
+		}
+		if cfg.Width != size[0] || cfg.Height != size[1] {
+			t.Errorf("%s: expected %dx%d, got %dx%d", name, size[0], size[1], cfg.Width, cfg.Height)
+		}
+	}
+}
+
+// TestProcessImage_PNGKeepsFormat PNGはPNGのまま保存し、拡大はしない
+func TestProcessImage_PNGKeepsFormat(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 100, 50))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+
+	processed, err := processImage(buf.Bytes())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if processed.Ext != "png" {
+		t.Errorf("expected png, got %s", processed.Ext)
+	}
+	cfg, err := png.DecodeConfig(bytes.NewReader(processed.Variants["full"]))
+	if err != nil {
+		t.Fatalf("failed to decode output: %v", err)
+	}
+	if cfg.Width != 100 || cfg.Height != 50 {
+		t.Errorf("expected 100x50, got %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
+// TestItemImageFromURL このストレージに保存したfull画像のURLからだけ縮小版のURLを導出する
+func TestItemImageFromURL(t *testing.T) {
+	usecase := NewItemUsecase(nil, NewMockImageDAO(), NewMockUploadSessionDAO(), storage.NewLocalStorage(t.TempDir(), "secret"))
+	hash := strings.Repeat("ab", 32)
+
+	// ローカルストレージはハンドラーがベースURLを付ける
+	img := usecase.ItemImageFromURL("https://example.com/uploads/" + hash + "_full.jpg")
+	if img.MediumURL != "https://example.com/uploads/"+hash+"_medium.jpg" {
+		t.Errorf("unexpected medium url: %s", img.MediumURL)
+	}
+	if img.ThumbnailURL != "https://example.com/uploads/"+hash+"_thumb.jpg" {
+		t.Errorf("unexpected thumbnail url: %s", img.ThumbnailURL)
+	}
+	if img.Hash != hash {
+		t.Errorf("expected hash %s, got %s", hash, img.Hash)
+	}
+	if rel := usecase.ItemImageFromURL("uploads/1700000000_abcd_full.jpg"); rel.MediumURL != "uploads/1700000000_abcd_medium.jpg" || rel.Hash != "" {
+		t.Errorf("unexpected image for relative url: %+v", rel)
+	}
+
+	// 命名規則が同じでもストレージ外のURL・private画像・クエリ付きのURLは縮小版なし
+	for _, url := range []string{
+		"https://ipfs.io/ipfs/Qm123",
+		"https://cdn.example.com/images/" + hash + "_full.jpg",
+		"https://example.com/uploads/private/" + hash + "_full.jpg",
+		"https://example.com/uploads/" + hash + "_full.jpg?v=1",
+		"ipfs://uploads/" + hash + "_full.jpg",
+	} {
+		external := usecase.ItemImageFromURL(url)
+		if external.MediumURL != "" || external.ThumbnailURL != "" || external.Hash != "" {
+			t.Errorf("expected no variants for %s, got %+v", url, external)
+		}
+		if external.URL != url {
+			t.Errorf("expected url to be kept, got %s", external.URL)
+		}
+	}
+}
+
+// TestApplyOrientation 全てのOrientationで各画素が正しい位置に移る（RGBAの部分画像・YCbCr）
+func TestApplyOrientation(t *testing.T) {
+	const w, h = 5, 3
+	full := image.NewRGBA(image.Rect(0, 0, w+2, h+2))
+	for y := 0; y < h+2; y++ {
+		for x := 0; x < w+2; x++ {
+			full.SetRGBA(x, y, color.RGBA{R: uint8(x * 40), G: uint8(y * 60), B: 100, A: 255})
+		}
+	}
+	ycbcr := image.NewYCbCr(image.Rect(0, 0, w, h), image.YCbCrSubsampleRatio444)
+	for i := range ycbcr.Y {
+		ycbcr.Y[i], ycbcr.Cb[i], ycbcr.Cr[i] = uint8(i*15), uint8(255-i*10), uint8(i*5)
+	}
+	sources := map[string]image.Image{
+		"rgba":  full.SubImage(image.Rect(1, 1, w+1, h+1)),
+		"ycbcr": ycbcr,
+	}
+
+	for name, src := range sources {
+		for orientation := 2; orientation <= 8; orientation++ {
+			got := applyOrientation(src, orientation)
+			b := src.Bounds()
+			for y := 0; y < h; y++ {
+				for x := 0; x < w; x++ {
+					var dx, dy int
+					switch orientation {
+					case 2:
+						dx, dy = w-1-x, y
+					case 3:
+						dx, dy = w-1-x, h-1-y
+					case 4:
+						dx, dy = x, h-1-y
+					case 5:
+						dx, dy = y, x
+					case 6:
+						dx, dy = h-1-y, x
+					case 7:
+						dx, dy = h-1-y, w-1-x
+					case 8:
+						dx, dy = y, w-1-x
+					}
+					want := color.RGBAModel.Convert(src.At(b.Min.X+x, b.Min.Y+y))
+					if c := got.At(dx, dy); c != want {
+						t.Fatalf("%s orientation %d: pixel (%d,%d) moved to (%d,%d) = %v, want %v", name, orientation, x, y, dx, dy, c, want)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package postItems
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	postItemsDao "uttc-hackathon-backend/dao/postItems"
+	uploadsDao "uttc-hackathon-backend/dao/uploads"
+	"uttc-hackathon-backend/storage"
+)
+
+const (
+	// uploadURLExpiry は直接アップロード用URLの有効期限
+	uploadURLExpiry = 15 * time.Minute
+	// stagingPrefix は検証前のアップロード先（公開URLとしては返さない）
+	stagingPrefix = "staging/"
+)
+
+var (
+	// ErrUploadNotFound はアップロードトークンが存在しない、または他人のものである場合のエラー
+	ErrUploadNotFound = errors.New("upload not found")
+	// ErrUploadExpired はアップロードURLの有効期限が切れた場合のエラー
+	ErrUploadExpired = errors.New("upload expired")
+	// ErrUploadNotCompleted は完了処理の済んでいないトークンで出品しようとした場合のエラー
+	ErrUploadNotCompleted = errors.New("upload is not completed")
+)
+
+// allowedContentTypes は直接アップロードで受け付けるContent-Typeと、マジックバイトで判定される形式の対応
+var allowedContentTypes = map[string]string{
+	"image/jpeg": "jpeg",
+	"image/png":  "png",
+	"image/webp": "webp",
+}
+
+// PresignedUpload はクライアントに返す直接アップロードの情報
+type PresignedUpload struct {
+	UploadURL   string    `json:"upload_url"`
+	Method      string    `json:"method"`
+	UploadToken string    `json:"upload_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// PresignUpload は申告されたサイズ・形式・ハッシュを記録し、ストレージへ直接PUTできる署名付きURLを発行する
+// アップロード後はCompleteUploadで中身を検証するまで出品に使えない
+func (h *ItemUsecase) PresignUpload(uid string, contentType string, sizeBytes int, sha256Hex string) (*PresignedUpload, error) {
+	if uid == "" {
+		return nil, fmt.Errorf("uid is required")
+	}
+	if _, ok := allowedContentTypes[strings.ToLower(contentType)]; !ok {
+		return nil, ErrUnsupportedImage
+	}
+	if sizeBytes <= 0 || sizeBytes > maxImageBytes {
+		return nil, fmt.Errorf("%w: size must be between 1 and %d bytes", ErrInvalidImage, maxImageBytes)
+	}
+	sha256Hex = strings.ToLower(sha256Hex)
+	if !isSHA256Hex(sha256Hex) {
+		return nil, fmt.Errorf("%w: sha256 must be 64 hex characters", ErrInvalidImage)
+	}
+
+	token, err := newUploadToken()
+	if err != nil {
+		return nil, err
+	}
+	stagingKey := stagingPrefix + token
+	uploadURL, err := h.storage.SignedPutURL(stagingKey, uploadURLExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign upload url: %w", err)
+	}
+
+	session := &uploadsDao.UploadSession{
+		Token:       token,
+		UID:         uid,
+		StagingKey:  stagingKey,
+		ContentType: strings.ToLower(contentType),
+		SizeBytes:   sizeBytes,
+		SHA256:      sha256Hex,
+		ExpiresAt:   h.now().Add(uploadURLExpiry),
+	}
+	if err := h.uploadDao.Create(session); err != nil {
+		return nil, err
+	}
+
+	return &PresignedUpload{
+		UploadURL:   uploadURL,
+		Method:      "PUT",
+		UploadToken: token,
+		ExpiresAt:   session.ExpiresAt,
+	}, nil
+}
+
+// CompleteUpload はアップロードされたオブジェクトのサイズ・形式・SHA-256を申告内容と照合し、
+// 通常のアップロードと同じく加工してimagesに登録する。検証に失敗したオブジェクトは削除する
+func (h *ItemUsecase) CompleteUpload(uid string, token string) (*postItemsDao.ItemImage, error) {
+	session, err := h.findUploadSession(uid, token)
+	if err != nil {
+		return nil, err
+	}
+	switch session.Status {
+	case "completed":
+		// 再送された場合は登録済みの画像を返す（冪等）
+		return h.imageFromUploadSession(session)
+	case "failed":
+		return nil, fmt.Errorf("%w: upload verification already failed", ErrInvalidImage)
+	}
+	if h.now().After(session.ExpiresAt) {
+		return nil, ErrUploadExpired
+	}
+
+	data, err := h.storage.Get(session.StagingKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("%w: object has not been uploaded yet", ErrInvalidImage)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uploaded object: %w", err)
+	}
+
+	if err := verifyUpload(session, data); err != nil {
+		h.discardUpload(session, "failed")
+		return nil, err
+	}
+
+	image, err := h.storeImage(data)
+	if err != nil {
+		if errors.Is(err, ErrInvalidImage) {
+			h.discardUpload(session, "failed")
+		}
+		return nil, err
+	}
+	h.discardUpload(session, "completed")
+	return image, nil
+}
+
+// imageFromUpload は出品時に指定されたトークンから検証済みの画像を取得する
+func (h *ItemUsecase) imageFromUpload(uid string, token string) (*postItemsDao.ItemImage, error) {
+	session, err := h.findUploadSession(uid, token)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != "completed" {
+		return nil, ErrUploadNotCompleted
+	}
+	return h.imageFromUploadSession(session)
+}
+
+func (h *ItemUsecase) findUploadSession(uid string, token string) (*uploadsDao.UploadSession, error) {
+	if token == "" {
+		return nil, fmt.Errorf("upload_token is required")
+	}
+	session, err := h.uploadDao.FindByToken(token)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find upload session: %w", err)
+	}
+	// 他人のトークンは存在しないものとして扱う
+	if session.UID != uid {
+		return nil, ErrUploadNotFound
+	}
+	return session, nil
+}
+
+// imageFromUploadSession は完了済みのセッションの画像を取得する
+// 出品されないままGCで画像が削除されていた場合はErrUploadExpired（アップロードし直してもらう）
+func (h *ItemUsecase) imageFromUploadSession(session *uploadsDao.UploadSession) (*postItemsDao.ItemImage, error) {
+	existing, err := h.imageDao.FindByHash(session.SHA256)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: uploaded image has been removed", ErrUploadExpired)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find image: %w", err)
+	}
+	return &postItemsDao.ItemImage{
+		URL:          existing.URL,
+		MediumURL:    existing.MediumURL,
+		ThumbnailURL: existing.ThumbnailURL,
+		Hash:         existing.Hash,
+	}, nil
+}
+
+// discardUpload はセッションの状態を更新し、stagingのオブジェクトを削除する
+func (h *ItemUsecase) discardUpload(session *uploadsDao.UploadSession, status string) {
+	if _, err := h.uploadDao.UpdateStatus(session.Token, "pending", status); err != nil {
+		log.Printf("[Upload] failed to mark %s as %s: %v", session.Token, status, err)
+	}
+	if err := h.storage.Delete(session.StagingKey); err != nil {
+		log.Printf("[Upload] failed to delete %s: %v", session.StagingKey, err)
+	}
+}
+
+// verifyUpload はアップロードされたデータが申告内容と一致するかを確認する
+// 署名付きURLではContent-Typeやサイズを強制できないため、ここで必ず検証する
+func verifyUpload(session *uploadsDao.UploadSession, data []byte) error {
+	if len(data) != session.SizeBytes {
+		return fmt.Errorf("%w: size mismatch (declared %d, uploaded %d)", ErrInvalidImage, session.SizeBytes, len(data))
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != session.SHA256 {
+		return fmt.Errorf("%w: sha256 mismatch", ErrInvalidImage)
+	}
+	format, err := detectImageFormat(data)
+	if err != nil {
+		return err
+	}
+	if allowedContentTypes[session.ContentType] != format {
+		return fmt.Errorf("%w: content type mismatch (declared %s, detected %s)", ErrInvalidImage, session.ContentType, format)
+	}
+	return nil
+}
+
+// newUploadToken は推測できないアップロードトークンを生成する
+func newUploadToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate upload token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,77 @@
+package postItems
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"time"
+	imagesDao "uttc-hackathon-backend/dao/images"
+)
+
+// privateImagePrefix は署名付きURLでのみ配信する画像の保存先（storage側でも private/ 配下は署名を必須にしている）
+const privateImagePrefix = "private/"
+
+// UploadPrivateImage は画像を商品画像と同じく検証・加工（形式・サイズ・画素数の制限、EXIF除去、リサイズ）し、
+// 公開URLを持たない private/ 配下に保存する。メッセージの添付画像に使う
+// registerは保存する前に呼ぶ（添付画像の行を先に登録しておけば、GCが同じ画像のファイルを消している間は登録が待たされ、
+// 消し終わってから保存し直すことになる）。registerが失敗した場合は保存しない
+func (h *ItemUsecase) UploadPrivateImage(file multipart.File, fileHeader *multipart.FileHeader, register func(*imagesDao.PrivateImage) error) (*imagesDao.PrivateImage, error) {
+	if file == nil {
+		return nil, fmt.Errorf("image file is required")
+	}
+	defer file.Close()
+
+	// 上限+1バイトまで読み込み、超過していればprocessImageで弾く
+	data, err := io.ReadAll(io.LimitReader(file, maxImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("could not read uploaded file: %w", err)
+	}
+
+	processed, err := processImage(data)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	contentType := "image/jpeg"
+	if processed.Ext == "png" {
+		contentType = "image/png"
+	}
+	image := &imagesDao.PrivateImage{Hash: hash, Ext: processed.Ext, SizeBytes: len(data)}
+	if err := register(image); err != nil {
+		return nil, err
+	}
+	// 同じ内容なら同じキーになるので、上書きしても問題ない
+	// 保存に失敗した場合、登録した行は送信されないままGCで消える
+	for name, content := range processed.Variants {
+		if err := h.storage.Put(privateImageKey(hash, name, processed.Ext), content, contentType); err != nil {
+			return nil, fmt.Errorf("could not save file content: %w", err)
+		}
+	}
+	return image, nil
+}
+
+// PrivateImageURL はprivate画像のvariant（thumb/medium/full）の署名付きURLを返す
+func (h *ItemUsecase) PrivateImageURL(hash, ext, variant string, expires time.Duration) (string, error) {
+	url, err := h.storage.SignedURL(privateImageKey(hash, variant, ext), expires)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign image url: %w", err)
+	}
+	return url, nil
+}
+
+func privateImageKey(hash, variant, ext string) string {
+	return privateImagePrefix + imageKey(hash, variant, ext)
+}
+
+// privateImageKeys はprivate画像の全サイズのストレージキーを返す（GCでの削除用）
+func privateImageKeys(hash, ext string) []string {
+	keys := imageKeys(hash, ext)
+	for i, key := range keys {
+		keys[i] = privateImagePrefix + key
+	}
+	return keys
+}
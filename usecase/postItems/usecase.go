@@ -3,19 +3,28 @@ package postItems
 import (
 	"fmt"
 	"mime/multipart"
+	"time"
+	imagesDao "uttc-hackathon-backend/dao/images"
 	postItemsDao "uttc-hackathon-backend/dao/postItems"
+	uploadsDao "uttc-hackathon-backend/dao/uploads"
+	"uttc-hackathon-backend/storage"
 )
 
 type ItemUsecase struct {
 	postItemsDao *postItemsDao.ItemDAO
+	imageDao     imagesDao.ImageDAOInterface
+	uploadDao    uploadsDao.UploadSessionDAOInterface
+	storage      storage.Storage
+	now          func() time.Time
 }
 
-func NewItemUsecase(dao *postItemsDao.ItemDAO) *ItemUsecase {
-	return &ItemUsecase{postItemsDao: dao}
+func NewItemUsecase(dao *postItemsDao.ItemDAO, imageDao imagesDao.ImageDAOInterface, uploadDao uploadsDao.UploadSessionDAOInterface, store storage.Storage) *ItemUsecase {
+	return &ItemUsecase{postItemsDao: dao, imageDao: imageDao, uploadDao: uploadDao, storage: store, now: time.Now}
 }
 
 // CreateItemメソッド（ロジックを外部関数に委譲）
-func (h *ItemUsecase) CreateItem(title string, explanation string, priceStr string, file multipart.File, fileHeader *multipart.FileHeader, uid string, status string, category string) (map[string]interface{}, []string, error) {
+// 画像はmultipartのファイル、または直接アップロードで検証済みのuploadTokenのどちらかで指定する
+func (h *ItemUsecase) CreateItem(title string, explanation string, priceStr string, file multipart.File, fileHeader *multipart.FileHeader, uploadToken string, uid string, status string, category string) (map[string]interface{}, []string, error) {
 
 	// バリデーション
 	if title == "" {
@@ -34,20 +43,27 @@ func (h *ItemUsecase) CreateItem(title string, explanation string, priceStr stri
 		return nil, nil, fmt.Errorf("price validation error: %w", err)
 	}
 
-	// 2. ファイルI/O処理を file.go に委譲
-	imagePath, err := saveUploadedFile(file, fileHeader)
+	// 2. ファイルI/O処理を file.go に委譲（検証・EXIF除去・リサイズを含む）
+	var image *postItemsDao.ItemImage
+	if file == nil && uploadToken != "" {
+		image, err = h.imageFromUpload(uid, uploadToken)
+	} else {
+		image, err = h.saveUploadedFile(file, fileHeader)
+	}
 	if err != nil {
 		return nil, nil, fmt.Errorf("file processing error: %w", err)
 	}
 
 	// 画像URLを配列として保持（現在は1枚のみ対応）
 	var imageURLs []string
-	if imagePath != "" {
-		imageURLs = append(imageURLs, imagePath)
+	var images []postItemsDao.ItemImage
+	if image != nil {
+		imageURLs = append(imageURLs, image.URL)
+		images = append(images, *image)
 	}
 
 	// 3. DAOの呼び出し（永続化）
-	if err := h.postItemsDao.InsertItem(title, price, explanation, imageURLs, uid, status, category); err != nil {
+	if err := h.postItemsDao.InsertItem(title, price, explanation, images, uid, status, category); err != nil {
 		return nil, nil, fmt.Errorf("database error: %w", err)
 	}
 
@@ -56,15 +72,22 @@ func (h *ItemUsecase) CreateItem(title string, explanation string, priceStr stri
 		"message":    "Item Created successfully",
 		"image_urls": imageURLs,
 	}
+	if image != nil {
+		response["thumbnail_url"] = image.ThumbnailURL
+		response["medium_url"] = image.MediumURL
+	}
 
 	return response, imageURLs, nil
 }
 
-// UploadImage は画像のみをアップロードしてURLを返す
-func (h *ItemUsecase) UploadImage(file multipart.File, fileHeader *multipart.FileHeader) (string, error) {
-	imagePath, err := saveUploadedFile(file, fileHeader)
+// UploadImage は画像のみをアップロードしてサイズ別のURLを返す
+func (h *ItemUsecase) UploadImage(file multipart.File, fileHeader *multipart.FileHeader) (*postItemsDao.ItemImage, error) {
+	image, err := h.saveUploadedFile(file, fileHeader)
 	if err != nil {
-		return "", fmt.Errorf("file processing error: %w", err)
+		return nil, fmt.Errorf("file processing error: %w", err)
+	}
+	if image == nil {
+		return nil, fmt.Errorf("image file is required")
 	}
-	return imagePath, nil
+	return image, nil
 }
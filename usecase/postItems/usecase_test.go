@@ -0,0 +1,601 @@
+package postItems
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"mime/multipart"
+	"strings"
+	"testing"
+	"time"
+
+	imagesDao "uttc-hackathon-backend/dao/images"
+	messagesDao "uttc-hackathon-backend/dao/messages"
+	uploadsDao "uttc-hackathon-backend/dao/uploads"
+	"uttc-hackathon-backend/storage"
+)
+
+// MockImageDAO はテスト用のモックDAO
+type MockImageDAO struct {
+	images     map[string]*imagesDao.Image
+	referenced map[string]bool // hash -> item_imagesから参照されているか
+	insertErr  error
+}
+
+func NewMockImageDAO() *MockImageDAO {
+	return &MockImageDAO{
+		images:     make(map[string]*imagesDao.Image),
+		referenced: make(map[string]bool),
+	}
+}
+
+func (m *MockImageDAO) FindByHash(hash string) (*imagesDao.Image, error) {
+	img, ok := m.images[hash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return img, nil
+}
+
+func (m *MockImageDAO) Touch(hash string) (bool, error) {
+	img, ok := m.images[hash]
+	if !ok {
+		return false, nil
+	}
+	img.LastUploadedAt = time.Now()
+	return true, nil
+}
+
+func (m *MockImageDAO) Insert(image *imagesDao.Image) error {
+	if m.insertErr != nil {
+		return m.insertErr
+	}
+	if _, ok := m.images[image.Hash]; ok {
+		return nil
+	}
+	image.CreatedAt = time.Now()
+	image.LastUploadedAt = image.CreatedAt
+	m.images[image.Hash] = image
+	return nil
+}
+
+func (m *MockImageDAO) ListUnreferenced(olderThan time.Time, limit int) ([]*imagesDao.Image, error) {
+	var result []*imagesDao.Image
+	for hash, img := range m.images {
+		if !m.referenced[hash] && img.LastUploadedAt.Before(olderThan) {
+			result = append(result, img)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockImageDAO) DeleteIfUnreferenced(hash string, olderThan time.Time, removeObjects func()) (bool, error) {
+	img, ok := m.images[hash]
+	if !ok || m.referenced[hash] || !img.LastUploadedAt.Before(olderThan) {
+		return false, nil
+	}
+	removeObjects()
+	delete(m.images, hash)
+	return true, nil
+}
+
+// MockUploadSessionDAO はテスト用のモックDAO
+type MockUploadSessionDAO struct {
+	sessions map[string]*uploadsDao.UploadSession
+}
+
+func NewMockUploadSessionDAO() *MockUploadSessionDAO {
+	return &MockUploadSessionDAO{sessions: make(map[string]*uploadsDao.UploadSession)}
+}
+
+func (m *MockUploadSessionDAO) Create(session *uploadsDao.UploadSession) error {
+	session.Status = "pending"
+	session.CreatedAt = time.Now()
+	m.sessions[session.Token] = session
+	return nil
+}
+
+func (m *MockUploadSessionDAO) FindByToken(token string) (*uploadsDao.UploadSession, error) {
+	s, ok := m.sessions[token]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *s
+	return &copied, nil
+}
+
+func (m *MockUploadSessionDAO) UpdateStatus(token string, fromStatus string, toStatus string) (bool, error) {
+	s, ok := m.sessions[token]
+	if !ok || s.Status != fromStatus {
+		return false, nil
+	}
+	s.Status = toStatus
+	return true, nil
+}
+
+func (m *MockUploadSessionDAO) ListExpired(before time.Time, limit int) ([]*uploadsDao.UploadSession, error) {
+	var result []*uploadsDao.UploadSession
+	for _, s := range m.sessions {
+		if s.ExpiresAt.Before(before) {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockUploadSessionDAO) Delete(token string) error {
+	delete(m.sessions, token)
+	return nil
+}
+
+// MockAttachmentDAO はテスト用のモックDAO（メッセージの添付画像）
+type MockAttachmentDAO struct {
+	attachments map[int64]*messagesDao.Attachment
+}
+
+func NewMockAttachmentDAO() *MockAttachmentDAO {
+	return &MockAttachmentDAO{attachments: make(map[int64]*messagesDao.Attachment)}
+}
+
+func (m *MockAttachmentDAO) ListOrphanAttachments(olderThan time.Time, limit int) ([]*messagesDao.Attachment, error) {
+	var result []*messagesDao.Attachment
+	for _, a := range m.attachments {
+		if a.MessageID == nil && a.CreatedAt.Before(olderThan) && len(result) < limit {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockAttachmentDAO) DeleteOrphanAttachment(id int64, hash string, olderThan time.Time, removeObjects func()) (bool, error) {
+	a, ok := m.attachments[id]
+	if !ok || a.MessageID != nil || !a.CreatedAt.Before(olderThan) {
+		return false, nil
+	}
+	delete(m.attachments, id)
+	for _, other := range m.attachments {
+		if other.Hash == hash {
+			return true, nil
+		}
+	}
+	removeObjects()
+	return true, nil
+}
+
+// nopFile はmultipart.Fileを満たすテスト用のファイル
+type nopFile struct {
+	*bytes.Reader
+}
+
+func (nopFile) Close() error { return nil }
+
+func newUploadFile(data []byte) multipart.File {
+	return nopFile{bytes.NewReader(data)}
+}
+
+// TestUploadImage_Deduplicates 同じ画像は1回だけ保存される
+func TestUploadImage_Deduplicates(t *testing.T) {
+	mockDAO := NewMockImageDAO()
+	store := storage.NewMemoryStorage()
+	usecase := NewItemUsecase(nil, mockDAO, NewMockUploadSessionDAO(), store)
+	data := newTestJPEG(t, 300, 200)
+
+	first, err := usecase.UploadImage(newUploadFile(data), &multipart.FileHeader{Filename: "a.jpg"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	second, err := usecase.UploadImage(newUploadFile(data), &multipart.FileHeader{Filename: "b.jpg"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if first.URL != second.URL || first.Hash != second.Hash {
+		t.Errorf("expected same image, got %+v and %+v", first, second)
+	}
+	if len(first.Hash) != 64 {
+		t.Errorf("expected sha256 hash, got %q", first.Hash)
+	}
+	if len(store.Objects) != len(imageVariants) {
+		t.Errorf("expected %d stored objects, got %d", len(imageVariants), len(store.Objects))
+	}
+	if len(mockDAO.images) != 1 {
+		t.Errorf("expected 1 image record, got %d", len(mockDAO.images))
+	}
+
+	// URLからハッシュを復元できる（オンチェーン経由の出品で参照を記録するため）
+	if got := usecase.ItemImageFromURL(first.URL).Hash; got != first.Hash {
+		t.Errorf("expected hash %s from url, got %s", first.Hash, got)
+	}
+}
+
+// TestUploadImage_DifferentImages 異なる画像は別キーで保存される
+func TestUploadImage_DifferentImages(t *testing.T) {
+	mockDAO := NewMockImageDAO()
+	store := storage.NewMemoryStorage()
+	usecase := NewItemUsecase(nil, mockDAO, NewMockUploadSessionDAO(), store)
+
+	first, _ := usecase.UploadImage(newUploadFile(newTestJPEG(t, 300, 200)), &multipart.FileHeader{Filename: "same.jpg"})
+	second, _ := usecase.UploadImage(newUploadFile(newTestJPEG(t, 200, 300)), &multipart.FileHeader{Filename: "same.jpg"})
+
+	if first == nil || second == nil {
+		t.Fatal("expected both uploads to succeed")
+	}
+	if first.URL == second.URL {
+		t.Error("expected different urls for different content with the same filename")
+	}
+	if len(store.Objects) != 2*len(imageVariants) {
+		t.Errorf("expected %d stored objects, got %d", 2*len(imageVariants), len(store.Objects))
+	}
+}
+
+// TestImageGC_DeletesOnlyUnreferencedAfterGrace 猶予期間後の未参照画像のみ削除
+func TestImageGC_DeletesOnlyUnreferencedAfterGrace(t *testing.T) {
+	mockDAO := NewMockImageDAO()
+	store := storage.NewMemoryStorage()
+	usecase := NewItemUsecase(nil, mockDAO, NewMockUploadSessionDAO(), store)
+
+	orphan, _ := usecase.UploadImage(newUploadFile(newTestJPEG(t, 300, 200)), &multipart.FileHeader{})
+	used, _ := usecase.UploadImage(newUploadFile(newTestJPEG(t, 200, 300)), &multipart.FileHeader{})
+	mockDAO.referenced[used.Hash] = true
+
+	gc := NewImageGC(mockDAO, NewMockUploadSessionDAO(), NewMockAttachmentDAO(), store, time.Hour)
+
+	// 猶予期間内は削除しない
+	deleted, err := gc.RunOnce()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if deleted != 0 {
+		t.Errorf("expected 0 deleted within grace period, got %d", deleted)
+	}
+
+	// 猶予期間経過後は未参照の画像だけ削除
+	gc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	deleted, err = gc.RunOnce()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 deleted, got %d", deleted)
+	}
+	if _, ok := mockDAO.images[orphan.Hash]; ok {
+		t.Error("expected orphan image record to be deleted")
+	}
+	if _, ok := mockDAO.images[used.Hash]; !ok {
+		t.Error("expected referenced image record to be kept")
+	}
+	for _, key := range imageKeys(orphan.Hash, "jpg") {
+		if _, ok := store.Objects[key]; ok {
+			t.Errorf("expected %s to be deleted from storage", key)
+		}
+	}
+	for _, key := range imageKeys(used.Hash, "jpg") {
+		if _, ok := store.Objects[key]; !ok {
+			t.Errorf("expected %s to be kept in storage", key)
+		}
+	}
+}
+
+// TestImageGC_ReuploadAfterDelete ファイルは行を消す前に削除し、削除後に同じ画像を再アップロードすると保存し直す
+func TestImageGC_ReuploadAfterDelete(t *testing.T) {
+	mockDAO := NewMockImageDAO()
+	store := storage.NewMemoryStorage()
+	usecase := NewItemUsecase(nil, mockDAO, NewMockUploadSessionDAO(), store)
+	data := newTestJPEG(t, 300, 200)
+
+	img, err := usecase.UploadImage(newUploadFile(data), &multipart.FileHeader{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	gc := NewImageGC(mockDAO, NewMockUploadSessionDAO(), NewMockAttachmentDAO(), store, time.Hour)
+	gc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	removed := false
+	dao := &deleteObserver{MockImageDAO: mockDAO, onRemove: func() {
+		// ファイルを消している間は行が残っている（再アップロードはTouchで待つ）
+		if _, ok := mockDAO.images[img.Hash]; !ok {
+			t.Error("expected image record to exist while removing objects")
+		}
+		removed = true
+	}}
+	gc.imageDao = dao
+	if deleted, err := gc.RunOnce(); err != nil || deleted != 1 {
+		t.Fatalf("expected 1 deleted, got %d (%v)", deleted, err)
+	}
+	if !removed {
+		t.Fatal("expected objects to be removed")
+	}
+
+	// 削除後の再アップロードでは行がないのでファイルを保存し直す
+	if _, err := usecase.UploadImage(newUploadFile(data), &multipart.FileHeader{}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, key := range imageKeys(img.Hash, "jpg") {
+		if _, ok := store.Objects[key]; !ok {
+			t.Errorf("expected %s to be stored again", key)
+		}
+	}
+}
+
+// deleteObserver はファイルの削除の直後にonRemoveを呼ぶ
+type deleteObserver struct {
+	*MockImageDAO
+	onRemove func()
+}
+
+func (d *deleteObserver) DeleteIfUnreferenced(hash string, olderThan time.Time, removeObjects func()) (bool, error) {
+	return d.MockImageDAO.DeleteIfUnreferenced(hash, olderThan, func() {
+		removeObjects()
+		d.onRemove()
+	})
+}
+
+// presignAndPut は署名付きURLを発行し、クライアントの代わりにstagingへ直接書き込む
+func presignAndPut(t *testing.T, usecase *ItemUsecase, store *storage.MemoryStorage, uid, contentType string, declared, uploaded []byte) *PresignedUpload {
+	t.Helper()
+	sum := sha256.Sum256(declared)
+	upload, err := usecase.PresignUpload(uid, contentType, len(declared), hex.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := store.Put(stagingPrefix+upload.UploadToken, uploaded, contentType); err != nil {
+		t.Fatalf("failed to put staging object: %v", err)
+	}
+	return upload
+}
+
+// TestPresignUpload_Validation 申告内容が不正な場合はURLを発行しない
+func TestPresignUpload_Validation(t *testing.T) {
+	usecase := NewItemUsecase(nil, NewMockImageDAO(), NewMockUploadSessionDAO(), storage.NewMemoryStorage())
+	validHash := strings.Repeat("a", 64)
+
+	tests := []struct {
+		name        string
+		contentType string
+		size        int
+		hash        string
+	}{
+		{"unsupported type", "image/gif", 100, validHash},
+		{"zero size", "image/jpeg", 0, validHash},
+		{"too large", "image/jpeg", maxImageBytes + 1, validHash},
+		{"invalid hash", "image/jpeg", 100, "not-a-hash"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := usecase.PresignUpload("user-1", tt.contentType, tt.size, tt.hash)
+			if !errors.Is(err, ErrInvalidImage) {
+				t.Errorf("expected ErrInvalidImage, got %v", err)
+			}
+		})
+	}
+}
+
+// TestCompleteUpload_Success 検証に通った画像はimagesに登録され、出品に使える
+func TestCompleteUpload_Success(t *testing.T) {
+	mockDAO := NewMockImageDAO()
+	uploadDAO := NewMockUploadSessionDAO()
+	store := storage.NewMemoryStorage()
+	usecase := NewItemUsecase(nil, mockDAO, uploadDAO, store)
+	data := newTestJPEG(t, 300, 200)
+
+	upload := presignAndPut(t, usecase, store, "user-1", "image/jpeg", data, data)
+	if !strings.Contains(upload.UploadURL, stagingPrefix+upload.UploadToken) || upload.Method != "PUT" {
+		t.Errorf("unexpected upload url: %+v", upload)
+	}
+
+	image, err := usecase.CompleteUpload("user-1", upload.UploadToken)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	sum := sha256.Sum256(data)
+	if image.Hash != hex.EncodeToString(sum[:]) {
+		t.Errorf("expected hash of uploaded data, got %s", image.Hash)
+	}
+	if _, ok := store.Objects[stagingPrefix+upload.UploadToken]; ok {
+		t.Error("expected staging object to be deleted")
+	}
+	if uploadDAO.sessions[upload.UploadToken].Status != "completed" {
+		t.Errorf("expected completed, got %s", uploadDAO.sessions[upload.UploadToken].Status)
+	}
+
+	// 再送しても同じ画像が返る
+	again, err := usecase.CompleteUpload("user-1", upload.UploadToken)
+	if err != nil || again.URL != image.URL {
+		t.Errorf("expected idempotent completion, got %+v, %v", again, err)
+	}
+
+	// 出品時はトークンから画像を参照できる（他人のトークンは使えない）
+	attached, err := usecase.imageFromUpload("user-1", upload.UploadToken)
+	if err != nil || attached.Hash != image.Hash {
+		t.Errorf("expected attached image, got %+v, %v", attached, err)
+	}
+	if _, err := usecase.imageFromUpload("user-2", upload.UploadToken); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("expected ErrUploadNotFound for another user, got %v", err)
+	}
+}
+
+// TestCompleteUpload_VerificationFailure 申告と異なるデータは拒否され、stagingから削除される
+func TestCompleteUpload_VerificationFailure(t *testing.T) {
+	jpegData := newTestJPEG(t, 300, 200)
+	otherData := newTestJPEG(t, 200, 300)
+	// 同じサイズで中身だけ異なるデータ
+	tampered := append([]byte(nil), jpegData...)
+	tampered[len(tampered)-3] ^= 0xFF
+	// 先頭だけPNGに見せかけたデータ（申告はJPEG）
+	fakePNG := append([]byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}, jpegData...)
+
+	tests := []struct {
+		name        string
+		contentType string
+		declared    []byte
+		uploaded    []byte
+	}{
+		{"hash mismatch", "image/jpeg", jpegData, tampered},
+		{"size mismatch", "image/jpeg", jpegData, otherData},
+		{"type mismatch", "image/jpeg", fakePNG, fakePNG},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDAO := NewMockImageDAO()
+			uploadDAO := NewMockUploadSessionDAO()
+			store := storage.NewMemoryStorage()
+			usecase := NewItemUsecase(nil, mockDAO, uploadDAO, store)
+
+			upload := presignAndPut(t, usecase, store, "user-1", tt.contentType, tt.declared, tt.uploaded)
+			if _, err := usecase.CompleteUpload("user-1", upload.UploadToken); !errors.Is(err, ErrInvalidImage) {
+				t.Fatalf("expected ErrInvalidImage, got %v", err)
+			}
+			if len(store.Objects) != 0 {
+				t.Errorf("expected staging object to be deleted, got %d objects", len(store.Objects))
+			}
+			if len(mockDAO.images) != 0 {
+				t.Errorf("expected no image record, got %d", len(mockDAO.images))
+			}
+			if _, err := usecase.imageFromUpload("user-1", upload.UploadToken); !errors.Is(err, ErrUploadNotCompleted) {
+				t.Errorf("expected ErrUploadNotCompleted, got %v", err)
+			}
+		})
+	}
+}
+
+// TestCompleteUpload_Errors 未アップロード・期限切れ・他人のトークン
+func TestCompleteUpload_Errors(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	usecase := NewItemUsecase(nil, NewMockImageDAO(), NewMockUploadSessionDAO(), store)
+	data := newTestJPEG(t, 300, 200)
+	sum := sha256.Sum256(data)
+
+	upload, err := usecase.PresignUpload("user-1", "image/jpeg", len(data), hex.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := usecase.CompleteUpload("user-1", upload.UploadToken); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("expected ErrInvalidImage before upload, got %v", err)
+	}
+	if _, err := usecase.CompleteUpload("user-2", upload.UploadToken); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("expected ErrUploadNotFound for another user, got %v", err)
+	}
+
+	store.Put(stagingPrefix+upload.UploadToken, data, "image/jpeg")
+	usecase.now = func() time.Time { return time.Now().Add(uploadURLExpiry + time.Minute) }
+	if _, err := usecase.CompleteUpload("user-1", upload.UploadToken); !errors.Is(err, ErrUploadExpired) {
+		t.Errorf("expected ErrUploadExpired, got %v", err)
+	}
+}
+
+// TestCompleteUpload_ImageRemoved 完了後にGCで画像が削除されたトークンは期限切れとして扱う
+func TestCompleteUpload_ImageRemoved(t *testing.T) {
+	mockDAO := NewMockImageDAO()
+	store := storage.NewMemoryStorage()
+	usecase := NewItemUsecase(nil, mockDAO, NewMockUploadSessionDAO(), store)
+	data := newTestJPEG(t, 300, 200)
+
+	upload := presignAndPut(t, usecase, store, "user-1", "image/jpeg", data, data)
+	image, err := usecase.CompleteUpload("user-1", upload.UploadToken)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	delete(mockDAO.images, image.Hash)
+
+	if _, err := usecase.CompleteUpload("user-1", upload.UploadToken); !errors.Is(err, ErrUploadExpired) {
+		t.Errorf("expected ErrUploadExpired on retry, got %v", err)
+	}
+	if _, err := usecase.imageFromUpload("user-1", upload.UploadToken); !errors.Is(err, ErrUploadExpired) {
+		t.Errorf("expected ErrUploadExpired when listing, got %v", err)
+	}
+}
+
+// TestImageGC_CleansUpExpiredUploads 期限切れのセッションとstagingのオブジェクトを削除する
+func TestImageGC_CleansUpExpiredUploads(t *testing.T) {
+	uploadDAO := NewMockUploadSessionDAO()
+	store := storage.NewMemoryStorage()
+	usecase := NewItemUsecase(nil, NewMockImageDAO(), uploadDAO, store)
+	data := newTestJPEG(t, 300, 200)
+	upload := presignAndPut(t, usecase, store, "user-1", "image/jpeg", data, data)
+
+	gc := NewImageGC(NewMockImageDAO(), uploadDAO, NewMockAttachmentDAO(), store, time.Hour)
+	gc.now = func() time.Time { return time.Now().Add(uploadURLExpiry + 2*time.Hour) }
+	if _, err := gc.RunOnce(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, ok := uploadDAO.sessions[upload.UploadToken]; ok {
+		t.Error("expected expired session to be deleted")
+	}
+	if len(store.Objects) != 0 {
+		t.Errorf("expected staging object to be deleted, got %d objects", len(store.Objects))
+	}
+}
+
+// TestImageGC_CleansUpOrphanAttachments 送信されないままの添付画像を削除し、同じ画像を添付したメッセージがあればファイルは残す
+func TestImageGC_CleansUpOrphanAttachments(t *testing.T) {
+	attachmentDAO := NewMockAttachmentDAO()
+	store := storage.NewMemoryStorage()
+	usecase := NewItemUsecase(nil, NewMockImageDAO(), NewMockUploadSessionDAO(), store)
+
+	register := func(*imagesDao.PrivateImage) error { return nil }
+	orphan, err := usecase.UploadPrivateImage(newUploadFile(newTestJPEG(t, 300, 200)), &multipart.FileHeader{}, register)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	shared, _ := usecase.UploadPrivateImage(newUploadFile(newTestJPEG(t, 200, 300)), &multipart.FileHeader{}, register)
+	messageID := 1
+	created := time.Now()
+	attachmentDAO.attachments[1] = &messagesDao.Attachment{ID: 1, Hash: orphan.Hash, Ext: orphan.Ext, CreatedAt: created}
+	attachmentDAO.attachments[2] = &messagesDao.Attachment{ID: 2, Hash: shared.Hash, Ext: shared.Ext, CreatedAt: created}
+	attachmentDAO.attachments[3] = &messagesDao.Attachment{ID: 3, MessageID: &messageID, Hash: shared.Hash, Ext: shared.Ext, CreatedAt: created}
+
+	gc := NewImageGC(NewMockImageDAO(), NewMockUploadSessionDAO(), attachmentDAO, store, time.Hour)
+	gc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := gc.RunOnce(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(attachmentDAO.attachments) != 1 || attachmentDAO.attachments[3] == nil {
+		t.Errorf("expected only the sent attachment to remain, got %d", len(attachmentDAO.attachments))
+	}
+	for _, key := range privateImageKeys(orphan.Hash, orphan.Ext) {
+		if _, ok := store.Objects[key]; ok {
+			t.Errorf("expected %s to be deleted from storage", key)
+		}
+	}
+	for _, key := range privateImageKeys(shared.Hash, shared.Ext) {
+		if _, ok := store.Objects[key]; !ok {
+			t.Errorf("expected %s to be kept in storage", key)
+		}
+	}
+}
+
+// TestUploadPrivateImage_RegistersBeforeStoring 添付画像の行はファイルを保存する前に登録し、登録に失敗したら保存しない
+func TestUploadPrivateImage_RegistersBeforeStoring(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	usecase := NewItemUsecase(nil, NewMockImageDAO(), NewMockUploadSessionDAO(), store)
+	data := newTestJPEG(t, 300, 200)
+
+	registered := false
+	_, err := usecase.UploadPrivateImage(newUploadFile(data), &multipart.FileHeader{}, func(image *imagesDao.PrivateImage) error {
+		if len(store.Objects) != 0 {
+			t.Errorf("expected no stored objects before registering, got %d", len(store.Objects))
+		}
+		registered = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !registered || len(store.Objects) != len(imageVariants) {
+		t.Errorf("expected registered image to be stored, got registered=%v objects=%d", registered, len(store.Objects))
+	}
+
+	store = storage.NewMemoryStorage()
+	usecase = NewItemUsecase(nil, NewMockImageDAO(), NewMockUploadSessionDAO(), store)
+	registerErr := errors.New("insert failed")
+	_, err = usecase.UploadPrivateImage(newUploadFile(data), &multipart.FileHeader{}, func(*imagesDao.PrivateImage) error { return registerErr })
+	if !errors.Is(err, registerErr) {
+		t.Errorf("expected register error, got %v", err)
+	}
+	if len(store.Objects) != 0 {
+		t.Errorf("expected nothing to be stored, got %d objects", len(store.Objects))
+	}
+}
@@ -2,7 +2,9 @@ package postUser
 
 import (
 	"fmt"
+	"time"
 	postUserDao "uttc-hackathon-backend/dao/postUser"
+	usersUc "uttc-hackathon-backend/usecase/users"
 )
 
 type UserUsecase struct {
@@ -13,8 +15,31 @@ func NewUserUsecase(dao *postUserDao.UserDAO) *UserUsecase {
 	return &UserUsecase{postUserDao: dao}
 }
 
-func (u *UserUsecase) RegisterUser(uid string, nickname string, sex string, birthyear int, birthdate int) (map[string]string, error) {
-	if err := u.postUserDao.InsertUser(uid, nickname, sex, birthyear, birthdate); err != nil {
+// RegisterUser はユーザーを登録する。birthdateは "YYYY-MM-DD"（空文字は未設定）
+// 入力エラーはプロフィール更新と同じく *usersUc.ValidationError で返す
+func (u *UserUsecase) RegisterUser(uid string, nickname string, sex string, birthyear int, birthdate string) (map[string]string, error) {
+	verr := &usersUc.ValidationError{Fields: map[string]string{}}
+	nickname, err := usersUc.ValidateNickname(nickname)
+	if err != nil {
+		verr.Fields["nickname"] = err.Error()
+	}
+	sex, err = usersUc.ValidateSex(sex)
+	if err != nil {
+		verr.Fields["sex"] = err.Error()
+	}
+	date, err := usersUc.ParseBirthdate(birthdate, time.Now())
+	if err != nil {
+		verr.Fields["birthdate"] = err.Error()
+	}
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+	// 生年月日がある場合は誕生年をそこから求める
+	if date.Valid {
+		birthyear = date.Time.Year()
+	}
+
+	if err := u.postUserDao.InsertUser(uid, nickname, sex, birthyear, date); err != nil {
 		return nil, fmt.Errorf("database error: %w", err)
 	}
 
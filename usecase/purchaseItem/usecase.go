@@ -1,20 +1,60 @@
 package purchaseItem
 
 import (
+	"log"
+	auditDao "uttc-hackathon-backend/dao/audit"
+	ordersDao "uttc-hackathon-backend/dao/orders"
 	dao "uttc-hackathon-backend/dao/purchaseItem"
+	notificationsUc "uttc-hackathon-backend/usecase/notifications"
+	ordersUc "uttc-hackathon-backend/usecase/orders"
 )
 
+// OrderPlacer は注文を作成する（ordersのOrderUsecase。onchain購入と同じ経路で注文を作る）
+type OrderPlacer interface {
+	PlaceOrder(input ordersUc.PlaceOrderInput) (*ordersDao.Order, error)
+}
+
+// Notifier は出品者に購入を通知する（notifications.NotificationUsecase）
+type Notifier interface {
+	Notify(in notificationsUc.NotifyInput) error
+}
+
 type PurchaseUsecase struct {
 	purchaseDAO dao.PurchaseDAOInterface
+	orders      OrderPlacer
+	notifier    Notifier
 }
 
-func NewPurchaseUsecase(purchaseDAO dao.PurchaseDAOInterface) *PurchaseUsecase {
-	return &PurchaseUsecase{purchaseDAO: purchaseDAO}
+func NewPurchaseUsecase(purchaseDAO dao.PurchaseDAOInterface, orders OrderPlacer, notifier Notifier) *PurchaseUsecase {
+	return &PurchaseUsecase{purchaseDAO: purchaseDAO, orders: orders, notifier: notifier}
 }
 
-func (u *PurchaseUsecase) PurchaseItem(itemID int, buyerUID string) error {
-	// 従来の購入フロー（cash購入）ではbuyer_addressは空文字列
-	return u.purchaseDAO.UpdatePurchaseStatus(itemID, buyerUID, "")
+func (u *PurchaseUsecase) PurchaseItem(itemID int, buyerUID string) (*ordersDao.Order, error) {
+	// 従来の購入フロー（cash購入）。監査ログには購入者本人の操作として記録する
+	order, err := u.orders.PlaceOrder(ordersUc.PlaceOrderInput{
+		ItemID:        itemID,
+		BuyerUID:      buyerUID,
+		PaymentMethod: ordersDao.PaymentCash,
+		Actor:         auditDao.UserActor(buyerUID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 出品者に通知する（失敗しても購入は成功とする）
+	if u.notifier != nil {
+		err := u.notifier.Notify(notificationsUc.NotifyInput{
+			UID:      order.SellerUID,
+			Type:     notificationsUc.TypePurchase,
+			ActorUID: buyerUID,
+			ItemID:   &itemID,
+			RefID:    order.ID,
+		})
+		if err != nil {
+			log.Printf("WARNING: failed to notify purchase of item_id=%d: %v", itemID, err)
+		}
+	}
+	return order, nil
 }
 
 func (u *PurchaseUsecase) GetPurchasedItems(buyerUID string, buyerAddress string) ([]*dao.PurchasedItem, error) {
@@ -5,41 +5,74 @@ import (
 	"testing"
 	"time"
 
+	auditDao "uttc-hackathon-backend/dao/audit"
+	ordersDao "uttc-hackathon-backend/dao/orders"
 	dao "uttc-hackathon-backend/dao/purchaseItem"
+	notificationsUc "uttc-hackathon-backend/usecase/notifications"
+	ordersUc "uttc-hackathon-backend/usecase/orders"
 )
 
 // MockPurchaseDAO はテスト用のモックDAO
 type MockPurchaseDAO struct {
-	purchasedItems map[int]bool           // itemID -> purchased
-	purchases      map[string][]*dao.PurchasedItem // buyerUID -> items
-	updateErr      error
-	getItemsErr    error
+	purchases   map[string][]*dao.PurchasedItem // buyerUID -> items
+	getItemsErr error
 }
 
 func NewMockPurchaseDAO() *MockPurchaseDAO {
 	return &MockPurchaseDAO{
-		purchasedItems: make(map[int]bool),
-		purchases:      make(map[string][]*dao.PurchasedItem),
+		purchases: make(map[string][]*dao.PurchasedItem),
 	}
 }
 
-func (m *MockPurchaseDAO) UpdatePurchaseStatus(itemID int, buyerUID string, buyerAddress string) error {
-	if m.updateErr != nil {
-		return m.updateErr
+// MockOrderPlacer はテスト用の注文作成（成功した注文はMockPurchaseDAOの購入履歴にも追加する）
+type MockOrderPlacer struct {
+	purchaseDAO    *MockPurchaseDAO
+	purchasedItems map[int]bool // itemID -> purchased
+	placeErr       error
+	lastInput      ordersUc.PlaceOrderInput
+	nextID         int64
+}
+
+func NewMockOrderPlacer(purchaseDAO *MockPurchaseDAO) *MockOrderPlacer {
+	return &MockOrderPlacer{purchaseDAO: purchaseDAO, purchasedItems: make(map[int]bool)}
+}
+
+func (m *MockOrderPlacer) PlaceOrder(input ordersUc.PlaceOrderInput) (*ordersDao.Order, error) {
+	m.lastInput = input
+	if m.placeErr != nil {
+		return nil, m.placeErr
 	}
-	if m.purchasedItems[itemID] {
-		return errors.New("item already purchased")
+	if m.purchasedItems[input.ItemID] {
+		return nil, ordersUc.ErrItemNotAvailable
 	}
-	m.purchasedItems[itemID] = true
+	m.purchasedItems[input.ItemID] = true
+	m.nextID++
 
 	// 購入履歴に追加
 	item := &dao.PurchasedItem{
-		ID:          itemID,
+		ID:          input.ItemID,
 		Title:       "Test Item",
 		Price:       1000,
 		PurchasedAt: time.Now(),
 	}
-	m.purchases[buyerUID] = append(m.purchases[buyerUID], item)
+	m.purchaseDAO.purchases[input.BuyerUID] = append(m.purchaseDAO.purchases[input.BuyerUID], item)
+	return &ordersDao.Order{
+		ID:            m.nextID,
+		ItemID:        input.ItemID,
+		SellerUID:     "seller123",
+		BuyerUID:      input.BuyerUID,
+		PaymentMethod: input.PaymentMethod,
+		Status:        ordersDao.StatusPaid,
+	}, nil
+}
+
+// MockNotifier はテスト用の通知（受け取った通知を記録する）
+type MockNotifier struct {
+	notified []notificationsUc.NotifyInput
+}
+
+func (m *MockNotifier) Notify(in notificationsUc.NotifyInput) error {
+	m.notified = append(m.notified, in)
 	return nil
 }
 
@@ -63,41 +96,89 @@ func (m *MockPurchaseDAO) GetWalletAddressByUID(uid string) (string, error) {
 // TestPurchaseItem_Success 購入成功
 func TestPurchaseItem_Success(t *testing.T) {
 	mockDAO := NewMockPurchaseDAO()
-	usecase := NewPurchaseUsecase(mockDAO)
+	orders := NewMockOrderPlacer(mockDAO)
+	usecase := NewPurchaseUsecase(mockDAO, orders, nil)
 
-	err := usecase.PurchaseItem(1, "buyer123")
+	order, err := usecase.PurchaseItem(1, "buyer123")
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
 
 	// 購入済みになっているか確認
-	if !mockDAO.purchasedItems[1] {
+	if !orders.purchasedItems[1] {
 		t.Error("expected item to be purchased")
 	}
+	if order == nil || order.Status != ordersDao.StatusPaid {
+		t.Errorf("expected paid order, got %+v", order)
+	}
+	if orders.lastInput.PaymentMethod != ordersDao.PaymentCash {
+		t.Errorf("expected cash payment, got %s", orders.lastInput.PaymentMethod)
+	}
+}
+
+// TestPurchaseItem_AuditActor 購入者本人の操作として監査ログに渡されるか
+func TestPurchaseItem_AuditActor(t *testing.T) {
+	mockDAO := NewMockPurchaseDAO()
+	orders := NewMockOrderPlacer(mockDAO)
+	usecase := NewPurchaseUsecase(mockDAO, orders, nil)
+
+	if _, err := usecase.PurchaseItem(1, "buyer123"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	actor := orders.lastInput.Actor
+	if actor.Type != auditDao.ActorUser || actor.UID != "buyer123" || actor.Source != auditDao.SourceAPI {
+		t.Errorf("unexpected actor: %+v", actor)
+	}
+}
+
+// TestPurchaseItem_NotifiesSeller 購入すると注文ごとに出品者に通知し、購入できなければ通知しない
+func TestPurchaseItem_NotifiesSeller(t *testing.T) {
+	mockDAO := NewMockPurchaseDAO()
+	notifier := &MockNotifier{}
+	usecase := NewPurchaseUsecase(mockDAO, NewMockOrderPlacer(mockDAO), notifier)
+
+	order, err := usecase.PurchaseItem(1, "buyer123")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(notifier.notified) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(notifier.notified))
+	}
+	in := notifier.notified[0]
+	if in.UID != "seller123" || in.Type != notificationsUc.TypePurchase || in.ActorUID != "buyer123" ||
+		in.ItemID == nil || *in.ItemID != 1 || in.RefID != order.ID {
+		t.Errorf("unexpected notification: %+v", in)
+	}
+
+	_, _ = usecase.PurchaseItem(1, "buyer456")
+	if len(notifier.notified) != 1 {
+		t.Errorf("expected no notification for failed purchase, got %d", len(notifier.notified))
+	}
 }
 
 // TestPurchaseItem_AlreadyPurchased 購入済み商品の再購入
 func TestPurchaseItem_AlreadyPurchased(t *testing.T) {
 	mockDAO := NewMockPurchaseDAO()
-	usecase := NewPurchaseUsecase(mockDAO)
+	usecase := NewPurchaseUsecase(mockDAO, NewMockOrderPlacer(mockDAO), nil)
 
 	// 1回目は成功
-	_ = usecase.PurchaseItem(1, "buyer123")
+	_, _ = usecase.PurchaseItem(1, "buyer123")
 
 	// 2回目は失敗（別のユーザーでも）
-	err := usecase.PurchaseItem(1, "buyer456")
-	if err == nil {
-		t.Error("expected error for already purchased item")
+	_, err := usecase.PurchaseItem(1, "buyer456")
+	if !errors.Is(err, ordersUc.ErrItemNotAvailable) {
+		t.Errorf("expected ErrItemNotAvailable, got %v", err)
 	}
 }
 
 // TestPurchaseItem_DAOError DAOエラー時
 func TestPurchaseItem_DAOError(t *testing.T) {
 	mockDAO := NewMockPurchaseDAO()
-	mockDAO.updateErr = errors.New("database error")
-	usecase := NewPurchaseUsecase(mockDAO)
+	orders := NewMockOrderPlacer(mockDAO)
+	orders.placeErr = errors.New("database error")
+	usecase := NewPurchaseUsecase(mockDAO, orders, nil)
 
-	err := usecase.PurchaseItem(1, "buyer123")
+	_, err := usecase.PurchaseItem(1, "buyer123")
 	if err == nil {
 		t.Error("expected error")
 	}
@@ -106,12 +187,12 @@ func TestPurchaseItem_DAOError(t *testing.T) {
 // TestGetPurchasedItems_Success 購入履歴取得成功
 func TestGetPurchasedItems_Success(t *testing.T) {
 	mockDAO := NewMockPurchaseDAO()
-	usecase := NewPurchaseUsecase(mockDAO)
+	usecase := NewPurchaseUsecase(mockDAO, NewMockOrderPlacer(mockDAO), nil)
 
 	// 複数商品を購入
-	_ = usecase.PurchaseItem(1, "buyer123")
-	_ = usecase.PurchaseItem(2, "buyer123")
-	_ = usecase.PurchaseItem(3, "buyer123")
+	_, _ = usecase.PurchaseItem(1, "buyer123")
+	_, _ = usecase.PurchaseItem(2, "buyer123")
+	_, _ = usecase.PurchaseItem(3, "buyer123")
 
 	items, err := usecase.GetPurchasedItems("buyer123", "")
 	if err != nil {
@@ -125,7 +206,7 @@ func TestGetPurchasedItems_Success(t *testing.T) {
 // TestGetPurchasedItems_Empty 購入履歴なし
 func TestGetPurchasedItems_Empty(t *testing.T) {
 	mockDAO := NewMockPurchaseDAO()
-	usecase := NewPurchaseUsecase(mockDAO)
+	usecase := NewPurchaseUsecase(mockDAO, NewMockOrderPlacer(mockDAO), nil)
 
 	items, err := usecase.GetPurchasedItems("buyer123", "")
 	if err != nil {
@@ -140,7 +221,7 @@ func TestGetPurchasedItems_Empty(t *testing.T) {
 func TestGetPurchasedItems_DAOError(t *testing.T) {
 	mockDAO := NewMockPurchaseDAO()
 	mockDAO.getItemsErr = errors.New("database error")
-	usecase := NewPurchaseUsecase(mockDAO)
+	usecase := NewPurchaseUsecase(mockDAO, NewMockOrderPlacer(mockDAO), nil)
 
 	_, err := usecase.GetPurchasedItems("buyer123", "")
 	if err == nil {
@@ -151,12 +232,12 @@ func TestGetPurchasedItems_DAOError(t *testing.T) {
 // TestGetPurchasedItems_DifferentUsers 異なるユーザーの購入履歴は分離
 func TestGetPurchasedItems_DifferentUsers(t *testing.T) {
 	mockDAO := NewMockPurchaseDAO()
-	usecase := NewPurchaseUsecase(mockDAO)
+	usecase := NewPurchaseUsecase(mockDAO, NewMockOrderPlacer(mockDAO), nil)
 
 	// 異なるユーザーがそれぞれ購入
-	_ = usecase.PurchaseItem(1, "buyer1")
-	_ = usecase.PurchaseItem(2, "buyer1")
-	_ = usecase.PurchaseItem(3, "buyer2")
+	_, _ = usecase.PurchaseItem(1, "buyer1")
+	_, _ = usecase.PurchaseItem(2, "buyer1")
+	_, _ = usecase.PurchaseItem(3, "buyer2")
 
 	items1, _ := usecase.GetPurchasedItems("buyer1", "")
 	items2, _ := usecase.GetPurchasedItems("buyer2", "")
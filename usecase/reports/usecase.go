@@ -0,0 +1,288 @@
+package reports
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	reportsDao "uttc-hackathon-backend/dao/reports"
+)
+
+// maxDetailLength は通報の詳細の最大文字数
+const maxDetailLength = 1000
+
+// 通報対象の種類
+const (
+	TargetItem    = "item"
+	TargetMessage = "message"
+	TargetUser    = "user"
+)
+
+// SystemReporterUID はメッセージのフィルタによる通報のreporter_uid
+const SystemReporterUID = "system"
+
+// 管理者が通報に対して行える対応
+const (
+	ActionHideItem    = "hide_item"
+	ActionSuspendUser = "suspend_user"
+	ActionDismiss     = "dismiss"
+)
+
+// validReasons は受け付ける通報理由
+var validReasons = map[string]bool{
+	"spam":          true,
+	"fraud":         true,
+	"prohibited":    true,
+	"inappropriate": true,
+	"harassment":    true,
+	"counterfeit":   true,
+	"other":         true,
+}
+
+var (
+	// ErrInvalidReport は通報内容が不正な場合のエラー
+	ErrInvalidReport = errors.New("invalid report")
+	// ErrTargetNotFound は通報対象が存在しない場合のエラー
+	ErrTargetNotFound = errors.New("report target not found")
+	// ErrCannotReportSelf は自分自身・自分の商品・自分のメッセージを通報しようとした場合のエラー
+	ErrCannotReportSelf = errors.New("cannot report yourself")
+	// ErrNotParticipant はやり取りの当事者以外がメッセージを通報しようとした場合のエラー
+	ErrNotParticipant = errors.New("only the recipient can report this message")
+	// ErrDuplicateReport は同じ対象を通報済みで未対応の場合のエラー
+	ErrDuplicateReport = errors.New("already reported")
+	// ErrReportNotFound は通報が存在しない場合のエラー
+	ErrReportNotFound = errors.New("report not found")
+	// ErrReportClosed は対応済みの通報に再度対応しようとした場合のエラー
+	ErrReportClosed = errors.New("report is already closed")
+	// ErrInvalidAction は通報対象に対して行えない対応の場合のエラー
+	ErrInvalidAction = errors.New("invalid action")
+)
+
+// MessageReleaser は保留を解除したメッセージを受信者に届ける（messages.MessageUsecase）
+type MessageReleaser interface {
+	MessageReleased(messageID int) error
+}
+
+type ReportUsecase struct {
+	reportDao reportsDao.ReportDAOInterface
+	releaser  MessageReleaser
+}
+
+// NewReportUsecase はReportUsecaseを返す
+// モデレーションキューの操作（ListQueue, Resolve）はmoderator以上のロールに限定してルーティングすること
+func NewReportUsecase(dao reportsDao.ReportDAOInterface) *ReportUsecase {
+	return &ReportUsecase{reportDao: dao}
+}
+
+// SetMessageReleaser は保留を解除したメッセージの届け先を設定する（nilなら届けない）
+// MessageUsecaseはReportUsecaseをモデレーションキューとして使うので、生成した後に設定する
+func (u *ReportUsecase) SetMessageReleaser(releaser MessageReleaser) {
+	u.releaser = releaser
+}
+
+// CreateReport は商品・メッセージ・ユーザーを通報し、モデレーションキューに追加する
+func (u *ReportUsecase) CreateReport(reporterUID, targetType, targetID, reason, detail string) (*reportsDao.Report, error) {
+	if reporterUID == "" {
+		return nil, fmt.Errorf("%w: reporter_uid is required", ErrInvalidReport)
+	}
+	if !validReasons[reason] {
+		return nil, fmt.Errorf("%w: unknown reason %q", ErrInvalidReport, reason)
+	}
+	detail = strings.TrimSpace(detail)
+	if utf8.RuneCountInString(detail) > maxDetailLength {
+		return nil, fmt.Errorf("%w: detail must be at most %d characters", ErrInvalidReport, maxDetailLength)
+	}
+	owner, recipient, err := u.findTarget(targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if owner == reporterUID {
+		return nil, ErrCannotReportSelf
+	}
+	// メッセージは受け取った人だけが通報できる
+	if targetType == TargetMessage && recipient != reporterUID {
+		return nil, ErrNotParticipant
+	}
+
+	exists, err := u.reportDao.HasOpenReport(reporterUID, targetType, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check report: %w", err)
+	}
+	if exists {
+		return nil, ErrDuplicateReport
+	}
+
+	report, err := u.reportDao.CreateReport(&reportsDao.Report{
+		ReporterUID: reporterUID,
+		TargetType:  targetType,
+		TargetID:    targetID,
+		Reason:      reason,
+		Detail:      detail,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report: %w", err)
+	}
+	return report, nil
+}
+
+// FlagMessage はコンテンツフィルタが引っかけたメッセージをモデレーションキューに追加する（通報者はSystemReporterUID）
+// 同じメッセージに未対応のフィルタの通報があれば追加しない
+func (u *ReportUsecase) FlagMessage(messageID int, reason, detail string) error {
+	if !validReasons[reason] {
+		reason = "other"
+	}
+	targetID := strconv.Itoa(messageID)
+	exists, err := u.reportDao.HasOpenReport(SystemReporterUID, TargetMessage, targetID)
+	if err != nil {
+		return fmt.Errorf("failed to check report: %w", err)
+	}
+	if exists {
+		return nil
+	}
+	_, err = u.reportDao.CreateReport(&reportsDao.Report{
+		ReporterUID: SystemReporterUID,
+		TargetType:  TargetMessage,
+		TargetID:    targetID,
+		Reason:      reason,
+		Detail:      detail,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	return nil
+}
+
+// findTarget は通報対象を取得し、対象の責任者（出品者・送信者・本人）のuidを返す
+// メッセージの場合はrecipientに受信者のuidを返す
+func (u *ReportUsecase) findTarget(targetType, targetID string) (owner, recipient string, err error) {
+	switch targetType {
+	case TargetItem:
+		itemID, err := strconv.Atoi(targetID)
+		if err != nil {
+			return "", "", fmt.Errorf("%w: invalid item id", ErrInvalidReport)
+		}
+		owner, err := u.reportDao.GetItemOwner(itemID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "", ErrTargetNotFound
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get item: %w", err)
+		}
+		return owner, "", nil
+	case TargetMessage:
+		messageID, err := strconv.Atoi(targetID)
+		if err != nil {
+			return "", "", fmt.Errorf("%w: invalid message id", ErrInvalidReport)
+		}
+		sender, receiver, err := u.reportDao.GetMessageParticipants(messageID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "", ErrTargetNotFound
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get message: %w", err)
+		}
+		return sender, receiver, nil
+	case TargetUser:
+		exists, err := u.reportDao.UserExists(targetID)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to check user: %w", err)
+		}
+		if !exists {
+			return "", "", ErrTargetNotFound
+		}
+		return targetID, "", nil
+	}
+	return "", "", fmt.Errorf("%w: target_type must be item, message or user", ErrInvalidReport)
+}
+
+// ListQueue はモデレーションキュー（指定した状態の通報）を古い順に返す
+func (u *ReportUsecase) ListQueue(status string, page, limit int) ([]*reportsDao.Report, error) {
+	if status == "" {
+		status = "open"
+	}
+	if status != "open" && status != "resolved" && status != "dismissed" {
+		return nil, fmt.Errorf("%w: status must be open, resolved or dismissed", ErrInvalidReport)
+	}
+	reports, err := u.reportDao.ListReports(status, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+	return reports, nil
+}
+
+// Resolve は通報に対応する（商品の非表示・ユーザーの利用停止・却下）
+// 同じ対象への未対応の通報はまとめてクローズし、その件数を返す
+// メッセージの通報を却下した場合は、フィルタで保留していたメッセージを受信者に見せる
+func (u *ReportUsecase) Resolve(moderatorUID string, reportID int, action string) (int64, error) {
+	report, err := u.reportDao.GetReport(reportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrReportNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get report: %w", err)
+	}
+	if report.Status != "open" {
+		return 0, ErrReportClosed
+	}
+
+	res := &reportsDao.Resolution{
+		TargetType:   report.TargetType,
+		TargetID:     report.TargetID,
+		Status:       "resolved",
+		Action:       action,
+		ModeratorUID: moderatorUID,
+		ReportID:     report.ID,
+	}
+	switch action {
+	case ActionDismiss:
+		res.Status = "dismissed"
+		res.Action = ""
+		if report.TargetType == TargetMessage {
+			messageID, err := strconv.Atoi(report.TargetID)
+			if err != nil {
+				return 0, fmt.Errorf("invalid message id in report %d: %w", report.ID, err)
+			}
+			res.ReleaseMessageID = messageID
+		}
+	case ActionHideItem:
+		if report.TargetType != TargetItem {
+			return 0, fmt.Errorf("%w: hide_item is only for item reports", ErrInvalidAction)
+		}
+		itemID, err := strconv.Atoi(report.TargetID)
+		if err != nil {
+			return 0, fmt.Errorf("invalid item id in report %d: %w", report.ID, err)
+		}
+		res.HideItemID = itemID
+	case ActionSuspendUser:
+		owner, _, err := u.findTarget(report.TargetType, report.TargetID)
+		if err != nil {
+			return 0, err
+		}
+		res.SuspendUID = owner
+	default:
+		return 0, fmt.Errorf("%w: action must be hide_item, suspend_user or dismiss", ErrInvalidAction)
+	}
+
+	closed, released, err := u.reportDao.Resolve(res)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve report: %w", err)
+	}
+	if released {
+		u.messageReleased(res.ReleaseMessageID)
+	}
+	return closed, nil
+}
+
+// messageReleased は保留を解除したメッセージを受信者に届ける
+// 解除は済んでいるので、失敗してもログに残すだけにする
+func (u *ReportUsecase) messageReleased(messageID int) {
+	if u.releaser == nil {
+		return
+	}
+	if err := u.releaser.MessageReleased(messageID); err != nil {
+		log.Printf("WARNING: failed to deliver released message id=%d: %v", messageID, err)
+	}
+}
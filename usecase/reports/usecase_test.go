@@ -0,0 +1,352 @@
+package reports
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	reportsDao "uttc-hackathon-backend/dao/reports"
+)
+
+// MockReportDAO はテスト用のモックDAO
+type MockReportDAO struct {
+	users       map[string]bool
+	itemOwners  map[int]string
+	messages    map[int][2]string // id -> {sender, receiver}
+	reports     []*reportsDao.Report
+	resolutions []*reportsDao.Resolution
+	hidden      map[int]bool
+	suspended   map[string]bool
+}
+
+func NewMockReportDAO() *MockReportDAO {
+	return &MockReportDAO{
+		users:      map[string]bool{"alice": true, "bob": true, "carol": true},
+		itemOwners: map[int]string{1: "bob"},
+		messages:   map[int][2]string{10: {"bob", "alice"}},
+		hidden:     make(map[int]bool),
+		suspended:  make(map[string]bool),
+	}
+}
+
+func (m *MockReportDAO) UserExists(uid string) (bool, error) {
+	return m.users[uid], nil
+}
+
+func (m *MockReportDAO) GetItemOwner(itemID int) (string, error) {
+	owner, ok := m.itemOwners[itemID]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return owner, nil
+}
+
+func (m *MockReportDAO) GetMessageParticipants(messageID int) (string, string, error) {
+	msg, ok := m.messages[messageID]
+	if !ok {
+		return "", "", sql.ErrNoRows
+	}
+	return msg[0], msg[1], nil
+}
+
+func (m *MockReportDAO) HasOpenReport(reporterUID, targetType, targetID string) (bool, error) {
+	for _, r := range m.reports {
+		if r.ReporterUID == reporterUID && r.TargetType == targetType && r.TargetID == targetID && r.Status == "open" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MockReportDAO) CreateReport(report *reportsDao.Report) (*reportsDao.Report, error) {
+	created := *report
+	created.ID = len(m.reports) + 1
+	created.Status = "open"
+	created.CreatedAt = time.Now()
+	m.reports = append(m.reports, &created)
+	return &created, nil
+}
+
+func (m *MockReportDAO) GetReport(id int) (*reportsDao.Report, error) {
+	for _, r := range m.reports {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *MockReportDAO) ListReports(status string, limit, offset int) ([]*reportsDao.Report, error) {
+	var result []*reportsDao.Report
+	for _, r := range m.reports {
+		if r.Status == status {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockReportDAO) Resolve(res *reportsDao.Resolution) (int64, bool, error) {
+	m.resolutions = append(m.resolutions, res)
+	if res.HideItemID != 0 {
+		m.hidden[res.HideItemID] = true
+	}
+	if res.SuspendUID != "" {
+		m.suspended[res.SuspendUID] = true
+	}
+	var closed int64
+	for _, r := range m.reports {
+		if r.TargetType == res.TargetType && r.TargetID == res.TargetID && r.Status == "open" {
+			r.Status = res.Status
+			r.Action = res.Action
+			r.ResolvedBy = res.ModeratorUID
+			closed++
+		}
+	}
+	// 保留中かどうかは持っていないので、公開を指定されたら公開したことにする
+	return closed, res.ReleaseMessageID != 0, nil
+}
+
+// MockMessageReleaser は保留を解除したメッセージを記録する
+type MockMessageReleaser struct {
+	released []int
+}
+
+func (r *MockMessageReleaser) MessageReleased(messageID int) error {
+	r.released = append(r.released, messageID)
+	return nil
+}
+
+// TestCreateReport_Success 商品・メッセージ・ユーザーを通報できる
+func TestCreateReport_Success(t *testing.T) {
+	usecase := NewReportUsecase(NewMockReportDAO())
+
+	cases := []struct {
+		targetType string
+		targetID   string
+	}{
+		{TargetItem, "1"},
+		{TargetMessage, "10"},
+		{TargetUser, "bob"},
+	}
+	for _, c := range cases {
+		report, err := usecase.CreateReport("alice", c.targetType, c.targetID, "spam", "  詳細  ")
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", c.targetType, err)
+		}
+		if report.Status != "open" || report.Detail != "詳細" {
+			t.Errorf("%s: unexpected report: %+v", c.targetType, report)
+		}
+	}
+}
+
+// TestCreateReport_Rejected 不正な通報はエラー
+func TestCreateReport_Rejected(t *testing.T) {
+	usecase := NewReportUsecase(NewMockReportDAO())
+
+	cases := []struct {
+		name        string
+		reporterUID string
+		targetType  string
+		targetID    string
+		reason      string
+		want        error
+	}{
+		{"unknown reason", "alice", TargetItem, "1", "boring", ErrInvalidReport},
+		{"unknown target type", "alice", "review", "1", "spam", ErrInvalidReport},
+		{"invalid item id", "alice", TargetItem, "abc", "spam", ErrInvalidReport},
+		{"missing item", "alice", TargetItem, "999", "spam", ErrTargetNotFound},
+		{"missing user", "alice", TargetUser, "nobody", "spam", ErrTargetNotFound},
+		{"own item", "bob", TargetItem, "1", "spam", ErrCannotReportSelf},
+		{"self", "alice", TargetUser, "alice", "spam", ErrCannotReportSelf},
+		{"own message", "bob", TargetMessage, "10", "spam", ErrCannotReportSelf},
+		{"other's message", "carol", TargetMessage, "10", "spam", ErrNotParticipant},
+	}
+	for _, c := range cases {
+		_, err := usecase.CreateReport(c.reporterUID, c.targetType, c.targetID, c.reason, "")
+		if !errors.Is(err, c.want) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, err)
+		}
+	}
+}
+
+// TestCreateReport_Duplicate 未対応の通報がある対象は再度通報できない
+func TestCreateReport_Duplicate(t *testing.T) {
+	usecase := NewReportUsecase(NewMockReportDAO())
+
+	if _, err := usecase.CreateReport("alice", TargetItem, "1", "fraud", ""); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := usecase.CreateReport("alice", TargetItem, "1", "fraud", ""); !errors.Is(err, ErrDuplicateReport) {
+		t.Errorf("expected ErrDuplicateReport, got %v", err)
+	}
+	// 別の人は通報できる
+	if _, err := usecase.CreateReport("carol", TargetItem, "1", "fraud", ""); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+// TestListQueue_Status モデレーションキューは状態で絞り込む（デフォルトは未対応）
+// 管理者以外のアクセスはauth.RequireRoleで拒否する
+func TestListQueue_Status(t *testing.T) {
+	usecase := NewReportUsecase(NewMockReportDAO())
+	_, _ = usecase.CreateReport("alice", TargetItem, "1", "fraud", "")
+
+	queue, err := usecase.ListQueue("", 1, 20)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(queue) != 1 {
+		t.Errorf("expected 1 open report, got %d", len(queue))
+	}
+	if _, err := usecase.ListQueue("closed", 1, 20); !errors.Is(err, ErrInvalidReport) {
+		t.Errorf("expected ErrInvalidReport, got %v", err)
+	}
+}
+
+// TestResolve_HideItem 商品を非表示にし、同じ商品への通報をまとめてクローズする
+func TestResolve_HideItem(t *testing.T) {
+	mockDAO := NewMockReportDAO()
+	usecase := NewReportUsecase(mockDAO)
+	report, _ := usecase.CreateReport("alice", TargetItem, "1", "fraud", "")
+	_, _ = usecase.CreateReport("carol", TargetItem, "1", "counterfeit", "")
+
+	closed, err := usecase.Resolve("admin", report.ID, ActionHideItem)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if closed != 2 {
+		t.Errorf("expected 2 closed reports, got %d", closed)
+	}
+	if !mockDAO.hidden[1] {
+		t.Error("expected item 1 to be hidden")
+	}
+	if len(mockDAO.suspended) != 0 {
+		t.Errorf("expected no suspensions, got %v", mockDAO.suspended)
+	}
+
+	// 対応済みの通報には再度対応できない
+	if _, err := usecase.Resolve("admin", report.ID, ActionDismiss); !errors.Is(err, ErrReportClosed) {
+		t.Errorf("expected ErrReportClosed, got %v", err)
+	}
+}
+
+// TestResolve_SuspendUser 通報対象の責任者を利用停止にする
+func TestResolve_SuspendUser(t *testing.T) {
+	cases := []struct {
+		targetType string
+		targetID   string
+		suspended  string
+	}{
+		{TargetItem, "1", "bob"},     // 出品者
+		{TargetMessage, "10", "bob"}, // 送信者
+		{TargetUser, "bob", "bob"},   // 本人
+	}
+	for _, c := range cases {
+		mockDAO := NewMockReportDAO()
+		usecase := NewReportUsecase(mockDAO)
+		report, err := usecase.CreateReport("alice", c.targetType, c.targetID, "harassment", "")
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", c.targetType, err)
+		}
+		if _, err := usecase.Resolve("admin", report.ID, ActionSuspendUser); err != nil {
+			t.Fatalf("%s: expected no error, got %v", c.targetType, err)
+		}
+		if !mockDAO.suspended[c.suspended] || len(mockDAO.suspended) != 1 {
+			t.Errorf("%s: expected only %s to be suspended, got %v", c.targetType, c.suspended, mockDAO.suspended)
+		}
+		if report.Status != "resolved" || report.Action != ActionSuspendUser {
+			t.Errorf("%s: unexpected report: %+v", c.targetType, report)
+		}
+	}
+}
+
+// TestResolve_InvalidAction 対象に対して行えない対応はエラー
+func TestResolve_InvalidAction(t *testing.T) {
+	mockDAO := NewMockReportDAO()
+	usecase := NewReportUsecase(mockDAO)
+	report, _ := usecase.CreateReport("alice", TargetUser, "bob", "spam", "")
+
+	if _, err := usecase.Resolve("admin", report.ID, ActionHideItem); !errors.Is(err, ErrInvalidAction) {
+		t.Errorf("expected ErrInvalidAction, got %v", err)
+	}
+	if _, err := usecase.Resolve("admin", report.ID, "delete_user"); !errors.Is(err, ErrInvalidAction) {
+		t.Errorf("expected ErrInvalidAction, got %v", err)
+	}
+	if _, err := usecase.Resolve("admin", 999, ActionDismiss); !errors.Is(err, ErrReportNotFound) {
+		t.Errorf("expected ErrReportNotFound, got %v", err)
+	}
+	if len(mockDAO.resolutions) != 0 {
+		t.Errorf("expected no resolutions, got %d", len(mockDAO.resolutions))
+	}
+
+	// 却下は対応内容なしでクローズする
+	if _, err := usecase.Resolve("admin", report.ID, ActionDismiss); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if report.Status != "dismissed" || report.Action != "" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+// TestFlagMessage フィルタに引っかかったメッセージはsystemの通報としてキューに入り、未対応のうちは重複しない
+func TestFlagMessage(t *testing.T) {
+	mockDAO := NewMockReportDAO()
+	usecase := NewReportUsecase(mockDAO)
+
+	if err := usecase.FlagMessage(10, "fraud", "off_platform_payment filter (flag)"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := usecase.FlagMessage(10, "spam", "classifier filter (flag)"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(mockDAO.reports) != 1 {
+		t.Fatalf("expected 1 report, got %d", len(mockDAO.reports))
+	}
+	report := mockDAO.reports[0]
+	if report.ReporterUID != SystemReporterUID || report.TargetType != TargetMessage || report.TargetID != "10" || report.Reason != "fraud" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	// 受信者の通報とは別に数える
+	if _, err := usecase.CreateReport("alice", TargetMessage, "10", "spam", ""); err != nil {
+		t.Errorf("expected recipient to report flagged message, got %v", err)
+	}
+	// 不明な理由はotherにする
+	_ = usecase.FlagMessage(11, "unknown", "")
+	if got := mockDAO.reports[len(mockDAO.reports)-1].Reason; got != "other" {
+		t.Errorf("expected other, got %s", got)
+	}
+}
+
+// TestResolve_DismissReleasesMessage メッセージの通報を却下すると保留していたメッセージを受信者に見せる
+func TestResolve_DismissReleasesMessage(t *testing.T) {
+	mockDAO := NewMockReportDAO()
+	releaser := &MockMessageReleaser{}
+	usecase := NewReportUsecase(mockDAO)
+	usecase.SetMessageReleaser(releaser)
+	_ = usecase.FlagMessage(10, "fraud", "")
+
+	if _, err := usecase.Resolve("moderator", mockDAO.reports[0].ID, ActionDismiss); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(mockDAO.resolutions) != 1 || mockDAO.resolutions[0].ReleaseMessageID != 10 {
+		t.Errorf("expected message 10 to be released, got %+v", mockDAO.resolutions)
+	}
+	if len(releaser.released) != 1 || releaser.released[0] != 10 {
+		t.Errorf("expected released message to be delivered, got %v", releaser.released)
+	}
+
+	// 利用停止にした場合は保留したまま
+	_ = usecase.FlagMessage(10, "fraud", "")
+	if _, err := usecase.Resolve("moderator", mockDAO.reports[1].ID, ActionSuspendUser); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res := mockDAO.resolutions[1]; res.ReleaseMessageID != 0 || res.SuspendUID != "bob" {
+		t.Errorf("expected sender to be suspended without release, got %+v", res)
+	}
+	if len(releaser.released) != 1 {
+		t.Errorf("expected no more deliveries, got %v", releaser.released)
+	}
+}
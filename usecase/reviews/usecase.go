@@ -0,0 +1,114 @@
+package reviews
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+	reviewsDao "uttc-hackathon-backend/dao/reviews"
+)
+
+const (
+	minRating = 1
+	maxRating = 5
+	// maxCommentLength はコメントの最大文字数
+	maxCommentLength = 1000
+)
+
+var (
+	// ErrInvalidReview は評価やコメントが不正な場合のエラー
+	ErrInvalidReview = errors.New("invalid review")
+	// ErrItemNotFound は商品が存在しない場合のエラー
+	ErrItemNotFound = errors.New("item not found")
+	// ErrNotCompleted は取引が完了していない商品をレビューしようとした場合のエラー
+	ErrNotCompleted = errors.New("transaction is not completed")
+	// ErrNotParticipant は取引の当事者以外がレビューしようとした場合のエラー
+	ErrNotParticipant = errors.New("only the buyer or seller can review this transaction")
+	// ErrAlreadyReviewed は同じ取引で既にレビュー済みの場合のエラー
+	ErrAlreadyReviewed = errors.New("already reviewed")
+)
+
+type ReviewUsecase struct {
+	reviewDao reviewsDao.ReviewDAOInterface
+}
+
+func NewReviewUsecase(dao reviewsDao.ReviewDAOInterface) *ReviewUsecase {
+	return &ReviewUsecase{reviewDao: dao}
+}
+
+// CreateReview は完了した取引の相手を評価する
+// 出品者は購入者を、購入者は出品者を、それぞれ1回だけ評価できる
+func (u *ReviewUsecase) CreateReview(itemID int, reviewerUID string, rating int, comment string) (*reviewsDao.Review, error) {
+	if reviewerUID == "" {
+		return nil, fmt.Errorf("%w: reviewer_uid is required", ErrInvalidReview)
+	}
+	if rating < minRating || rating > maxRating {
+		return nil, fmt.Errorf("%w: rating must be between %d and %d", ErrInvalidReview, minRating, maxRating)
+	}
+	comment = strings.TrimSpace(comment)
+	if utf8.RuneCountInString(comment) > maxCommentLength {
+		return nil, fmt.Errorf("%w: comment must be at most %d characters", ErrInvalidReview, maxCommentLength)
+	}
+
+	tx, err := u.reviewDao.GetTransaction(itemID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	if tx.Status != "completed" {
+		return nil, ErrNotCompleted
+	}
+
+	review := &reviewsDao.Review{
+		ItemID:      itemID,
+		ReviewerUID: reviewerUID,
+		Rating:      rating,
+		Comment:     comment,
+	}
+	switch {
+	case tx.BuyerUID == "":
+		// 購入者が特定できない取引は評価できない
+		return nil, ErrNotParticipant
+	case reviewerUID == tx.SellerUID:
+		review.Role = "seller"
+		review.RevieweeUID = tx.BuyerUID
+	case reviewerUID == tx.BuyerUID:
+		review.Role = "buyer"
+		review.RevieweeUID = tx.SellerUID
+	default:
+		return nil, ErrNotParticipant
+	}
+
+	if err := u.reviewDao.CreateReview(review); err != nil {
+		if errors.Is(err, reviewsDao.ErrDuplicateReview) {
+			return nil, ErrAlreadyReviewed
+		}
+		return nil, fmt.Errorf("failed to create review: %w", err)
+	}
+	return review, nil
+}
+
+// GetReviewsForUser はユーザーが受け取ったレビューと評価の集計を取得する
+func (u *ReviewUsecase) GetReviewsForUser(uid string, page, limit int) ([]*reviewsDao.Review, *reviewsDao.RatingSummary, error) {
+	reviews, err := u.reviewDao.GetReviewsByReviewee(uid, limit, (page-1)*limit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get reviews: %w", err)
+	}
+	summary, err := u.reviewDao.GetRatingSummary(uid)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get rating summary: %w", err)
+	}
+	return reviews, summary, nil
+}
+
+// GetReviewsForItem は取引に付いたレビューを取得する
+func (u *ReviewUsecase) GetReviewsForItem(itemID int) ([]*reviewsDao.Review, error) {
+	reviews, err := u.reviewDao.GetReviewsByItem(itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reviews: %w", err)
+	}
+	return reviews, nil
+}
@@ -0,0 +1,180 @@
+package reviews
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	reviewsDao "uttc-hackathon-backend/dao/reviews"
+)
+
+// MockReviewDAO はテスト用のモックDAO
+type MockReviewDAO struct {
+	transactions map[int]*reviewsDao.Transaction
+	reviews      []*reviewsDao.Review
+	createErr    error
+}
+
+func NewMockReviewDAO() *MockReviewDAO {
+	return &MockReviewDAO{transactions: make(map[int]*reviewsDao.Transaction)}
+}
+
+func (m *MockReviewDAO) GetTransaction(itemID int) (*reviewsDao.Transaction, error) {
+	tx, ok := m.transactions[itemID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return tx, nil
+}
+
+func (m *MockReviewDAO) CreateReview(review *reviewsDao.Review) error {
+	if m.createErr != nil {
+		return m.createErr
+	}
+	for _, r := range m.reviews {
+		if r.ItemID == review.ItemID && r.ReviewerUID == review.ReviewerUID {
+			return reviewsDao.ErrDuplicateReview
+		}
+	}
+	review.ID = len(m.reviews) + 1
+	m.reviews = append(m.reviews, review)
+	return nil
+}
+
+func (m *MockReviewDAO) GetReviewsByReviewee(uid string, limit, offset int) ([]*reviewsDao.Review, error) {
+	var result []*reviewsDao.Review
+	for _, r := range m.reviews {
+		if r.RevieweeUID == uid {
+			result = append(result, r)
+		}
+	}
+	if offset >= len(result) {
+		return []*reviewsDao.Review{}, nil
+	}
+	result = result[offset:]
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (m *MockReviewDAO) GetReviewsByItem(itemID int) ([]*reviewsDao.Review, error) {
+	var result []*reviewsDao.Review
+	for _, r := range m.reviews {
+		if r.ItemID == itemID {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockReviewDAO) GetRatingSummary(uid string) (*reviewsDao.RatingSummary, error) {
+	summary := &reviewsDao.RatingSummary{}
+	total := 0
+	for _, r := range m.reviews {
+		if r.RevieweeUID == uid {
+			total += r.Rating
+			summary.Count++
+		}
+	}
+	if summary.Count > 0 {
+		avg := float64(total) / float64(summary.Count)
+		summary.Average = &avg
+	}
+	return summary, nil
+}
+
+func newTestUsecase() (*ReviewUsecase, *MockReviewDAO) {
+	mockDAO := NewMockReviewDAO()
+	mockDAO.transactions[1] = &reviewsDao.Transaction{ItemID: 1, SellerUID: "seller", BuyerUID: "buyer", Status: "completed"}
+	mockDAO.transactions[2] = &reviewsDao.Transaction{ItemID: 2, SellerUID: "seller", BuyerUID: "buyer", Status: "purchased"}
+	mockDAO.transactions[3] = &reviewsDao.Transaction{ItemID: 3, SellerUID: "seller", Status: "completed"}
+	return NewReviewUsecase(mockDAO), mockDAO
+}
+
+// TestCreateReview_BothParties 購入者と出品者がそれぞれ相手を評価できる
+func TestCreateReview_BothParties(t *testing.T) {
+	usecase, _ := newTestUsecase()
+
+	byBuyer, err := usecase.CreateReview(1, "buyer", 5, "  丁寧な梱包でした  ")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if byBuyer.RevieweeUID != "seller" || byBuyer.Role != "buyer" || byBuyer.Comment != "丁寧な梱包でした" {
+		t.Errorf("unexpected review: %+v", byBuyer)
+	}
+
+	bySeller, err := usecase.CreateReview(1, "seller", 4, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if bySeller.RevieweeUID != "buyer" || bySeller.Role != "seller" {
+		t.Errorf("unexpected review: %+v", bySeller)
+	}
+
+	reviews, summary, err := usecase.GetReviewsForUser("seller", 1, 20)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(reviews) != 1 || summary.Count != 1 || summary.Average == nil || *summary.Average != 5 {
+		t.Errorf("unexpected reviews for seller: %d reviews, %+v", len(reviews), summary)
+	}
+}
+
+// TestCreateReview_Rejected 取引状態・当事者・入力値のチェック
+func TestCreateReview_Rejected(t *testing.T) {
+	tests := []struct {
+		name        string
+		itemID      int
+		reviewerUID string
+		rating      int
+		comment     string
+		wantErr     error
+	}{
+		{"rating too low", 1, "buyer", 0, "", ErrInvalidReview},
+		{"rating too high", 1, "buyer", 6, "", ErrInvalidReview},
+		{"comment too long", 1, "buyer", 3, strings.Repeat("あ", maxCommentLength+1), ErrInvalidReview},
+		{"item not found", 99, "buyer", 3, "", ErrItemNotFound},
+		{"not completed", 2, "buyer", 3, "", ErrNotCompleted},
+		{"not participant", 1, "stranger", 3, "", ErrNotParticipant},
+		{"buyer unknown", 3, "seller", 3, "", ErrNotParticipant},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase, mockDAO := newTestUsecase()
+			_, err := usecase.CreateReview(tt.itemID, tt.reviewerUID, tt.rating, tt.comment)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+			if len(mockDAO.reviews) != 0 {
+				t.Errorf("expected no review to be created, got %d", len(mockDAO.reviews))
+			}
+		})
+	}
+}
+
+// TestCreateReview_OncePerTransaction 同じ取引で2回目のレビューはできない
+func TestCreateReview_OncePerTransaction(t *testing.T) {
+	usecase, _ := newTestUsecase()
+
+	if _, err := usecase.CreateReview(1, "buyer", 5, ""); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_, err := usecase.CreateReview(1, "buyer", 1, "やっぱり")
+	if !errors.Is(err, ErrAlreadyReviewed) {
+		t.Errorf("expected ErrAlreadyReviewed, got %v", err)
+	}
+}
+
+// TestCreateReview_DAOError DAOのエラーはラップして返す
+func TestCreateReview_DAOError(t *testing.T) {
+	usecase, mockDAO := newTestUsecase()
+	mockDAO.createErr = fmt.Errorf("connection refused")
+
+	_, err := usecase.CreateReview(1, "buyer", 5, "")
+	if err == nil || !strings.Contains(err.Error(), "failed to create review") {
+		t.Errorf("expected wrapped error, got %v", err)
+	}
+}
@@ -0,0 +1,418 @@
+package shipping
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	auditDao "uttc-hackathon-backend/dao/audit"
+	messagesDao "uttc-hackathon-backend/dao/messages"
+	shippingDao "uttc-hackathon-backend/dao/shipping"
+	"uttc-hackathon-backend/secret"
+)
+
+const (
+	// maxAddresses は1ユーザーが登録できる住所の数
+	maxAddresses = 10
+	// maxFieldLength は住所の各項目の最大文字数
+	maxFieldLength = 100
+	// maxLabelLength は住所の表示名の最大文字数
+	maxLabelLength = 50
+)
+
+var (
+	// ErrInvalidInput は住所や追跡番号が不正な場合のエラー
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrAddressNotFound は住所が存在しない（他のユーザーの住所を含む）場合のエラー
+	ErrAddressNotFound = errors.New("address not found")
+	// ErrTooManyAddresses は登録できる住所の上限を超えた場合のエラー
+	ErrTooManyAddresses = errors.New("too many addresses")
+	// ErrPurchaseNotFound は商品が存在しないか、まだ購入されていない場合のエラー
+	ErrPurchaseNotFound = errors.New("purchase not found")
+	// ErrForbidden は取引の当事者以外が配送情報にアクセスした場合のエラー
+	ErrForbidden = errors.New("only the buyer or seller can access shipping details")
+	// ErrNotBuyer は購入者以外が配送先を指定しようとした場合のエラー
+	ErrNotBuyer = errors.New("only the buyer can set the shipping address")
+	// ErrNotSeller は出品者以外が追跡番号を登録しようとした場合のエラー
+	ErrNotSeller = errors.New("only the seller can set the tracking number")
+	// ErrNotInTransit は取引中（purchased）でない商品の配送情報を変更しようとした場合のエラー
+	ErrNotInTransit = errors.New("shipping details can only be changed while the transaction is in progress")
+	// ErrAlreadyShipped は発送後に配送先を変更しようとした場合のエラー
+	ErrAlreadyShipped = errors.New("item has already been shipped")
+	// ErrAddressRequired は配送先が指定される前に発送しようとした場合のエラー
+	ErrAddressRequired = errors.New("buyer has not set a shipping address yet")
+)
+
+var (
+	postalCodePattern = regexp.MustCompile(`^[0-9]{3}-?[0-9]{4}$`)
+	phonePattern      = regexp.MustCompile(`^[0-9+\-]{10,15}$`)
+	trackingPattern   = regexp.MustCompile(`^[0-9A-Za-z\-]{4,50}$`)
+)
+
+// AddressDetails は暗号化して保存する住所の本体
+type AddressDetails struct {
+	RecipientName string `json:"recipient_name"`
+	PostalCode    string `json:"postal_code"`
+	Prefecture    string `json:"prefecture"`
+	City          string `json:"city"`
+	Line1         string `json:"line1"`
+	Line2         string `json:"line2"`
+	Phone         string `json:"phone"`
+}
+
+// AddressInput は住所の登録・更新で受け取る値
+type AddressInput struct {
+	Label     string `json:"label"`
+	IsDefault bool   `json:"is_default"`
+	AddressDetails
+}
+
+// Address は本人に返す住所
+type Address struct {
+	ID        int64     `json:"id"`
+	Label     string    `json:"label"`
+	IsDefault bool      `json:"is_default"`
+	CreatedAt time.Time `json:"created_at"`
+	AddressDetails
+}
+
+// Shipment は取引の当事者に返す配送情報
+type Shipment struct {
+	ItemID          int             `json:"item_id"`
+	Role            string          `json:"role"` // buyer または seller
+	ItemStatus      string          `json:"item_status"`
+	ShippingAddress *AddressDetails `json:"shipping_address"` // 未指定の場合はnull
+	Carrier         string          `json:"carrier"`
+	TrackingNumber  string          `json:"tracking_number"`
+	ShippedAt       *time.Time      `json:"shipped_at"`
+}
+
+// Timeline は発送を購入者と出品者のメッセージスレッドにシステムメッセージとして書き込む（messages.MessageUsecase）
+type Timeline interface {
+	PostSystemMessage(itemID int, fromUID, toUID, event string) error
+}
+
+type ShippingUsecase struct {
+	shippingDao shippingDao.ShippingDAOInterface
+	box         *secret.Box
+	timeline    Timeline
+}
+
+func NewShippingUsecase(dao shippingDao.ShippingDAOInterface, box *secret.Box, timeline Timeline) *ShippingUsecase {
+	return &ShippingUsecase{shippingDao: dao, box: box, timeline: timeline}
+}
+
+// ListAddresses は本人の住所を返す
+func (u *ShippingUsecase) ListAddresses(uid string) ([]*Address, error) {
+	rows, err := u.shippingDao.ListAddresses(uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses: %w", err)
+	}
+	addresses := make([]*Address, 0, len(rows))
+	for _, row := range rows {
+		address, err := u.decryptAddress(row)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// CreateAddress は住所を暗号化して登録する（最初の住所は自動的にデフォルトになる）
+func (u *ShippingUsecase) CreateAddress(uid string, input AddressInput) (*Address, error) {
+	if uid == "" {
+		return nil, fmt.Errorf("%w: uid is required", ErrInvalidInput)
+	}
+	if err := normalizeAddress(&input); err != nil {
+		return nil, err
+	}
+	existing, err := u.shippingDao.ListAddresses(uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses: %w", err)
+	}
+	if len(existing) >= maxAddresses {
+		return nil, ErrTooManyAddresses
+	}
+
+	encrypted, err := u.seal(input.AddressDetails, addressAAD(uid))
+	if err != nil {
+		return nil, err
+	}
+	row := &shippingDao.Address{
+		UID:       uid,
+		Label:     input.Label,
+		Encrypted: encrypted,
+		IsDefault: input.IsDefault || len(existing) == 0,
+	}
+	id, err := u.shippingDao.CreateAddress(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create address: %w", err)
+	}
+	return u.getOwnAddress(uid, id)
+}
+
+// UpdateAddress は本人の住所を更新する
+func (u *ShippingUsecase) UpdateAddress(uid string, id int64, input AddressInput) (*Address, error) {
+	row, err := u.getOwnAddressRow(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := normalizeAddress(&input); err != nil {
+		return nil, err
+	}
+	encrypted, err := u.seal(input.AddressDetails, addressAAD(uid))
+	if err != nil {
+		return nil, err
+	}
+	row.Label = input.Label
+	row.Encrypted = encrypted
+	// デフォルトを外すのは別の住所をデフォルトにしたときだけ
+	row.IsDefault = row.IsDefault || input.IsDefault
+	if err := u.shippingDao.UpdateAddress(row); err != nil {
+		return nil, fmt.Errorf("failed to update address: %w", err)
+	}
+	return u.getOwnAddress(uid, id)
+}
+
+// DeleteAddress は本人の住所を削除する
+func (u *ShippingUsecase) DeleteAddress(uid string, id int64) error {
+	if _, err := u.getOwnAddressRow(uid, id); err != nil {
+		return err
+	}
+	if err := u.shippingDao.DeleteAddress(id); err != nil {
+		return fmt.Errorf("failed to delete address: %w", err)
+	}
+	return nil
+}
+
+// GetShipment は取引の当事者に配送情報を返す
+// 配送先の住所は出品者（と指定した購入者本人）にだけ見える
+func (u *ShippingUsecase) GetShipment(itemID int, uid string) (*Shipment, error) {
+	shipment, role, err := u.loadShipment(itemID, uid)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, ErrForbidden
+	}
+	return u.toView(shipment, role)
+}
+
+// AttachAddress は購入者が登録済みの住所を購入の配送先に指定する
+// 購入時点の住所をコピーして保存するため、あとで住所を編集・削除しても配送先は変わらない
+func (u *ShippingUsecase) AttachAddress(itemID int, uid string, addressID int64) (*Shipment, error) {
+	shipment, role, err := u.loadShipment(itemID, uid)
+	if err != nil {
+		return nil, err
+	}
+	if role != "buyer" {
+		return nil, ErrNotBuyer
+	}
+	if shipment.ItemStatus != "purchased" {
+		return nil, ErrNotInTransit
+	}
+	if shipment.ShippedAt.Valid {
+		return nil, ErrAlreadyShipped
+	}
+
+	address, err := u.getOwnAddress(uid, addressID)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := u.seal(address.AddressDetails, purchaseAAD(shipment.PurchaseID))
+	if err != nil {
+		return nil, err
+	}
+	if err := u.shippingDao.AttachAddress(shipment, encrypted, auditDao.UserActor(uid)); err != nil {
+		return nil, fmt.Errorf("failed to attach address: %w", err)
+	}
+	return u.GetShipment(itemID, uid)
+}
+
+// SetTracking は出品者が配送業者と追跡番号を登録する（発送後の修正も可能）
+func (u *ShippingUsecase) SetTracking(itemID int, uid string, carrier, trackingNumber string) (*Shipment, error) {
+	carrier = strings.TrimSpace(carrier)
+	trackingNumber = strings.TrimSpace(trackingNumber)
+	if carrier == "" || utf8.RuneCountInString(carrier) > 50 {
+		return nil, fmt.Errorf("%w: carrier is required (max 50 characters)", ErrInvalidInput)
+	}
+	if !trackingPattern.MatchString(trackingNumber) {
+		return nil, fmt.Errorf("%w: tracking_number must be 4-50 letters, digits or hyphens", ErrInvalidInput)
+	}
+
+	shipment, role, err := u.loadShipment(itemID, uid)
+	if err != nil {
+		return nil, err
+	}
+	if role != "seller" {
+		return nil, ErrNotSeller
+	}
+	if shipment.ItemStatus != "purchased" {
+		return nil, ErrNotInTransit
+	}
+	if shipment.EncryptedAddress == nil {
+		return nil, ErrAddressRequired
+	}
+	if err := u.shippingDao.SetTracking(shipment, carrier, trackingNumber, auditDao.UserActor(uid)); err != nil {
+		return nil, fmt.Errorf("failed to set tracking number: %w", err)
+	}
+	// 追跡番号の修正ではなく初めて発送したときだけスレッドに書き込む（失敗してもログに残すだけ）
+	if !shipment.ShippedAt.Valid && u.timeline != nil {
+		if err := u.timeline.PostSystemMessage(itemID, shipment.SellerUID, shipment.BuyerUID, messagesDao.SystemEventShipped); err != nil {
+			log.Printf("WARNING: failed to post shipped message for item_id=%d: %v", itemID, err)
+		}
+	}
+	return u.GetShipment(itemID, uid)
+}
+
+// loadShipment は購入記録とuidの立場（buyer / seller / 当事者でなければ空）を返す
+func (u *ShippingUsecase) loadShipment(itemID int, uid string) (*shippingDao.Shipment, string, error) {
+	if uid == "" {
+		return nil, "", fmt.Errorf("%w: uid is required", ErrInvalidInput)
+	}
+	shipment, err := u.shippingDao.GetShipment(itemID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", ErrPurchaseNotFound
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get shipment: %w", err)
+	}
+	switch uid {
+	case shipment.SellerUID:
+		return shipment, "seller", nil
+	case shipment.BuyerUID:
+		return shipment, "buyer", nil
+	}
+	return shipment, "", nil
+}
+
+func (u *ShippingUsecase) toView(shipment *shippingDao.Shipment, role string) (*Shipment, error) {
+	view := &Shipment{
+		ItemID:         shipment.ItemID,
+		Role:           role,
+		ItemStatus:     shipment.ItemStatus,
+		Carrier:        shipment.Carrier,
+		TrackingNumber: shipment.TrackingNumber,
+	}
+	if shipment.ShippedAt.Valid {
+		view.ShippedAt = &shipment.ShippedAt.Time
+	}
+	if shipment.EncryptedAddress != nil {
+		var details AddressDetails
+		if err := u.open(shipment.EncryptedAddress, purchaseAAD(shipment.PurchaseID), &details); err != nil {
+			return nil, err
+		}
+		view.ShippingAddress = &details
+	}
+	return view, nil
+}
+
+func (u *ShippingUsecase) getOwnAddressRow(uid string, id int64) (*shippingDao.Address, error) {
+	row, err := u.shippingDao.GetAddress(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAddressNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get address: %w", err)
+	}
+	// 他のユーザーの住所は存在しないものとして扱う
+	if row.UID != uid {
+		return nil, ErrAddressNotFound
+	}
+	return row, nil
+}
+
+func (u *ShippingUsecase) getOwnAddress(uid string, id int64) (*Address, error) {
+	row, err := u.getOwnAddressRow(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	return u.decryptAddress(row)
+}
+
+func (u *ShippingUsecase) decryptAddress(row *shippingDao.Address) (*Address, error) {
+	address := &Address{
+		ID:        row.ID,
+		Label:     row.Label,
+		IsDefault: row.IsDefault,
+		CreatedAt: row.CreatedAt,
+	}
+	if err := u.open(row.Encrypted, addressAAD(row.UID), &address.AddressDetails); err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+func (u *ShippingUsecase) seal(details AddressDetails, aad string) ([]byte, error) {
+	plaintext, err := json.Marshal(details)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode address: %w", err)
+	}
+	encrypted, err := u.box.Seal(plaintext, []byte(aad))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt address: %w", err)
+	}
+	return encrypted, nil
+}
+
+func (u *ShippingUsecase) open(encrypted []byte, aad string, details *AddressDetails) error {
+	plaintext, err := u.box.Open(encrypted, []byte(aad))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt address: %w", err)
+	}
+	if err := json.Unmarshal(plaintext, details); err != nil {
+		return fmt.Errorf("failed to decode address: %w", err)
+	}
+	return nil
+}
+
+// addressAAD / purchaseAAD は暗号文を保存先の行に結びつける（別の行にコピーしても復号できない）
+func addressAAD(uid string) string {
+	return "shipping_address:" + uid
+}
+
+func purchaseAAD(purchaseID int64) string {
+	return "purchase:" + strconv.FormatInt(purchaseID, 10)
+}
+
+// normalizeAddress は前後の空白を除いて検証する
+func normalizeAddress(input *AddressInput) error {
+	fields := []struct {
+		name     string
+		value    *string
+		required bool
+		max      int
+	}{
+		{"label", &input.Label, false, maxLabelLength},
+		{"recipient_name", &input.RecipientName, true, maxFieldLength},
+		{"postal_code", &input.PostalCode, true, maxFieldLength},
+		{"prefecture", &input.Prefecture, true, maxFieldLength},
+		{"city", &input.City, true, maxFieldLength},
+		{"line1", &input.Line1, true, maxFieldLength},
+		{"line2", &input.Line2, false, maxFieldLength},
+		{"phone", &input.Phone, true, maxFieldLength},
+	}
+	for _, f := range fields {
+		*f.value = strings.TrimSpace(*f.value)
+		if f.required && *f.value == "" {
+			return fmt.Errorf("%w: %s is required", ErrInvalidInput, f.name)
+		}
+		if utf8.RuneCountInString(*f.value) > f.max {
+			return fmt.Errorf("%w: %s must be at most %d characters", ErrInvalidInput, f.name, f.max)
+		}
+	}
+	if !postalCodePattern.MatchString(input.PostalCode) {
+		return fmt.Errorf("%w: postal_code must be like 123-4567", ErrInvalidInput)
+	}
+	if !phonePattern.MatchString(input.Phone) {
+		return fmt.Errorf("%w: phone must be 10-15 digits", ErrInvalidInput)
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package shipping
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	auditDao "uttc-hackathon-backend/dao/audit"
+	shippingDao "uttc-hackathon-backend/dao/shipping"
+	"uttc-hackathon-backend/secret"
+)
+
+// MockShippingDAO はテスト用のモックDAO
+type MockShippingDAO struct {
+	addresses map[int64]*shippingDao.Address
+	shipments map[int]*shippingDao.Shipment
+	nextID    int64
+	actions   []string
+}
+
+func NewMockShippingDAO() *MockShippingDAO {
+	return &MockShippingDAO{
+		addresses: make(map[int64]*shippingDao.Address),
+		shipments: make(map[int]*shippingDao.Shipment),
+	}
+}
+
+func (m *MockShippingDAO) ListAddresses(uid string) ([]*shippingDao.Address, error) {
+	var result []*shippingDao.Address
+	for id := int64(1); id <= m.nextID; id++ {
+		if a, ok := m.addresses[id]; ok && a.UID == uid {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockShippingDAO) GetAddress(id int64) (*shippingDao.Address, error) {
+	a, ok := m.addresses[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *a
+	return &copied, nil
+}
+
+func (m *MockShippingDAO) CreateAddress(address *shippingDao.Address) (int64, error) {
+	m.nextID++
+	copied := *address
+	copied.ID = m.nextID
+	m.addresses[copied.ID] = &copied
+	return copied.ID, nil
+}
+
+func (m *MockShippingDAO) UpdateAddress(address *shippingDao.Address) error {
+	copied := *address
+	m.addresses[address.ID] = &copied
+	return nil
+}
+
+func (m *MockShippingDAO) DeleteAddress(id int64) error {
+	delete(m.addresses, id)
+	return nil
+}
+
+func (m *MockShippingDAO) GetShipment(itemID int) (*shippingDao.Shipment, error) {
+	s, ok := m.shipments[itemID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *s
+	return &copied, nil
+}
+
+func (m *MockShippingDAO) AttachAddress(shipment *shippingDao.Shipment, encrypted []byte, actor auditDao.Actor) error {
+	m.shipments[shipment.ItemID].EncryptedAddress = encrypted
+	m.actions = append(m.actions, "attach:"+actor.UID)
+	return nil
+}
+
+func (m *MockShippingDAO) SetTracking(shipment *shippingDao.Shipment, carrier, trackingNumber string, actor auditDao.Actor) error {
+	s := m.shipments[shipment.ItemID]
+	s.Carrier = carrier
+	s.TrackingNumber = trackingNumber
+	s.ShippedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	m.actions = append(m.actions, "ship:"+actor.UID)
+	return nil
+}
+
+func newTestUsecase(t *testing.T) (*ShippingUsecase, *MockShippingDAO) {
+	t.Helper()
+	box, err := secret.NewBox(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatalf("failed to create box: %v", err)
+	}
+	mockDAO := NewMockShippingDAO()
+	mockDAO.shipments[1] = &shippingDao.Shipment{PurchaseID: 10, ItemID: 1, SellerUID: "seller", BuyerUID: "buyer", ItemStatus: "purchased"}
+	return NewShippingUsecase(mockDAO, box, nil), mockDAO
+}
+
+func validInput() AddressInput {
+	return AddressInput{
+		Label: "自宅",
+		AddressDetails: AddressDetails{
+			RecipientName: "山田 太郎",
+			PostalCode:    "100-0001",
+			Prefecture:    "東京都",
+			City:          "千代田区",
+			Line1:         "千代田1-1",
+			Phone:         "090-1234-5678",
+		},
+	}
+}
+
+// TestCreateAddress_Encrypted 住所は暗号化して保存され、本人には復号して返す
+func TestCreateAddress_Encrypted(t *testing.T) {
+	usecase, mockDAO := newTestUsecase(t)
+
+	address, err := usecase.CreateAddress("buyer", validInput())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if address.City != "千代田区" || !address.IsDefault {
+		t.Errorf("unexpected address: %+v", address)
+	}
+	stored := mockDAO.addresses[address.ID]
+	if bytes.Contains(stored.Encrypted, []byte("千代田区")) {
+		t.Error("expected address to be encrypted at rest")
+	}
+
+	// 他のユーザーからは存在しないものとして扱う
+	if _, err := usecase.UpdateAddress("other", address.ID, validInput()); !errors.Is(err, ErrAddressNotFound) {
+		t.Errorf("expected ErrAddressNotFound, got %v", err)
+	}
+	if err := usecase.DeleteAddress("other", address.ID); !errors.Is(err, ErrAddressNotFound) {
+		t.Errorf("expected ErrAddressNotFound, got %v", err)
+	}
+}
+
+// TestCreateAddress_Invalid 必須項目や郵便番号の形式を検証する
+func TestCreateAddress_Invalid(t *testing.T) {
+	usecase, _ := newTestUsecase(t)
+
+	missing := validInput()
+	missing.City = " "
+	badPostal := validInput()
+	badPostal.PostalCode = "1000"
+	for name, input := range map[string]AddressInput{"missing city": missing, "bad postal code": badPostal} {
+		if _, err := usecase.CreateAddress("buyer", input); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: expected ErrInvalidInput, got %v", name, err)
+		}
+	}
+}
+
+// TestAttachAddress_Visibility 購入者が指定した配送先は出品者に見え、当事者以外は見られない
+func TestAttachAddress_Visibility(t *testing.T) {
+	usecase, mockDAO := newTestUsecase(t)
+	address, _ := usecase.CreateAddress("buyer", validInput())
+	others, _ := usecase.CreateAddress("seller", validInput())
+
+	if _, err := usecase.AttachAddress(1, "seller", others.ID); !errors.Is(err, ErrNotBuyer) {
+		t.Errorf("expected ErrNotBuyer, got %v", err)
+	}
+	if _, err := usecase.AttachAddress(1, "buyer", others.ID); !errors.Is(err, ErrAddressNotFound) {
+		t.Errorf("expected ErrAddressNotFound for another user's address, got %v", err)
+	}
+	if _, err := usecase.AttachAddress(1, "buyer", address.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// 元の住所を削除しても配送先は変わらない
+	if err := usecase.DeleteAddress("buyer", address.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	shipment, err := usecase.GetShipment(1, "seller")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if shipment.Role != "seller" || shipment.ShippingAddress == nil || shipment.ShippingAddress.RecipientName != "山田 太郎" {
+		t.Errorf("unexpected shipment: %+v", shipment)
+	}
+	if _, err := usecase.GetShipment(1, "stranger"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+	if len(mockDAO.actions) != 1 || mockDAO.actions[0] != "attach:buyer" {
+		t.Errorf("unexpected actions: %v", mockDAO.actions)
+	}
+}
+
+// TestSetTracking 出品者だけが配送先の指定後に追跡番号を登録でき、発送後は配送先を変更できない
+func TestSetTracking(t *testing.T) {
+	usecase, mockDAO := newTestUsecase(t)
+	address, _ := usecase.CreateAddress("buyer", validInput())
+
+	if _, err := usecase.SetTracking(1, "seller", "ヤマト運輸", "1234-5678-9012"); !errors.Is(err, ErrAddressRequired) {
+		t.Errorf("expected ErrAddressRequired, got %v", err)
+	}
+	if _, err := usecase.AttachAddress(1, "buyer", address.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := usecase.SetTracking(1, "buyer", "ヤマト運輸", "1234-5678-9012"); !errors.Is(err, ErrNotSeller) {
+		t.Errorf("expected ErrNotSeller, got %v", err)
+	}
+	if _, err := usecase.SetTracking(1, "seller", "ヤマト運輸", "12 34"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput, got %v", err)
+	}
+
+	shipment, err := usecase.SetTracking(1, "seller", "ヤマト運輸", "1234-5678-9012")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if shipment.TrackingNumber != "1234-5678-9012" || shipment.ShippedAt == nil {
+		t.Errorf("unexpected shipment: %+v", shipment)
+	}
+	if _, err := usecase.AttachAddress(1, "buyer", address.ID); !errors.Is(err, ErrAlreadyShipped) {
+		t.Errorf("expected ErrAlreadyShipped, got %v", err)
+	}
+
+	mockDAO.shipments[1].ItemStatus = "completed"
+	if _, err := usecase.SetTracking(1, "seller", "佐川急便", "9999-0000"); !errors.Is(err, ErrNotInTransit) {
+		t.Errorf("expected ErrNotInTransit, got %v", err)
+	}
+}
+
+// MockTimeline はテスト用のスレッドへの書き込み
+type MockTimeline struct {
+	posts []string
+}
+
+func (m *MockTimeline) PostSystemMessage(itemID int, fromUID, toUID, event string) error {
+	m.posts = append(m.posts, fromUID+"->"+toUID+":"+event)
+	return nil
+}
+
+// TestSetTracking_Timeline 初めて発送したときだけ出品者から購入者へ発送のシステムメッセージを書き込む
+func TestSetTracking_Timeline(t *testing.T) {
+	usecase, _ := newTestUsecase(t)
+	timeline := &MockTimeline{}
+	usecase.timeline = timeline
+	address, _ := usecase.CreateAddress("buyer", validInput())
+	_, _ = usecase.AttachAddress(1, "buyer", address.ID)
+
+	if _, err := usecase.SetTracking(1, "seller", "ヤマト運輸", "1234-5678-9012"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := usecase.SetTracking(1, "seller", "ヤマト運輸", "1234-5678-9013"); err != nil {
+		t.Fatalf("expected correction to succeed, got %v", err)
+	}
+	if len(timeline.posts) != 1 || timeline.posts[0] != "seller->buyer:shipped" {
+		t.Errorf("expected a single shipped message, got %v", timeline.posts)
+	}
+}
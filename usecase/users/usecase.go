@@ -0,0 +1,316 @@
+package users
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"time"
+	postItemsDao "uttc-hackathon-backend/dao/postItems"
+	usersDao "uttc-hackathon-backend/dao/users"
+	postItemsUc "uttc-hackathon-backend/usecase/postItems"
+)
+
+var (
+	// ErrUserNotFound は指定したuidのユーザーが登録されていない場合のエラー
+	ErrUserNotFound = errors.New("user not found")
+	// ErrActiveTransactions は取引中の商品やonchainの出品が残っていて退会できない場合のエラー
+	ErrActiveTransactions = errors.New("finish open transactions and cancel on-chain listings before deleting the account")
+)
+
+// PublicProfile は他のユーザーにも公開するプロフィール
+type PublicProfile struct {
+	UID                string                 `json:"uid"`
+	Nickname           string                 `json:"nickname"`
+	AvatarURL          string                 `json:"avatar_url"`
+	AvatarThumbnailURL string                 `json:"avatar_thumbnail_url"`
+	Bio                string                 `json:"bio"`
+	JoinedAt           time.Time              `json:"joined_at"`
+	Listings           usersDao.ListingCounts `json:"listings"`
+	Rating             usersDao.RatingSummary `json:"rating"`
+	FollowerCount      int                    `json:"follower_count"`
+	FollowingCount     int                    `json:"following_count"`
+	Deleted            bool                   `json:"deleted"`
+}
+
+// Profile は本人にだけ返すプロフィール（公開情報 + 個人情報）
+type Profile struct {
+	PublicProfile
+	Sex           string `json:"sex"`
+	Birthdate     string `json:"birthdate"`
+	WalletAddress string `json:"wallet_address"`
+}
+
+// DataExport は本人のデータ一式（GET /api/v1/users/me/export）
+type DataExport struct {
+	ExportedAt time.Time                  `json:"exported_at"`
+	Profile    *Profile                   `json:"profile"`
+	Listings   []*usersDao.ExportItem     `json:"listings"`
+	Purchases  []*usersDao.ExportPurchase `json:"purchases"`
+	Likes      []*usersDao.ExportLike     `json:"likes"`
+	Messages   []*usersDao.ExportMessage  `json:"messages"`
+}
+
+// UpdateProfileInput はPATCHで受け取るフィールド。省略されたフィールドは更新しない
+type UpdateProfileInput struct {
+	Nickname  *string `json:"nickname"`
+	Bio       *string `json:"bio"`
+	Sex       *string `json:"sex"`
+	Birthdate *string `json:"birthdate"`
+}
+
+// ImageUploader は画像を検証・加工して保存する（postItemsの画像アップロード処理を使う）
+type ImageUploader interface {
+	UploadImage(file multipart.File, fileHeader *multipart.FileHeader) (*postItemsDao.ItemImage, error)
+}
+
+type UserUsecase struct {
+	userDao  usersDao.UserDAOInterface
+	uploader ImageUploader
+	now      func() time.Time
+}
+
+func NewUserUsecase(dao usersDao.UserDAOInterface, uploader ImageUploader) *UserUsecase {
+	return &UserUsecase{userDao: dao, uploader: uploader, now: time.Now}
+}
+
+// GetMyProfile は本人のプロフィールを取得する（退会済みの場合はErrUserNotFound）
+func (u *UserUsecase) GetMyProfile(uid string) (*Profile, error) {
+	user, public, err := u.loadProfile(uid)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt.Valid {
+		return nil, ErrUserNotFound
+	}
+	return &Profile{
+		PublicProfile: *public,
+		Sex:           user.Sex,
+		Birthdate:     formatDate(user.Birthdate),
+		WalletAddress: user.WalletAddress,
+	}, nil
+}
+
+// GetPublicProfile は他のユーザーに公開するプロフィールを取得する
+func (u *UserUsecase) GetPublicProfile(uid string) (*PublicProfile, error) {
+	_, public, err := u.loadProfile(uid)
+	if err != nil {
+		return nil, err
+	}
+	return public, nil
+}
+
+// UpdateMyProfile は指定されたフィールドを検証して更新し、更新後のプロフィールを返す
+// 検証エラーは *ValidationError でフィールドごとに返す
+func (u *UserUsecase) UpdateMyProfile(uid string, input UpdateProfileInput) (*Profile, error) {
+	if _, err := u.getActiveUser(uid); err != nil {
+		return nil, err
+	}
+
+	update := &usersDao.ProfileUpdate{}
+	verr := &ValidationError{}
+	if input.Nickname != nil {
+		nickname, err := ValidateNickname(*input.Nickname)
+		if err != nil {
+			verr.add("nickname", err.Error())
+		}
+		update.Nickname = &nickname
+	}
+	if input.Bio != nil {
+		bio, err := ValidateBio(*input.Bio)
+		if err != nil {
+			verr.add("bio", err.Error())
+		}
+		update.Bio = &bio
+	}
+	if input.Sex != nil {
+		sex, err := ValidateSex(*input.Sex)
+		if err != nil {
+			verr.add("sex", err.Error())
+		}
+		update.Sex = &sex
+	}
+	if input.Birthdate != nil {
+		birthdate, err := ParseBirthdate(*input.Birthdate, u.now())
+		if err != nil {
+			verr.add("birthdate", err.Error())
+		}
+		update.Birthdate = &birthdate
+	}
+	if err := verr.errOrNil(); err != nil {
+		return nil, err
+	}
+
+	if err := u.userDao.UpdateProfile(uid, update); err != nil {
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+	return u.GetMyProfile(uid)
+}
+
+// UploadAvatar はアバター画像を商品画像と同じ処理（検証・EXIF除去・リサイズ）で保存して設定する
+func (u *UserUsecase) UploadAvatar(uid string, file multipart.File, fileHeader *multipart.FileHeader) (*Profile, error) {
+	if _, err := u.getActiveUser(uid); err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, &ValidationError{Fields: map[string]string{"image": "image file is required"}}
+	}
+
+	image, err := u.uploader.UploadImage(file, fileHeader)
+	if errors.Is(err, postItemsUc.ErrInvalidImage) {
+		return nil, &ValidationError{Fields: map[string]string{"image": err.Error()}}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload avatar: %w", err)
+	}
+
+	// プロフィールでは中サイズ、一覧や出品者表示ではサムネイルを使う
+	avatar := &usersDao.Avatar{
+		URL:          image.MediumURL,
+		ThumbnailURL: image.ThumbnailURL,
+		Hash:         image.Hash,
+	}
+	if avatar.URL == "" {
+		avatar.URL = image.URL
+	}
+	if err := u.userDao.UpdateAvatar(uid, avatar); err != nil {
+		return nil, fmt.Errorf("failed to update avatar: %w", err)
+	}
+	return u.GetMyProfile(uid)
+}
+
+// DeleteAvatar はアバター画像を削除する（画像自体は参照がなくなればGCで削除される）
+func (u *UserUsecase) DeleteAvatar(uid string) (*Profile, error) {
+	if _, err := u.getActiveUser(uid); err != nil {
+		return nil, err
+	}
+	if err := u.userDao.UpdateAvatar(uid, nil); err != nil {
+		return nil, fmt.Errorf("failed to delete avatar: %w", err)
+	}
+	return u.GetMyProfile(uid)
+}
+
+// ExportMyData は本人のプロフィール・出品・購入・いいね・メッセージをまとめて返す
+func (u *UserUsecase) ExportMyData(uid string) (*DataExport, error) {
+	profile, err := u.GetMyProfile(uid)
+	if err != nil {
+		return nil, err
+	}
+	data, err := u.userDao.GetExportData(uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export data: %w", err)
+	}
+	return &DataExport{
+		ExportedAt: u.now(),
+		Profile:    profile,
+		Listings:   data.Listings,
+		Purchases:  data.Purchases,
+		Likes:      data.Likes,
+		Messages:   data.Messages,
+	}, nil
+}
+
+// WriteExportZip はエクスポートを種類ごとのJSONファイルにしてZIPで書き出す
+func WriteExportZip(w io.Writer, export *DataExport) error {
+	files := []struct {
+		name string
+		v    interface{}
+	}{
+		{"profile.json", map[string]interface{}{"exported_at": export.ExportedAt, "profile": export.Profile}},
+		{"listings.json", export.Listings},
+		{"purchases.json", export.Purchases},
+		{"likes.json", export.Likes},
+		{"messages.json", export.Messages},
+	}
+
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", f.name, err)
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.v); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+	return zw.Close()
+}
+
+// DeleteMyAccount は退会処理を行う（個人情報を消去し、取引の記録は匿名化して残す）
+// 取引中の商品やonchainの出品が残っている場合は取引相手に影響するためErrActiveTransactions
+func (u *UserUsecase) DeleteMyAccount(uid string) error {
+	if _, err := u.getActiveUser(uid); err != nil {
+		return err
+	}
+	count, err := u.userDao.CountBlockingTransactions(uid)
+	if err != nil {
+		return fmt.Errorf("failed to check transactions: %w", err)
+	}
+	if count > 0 {
+		return ErrActiveTransactions
+	}
+	if err := u.userDao.DeleteAccount(uid); err != nil {
+		return fmt.Errorf("failed to delete account: %w", err)
+	}
+	return nil
+}
+
+// getActiveUser は退会済みのユーザーを存在しないものとして扱う
+func (u *UserUsecase) getActiveUser(uid string) (*usersDao.User, error) {
+	user, err := u.getUser(uid)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt.Valid {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (u *UserUsecase) getUser(uid string) (*usersDao.User, error) {
+	user, err := u.userDao.GetUserByUID(uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+func (u *UserUsecase) loadProfile(uid string) (*usersDao.User, *PublicProfile, error) {
+	user, err := u.getUser(uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	counts, err := u.userDao.GetListingCounts(uid)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get listing counts: %w", err)
+	}
+	rating, err := u.userDao.GetRatingSummary(uid)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get rating summary: %w", err)
+	}
+	follows, err := u.userDao.GetFollowCounts(uid)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get follow counts: %w", err)
+	}
+	return user, &PublicProfile{
+		UID:                user.UID,
+		Nickname:           user.Nickname,
+		AvatarURL:          user.AvatarURL,
+		AvatarThumbnailURL: user.AvatarThumbnailURL,
+		Bio:                user.Bio,
+		JoinedAt:           user.CreatedAt,
+		Listings:           *counts,
+		Rating:             *rating,
+		FollowerCount:      follows.Followers,
+		FollowingCount:     follows.Following,
+		Deleted:            user.DeletedAt.Valid,
+	}, nil
+}
@@ -0,0 +1,438 @@
+package users
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"strings"
+	"testing"
+	"time"
+
+	postItemsDao "uttc-hackathon-backend/dao/postItems"
+	usersDao "uttc-hackathon-backend/dao/users"
+	postItemsUc "uttc-hackathon-backend/usecase/postItems"
+)
+
+// MockUserDAO はテスト用のモックDAO
+type MockUserDAO struct {
+	users     map[string]*usersDao.User
+	counts    map[string]*usersDao.ListingCounts
+	ratings   map[string]*usersDao.RatingSummary
+	followers map[string]int
+	following map[string]int
+	updateErr error
+	updates   int
+	exports   map[string]*usersDao.ExportData
+	blocking  map[string]int
+	deleted   []string
+}
+
+func NewMockUserDAO() *MockUserDAO {
+	return &MockUserDAO{
+		users:     make(map[string]*usersDao.User),
+		counts:    make(map[string]*usersDao.ListingCounts),
+		ratings:   make(map[string]*usersDao.RatingSummary),
+		followers: make(map[string]int),
+		following: make(map[string]int),
+		exports:   make(map[string]*usersDao.ExportData),
+		blocking:  make(map[string]int),
+	}
+}
+
+func (m *MockUserDAO) GetUserByUID(uid string) (*usersDao.User, error) {
+	user, ok := m.users[uid]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (m *MockUserDAO) GetListingCounts(uid string) (*usersDao.ListingCounts, error) {
+	if counts, ok := m.counts[uid]; ok {
+		return counts, nil
+	}
+	return &usersDao.ListingCounts{}, nil
+}
+
+func (m *MockUserDAO) GetRatingSummary(uid string) (*usersDao.RatingSummary, error) {
+	if summary, ok := m.ratings[uid]; ok {
+		return summary, nil
+	}
+	return &usersDao.RatingSummary{}, nil
+}
+
+func (m *MockUserDAO) GetFollowCounts(uid string) (*usersDao.FollowCounts, error) {
+	return &usersDao.FollowCounts{Followers: m.followers[uid], Following: m.following[uid]}, nil
+}
+
+func (m *MockUserDAO) UpdateProfile(uid string, update *usersDao.ProfileUpdate) error {
+	if m.updateErr != nil {
+		return m.updateErr
+	}
+	m.updates++
+	user := m.users[uid]
+	if update.Nickname != nil {
+		user.Nickname = *update.Nickname
+	}
+	if update.Bio != nil {
+		user.Bio = *update.Bio
+	}
+	if update.Sex != nil {
+		user.Sex = *update.Sex
+	}
+	if update.Birthdate != nil {
+		user.Birthdate = *update.Birthdate
+	}
+	return nil
+}
+
+func (m *MockUserDAO) UpdateAvatar(uid string, avatar *usersDao.Avatar) error {
+	user := m.users[uid]
+	if avatar == nil {
+		avatar = &usersDao.Avatar{}
+	}
+	user.AvatarURL = avatar.URL
+	user.AvatarThumbnailURL = avatar.ThumbnailURL
+	return nil
+}
+
+func (m *MockUserDAO) GetExportData(uid string) (*usersDao.ExportData, error) {
+	if data, ok := m.exports[uid]; ok {
+		return data, nil
+	}
+	return &usersDao.ExportData{}, nil
+}
+
+func (m *MockUserDAO) CountBlockingTransactions(uid string) (int, error) {
+	return m.blocking[uid], nil
+}
+
+func (m *MockUserDAO) DeleteAccount(uid string) error {
+	m.deleted = append(m.deleted, uid)
+	user := m.users[uid]
+	user.Nickname = usersDao.DeletedNickname
+	user.Sex = ""
+	user.Birthdate = sql.NullTime{}
+	user.WalletAddress = ""
+	user.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+// MockUploader はpostItemsの画像アップロードの代わりに固定のURLを返す
+type MockUploader struct {
+	err error
+}
+
+func (m *MockUploader) UploadImage(file multipart.File, fileHeader *multipart.FileHeader) (*postItemsDao.ItemImage, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &postItemsDao.ItemImage{
+		URL:          "uploads/abc_full.jpg",
+		MediumURL:    "uploads/abc_medium.jpg",
+		ThumbnailURL: "uploads/abc_thumb.jpg",
+		Hash:         "abc",
+	}, nil
+}
+
+// nopFile はmultipart.Fileを満たすテスト用のファイル
+type nopFile struct {
+	*bytes.Reader
+}
+
+func (nopFile) Close() error { return nil }
+
+func newTestUsecase() (*UserUsecase, *MockUserDAO) {
+	mockDAO := NewMockUserDAO()
+	joined := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	mockDAO.users["user-1"] = &usersDao.User{
+		UID:           "user-1",
+		Nickname:      "たろう",
+		Sex:           "male",
+		Birthdate:     sql.NullTime{Time: time.Date(2000, 12, 25, 0, 0, 0, 0, time.UTC), Valid: true},
+		WalletAddress: "0xabc",
+		CreatedAt:     joined,
+	}
+	mockDAO.counts["user-1"] = &usersDao.ListingCounts{Total: 3, Listed: 1, Purchased: 1, Completed: 1}
+	average := 4.5
+	mockDAO.ratings["user-1"] = &usersDao.RatingSummary{Average: &average, Count: 2}
+	mockDAO.followers["user-1"] = 10
+	mockDAO.following["user-1"] = 3
+	usecase := NewUserUsecase(mockDAO, &MockUploader{})
+	usecase.now = func() time.Time { return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC) }
+	return usecase, mockDAO
+}
+
+func strPtr(s string) *string { return &s }
+
+// TestGetMyProfile_Success 本人には個人情報を含むプロフィールを返す
+func TestGetMyProfile_Success(t *testing.T) {
+	usecase, _ := newTestUsecase()
+
+	profile, err := usecase.GetMyProfile("user-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if profile.Nickname != "たろう" || profile.Birthdate != "2000-12-25" || profile.WalletAddress != "0xabc" {
+		t.Errorf("unexpected profile: %+v", profile)
+	}
+	if profile.Listings.Total != 3 || profile.Listings.Completed != 1 {
+		t.Errorf("unexpected listing counts: %+v", profile.Listings)
+	}
+	if profile.Rating.Average == nil || *profile.Rating.Average != 4.5 || profile.Rating.Count != 2 {
+		t.Errorf("unexpected rating: %+v", profile.Rating)
+	}
+	if profile.FollowerCount != 10 || profile.FollowingCount != 3 {
+		t.Errorf("unexpected follow counts: %d followers, %d following", profile.FollowerCount, profile.FollowingCount)
+	}
+}
+
+// TestGetPublicProfile_NotFound 未登録のユーザーはErrUserNotFound
+func TestGetPublicProfile_NotFound(t *testing.T) {
+	usecase, _ := newTestUsecase()
+
+	_, err := usecase.GetPublicProfile("unknown")
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+// TestUpdateMyProfile_Partial 指定したフィールドだけ更新される
+func TestUpdateMyProfile_Partial(t *testing.T) {
+	usecase, mockDAO := newTestUsecase()
+
+	profile, err := usecase.UpdateMyProfile("user-1", UpdateProfileInput{Nickname: strPtr("  じろう  ")})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if profile.Nickname != "じろう" {
+		t.Errorf("expected trimmed nickname, got %q", profile.Nickname)
+	}
+	if profile.Sex != "male" || profile.Birthdate != "2000-12-25" {
+		t.Errorf("expected other fields to be unchanged, got %+v", profile)
+	}
+
+	// 空文字のbirthdateは未設定に戻す
+	profile, err = usecase.UpdateMyProfile("user-1", UpdateProfileInput{Birthdate: strPtr("")})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if profile.Birthdate != "" || mockDAO.users["user-1"].Birthdate.Valid {
+		t.Errorf("expected birthdate to be cleared, got %q", profile.Birthdate)
+	}
+}
+
+// TestUpdateMyProfile_Validation 不正なフィールドはまとめてフィールド単位で返し、更新しない
+func TestUpdateMyProfile_Validation(t *testing.T) {
+	usecase, mockDAO := newTestUsecase()
+
+	_, err := usecase.UpdateMyProfile("user-1", UpdateProfileInput{
+		Nickname:  strPtr("   "),
+		Sex:       strPtr(strings.Repeat("x", maxSexLength+1)),
+		Birthdate: strPtr("2000-02-30"),
+	})
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	for _, field := range []string{"nickname", "sex", "birthdate"} {
+		if verr.Fields[field] == "" {
+			t.Errorf("expected error for %s, got %+v", field, verr.Fields)
+		}
+	}
+	if mockDAO.updates != 0 {
+		t.Errorf("expected no update, got %d", mockDAO.updates)
+	}
+}
+
+// TestUpdateMyProfile_NotFound 未登録のユーザーは更新できない
+func TestUpdateMyProfile_NotFound(t *testing.T) {
+	usecase, _ := newTestUsecase()
+
+	_, err := usecase.UpdateMyProfile("unknown", UpdateProfileInput{Nickname: strPtr("a")})
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+// TestParseBirthdate 日付形式と範囲の検証
+func TestParseBirthdate(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input   string
+		valid   bool
+		wantErr bool
+	}{
+		{"", false, false},
+		{"2000-12-25", true, false},
+		{"1899-12-31", false, true},
+		{"2025-01-02", false, true},
+		{"2000/12/25", false, true},
+		{"2001-02-29", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseBirthdate(tt.input, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error=%v, got %v", tt.wantErr, err)
+			}
+			if got.Valid != tt.valid {
+				t.Errorf("expected valid=%v, got %v", tt.valid, got.Valid)
+			}
+		})
+	}
+}
+
+// TestLegacyBirthdate 旧形式（誕生年 + MMDD）からの変換
+func TestLegacyBirthdate(t *testing.T) {
+	if got := LegacyBirthdate(2000, 1225); got != "2000-12-25" {
+		t.Errorf("expected 2000-12-25, got %s", got)
+	}
+	if got := LegacyBirthdate(2000, 101); got != "2000-01-01" {
+		t.Errorf("expected 2000-01-01, got %s", got)
+	}
+	if got := LegacyBirthdate(2000, 0); got != "" {
+		t.Errorf("expected empty, got %s", got)
+	}
+}
+
+// TestUpdateMyProfile_Bio 自己紹介の更新と長さ制限
+func TestUpdateMyProfile_Bio(t *testing.T) {
+	usecase, _ := newTestUsecase()
+
+	profile, err := usecase.UpdateMyProfile("user-1", UpdateProfileInput{Bio: strPtr("よろしくお願いします\n古着を出品しています")})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(profile.Bio, "\n") {
+		t.Errorf("expected newline to be kept, got %q", profile.Bio)
+	}
+
+	_, err = usecase.UpdateMyProfile("user-1", UpdateProfileInput{Bio: strPtr(strings.Repeat("あ", maxBioLength+1))})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Fields["bio"] == "" {
+		t.Errorf("expected bio validation error, got %v", err)
+	}
+}
+
+// TestUploadAvatar_Success アップロードした画像の中サイズとサムネイルが設定される
+func TestUploadAvatar_Success(t *testing.T) {
+	usecase, _ := newTestUsecase()
+
+	profile, err := usecase.UploadAvatar("user-1", nopFile{bytes.NewReader([]byte("img"))}, &multipart.FileHeader{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if profile.AvatarURL != "uploads/abc_medium.jpg" || profile.AvatarThumbnailURL != "uploads/abc_thumb.jpg" {
+		t.Errorf("unexpected avatar urls: %+v", profile.PublicProfile)
+	}
+
+	profile, err = usecase.DeleteAvatar("user-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if profile.AvatarURL != "" || profile.AvatarThumbnailURL != "" {
+		t.Errorf("expected avatar to be cleared, got %+v", profile.PublicProfile)
+	}
+}
+
+// TestUploadAvatar_InvalidImage 不正な画像はimageフィールドの入力エラーになる
+func TestUploadAvatar_InvalidImage(t *testing.T) {
+	usecase, mockDAO := newTestUsecase()
+	usecase.uploader = &MockUploader{err: fmt.Errorf("file processing error: %w", postItemsUc.ErrUnsupportedImage)}
+
+	_, err := usecase.UploadAvatar("user-1", nopFile{bytes.NewReader([]byte("gif"))}, &multipart.FileHeader{})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Fields["image"] == "" {
+		t.Fatalf("expected image validation error, got %v", err)
+	}
+	if mockDAO.users["user-1"].AvatarURL != "" {
+		t.Error("expected avatar to be unchanged")
+	}
+
+	// ファイルなし
+	_, err = usecase.UploadAvatar("user-1", nil, nil)
+	if !errors.As(err, &verr) {
+		t.Errorf("expected validation error without file, got %v", err)
+	}
+}
+
+// TestExportMyData_Zip エクスポートにプロフィールと各データが含まれ、ZIPに種類ごとのファイルが入る
+func TestExportMyData_Zip(t *testing.T) {
+	usecase, mockDAO := newTestUsecase()
+	mockDAO.exports["user-1"] = &usersDao.ExportData{
+		Listings: []*usersDao.ExportItem{{ID: 1, Title: "本"}},
+		Messages: []*usersDao.ExportMessage{{ID: 5, SenderUID: "user-1", ReceiverUID: "user-2", Content: "こんにちは"}},
+	}
+
+	export, err := usecase.ExportMyData("user-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if export.Profile.WalletAddress != "0xabc" || len(export.Listings) != 1 || len(export.Messages) != 1 {
+		t.Errorf("unexpected export: %+v", export)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteExportZip(&buf, export); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if got := strings.Join(names, ","); got != "profile.json,listings.json,purchases.json,likes.json,messages.json" {
+		t.Errorf("unexpected files: %s", got)
+	}
+}
+
+// TestDeleteMyAccount 退会すると個人情報が消え、公開プロフィールは匿名で残る
+func TestDeleteMyAccount(t *testing.T) {
+	usecase, mockDAO := newTestUsecase()
+
+	if err := usecase.DeleteMyAccount("user-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	public, err := usecase.GetPublicProfile("user-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !public.Deleted || public.Nickname != usersDao.DeletedNickname {
+		t.Errorf("unexpected public profile: %+v", public)
+	}
+
+	// 退会後は本人向けのAPIは使えない
+	if _, err := usecase.GetMyProfile("user-1"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+	if _, err := usecase.ExportMyData("user-1"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+	if err := usecase.DeleteMyAccount("user-1"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+	if len(mockDAO.deleted) != 1 {
+		t.Errorf("expected 1 deletion, got %d", len(mockDAO.deleted))
+	}
+}
+
+// TestDeleteMyAccount_ActiveTransactions 取引中の商品があると退会できない
+func TestDeleteMyAccount_ActiveTransactions(t *testing.T) {
+	usecase, mockDAO := newTestUsecase()
+	mockDAO.blocking["user-1"] = 1
+
+	if err := usecase.DeleteMyAccount("user-1"); !errors.Is(err, ErrActiveTransactions) {
+		t.Errorf("expected ErrActiveTransactions, got %v", err)
+	}
+	if len(mockDAO.deleted) != 0 {
+		t.Error("expected account not to be deleted")
+	}
+}
@@ -0,0 +1,135 @@
+package users
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// dateLayout はAPIで受け渡す日付の形式
+	dateLayout = "2006-01-02"
+	// maxNicknameLength はニックネームの最大文字数
+	maxNicknameLength = 30
+	// maxSexLength は性別の最大文字数（users.sex VARCHAR(10)）
+	maxSexLength = 10
+	// maxBioLength は自己紹介の最大文字数
+	maxBioLength = 500
+)
+
+// minBirthdate はこれより前の生年月日を受け付けない
+var minBirthdate = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ValidationError はフィールドごとの入力エラー（キーはJSONのフィールド名）
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	names := make([]string, 0, len(e.Fields))
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+": "+e.Fields[name])
+	}
+	return "validation failed: " + strings.Join(parts, ", ")
+}
+
+// add はフィールドのエラーを追加する（同じフィールドは最初のエラーを残す）
+func (e *ValidationError) add(field, message string) {
+	if e.Fields == nil {
+		e.Fields = make(map[string]string)
+	}
+	if _, ok := e.Fields[field]; !ok {
+		e.Fields[field] = message
+	}
+}
+
+// errOrNil はエラーが1つもなければnilを返す
+func (e *ValidationError) errOrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// ValidateNickname は前後の空白を除いたニックネームを検証して返す
+func ValidateNickname(nickname string) (string, error) {
+	nickname = strings.TrimSpace(nickname)
+	if nickname == "" {
+		return "", fmt.Errorf("nickname is required")
+	}
+	if utf8.RuneCountInString(nickname) > maxNicknameLength {
+		return "", fmt.Errorf("nickname must be at most %d characters", maxNicknameLength)
+	}
+	for _, r := range nickname {
+		if unicode.IsControl(r) {
+			return "", fmt.Errorf("nickname must not contain control characters")
+		}
+	}
+	return nickname, nil
+}
+
+// ValidateSex は性別を検証して返す（空文字は未設定）
+func ValidateSex(sex string) (string, error) {
+	sex = strings.TrimSpace(sex)
+	if utf8.RuneCountInString(sex) > maxSexLength {
+		return "", fmt.Errorf("sex must be at most %d characters", maxSexLength)
+	}
+	return sex, nil
+}
+
+// ValidateBio は自己紹介を検証して返す（空文字は未設定、改行は可）
+func ValidateBio(bio string) (string, error) {
+	bio = strings.TrimSpace(bio)
+	if utf8.RuneCountInString(bio) > maxBioLength {
+		return "", fmt.Errorf("bio must be at most %d characters", maxBioLength)
+	}
+	for _, r := range bio {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return "", fmt.Errorf("bio must not contain control characters")
+		}
+	}
+	return bio, nil
+}
+
+// ParseBirthdate は "YYYY-MM-DD" 形式の生年月日を検証して返す（空文字は未設定）
+func ParseBirthdate(s string, now time.Time) (sql.NullTime, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return sql.NullTime{}, nil
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return sql.NullTime{}, fmt.Errorf("birthdate must be a valid date in YYYY-MM-DD format")
+	}
+	if t.Before(minBirthdate) || t.After(now) {
+		return sql.NullTime{}, fmt.Errorf("birthdate must be between %s and today", minBirthdate.Format(dateLayout))
+	}
+	return sql.NullTime{Time: t, Valid: true}, nil
+}
+
+// LegacyBirthdate は旧形式（誕生年 + MMDDの数値）の誕生日を "YYYY-MM-DD" に変換する
+// 旧クライアントは birthdate を 1225 のような数値で送っていた。0は未設定
+func LegacyBirthdate(birthyear int, mmdd int) string {
+	if mmdd == 0 {
+		return ""
+	}
+	// 不正な組み合わせはParseBirthdateで弾かれるよう、そのまま文字列にする
+	return fmt.Sprintf("%04d-%02d-%02d", birthyear, mmdd/100, mmdd%100)
+}
+
+// formatDate はDATE列を "YYYY-MM-DD" にする（NULLは空文字）
+func formatDate(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.Format(dateLayout)
+}